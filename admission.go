@@ -0,0 +1,143 @@
+package dbresolver
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOverloaded is matched by the AdmissionError of the queries rejected by the admission control
+var ErrOverloaded = errors.New("dbresolver: overloaded")
+
+// Reasons of the admission errors
+const (
+	AdmissionQueueFull    = "queue full"
+	AdmissionQueueTimeout = "queue timeout"
+)
+
+// AdmissionError is returned for a query rejected by the admission control, it matches ErrOverloaded
+type AdmissionError struct {
+	// Reason is AdmissionQueueFull or AdmissionQueueTimeout
+	Reason string
+	// Inflight and Queued are the queries in flight and in the queue when the query was rejected
+	Inflight int
+	Queued   int
+}
+
+func (e *AdmissionError) Error() string {
+	return fmt.Sprintf("dbresolver: query rejected, %s (%d in flight, %d queued)", e.Reason, e.Inflight, e.Queued)
+}
+
+// Unwrap returns ErrOverloaded
+func (e *AdmissionError) Unwrap() error {
+	return ErrOverloaded
+}
+
+// AdmissionControl define the resolver-wide limit of the queries in flight
+type AdmissionControl struct {
+	// MaxInflight is the number of queries running concurrently across the nodes
+	MaxInflight int
+	// MaxQueue is the number of queries waiting for a slot, the queries beyond are rejected. Zero queues none.
+	MaxQueue int
+	// QueueTimeout is how long a query waits in the queue before it's rejected, until its context is done when zero
+	QueueTimeout time.Duration
+}
+
+// WithAdmissionControl bounds the queries in flight across every node of the resolver. The queries beyond
+// the limit wait in a bounded FIFO queue, and are rejected with an AdmissionError when the queue is full
+// or after the queue timeout. It sheds the load during the traffic spikes instead of letting every pool
+// pile up its own waiters.
+//
+// A slot is held until Exec returns, until the rows of Query are closed, or until the row of QueryRow is scanned,
+// like WithWorkloadPartitions.
+// The transactions, the connections and the prepared statements aren't limited.
+func WithAdmissionControl(config AdmissionControl) OptionFunc {
+	if config.MaxInflight < 1 || config.MaxQueue < 0 {
+		panic(fmt.Sprintf("dbresolver: invalid admission control %+v", config))
+	}
+	return func(opt *Option) {
+		opt.AdmissionControl = &config
+	}
+}
+
+// admissionWaiter is a queued query, ready is closed when the slot is handed over
+type admissionWaiter struct {
+	ready chan struct{}
+}
+
+// admission is the resolver-wide semaphore of the queries, with its FIFO queue
+type admission struct {
+	config AdmissionControl
+	clock  Clock
+
+	mu       sync.Mutex
+	inflight int
+	queue    list.List
+}
+
+func newAdmission(config AdmissionControl, clock Clock) *admission {
+	return &admission{config: config, clock: clock}
+}
+
+// acquire takes a slot, or waits for a slot in the queue. A released slot is handed over to the first waiter.
+func (a *admission) acquire(ctx context.Context) (release func(), err error) {
+	if a == nil {
+		return func() {}, nil
+	}
+	a.mu.Lock()
+	if a.inflight < a.config.MaxInflight && a.queue.Len() == 0 {
+		a.inflight++
+		a.mu.Unlock()
+		return a.release, nil
+	}
+	if a.queue.Len() >= a.config.MaxQueue {
+		err := &AdmissionError{Reason: AdmissionQueueFull, Inflight: a.inflight, Queued: a.queue.Len()}
+		a.mu.Unlock()
+		return nil, err
+	}
+	waiter := &admissionWaiter{ready: make(chan struct{})}
+	elem := a.queue.PushBack(waiter)
+	a.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if a.config.QueueTimeout > 0 {
+		timer := a.clock.NewTimer(a.config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C()
+	}
+	select {
+	case <-waiter.ready:
+		return a.release, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-waiter.ready:
+		// the slot was handed over meanwhile
+		return a.release, nil
+	default:
+	}
+	a.queue.Remove(elem)
+	if err == nil {
+		err = &AdmissionError{Reason: AdmissionQueueTimeout, Inflight: a.inflight, Queued: a.queue.Len()}
+	}
+	return nil, err
+}
+
+// release hands the slot over to the first waiter, or frees it
+func (a *admission) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if front := a.queue.Front(); front != nil {
+		close(a.queue.Remove(front).(*admissionWaiter).ready)
+		return
+	}
+	a.inflight--
+}
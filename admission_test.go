@@ -0,0 +1,166 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAdmissionQueue(t *testing.T) {
+	a := newAdmission(AdmissionControl{MaxInflight: 1, MaxQueue: 2}, systemClock{})
+	release, err := a.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the waiters are admitted in order
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			release, err := a.acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- i
+			release()
+		}(i)
+		for queued(a) != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// the queue is full
+	_, err = a.acquire(context.Background())
+	var admissionErr *AdmissionError
+	if !errors.Is(err, ErrOverloaded) || !errors.As(err, &admissionErr) || admissionErr.Reason != AdmissionQueueFull ||
+		admissionErr.Inflight != 1 || admissionErr.Queued != 2 {
+		t.Fatalf("want the queue full error, got %v", err)
+	}
+
+	release()
+	if first, second := <-order, <-order; first != 0 || second != 1 {
+		t.Errorf("want the waiters admitted in FIFO order, got %d then %d", first, second)
+	}
+	// the last waiter releases its slot after it's recorded
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		a.mu.Lock()
+		inflight, queued := a.inflight, a.queue.Len()
+		a.mu.Unlock()
+		if inflight == 0 && queued == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want every slot released, %d in flight, %d queued", inflight, queued)
+		}
+	}
+}
+
+func queued(a *admission) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.queue.Len()
+}
+
+func inflight(a *admission) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.inflight
+}
+
+func TestAdmissionTimeout(t *testing.T) {
+	a := newAdmission(AdmissionControl{MaxInflight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond}, systemClock{})
+	release, err := a.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	_, err = a.acquire(context.Background())
+	var admissionErr *AdmissionError
+	if !errors.As(err, &admissionErr) || admissionErr.Reason != AdmissionQueueTimeout {
+		t.Errorf("want the queue timeout error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("want the context error, got %v", err)
+	}
+	if queued(a) != 0 {
+		t.Errorf("want the rejected waiters dequeued, %d queued", queued(a))
+	}
+}
+
+func TestAdmissionControl(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithAdmissionControl(AdmissionControl{MaxInflight: 1})).(*sqlDB)
+
+	release, err := resolver.admission.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolver.ExecContext(context.Background(), "DELETE FROM book"); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("want the query rejected, got %v", err)
+	}
+	if err := resolver.QueryRowContext(context.Background(), "SELECT 1").Scan(new(int)); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("want the row holding the admission error, got %v", err)
+	}
+
+	release()
+	primaryMock.ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.ExecContext(context.Background(), "DELETE FROM book"); err != nil {
+		t.Errorf("want the query admitted, got %v", err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAdmissionControlRows(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithAdmissionControl(AdmissionControl{MaxInflight: 1})).(*sqlDB)
+
+	// the open rows hold the admission slot
+	primaryMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	rows, err := resolver.Query("SELECT id FROM book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolver.Query("SELECT id FROM book"); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("want the query rejected while the rows are open, got %v", err)
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	primaryMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	var id int
+	if err := resolver.QueryRow("SELECT id FROM book").Scan(&id); err != nil || id != 2 {
+		t.Fatalf("want the query admitted once the rows are closed, got %d, %v", id, err)
+	}
+	if n := inflight(resolver.admission); n != 0 {
+		t.Errorf("want the slot released by the Scan, got %d in flight", n)
+	}
+}
+
+func TestAdmissionControlInvalid(t *testing.T) {
+	for _, config := range []AdmissionControl{{}, {MaxInflight: 1, MaxQueue: -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want a panic for %+v", config)
+				}
+			}()
+			WithAdmissionControl(config)
+		}()
+	}
+}
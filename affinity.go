@@ -0,0 +1,126 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"math"
+	"strconv"
+)
+
+type affinityKey struct{}
+
+// WithAffinityKey tags the context with the affinity key of its reads, eg. the user or tenant ID,
+// the reads of a key go to the same replica with AffinityLB, see AffinityLoadBalancer
+func WithAffinityKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, affinityKey{}, key)
+}
+
+// AffinityKeyFromContext returns the affinity key of the context, see WithAffinityKey
+func AffinityKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(affinityKey{}).(string)
+	return key, ok
+}
+
+// keyResolver is satisfied by the load balancers resolving the connection of an affinity key
+// among a rotation, the connections repeated by weight. id returns the stable ID of a connection,
+// its position among the distinct connections when nil.
+type keyResolver[T DBConnection] interface {
+	resolveKey(rotation []T, key string, id func(T) string) T
+}
+
+// AffinityLoadBalancer represent for Affinity LB policy.
+// It resolves the reads of the same affinity key, see WithAffinityKey, to the same replica with the weighted
+// rendezvous hashing, so each replica caches the data of its keys. The keys are spread by the weights of the replicas,
+// a replica added to the rotation only takes keys from the others, and a replica leaving the rotation, eg. unhealthy,
+// only moves its own keys. The replicas are identified by their NodeNameLabel, by their position otherwise:
+// name them to keep the keys of the others when a replica is removed. The reads without key are resolved round robin.
+// It must not be copied after first use.
+type AffinityLoadBalancer[T DBConnection] struct {
+	roundRobin RoundRobinLoadBalancer[T]
+}
+
+// Name return the LB policy name
+func (lb *AffinityLoadBalancer[T]) Name() LoadBalancerPolicy {
+	return AffinityLB
+}
+
+// Resolve return the resolved option for the reads without affinity key, round robin
+func (lb *AffinityLoadBalancer[T]) Resolve(dbs []T) T {
+	return lb.roundRobin.Resolve(dbs)
+}
+
+func (lb *AffinityLoadBalancer[T]) resolveKey(rotation []T, key string, id func(T) string) T {
+	return rendezvous(rotation, key, id)
+}
+
+func (lb *AffinityLoadBalancer[T]) peek(n int) int {
+	return lb.roundRobin.peek(n)
+}
+
+func (lb *AffinityLoadBalancer[T]) predict(n int) int {
+	return lb.roundRobin.predict(n)
+}
+
+// rendezvous returns the connection of the key among the rotation with the weighted rendezvous hashing:
+// each distinct connection scores the key with the hash of its ID and the key, scaled by its weight,
+// the number of times it appears in the rotation, and the highest score wins
+func rendezvous[T DBConnection](rotation []T, key string, id func(T) string) T {
+	nodes, weights := weightedNodes(rotation)
+	best, bestScore := 0, math.Inf(-1)
+	for i, node := range nodes {
+		nodeID := strconv.Itoa(i)
+		if id != nil {
+			nodeID = id(node)
+		}
+		// like HashShardResolver, scaled by the weight: with u the hash as a uniform draw in (0, 1),
+		// the score -w/ln(u) is the highest for each node with a probability proportional to its weight
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(nodeID))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(key))
+		u := (float64(mix64(h.Sum64())>>11) + 0.5) / (1 << 53)
+		if score := -float64(weights[i]) / math.Log(u); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return nodes[best]
+}
+
+// weightedNodes returns the distinct connections of the rotation, with the number of times they appear in it
+func weightedNodes[T DBConnection](rotation []T) ([]T, []int) {
+	index := make(map[any]int, len(rotation))
+	nodes := make([]T, 0, len(rotation))
+	weights := make([]int, 0, len(rotation))
+	for _, node := range rotation {
+		if i, ok := index[node]; ok {
+			weights[i]++
+			continue
+		}
+		index[node] = len(nodes)
+		nodes = append(nodes, node)
+		weights = append(weights, 1)
+	}
+	return nodes, weights
+}
+
+// resolveRead resolves the node of a read among the nodes, the node of its affinity key with AffinityLB
+func (db *sqlDB) resolveRead(ctx context.Context, nodes []*sql.DB) *sql.DB {
+	if db.affinity != nil && len(nodes) > 1 {
+		if key, ok := AffinityKeyFromContext(ctx); ok {
+			return db.affinity.resolveKey(nodes, key, db.affinityID())
+		}
+	}
+	return resolve(db.loadBalancer, nodes)
+}
+
+// affinityID returns the ID of the replicas for the rendezvous hashing, their name, their position otherwise
+func (db *sqlDB) affinityID() func(node *sql.DB) string {
+	set := db.nodes.Load()
+	return func(node *sql.DB) string {
+		if name := db.nodeName(node); name != "" {
+			return "name:" + name
+		}
+		return strconv.Itoa(indexOf(set.replicas, node))
+	}
+}
@@ -0,0 +1,103 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestRendezvous(t *testing.T) {
+	nodes := make([]*sql.DB, 5)
+	names := map[*sql.DB]string{}
+	for i := range nodes {
+		nodes[i] = sql.OpenDB(unreachableConnector{})
+		names[nodes[i]] = fmt.Sprintf("replica-%d", i)
+	}
+	id := func(node *sql.DB) string { return names[node] }
+	// the weights 1, 1 and 2
+	rotation := []*sql.DB{nodes[0], nodes[2], nodes[1], nodes[2]}
+
+	// the keys are spread by weight
+	const keys = 10000
+	owners := make([]*sql.DB, keys)
+	counts := map[*sql.DB]int{}
+	for i := range owners {
+		key := fmt.Sprintf("user-%d", i)
+		owners[i] = rendezvous(rotation, key, id)
+		if owners[i] != rendezvous(rotation, key, id) {
+			t.Fatalf("want the same node for the key %q", key)
+		}
+		counts[owners[i]]++
+	}
+	for i, want := range []int{2500, 2500, 5000} {
+		if got := counts[nodes[i]]; got < want*9/10 || got > want*11/10 {
+			t.Errorf("want about %d keys on the node %d, got %d", want, i, got)
+		}
+	}
+
+	// a replica of weight 2 added only takes its share of the keys, 2 out of 6, from the others
+	grown := append(rotation[:len(rotation):len(rotation)], nodes[3], nodes[3])
+	moved := 0
+	for i, owner := range owners {
+		key := fmt.Sprintf("user-%d", i)
+		if node := rendezvous(grown, key, id); node != owner {
+			if node != nodes[3] {
+				t.Fatalf("want the key %q on its node or on the new node, got %s", key, names[node])
+			}
+			moved++
+		}
+	}
+	if moved < keys/3*9/10 || moved > keys/3*11/10 {
+		t.Errorf("want about %d keys moved to the new replica, got %d", keys/3, moved)
+	}
+
+	// a replica leaving the rotation only moves its own keys
+	shrunk := []*sql.DB{nodes[0], nodes[2], nodes[2]}
+	for i, owner := range owners {
+		key := fmt.Sprintf("user-%d", i)
+		if node := rendezvous(shrunk, key, id); owner != nodes[1] && node != owner {
+			t.Fatalf("want the key %q kept on %s, got %s", key, names[owner], names[node])
+		}
+	}
+}
+
+func TestAffinityLoadBalancer(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replicas := make([]*sql.DB, 3)
+	for i := range replicas {
+		if replicas[i], _, err = createMock(); err != nil {
+			t.Fatal("creating of mock failed")
+		}
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicas...), WithLoadBalancer(AffinityLB)).(*sqlDB)
+
+	// the reads of a key stick to a replica
+	ctx := WithAffinityKey(context.Background(), "tenant-42")
+	first, _ := resolver.readOnly(ctx)
+	for i := 0; i < 10; i++ {
+		if node, _ := resolver.readOnly(ctx); node != first {
+			t.Fatalf("want the reads of the key on the same replica, got %p and %p", first, node)
+		}
+	}
+	decision, err := resolver.ExplainRoute(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Node != first {
+		t.Errorf("want the replica of the key explained, got %+v", decision)
+	}
+
+	// the reads without key are round robin
+	seen := map[*sql.DB]bool{}
+	for i := 0; i < 3; i++ {
+		node, _ := resolver.readOnly(context.Background())
+		seen[node] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("want the reads without key on every replica, got %d replicas", len(seen))
+	}
+}
@@ -0,0 +1,252 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Topology is a desired topology of the resolver, see DB.ApplyTopology
+type Topology struct {
+	Nodes []TopologyNode
+}
+
+// TopologyNode is a desired node, identified by its name
+type TopologyNode struct {
+	Name string
+	Role Role
+	// Weight is the weight of the node, 1 by default, see WithPrimaryWeights and WithReplicaWeights
+	Weight int
+	// Labels are the labels of the node, besides its name
+	Labels map[string]string
+	// Open opens the node when it's added
+	Open func(ctx context.Context) (*sql.DB, error)
+}
+
+// TopologyChangeType is the type of a TopologyChange
+type TopologyChangeType string
+
+// Supported topology changes
+const (
+	NodeAdded       TopologyChangeType = "ADDED"
+	NodeRemoved     TopologyChangeType = "REMOVED"
+	NodeRoleChanged TopologyChangeType = "ROLE_CHANGED"
+	NodeRelabeled   TopologyChangeType = "RELABELED"
+	NodeReweighted  TopologyChangeType = "REWEIGHTED"
+)
+
+// TopologyChange is a change between the current and the desired topology
+type TopologyChange struct {
+	Type TopologyChangeType
+	Name string
+	// Current is the node before the change, nil for an added node
+	Current *NodeInfo
+	// Desired is the node after the change, nil for a removed node
+	Desired *TopologyNode
+}
+
+// DiffTopology returns the changes between the current and the desired topology,
+// the named nodes missing from the desired topology are removed and the unnamed nodes are left untouched.
+// The changes of a node are ordered as role, labels and weight.
+func (db *sqlDB) DiffTopology(desired Topology) ([]TopologyChange, error) {
+	if err := desired.validate(); err != nil {
+		return nil, err
+	}
+
+	current := map[string]NodeInfo{}
+	var names []string
+	for _, node := range db.Nodes() {
+		if name := node.Name(); name != "" {
+			if _, ok := current[name]; !ok {
+				names = append(names, name)
+			}
+			current[name] = node
+		}
+	}
+
+	var changes []TopologyChange
+	wanted := make(map[string]struct{}, len(desired.Nodes))
+	for i := range desired.Nodes {
+		node := &desired.Nodes[i]
+		wanted[node.Name] = struct{}{}
+		cur, ok := current[node.Name]
+		if !ok {
+			changes = append(changes, TopologyChange{Type: NodeAdded, Name: node.Name, Desired: node})
+			continue
+		}
+		change := func(typ TopologyChangeType) {
+			changes = append(changes, TopologyChange{Type: typ, Name: node.Name, Current: &cur, Desired: node})
+		}
+		if cur.Role != node.Role {
+			change(NodeRoleChanged)
+		}
+		if !sameLabels(cur.Labels, node.labels()) {
+			change(NodeRelabeled)
+		}
+		if cur.Weight != node.weight() {
+			change(NodeReweighted)
+		}
+	}
+	for _, name := range names {
+		if _, ok := wanted[name]; !ok {
+			cur := current[name]
+			changes = append(changes, TopologyChange{Type: NodeRemoved, Name: name, Current: &cur})
+		}
+	}
+	return changes, nil
+}
+
+// ApplyTopology applies the changes between the current and the desired topology, see DiffTopology.
+// The added nodes are opened first, nothing is changed when one of them can't be opened.
+// Then the nodes are added before being removed, so the primaries are replaced without downtime,
+// and the removed nodes are closed. The applied changes are returned.
+func (db *sqlDB) ApplyTopology(ctx context.Context, desired Topology) (_ []TopologyChange, err error) {
+	changes, err := db.DiffTopology(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	opened := map[string]*sql.DB{}
+	for _, change := range changes {
+		if change.Type != NodeAdded {
+			continue
+		}
+		node, err := change.Desired.Open(ctx)
+		if err != nil {
+			for _, d := range opened {
+				err = errors.Join(err, d.Close())
+			}
+			return nil, fmt.Errorf("dbresolver: opening node %s: %w", change.Name, err)
+		}
+		opened[change.Name] = node
+	}
+
+	// add first, so the last primary can be replaced
+	for _, change := range changes {
+		switch change.Type {
+		case NodeAdded:
+			node := opened[change.Name]
+			db.setLabels(node, change.Desired.labels())
+			db.setWeight(node, change.Desired.Role, change.Desired.weight())
+			db.addNode(node, change.Desired.Role)
+		case NodeRoleChanged:
+			db.setWeight(change.Current.DB, change.Desired.Role, change.Desired.weight())
+			db.addNode(change.Current.DB, change.Desired.Role)
+		case NodeRelabeled:
+			db.setLabels(change.Current.DB, change.Desired.labels())
+		case NodeReweighted:
+			db.setWeight(change.Current.DB, change.Desired.Role, change.Desired.weight())
+		}
+	}
+
+	var closing []*sql.DB
+	for _, change := range changes {
+		switch change.Type {
+		case NodeRoleChanged:
+			err = db.removeNode(change.Current.DB, change.Current.Role)
+		case NodeRemoved:
+			if err = db.removeNode(change.Current.DB, change.Current.Role); err == nil {
+				closing = append(closing, change.Current.DB)
+			}
+		}
+		if err != nil {
+			return changes, fmt.Errorf("dbresolver: removing node %s: %w", change.Name, err)
+		}
+	}
+	for _, node := range closing {
+		db.setLabels(node, nil)
+		db.setWeight(node, RolePrimary, 1)
+		db.setWeight(node, RoleReplica, 1)
+		err = errors.Join(err, node.Close())
+	}
+	return changes, err
+}
+
+func (t Topology) validate() error {
+	names := make(map[string]struct{}, len(t.Nodes))
+	primaries := 0
+	for i, node := range t.Nodes {
+		if node.Name == "" {
+			return fmt.Errorf("dbresolver: topology node %d: missing name", i)
+		}
+		if _, ok := names[node.Name]; ok {
+			return fmt.Errorf("dbresolver: topology node %d: duplicate name %s", i, node.Name)
+		}
+		names[node.Name] = struct{}{}
+		switch node.Role {
+		case RolePrimary:
+			primaries++
+		case RoleReplica:
+		default:
+			return fmt.Errorf("dbresolver: topology node %s: unknown role %q", node.Name, node.Role)
+		}
+		if node.Weight < 0 || node.Weight > MaxNodeWeight {
+			return fmt.Errorf("dbresolver: topology node %s: invalid weight %d", node.Name, node.Weight)
+		}
+		if node.Open == nil {
+			return fmt.Errorf("dbresolver: topology node %s: missing open function", node.Name)
+		}
+	}
+	if primaries == 0 {
+		return errors.New("dbresolver: the topology requires at least one primary")
+	}
+	return nil
+}
+
+// labels returns the labels of the node with its name
+func (n *TopologyNode) labels() map[string]string {
+	return mergeLabels(n.Labels, map[string]string{NodeNameLabel: n.Name})
+}
+
+func (n *TopologyNode) weight() int {
+	if n.Role != RoleReplica {
+		return 1
+	}
+	return max(n.Weight, 1)
+}
+
+func sameLabels(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (db *sqlDB) addNode(node *sql.DB, role Role) {
+	if role == RolePrimary {
+		db.AddPrimary(node)
+	} else {
+		db.AddReplica(node)
+	}
+}
+
+func (db *sqlDB) removeNode(node *sql.DB, role Role) error {
+	if role == RolePrimary {
+		return db.RemovePrimary(node)
+	}
+	db.RemoveReplica(node)
+	return nil
+}
+
+// setWeight sets the weight of the node in the role, the weights are copied on write
+func (db *sqlDB) setWeight(node *sql.DB, role Role, weight int) {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+
+	set := *db.nodes.Load()
+	weights := &set.replicaWeights
+	if role == RolePrimary {
+		weights = &set.primaryWeights
+	}
+	if nodeWeight(*weights, node) == weight {
+		return
+	}
+	*weights = withWeight(*weights, node, weight)
+	db.storeNodes(set)
+}
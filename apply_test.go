@@ -0,0 +1,116 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestApplyTopology(t *testing.T) {
+	mocks := map[string]sqlmock.Sqlmock{}
+	dbs := map[string]*sql.DB{}
+	node := func(name string, role Role, weight int, labels map[string]string) TopologyNode {
+		return TopologyNode{Name: name, Role: role, Weight: weight, Labels: labels,
+			Open: func(ctx context.Context) (*sql.DB, error) {
+				db, mock, err := createMock()
+				if err != nil {
+					return nil, err
+				}
+				mocks[name], dbs[name] = mock, db
+				return db, nil
+			}}
+	}
+	open := func(name string) *sql.DB {
+		db, err := node(name, "", 0, nil).Open(context.Background())
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+		return db
+	}
+
+	unnamed := open("unnamed")
+	resolver := New(
+		WithPrimaryDBs(open("primary-1"), unnamed),
+		WithReplicaDBs(open("replica-1"), open("replica-2")),
+		WithNodeName(dbs["primary-1"], "primary-1"),
+		WithNodeName(dbs["replica-1"], "replica-1"),
+		WithNodeLabels(dbs["replica-2"], map[string]string{NodeNameLabel: "replica-2", "zone": "a"}),
+	)
+	mocks["primary-1"].ExpectClose()
+
+	// primary-1 is replaced by replica-1, replica-2 moves to the zone b with a weight of 2
+	desired := Topology{Nodes: []TopologyNode{
+		node("replica-1", RolePrimary, 0, nil),
+		node("replica-2", RoleReplica, 2, map[string]string{"zone": "b"}),
+		node("replica-3", RoleReplica, 0, nil),
+	}}
+	changes, err := resolver.ApplyTopology(context.Background(), desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[ROLE_CHANGED replica-1 RELABELED replica-2 REWEIGHTED replica-2 ADDED replica-3 REMOVED primary-1]"
+	var got []string
+	for _, change := range changes {
+		got = append(got, string(change.Type), change.Name)
+	}
+	if fmt.Sprint(got) != want {
+		t.Errorf("want %s, got %v", want, got)
+	}
+
+	nodes := resolver.Nodes()
+	if len(nodes) != 4 ||
+		nodes[0].DB != unnamed ||
+		nodes[1].Name() != "replica-1" || nodes[1].Role != RolePrimary ||
+		nodes[2].Name() != "replica-2" || nodes[2].Weight != 2 || nodes[2].Labels["zone"] != "b" ||
+		nodes[3].Name() != "replica-3" || nodes[3].Role != RoleReplica {
+		t.Errorf("unexpected topology %+v", nodes)
+	}
+	if err := mocks["primary-1"].ExpectationsWereMet(); err != nil {
+		t.Errorf("want the removed node closed: %s", err)
+	}
+
+	changes, err = resolver.DiffTopology(desired)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("want no change, got %+v %v", changes, err)
+	}
+}
+
+func TestApplyTopologyOpenError(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	added, addedMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	addedMock.ExpectClose()
+
+	resolver := New(WithPrimaryDBs(primary), WithNodeName(primary, "primary"))
+	_, err = resolver.ApplyTopology(context.Background(), Topology{Nodes: []TopologyNode{
+		{Name: "primary", Role: RolePrimary, Open: func(context.Context) (*sql.DB, error) { return primary, nil }},
+		{Name: "added", Role: RoleReplica, Open: func(context.Context) (*sql.DB, error) { return added, nil }},
+		{Name: "broken", Role: RoleReplica, Open: func(context.Context) (*sql.DB, error) {
+			return nil, errors.New("connection refused")
+		}},
+	}})
+	if err == nil {
+		t.Fatal("want the open error")
+	}
+	if len(resolver.ReplicaDBs()) != 0 {
+		t.Errorf("want the topology unchanged, got %v", resolver.ReplicaDBs())
+	}
+	if err := addedMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("want the opened node closed: %s", err)
+	}
+
+	if _, err := resolver.DiffTopology(Topology{Nodes: []TopologyNode{
+		{Name: "replica", Role: RoleReplica, Open: func(context.Context) (*sql.DB, error) { return added, nil }},
+	}}); err == nil {
+		t.Error("want error without primary")
+	}
+}
@@ -0,0 +1,126 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncStmt is a prepared statement whose replica statements are prepared in the background, see PrepareAsync
+type AsyncStmt interface {
+	Stmt
+	// Ready is closed once the replica statements are prepared, or failed to
+	Ready() <-chan struct{}
+	// Err returns the error preparing the replica statements once Ready is closed,
+	// the statement keeps running on the primaries then
+	Err() error
+}
+
+// PrepareAsync creates a prepared statement like PrepareContext, but returns as soon as the statement is prepared
+// on the primaries and prepares it on the replicas in the background, cutting the latency of Prepare
+// for the write-mostly statements on large replica fleets.
+// The reads of the statement run on the primaries until the replica statements are ready.
+// The background preparation ignores the cancellation of the context, closing the statement cancels it.
+func (db *sqlDB) PrepareAsync(ctx context.Context, query string) (AsyncStmt, error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db = shard
+	primaries, replicas := db.topology()
+	dbStmt := map[*sql.DB]*sql.Stmt{}
+	var dbStmtLock sync.Mutex
+	primaryStmts, err := db.prepareNodes(ctx, primaries, query, dbStmt, &dbStmtLock)
+	if err != nil {
+		return nil, err
+	}
+
+	// the replica statements aren't recorded into dbStmt: it's read without lock, and only the primaries begin a Tx
+	bgCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	pending := &pendingReplicas{done: make(chan struct{}), cancel: cancel}
+	go func() {
+		defer cancel()
+		defer close(pending.done)
+		var fallback *sql.Stmt
+		if len(primaryStmts) > 0 {
+			fallback = primaryStmts[0]
+		}
+		nodeStmts := map[*sql.DB]*sql.Stmt{}
+		stmts, err := db.prepareReplicas(bgCtx, replicas, query, fallback, nodeStmts, &sync.Mutex{})
+		if err != nil {
+			pending.err = err
+			closeReplicaStmts(stmts, fallback)
+			return
+		}
+		pending.nodeStmts = nodeStmts
+		pending.stmts.Store(&stmts)
+	}()
+
+	return &asyncStmt{stmt: &stmt{
+		loadBalancer: db.stmtLoadBalancer,
+		primaryStmts: db.weightedPrimaryStmts(primaries, primaryStmts),
+		dbStmt:       dbStmt,
+		writeFlag:    isReturning(query),
+		query:        query,
+		hooks:        db.hooks,
+		parallelism:  db.parallelism,
+		retry:        db.retry,
+		classifier:   db.classifier,
+		readMode:     &db.readMode,
+		pending:      pending,
+		health:       db.health,
+	}}, nil
+}
+
+// closeReplicaStmts closes the replica statements prepared before a failure, but the fallback
+func closeReplicaStmts(stmts []*sql.Stmt, fallback *sql.Stmt) {
+	for _, st := range stmts {
+		if st != nil && st != fallback {
+			_ = st.Close()
+		}
+	}
+}
+
+// pendingReplicas are the replica statements prepared in the background,
+// err is set before done is closed
+type pendingReplicas struct {
+	stmts atomic.Pointer[[]*sql.Stmt]
+	// nodeStmts are the replica statements of each replica, set before stmts is stored
+	nodeStmts map[*sql.DB]*sql.Stmt
+	done      chan struct{}
+	err       error
+	cancel    context.CancelFunc
+}
+
+// ready returns the replica statements, none until they're prepared
+func (p *pendingReplicas) ready() []*sql.Stmt {
+	if stmts := p.stmts.Load(); stmts != nil {
+		return *stmts
+	}
+	return nil
+}
+
+// wait cancels the preparation and returns the replica statements prepared
+func (p *pendingReplicas) wait() []*sql.Stmt {
+	p.cancel()
+	<-p.done
+	return p.ready()
+}
+
+type asyncStmt struct {
+	*stmt
+}
+
+func (s *asyncStmt) Ready() <-chan struct{} {
+	return s.pending.done
+}
+
+func (s *asyncStmt) Err() error {
+	select {
+	case <-s.pending.done:
+		return s.pending.err
+	default:
+		return nil
+	}
+}
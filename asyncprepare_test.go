@@ -0,0 +1,103 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPrepareAsync(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica))
+
+	query := "SELECT title FROM book WHERE id = ?"
+	primaryPrepared := primaryMock.ExpectPrepare(query)
+	replicaPrepared := replicaMock.ExpectPrepare(query).WillDelayFor(100 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	st, err := resolver.PrepareAsync(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the replica preparation outlives the context of the call
+	cancel()
+
+	// the reads run on the primary until the replica statement is ready
+	primaryPrepared.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Dune"))
+	var title string
+	if err := st.QueryRow(1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+
+	<-st.Ready()
+	if err := st.Err(); err != nil {
+		t.Fatal(err)
+	}
+	replicaPrepared.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Emma"))
+	if err := st.QueryRow(2).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+
+	primaryPrepared.WillBeClosed()
+	replicaPrepared.WillBeClosed()
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestPrepareAsyncReplicaError(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica))
+
+	query := "SELECT title FROM book WHERE id = ?"
+	primaryPrepared := primaryMock.ExpectPrepare(query)
+	replicaMock.ExpectPrepare(query).WillReturnError(errors.New("syntax error"))
+
+	st, err := resolver.PrepareAsync(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-st.Ready()
+	if err := st.Err(); err == nil || err.Error() != "syntax error" {
+		t.Errorf("want the replica prepare error, got %v", err)
+	}
+
+	// the statement keeps running on the primary
+	primaryPrepared.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Dune"))
+	var title string
+	if err := st.QueryRow(1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+
+	primaryPrepared.WillBeClosed()
+	if err := st.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package aurora
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+const defaultRefreshInterval = 30 * time.Second
+
+// Config define how the cluster topology is monitored
+type Config struct {
+	Engine Engine
+	// Open opens the connection to an instance of the cluster,
+	// eg. using the instance endpoint <instance-id>.<cluster-id>.<region>.rds.amazonaws.com
+	Open func(instanceID string) (*sql.DB, error)
+	// RefreshInterval is the interval between two topology queries, 30 seconds by default
+	RefreshInterval time.Duration
+	// OnError is called when the topology can't be refreshed, it's optional
+	OnError func(err error)
+}
+
+// Monitor keeps the primaries and replicas of the resolver in sync with the cluster topology.
+//
+// The nodes configured in the resolver (eg. the cluster endpoints) are used to bootstrap the discovery.
+// They are removed from the rotation once the topology is known,
+// but are still used to query the topology when no instance can be reached.
+type Monitor struct {
+	db     dbresolver.DB
+	config Config
+
+	mu        sync.Mutex
+	bootstrap []*sql.DB
+	instances map[string]*sql.DB
+	writer    string
+}
+
+// NewMonitor creates the topology monitor of the resolver
+func NewMonitor(db dbresolver.DB, config Config) *Monitor {
+	return &Monitor{
+		db:        db,
+		config:    config,
+		instances: map[string]*sql.DB{},
+	}
+}
+
+// Run refreshes the topology on every refresh interval until the context is done
+func (m *Monitor) Run(ctx context.Context) error {
+	interval := m.config.RefreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Refresh(ctx); err != nil && m.config.OnError != nil {
+			m.config.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh queries the cluster topology and reconfigures the resolver
+func (m *Monitor) Refresh(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.bootstrap == nil {
+		m.bootstrap = append(m.bootstrap, m.db.PrimaryDBs()...)
+		m.bootstrap = append(m.bootstrap, m.db.ReplicaDBs()...)
+	}
+
+	topology, err := m.queryTopology(ctx)
+	if err != nil {
+		return err
+	}
+	return m.apply(topology)
+}
+
+// queryTopology queries the writer first, then any other known node
+func (m *Monitor) queryTopology(ctx context.Context) (Topology, error) {
+	candidates := make([]*sql.DB, 0, len(m.instances)+len(m.bootstrap))
+	if writer, ok := m.instances[m.writer]; ok {
+		candidates = append(candidates, writer)
+	}
+	for id, instance := range m.instances {
+		if id != m.writer {
+			candidates = append(candidates, instance)
+		}
+	}
+	candidates = append(candidates, m.bootstrap...)
+
+	var errs error
+	for _, candidate := range candidates {
+		topology, err := QueryTopology(ctx, candidate, m.config.Engine)
+		if err == nil {
+			return topology, nil
+		}
+		errs = errors.Join(errs, err)
+	}
+	return Topology{}, errs
+}
+
+// apply reconfigures the resolver, nodes are always added before being removed,
+// so the resolver never runs out of primary.
+func (m *Monitor) apply(topology Topology) error {
+	desired := map[string]bool{topology.Writer.ID: true}
+	for _, reader := range topology.Readers {
+		desired[reader.ID] = false
+	}
+
+	var errs error
+	for id := range desired {
+		if _, ok := m.instances[id]; ok {
+			continue
+		}
+		instance, err := m.config.Open(id)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		m.instances[id] = instance
+	}
+
+	writer, ok := m.instances[topology.Writer.ID]
+	if !ok {
+		return errs
+	}
+	m.db.AddPrimary(writer)
+	m.db.RemoveReplica(writer)
+
+	for id, instance := range m.instances {
+		isWriter, ok := desired[id]
+		switch {
+		case isWriter:
+			continue
+		case ok:
+			m.db.AddReplica(instance)
+			errs = errors.Join(errs, m.removePrimary(instance))
+		default:
+			m.db.RemoveReplica(instance)
+			errs = errors.Join(errs, m.removePrimary(instance))
+			delete(m.instances, id)
+			errs = errors.Join(errs, instance.Close())
+		}
+	}
+
+	for _, node := range m.bootstrap {
+		m.db.RemoveReplica(node)
+		errs = errors.Join(errs, m.removePrimary(node))
+	}
+	m.writer = topology.Writer.ID
+	return errs
+}
+
+func (m *Monitor) removePrimary(db *sql.DB) error {
+	if err := m.db.RemovePrimary(db); err != nil && err != dbresolver.ErrLastPrimary {
+		return err
+	}
+	return nil
+}
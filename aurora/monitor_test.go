@@ -0,0 +1,121 @@
+package aurora
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+func createMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	return db, mock
+}
+
+func topologyRows(writer string, readers ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"server_id", "is_writer"}).AddRow(writer, true)
+	for _, reader := range readers {
+		rows.AddRow(reader, false)
+	}
+	return rows
+}
+
+func TestQueryTopology(t *testing.T) {
+	db, mock := createMock(t)
+	mock.ExpectQuery(mysqlTopologyQuery).WillReturnRows(topologyRows("instance-1", "instance-2", "instance-3"))
+
+	topology, err := QueryTopology(context.Background(), db, MySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topology.Writer.ID != "instance-1" || len(topology.Readers) != 2 {
+		t.Errorf("unexpected topology %+v", topology)
+	}
+
+	mock.ExpectQuery(mysqlTopologyQuery).WillReturnRows(sqlmock.NewRows([]string{"server_id", "is_writer"}).AddRow("instance-2", false))
+	if _, err := QueryTopology(context.Background(), db, MySQL); err == nil {
+		t.Error("want error when no writer is found")
+	}
+
+	if _, err := QueryTopology(context.Background(), db, Engine("oracle")); err == nil {
+		t.Error("want error for unsupported engine")
+	}
+}
+
+func TestMonitorFailover(t *testing.T) {
+	ctx := context.Background()
+	clusterEndpoint, clusterMock := createMock(t)
+	resolver := dbresolver.New(dbresolver.WithPrimaryDBs(clusterEndpoint))
+
+	instances := map[string]*sql.DB{}
+	mocks := map[string]sqlmock.Sqlmock{}
+	for _, id := range []string{"instance-1", "instance-2", "instance-3"} {
+		instances[id], mocks[id] = createMock(t)
+	}
+	monitor := NewMonitor(resolver, Config{
+		Engine: PostgreSQL,
+		Open: func(instanceID string) (*sql.DB, error) {
+			return instances[instanceID], nil
+		},
+	})
+
+	// bootstrap from the cluster endpoint
+	clusterMock.ExpectQuery(postgresTopologyQuery).WillReturnRows(topologyRows("instance-1", "instance-2", "instance-3"))
+	if err := monitor.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertTopology(t, resolver, []*sql.DB{instances["instance-1"]}, []*sql.DB{instances["instance-2"], instances["instance-3"]})
+
+	// instance-2 is promoted, and instance-3 is deleted
+	mocks["instance-1"].ExpectQuery(postgresTopologyQuery).WillReturnRows(topologyRows("instance-2", "instance-1"))
+	mocks["instance-3"].ExpectClose()
+	if err := monitor.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertTopology(t, resolver, []*sql.DB{instances["instance-2"]}, []*sql.DB{instances["instance-1"]})
+
+	// the writer is unreachable, the topology is queried from the readers
+	mocks["instance-2"].ExpectQuery(postgresTopologyQuery).WillReturnError(sql.ErrConnDone)
+	mocks["instance-1"].ExpectQuery(postgresTopologyQuery).WillReturnRows(topologyRows("instance-1", "instance-2"))
+	if err := monitor.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertTopology(t, resolver, []*sql.DB{instances["instance-1"]}, []*sql.DB{instances["instance-2"]})
+
+	for id, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: sqlmock:unmet expectations: %s", id, err)
+		}
+	}
+}
+
+func assertTopology(t *testing.T, db dbresolver.DB, primaries, replicas []*sql.DB) {
+	t.Helper()
+	if !sameDBs(db.PrimaryDBs(), primaries) {
+		t.Errorf("want primaries %v, got %v", primaries, db.PrimaryDBs())
+	}
+	if !sameDBs(db.ReplicaDBs(), replicas) {
+		t.Errorf("want replicas %v, got %v", replicas, db.ReplicaDBs())
+	}
+}
+
+func sameDBs(got, want []*sql.DB) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			found = found || g == w
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
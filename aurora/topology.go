@@ -0,0 +1,86 @@
+// Package aurora discovers the writer and readers of an AWS Aurora cluster,
+// and keeps the primaries and replicas of a dbresolver.DB in sync with them.
+//
+// Aurora failovers promote a reader to writer, while the cluster endpoints
+// keep resolving to the previous writer until their DNS TTL expires.
+// Querying the topology directly from the instances avoids relying on the stale DNS.
+package aurora
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Engine is the Aurora database engine
+type Engine string
+
+// Supported Aurora engines
+const (
+	PostgreSQL Engine = "postgresql"
+	MySQL      Engine = "mysql"
+)
+
+// topology queries return the instance id and whether the instance is the writer,
+// for the instances which reported their status recently
+const (
+	postgresTopologyQuery = `SELECT server_id, session_id = 'MASTER_SESSION_ID' AS is_writer ` +
+		`FROM aurora_replica_status() ` +
+		`WHERE last_update_timestamp > NOW() - INTERVAL '5 minutes'`
+	mysqlTopologyQuery = `SELECT server_id, session_id = 'MASTER_SESSION_ID' AS is_writer ` +
+		`FROM information_schema.replica_host_status ` +
+		`WHERE TIME_TO_SEC(TIMEDIFF(NOW(), last_update_timestamp)) <= 300`
+)
+
+// Instance is a database instance of the cluster
+type Instance struct {
+	ID     string
+	Writer bool
+}
+
+// Topology is the current writer and readers of the cluster
+type Topology struct {
+	Writer  Instance
+	Readers []Instance
+}
+
+// QueryTopology queries the cluster topology from any instance of the cluster
+func QueryTopology(ctx context.Context, db *sql.DB, engine Engine) (Topology, error) {
+	var query string
+	switch engine {
+	case PostgreSQL:
+		query = postgresTopologyQuery
+	case MySQL:
+		query = mysqlTopologyQuery
+	default:
+		return Topology{}, fmt.Errorf("aurora: engine %s is not supported", engine)
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return Topology{}, err
+	}
+	defer rows.Close()
+
+	var topology Topology
+	var hasWriter bool
+	for rows.Next() {
+		var instance Instance
+		if err := rows.Scan(&instance.ID, &instance.Writer); err != nil {
+			return Topology{}, err
+		}
+		if instance.Writer {
+			topology.Writer, hasWriter = instance, true
+			continue
+		}
+		topology.Readers = append(topology.Readers, instance)
+	}
+	if err := rows.Err(); err != nil {
+		return Topology{}, err
+	}
+	if !hasWriter {
+		// a failover is in progress
+		return Topology{}, fmt.Errorf("aurora: no writer instance found")
+	}
+	return topology, nil
+}
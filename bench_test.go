@@ -0,0 +1,225 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newBenchmarkResolver(replicas int, opts ...OptionFunc) DB {
+	replicaDBs := make([]*sql.DB, replicas)
+	for i := range replicaDBs {
+		replicaDBs[i] = &sql.DB{}
+	}
+	return New(append([]OptionFunc{WithPrimaryDBs(&sql.DB{}), WithReplicaDBs(replicaDBs...)}, opts...)...)
+}
+
+func BenchmarkReadOnly(b *testing.B) {
+	for _, lb := range []LoadBalancerPolicy{RoundRobinLB, RandomLB} {
+		for _, replicas := range []int{0, 1, 3} {
+			b.Run(fmt.Sprintf("%s/1P%dR", lb, replicas), func(b *testing.B) {
+				resolver := newBenchmarkResolver(replicas, WithLoadBalancer(lb))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					resolver.ReadOnly()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkRoundRobinResolve(b *testing.B) {
+	dbs := []*sql.DB{{}, {}, {}}
+	var lb LoadBalancer[*sql.DB] = &RoundRobinLoadBalancer[*sql.DB]{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		lb.Resolve(dbs)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { lb.Resolve(dbs) }); allocs != 0 {
+		b.Errorf("want no allocation, got %v", allocs)
+	}
+}
+
+func BenchmarkReadWrite(b *testing.B) {
+	for _, lb := range []LoadBalancerPolicy{RoundRobinLB, RandomLB} {
+		b.Run(string(lb), func(b *testing.B) {
+			resolver := newBenchmarkResolver(1, WithLoadBalancer(lb))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resolver.ReadWrite()
+			}
+		})
+	}
+}
+
+func BenchmarkReadOnlyParallel(b *testing.B) {
+	resolver := newBenchmarkResolver(3)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			resolver.ReadOnly()
+		}
+	})
+}
+
+// rwMutexTopology is the topology guarded by a RWMutex, the design replaced by the atomic snapshots
+type rwMutexTopology struct {
+	lock            sync.RWMutex
+	primaries       []*sql.DB
+	replicaRotation []*sql.DB
+}
+
+func (t *rwMutexTopology) readTopology() (primaries, replicaRotation []*sql.DB) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.primaries, t.replicaRotation
+}
+
+// BenchmarkTopologySnapshot compares the reads of the topology of the resolver,
+// stored in an atomic pointer, with a RWMutex design. The writers change the topology concurrently.
+func BenchmarkTopologySnapshot(b *testing.B) {
+	replicas := []*sql.DB{{}, {}, {}}
+	rw := &rwMutexTopology{primaries: []*sql.DB{{}}, replicaRotation: replicas}
+	snapshot := newBenchmarkResolver(3).(*sqlDB)
+
+	designs := map[string]struct {
+		read  func() (primaries, replicaRotation []*sql.DB)
+		write func()
+	}{
+		"atomic": {
+			read: snapshot.readTopology,
+			write: func() {
+				snapshot.RemoveReplica(replicas[0])
+				snapshot.AddReplica(replicas[0])
+			},
+		},
+		"rwmutex": {
+			read: rw.readTopology,
+			write: func() {
+				rw.lock.Lock()
+				rw.replicaRotation = removeDB(rw.replicaRotation, replicas[0])
+				rw.lock.Unlock()
+				rw.lock.Lock()
+				rw.replicaRotation = appendDB(rw.replicaRotation, replicas[0])
+				rw.lock.Unlock()
+			},
+		},
+	}
+	for name, design := range designs {
+		for _, writers := range []int{0, 1} {
+			b.Run(fmt.Sprintf("%s/%dW", name, writers), func(b *testing.B) {
+				done := make(chan struct{})
+				var wg sync.WaitGroup
+				for w := 0; w < writers; w++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for {
+							select {
+							case <-done:
+								return
+							default:
+								design.write()
+								time.Sleep(time.Microsecond)
+							}
+						}
+					}()
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						design.read()
+					}
+				})
+				b.StopTimer()
+				close(done)
+				wg.Wait()
+			})
+		}
+	}
+}
+
+func BenchmarkQueryTypeChecker(b *testing.B) {
+	checker := &DefaultQueryTypeChecker{}
+	for name, query := range map[string]string{
+		"read":  "SELECT id, title, author FROM book WHERE id = $1",
+		"write": "INSERT INTO book (title, author) VALUES ($1, $2) RETURNING id",
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				checker.Check(query)
+			}
+		})
+	}
+}
+
+func BenchmarkDoParallely(b *testing.B) {
+	for _, n := range []int{1, 3, 10} {
+		b.Run(fmt.Sprintf("N%d", n), func(b *testing.B) {
+			ctx := context.Background()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = doParallely(ctx, 0, n, func(int) error { return nil })
+			}
+		})
+	}
+}
+
+func BenchmarkPing(b *testing.B) {
+	for _, replicas := range []int{0, 3} {
+		b.Run(fmt.Sprintf("1P%dR", replicas), func(b *testing.B) {
+			primary, _, err := sqlmock.New()
+			if err != nil {
+				b.Fatal("creating of mock failed")
+			}
+			replicaDBs := make([]*sql.DB, replicas)
+			for i := range replicaDBs {
+				if replicaDBs[i], _, err = sqlmock.New(); err != nil {
+					b.Fatal("creating of mock failed")
+				}
+			}
+			resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicaDBs...))
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := resolver.PingContext(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkQueryOverhead compares the queries through an un-configured resolver to the bare queries,
+// on the in-memory results driver so the overhead of the resolver dominates
+func BenchmarkQueryOverhead(b *testing.B) {
+	ctx := context.Background()
+	res := &result{columns: []string{"id"}}
+	node := newResultDB()
+	defer node.Close()
+	resolver := New(WithPrimaryDBs(node), WithReplicaDBs(node))
+
+	query := func(b *testing.B, queryContext func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rows, err := queryContext(ctx, "SELECT id FROM book", res)
+			if err != nil {
+				b.Fatal(err)
+			}
+			rows.Close()
+		}
+	}
+	b.Run("bare", func(b *testing.B) { query(b, node.QueryContext) })
+	b.Run("resolver", func(b *testing.B) { query(b, resolver.QueryContext) })
+}
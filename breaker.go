@@ -0,0 +1,191 @@
+package dbresolver
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the circuit breakers
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerCoolDown         = 30 * time.Second
+)
+
+// CircuitState is the state of the circuit breaker of a node, see WithCircuitBreaker
+type CircuitState string
+
+// States of the circuit breakers
+const (
+	// CircuitClosed is the state of the nodes in rotation
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen is the state of the nodes out of rotation until the end of their cool-down
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen is the state of the nodes back in rotation after their cool-down,
+	// their next query closes the circuit or opens it again
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// CircuitBreaker define when the circuit of a node opens, see WithCircuitBreaker
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failed queries opening the circuit of a node, 5 by default
+	FailureThreshold int
+	// CoolDown is the duration the circuit of a node stays open, 30 seconds by default
+	CoolDown time.Duration
+	// IsFailure reports whether the error of a query is a failure of the node, the other errors reset
+	// the consecutive failures. By default the connection errors are, see ErrorClassConnection, not the errors
+	// of the queries themselves like a syntax error.
+	IsFailure func(err error) bool
+	// OnStateChange is called when the circuit of a node changes its state, eg. to alert on the open circuits.
+	// It's called synchronously by the query changing the state, it must not block.
+	OnStateChange func(node *sql.DB, from, to CircuitState)
+}
+
+// WithCircuitBreaker opens the circuit of a node after FailureThreshold consecutive failed queries: the node
+// is out of rotation for the cool-down instead of failing the queries routed to it, the reads go to the primaries
+// when the circuit of every replica is open, and the writes go to every primary when they're all open.
+// After the cool-down the circuit is half-open, the node is back in rotation: its next query closes the circuit
+// when it succeeds, and opens it again when it fails. Nodes reports the state of the circuits.
+// The prepared statements, the transactions and the connections don't change the circuits.
+func WithCircuitBreaker(config CircuitBreaker) OptionFunc {
+	if config.FailureThreshold < 0 || config.CoolDown < 0 {
+		panic(fmt.Sprintf("dbresolver: invalid circuit breaker %+v", config))
+	}
+	return func(opt *Option) {
+		opt.CircuitBreaker = &config
+	}
+}
+
+// breaker holds the circuits of the nodes of the resolver
+type breaker struct {
+	config CircuitBreaker
+	clock  Clock
+
+	mu       sync.Mutex
+	circuits map[*sql.DB]*circuit
+	// tracked is the number of circuits, the successful queries of the untracked nodes don't take the lock
+	tracked atomic.Int64
+	// openUntil are the end of the cool-down of the open circuits, the map is replaced on every change
+	openUntil atomic.Pointer[map[*sql.DB]time.Time]
+}
+
+// circuit is the circuit of a node, guarded by the lock of the breaker
+type circuit struct {
+	failures  int
+	open      bool
+	openUntil time.Time
+}
+
+func newBreaker(config CircuitBreaker, clock Clock) *breaker {
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = defaultBreakerFailureThreshold
+	}
+	if config.CoolDown == 0 {
+		config.CoolDown = defaultBreakerCoolDown
+	}
+	b := &breaker{config: config, clock: clock, circuits: map[*sql.DB]*circuit{}}
+	b.openUntil.Store(&map[*sql.DB]time.Time{})
+	return b
+}
+
+// record records the result of a query of the node, and changes the state of its circuit
+func (b *breaker) record(node *sql.DB, err error) {
+	failed := err != nil && b.config.IsFailure(err)
+	if !failed && b.tracked.Load() == 0 {
+		return
+	}
+	b.mu.Lock()
+	c, ok := b.circuits[node]
+	if !ok {
+		if !failed {
+			b.mu.Unlock()
+			return
+		}
+		c = &circuit{}
+		b.circuits[node] = c
+	}
+	now := b.clock.Now()
+	from := b.state(c, now)
+	switch {
+	case !failed:
+		delete(b.circuits, node)
+	case from == CircuitHalfOpen:
+		c.openUntil = now.Add(b.config.CoolDown)
+	default:
+		c.failures++
+		if c.failures >= b.config.FailureThreshold && !c.open {
+			c.open, c.openUntil = true, now.Add(b.config.CoolDown)
+		}
+	}
+	to := CircuitClosed
+	if c, ok := b.circuits[node]; ok {
+		to = b.state(c, now)
+	}
+	if from != to {
+		b.storeOpenUntil()
+	}
+	b.tracked.Store(int64(len(b.circuits)))
+	b.mu.Unlock()
+
+	if from != to && b.config.OnStateChange != nil {
+		b.config.OnStateChange(node, from, to)
+	}
+}
+
+// state returns the state of the circuit at the time
+func (b *breaker) state(c *circuit, now time.Time) CircuitState {
+	switch {
+	case !c.open:
+		return CircuitClosed
+	case now.Before(c.openUntil):
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+// storeOpenUntil stores the end of the cool-down of the open circuits, it's called with the lock
+func (b *breaker) storeOpenUntil() {
+	openUntil := make(map[*sql.DB]time.Time)
+	for node, c := range b.circuits {
+		if c.open {
+			openUntil[node] = c.openUntil
+		}
+	}
+	b.openUntil.Store(&openUntil)
+}
+
+// closed returns the nodes whose circuit isn't open, the nodes themselves when no circuit is open or without breaker
+func (b *breaker) closed(nodes []*sql.DB) []*sql.DB {
+	if b == nil {
+		return nodes
+	}
+	openUntil := b.openUntil.Load()
+	if len(*openUntil) == 0 {
+		return nodes
+	}
+	now := b.clock.Now()
+	res := make([]*sql.DB, 0, len(nodes))
+	for _, node := range nodes {
+		if until, ok := (*openUntil)[node]; !ok || !now.Before(until) {
+			res = append(res, node)
+		}
+	}
+	return res
+}
+
+// circuitState returns the state of the circuit of the node, closed without breaker
+func (b *breaker) circuitState(node *sql.DB) CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[node]
+	if !ok {
+		return CircuitClosed
+	}
+	return b.state(c, b.clock.Now())
+}
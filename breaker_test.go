@@ -0,0 +1,96 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, replica2Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	now := time.Now()
+	var changes []string
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica1, replica2), WithClock(stubClock{now: &now}),
+		WithCircuitBreaker(CircuitBreaker{FailureThreshold: 2, CoolDown: time.Minute,
+			OnStateChange: func(node *sql.DB, from, to CircuitState) {
+				changes = append(changes, fmt.Sprintf("%v %s->%s", node == replica2, from, to))
+			}})).(*sqlDB)
+
+	// the read failing with a connection error counts as a failure of the replica, not its fallback
+	connErr := &net.OpError{Op: "read", Err: errors.New("reset")}
+	replica2Mock.ExpectQuery("SELECT 1").WillReturnError(connErr)
+	primaryMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	rows, err := resolver.QueryContext(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if state := resolver.breaker.circuitState(replica2); state != CircuitClosed {
+		t.Errorf("want the circuit closed below the threshold, got %s", state)
+	}
+
+	// the errors of the queries themselves aren't failures of the node, the node answered
+	resolver.breaker.record(replica2, errors.New("syntax error"))
+	resolver.breaker.record(replica2, connErr)
+	if state := resolver.breaker.circuitState(replica2); state != CircuitClosed {
+		t.Errorf("want the consecutive failures reset, got %s", state)
+	}
+	resolver.breaker.record(replica2, connErr)
+	if seen := readNodes(resolver, 4); seen[replica1] != 4 {
+		t.Errorf("want the replica with an open circuit out of rotation, got %v", seen)
+	}
+	for _, node := range resolver.Nodes() {
+		if (node.Circuit == CircuitOpen) != (node.DB == replica2) {
+			t.Errorf("want the open circuit reported, got %+v", node)
+		}
+	}
+
+	// the circuit is half-open after the cool-down, a failure opens it again
+	now = now.Add(time.Minute)
+	if state := resolver.breaker.circuitState(replica2); state != CircuitHalfOpen {
+		t.Errorf("want the circuit half-open after the cool-down, got %s", state)
+	}
+	if seen := readNodes(resolver, 4); seen[replica2] != 2 {
+		t.Errorf("want the replica with a half-open circuit in rotation, got %v", seen)
+	}
+	resolver.breaker.record(replica2, connErr)
+	if seen := readNodes(resolver, 4); seen[replica1] != 4 {
+		t.Errorf("want the replica out of rotation again, got %v", seen)
+	}
+
+	// a success closes it
+	now = now.Add(time.Minute)
+	resolver.breaker.record(replica2, nil)
+	if seen := readNodes(resolver, 4); seen[replica2] != 2 {
+		t.Errorf("want the replica back in rotation, got %v", seen)
+	}
+
+	// every primary is used when their circuits are all open
+	resolver.breaker.record(primary, connErr)
+	resolver.breaker.record(primary, connErr)
+	if resolver.ReadWrite() != primary {
+		t.Error("want the writes on the primary")
+	}
+
+	want := []string{"true closed->open", "true half-open->open", "true half-open->closed", "false closed->open"}
+	if fmt.Sprint(changes) != fmt.Sprint(want) {
+		t.Errorf("want the state changes %q, got %q", want, changes)
+	}
+}
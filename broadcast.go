@@ -0,0 +1,92 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ExecAllOptions define how ExecAllPrimaries broadcasts the query
+type ExecAllOptions struct {
+	// Parallelism bounds the number of primaries executing the query concurrently,
+	// the max parallelism of the resolver when it's zero
+	Parallelism int
+}
+
+// PrimaryExecResult is the outcome of the query on a primary
+type PrimaryExecResult struct {
+	DB       *sql.DB
+	Result   sql.Result
+	Duration time.Duration
+	Err      error
+}
+
+// ExecAllResult is the outcome of ExecAllPrimaries, in the order of the primaries.
+// It's the sql.Result of the writes fanned out by WithWriteStrategy.
+type ExecAllResult struct {
+	Primaries []PrimaryExecResult
+}
+
+// LastInsertId returns the last insert ID of the first primary the query succeeded on
+func (r *ExecAllResult) LastInsertId() (int64, error) {
+	for _, primary := range r.Primaries {
+		if primary.Result != nil {
+			return primary.Result.LastInsertId()
+		}
+	}
+	return 0, errors.New("dbresolver: the query didn't succeed on any primary")
+}
+
+// RowsAffected returns the sum of the rows affected on the primaries
+func (r *ExecAllResult) RowsAffected() (int64, error) {
+	var total int64
+	for _, primary := range r.Primaries {
+		if primary.Result == nil {
+			continue
+		}
+		n, err := primary.Result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// ExecAllPrimaries executes the query on every primary concurrently, eg. a DDL on sharded primaries,
+// instead of a sequential loop over PrimaryDBs. The provided ExecAllOptions is optional and may be nil.
+// The result holds the outcome and the duration of the query on each primary,
+// the error lists the failed primaries.
+func (db *sqlDB) ExecAllPrimaries(ctx context.Context, opts *ExecAllOptions, query string,
+	args ...interface{}) (*ExecAllResult, error) {
+	parallelism := db.parallelism
+	if opts != nil && opts.Parallelism > 0 {
+		parallelism = opts.Parallelism
+	}
+
+	primaries, _ := db.topology()
+	result := &ExecAllResult{Primaries: make([]PrimaryExecResult, len(primaries))}
+	err := doParallely(ctx, parallelism, len(primaries), func(i int) error {
+		primary := &result.Primaries[i]
+		primary.DB = primaries[i]
+		start := db.clock.Now()
+		primary.Result, primary.Err = execWithHooks(ctx, db.hooks, primaryRoute.to(primaries[i]), query, args,
+			func(ctx context.Context) (sql.Result, error) {
+				return db.execContext(ctx, primaries[i], query, args)
+			})
+		primary.Duration = db.clock.Now().Sub(start)
+		if primary.Err != nil {
+			return fmt.Errorf("primary %d: %w", i, primary.Err)
+		}
+		return nil
+	})
+	for i := range result.Primaries {
+		if result.Primaries[i].DB == nil {
+			// skipped once the context was done
+			result.Primaries[i] = PrimaryExecResult{DB: primaries[i], Err: ctx.Err()}
+		}
+	}
+	return result, err
+}
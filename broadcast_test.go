@@ -0,0 +1,63 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExecAllPrimaries(t *testing.T) {
+	primaries := make([]*sql.DB, 3)
+	mocks := make([]sqlmock.Sqlmock, 3)
+	for i := range primaries {
+		var err error
+		primaries[i], mocks[i], err = createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+	}
+	resolver := New(WithPrimaryDBs(primaries...))
+
+	query := "ALTER TABLE book ADD COLUMN isbn TEXT"
+	mocks[0].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	mocks[1].ExpectExec(query).WillReturnError(errors.New("lock timeout"))
+	mocks[2].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 2))
+	result, err := resolver.ExecAllPrimaries(context.Background(), &ExecAllOptions{Parallelism: 2}, query)
+	if err == nil || !strings.Contains(err.Error(), "primary 1: lock timeout") {
+		t.Errorf("want the error of the failed primary, got %v", err)
+	}
+	if len(result.Primaries) != 3 {
+		t.Fatalf("want the 3 primaries, got %+v", result.Primaries)
+	}
+	for i, primary := range result.Primaries {
+		if primary.DB != primaries[i] || (primary.Err != nil) != (i == 1) || primary.Duration <= 0 {
+			t.Errorf("unexpected result of primary %d: %+v", i, primary)
+		}
+	}
+	if n, err := result.RowsAffected(); err != nil || n != 3 {
+		t.Errorf("want 3 rows affected, got %d, %v", n, err)
+	}
+
+	// the primaries not started yet are skipped once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = resolver.ExecAllPrimaries(ctx, nil, query)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want the context error, got %v", err)
+	}
+	for _, primary := range result.Primaries {
+		if !errors.Is(primary.Err, context.Canceled) {
+			t.Errorf("want the primaries skipped, got %+v", primary)
+		}
+	}
+
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
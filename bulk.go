@@ -0,0 +1,167 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Defaults of the bulk loads
+const (
+	defaultBulkBatchSize = 500
+	// maxBulkParams is the number of parameters of a statement supported by the common databases, eg. PostgreSQL
+	maxBulkParams = 65535
+)
+
+// BulkCopier copies the rows into the table on the driver connection of a primary, see sql.Conn.Raw,
+// eg. pgxv5.CopyFrom with the PostgreSQL COPY protocol of the pgx stdlib driver. It returns the number of rows copied.
+type BulkCopier func(ctx context.Context, driverConn any, table string, columns []string, rows [][]interface{}) (int64, error)
+
+// BulkLoad define how DB.BulkInsert loads the rows
+type BulkLoad struct {
+	// Copier copies the rows with the bulk protocol of the driver, the rows are inserted by batches when it's nil
+	Copier BulkCopier
+	// BatchSize is the number of rows of each INSERT statement, 500 by default.
+	// The batches are smaller when they would exceed 65535 parameters.
+	BatchSize int
+	// Placeholder returns the placeholder of the nth parameter of a statement, from 1, QuestionPlaceholder by default
+	Placeholder func(n int) string
+}
+
+// QuestionPlaceholder returns the ? placeholders, eg. MySQL or SQLite
+func QuestionPlaceholder(int) string { return "?" }
+
+// DollarPlaceholder returns the $n placeholders, eg. PostgreSQL
+func DollarPlaceholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// WithBulkLoad sets how DB.BulkInsert loads the rows
+func WithBulkLoad(config BulkLoad) OptionFunc {
+	return func(opt *Option) {
+		opt.BulkLoad = config
+	}
+}
+
+// BulkInsert loads the rows into the table on a primary: with the copier of the bulk load when it's set,
+// see WithBulkLoad, with multi-row INSERT statements in a transaction otherwise. It returns the number of rows loaded.
+// The table and the columns are written as is into the statements, they must be trusted identifiers.
+// The load runs through the hooks, and holds a slot of the admission control and the limits of the primary.
+// With WithRetryPolicy the whole load is retried on a retryable error, like the writes, in a new transaction.
+func (db *sqlDB) BulkInsert(ctx context.Context, table string, columns []string,
+	rows [][]interface{}) (loaded int64, err error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return 0, err
+	}
+	db = shard
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return 0, fmt.Errorf("dbresolver: the row %d has %d values for %d columns", i, len(row), len(columns))
+		}
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	err = db.retry.do(ctx, true, func() (err error) {
+		loaded, err = db.bulkInsert(ctx, table, columns, rows)
+		return err
+	})
+	return loaded, err
+}
+
+// bulkInsert is an attempt of BulkInsert, on a primary
+func (db *sqlDB) bulkInsert(ctx context.Context, table string, columns []string,
+	rows [][]interface{}) (_ int64, err error) {
+	node := db.ReadWrite()
+	release, err := db.acquire(ctx, node)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	if db.bulkLoad.Copier != nil {
+		return db.bulkCopy(ctx, node, table, columns, rows)
+	}
+
+	tx, err := node.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	batchSize := db.bulkLoad.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBulkBatchSize
+	}
+	batchSize = max(min(batchSize, maxBulkParams/max(len(columns), 1)), 1)
+	var loaded int64
+	for start := 0; start < len(rows); start += batchSize {
+		batch := rows[start:min(start+batchSize, len(rows))]
+		query, args := db.insertStatement(table, columns, batch)
+		res, err := execWithHooks(ctx, db.hooks, primaryRoute.to(node), query, args, func(ctx context.Context) (sql.Result, error) {
+			return tx.ExecContext(ctx, query, args...)
+		})
+		if err != nil {
+			return 0, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		loaded += affected
+	}
+	return loaded, tx.Commit()
+}
+
+// bulkCopy copies the rows with the copier, on a connection of the node
+func (db *sqlDB) bulkCopy(ctx context.Context, node *sql.DB, table string, columns []string,
+	rows [][]interface{}) (loaded int64, err error) {
+	query := fmt.Sprintf("COPY %s (%s) FROM STDIN", table, strings.Join(columns, ", "))
+	_, err = execWithHooks(ctx, db.hooks, primaryRoute.to(node), query, nil, func(ctx context.Context) (sql.Result, error) {
+		conn, err := node.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = conn.Raw(func(driverConn any) (err error) {
+			loaded, err = db.bulkLoad.Copier(ctx, driverConn, table, columns, rows)
+			return err
+		})
+		return driver.RowsAffected(loaded), errors.Join(err, conn.Close())
+	})
+	if err != nil {
+		return 0, err
+	}
+	return loaded, nil
+}
+
+// insertStatement returns the multi-row INSERT statement of the rows, with its args
+func (db *sqlDB) insertStatement(table string, columns []string, rows [][]interface{}) (string, []interface{}) {
+	placeholder := db.bulkLoad.Placeholder
+	if placeholder == nil {
+		placeholder = QuestionPlaceholder
+	}
+	var query strings.Builder
+	fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+	args := make([]interface{}, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteByte('(')
+		for j, value := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			args = append(args, value)
+			query.WriteString(placeholder(len(args)))
+		}
+		query.WriteByte(')')
+	}
+	return query.String(), args
+}
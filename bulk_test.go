@@ -0,0 +1,117 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBulkInsertBatches(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica),
+		WithBulkLoad(BulkLoad{BatchSize: 2, Placeholder: DollarPlaceholder}))
+
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("INSERT INTO book (id, title) VALUES ($1, $2), ($3, $4)").
+		WithArgs(1, "a", 2, "b").WillReturnResult(sqlmock.NewResult(0, 2))
+	primaryMock.ExpectExec("INSERT INTO book (id, title) VALUES ($1, $2)").
+		WithArgs(3, "c").WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectCommit()
+	loaded, err := resolver.BulkInsert(context.Background(), "book", []string{"id", "title"},
+		[][]interface{}{{1, "a"}, {2, "b"}, {3, "c"}})
+	if err != nil || loaded != 3 {
+		t.Errorf("want 3 rows loaded, got %d, %v", loaded, err)
+	}
+
+	// a failed batch rolls back the load
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("INSERT INTO book (id, title) VALUES ($1, $2), ($3, $4)").
+		WithArgs(1, "a", 2, "b").WillReturnError(errors.New("duplicate key"))
+	primaryMock.ExpectRollback()
+	if _, err := resolver.BulkInsert(context.Background(), "book", []string{"id", "title"},
+		[][]interface{}{{1, "a"}, {2, "b"}}); err == nil {
+		t.Error("want the batch error")
+	}
+
+	if _, err := resolver.BulkInsert(context.Background(), "book", []string{"id", "title"},
+		[][]interface{}{{1}}); err == nil {
+		t.Error("want an error for a row missing values")
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBulkInsertCopier(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	var copied [][]interface{}
+	copier := func(_ context.Context, _ any, table string, columns []string, rows [][]interface{}) (int64, error) {
+		copied = rows
+		return int64(len(rows)), nil
+	}
+	hooks := &recordingHooks{}
+	resolver := New(WithPrimaryDBs(primary), WithBulkLoad(BulkLoad{Copier: copier}), WithHooks(hooks))
+
+	loaded, err := resolver.BulkInsert(context.Background(), "book", []string{"id", "title"},
+		[][]interface{}{{1, "a"}, {2, "b"}})
+	if err != nil || loaded != 2 || len(copied) != 2 {
+		t.Errorf("want 2 rows copied, got %d, %v", loaded, err)
+	}
+	if len(hooks.calls) != 2 || hooks.calls[0] != "before primary COPY book (id, title) FROM STDIN fallback=false" {
+		t.Errorf("want the copy run through the hooks, got %v", hooks.calls)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBulkInsertRetry(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithBulkLoad(BulkLoad{BatchSize: 1}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}))
+
+	// the deadlock rolls back the load, and the whole load is retried in a new transaction
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("INSERT INTO book (id) VALUES (?)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectExec("INSERT INTO book (id) VALUES (?)").WithArgs(2).
+		WillReturnError(errors.New("Error 1213 (40001): Deadlock found when trying to get lock"))
+	primaryMock.ExpectRollback()
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("INSERT INTO book (id) VALUES (?)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectExec("INSERT INTO book (id) VALUES (?)").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectCommit()
+	loaded, err := resolver.BulkInsert(context.Background(), "book", []string{"id"}, [][]interface{}{{1}, {2}})
+	if err != nil || loaded != 2 {
+		t.Errorf("want 2 rows loaded by the retry, got %d, %v", loaded, err)
+	}
+
+	// the errors which aren't retryable aren't retried
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("INSERT INTO book (id) VALUES (?)").WithArgs(1).WillReturnError(errors.New("duplicate key"))
+	primaryMock.ExpectRollback()
+	if _, err := resolver.BulkInsert(context.Background(), "book", []string{"id"}, [][]interface{}{{1}}); err == nil {
+		t.Error("want the error of the load")
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,159 @@
+// Package clickhouse adapts the resolver to a ClickHouse cluster.
+//
+// The inserts and the other writes are sent to the nodes designated for inserts,
+// the reads are balanced across all the nodes of the cluster.
+// ClickHouse has no transactions, Begin returns an *UnsupportedError instead of
+// opening a transaction on a single node the following statements would bypass.
+// The statements are session-less, two statements can be served by different nodes:
+// use Conn to run the statements depending on the session (temporary tables, SET) on the same node.
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// UnsupportedError is returned by the operations ClickHouse doesn't support
+type UnsupportedError struct {
+	Operation string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "dbresolver/clickhouse: " + e.Operation + " is not supported"
+}
+
+// Is reports the error as errors.ErrUnsupported
+func (e *UnsupportedError) Is(target error) bool {
+	return target == errors.ErrUnsupported
+}
+
+// writeStatements are the ClickHouse statements modifying the data or the schema
+var writeStatements = map[string]bool{
+	"INSERT":   true,
+	"ALTER":    true,
+	"CREATE":   true,
+	"DROP":     true,
+	"TRUNCATE": true,
+	"RENAME":   true,
+	"OPTIMIZE": true,
+	"DELETE":   true,
+	"UPDATE":   true,
+	"ATTACH":   true,
+	"DETACH":   true,
+	"EXCHANGE": true,
+	"SYSTEM":   true,
+	"KILL":     true,
+	"GRANT":    true,
+	"REVOKE":   true,
+}
+
+// QueryTypeChecker detects the write queries from their statement keyword,
+// eg. an INSERT INTO ... SELECT sent with Query is routed to the insert nodes.
+// The leading comments are skipped, a query starting with an unterminated comment is a write,
+// the safe target when the statement can't be read.
+type QueryTypeChecker struct{}
+
+// Check returns the type of the query
+func (QueryTypeChecker) Check(query string) dbresolver.QueryType {
+	query, ok := skipLeadingComments(query)
+	if !ok {
+		return dbresolver.QueryTypeWrite
+	}
+	fields := strings.FieldsFunc(query, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '(' || r == ';'
+	})
+	if len(fields) == 0 {
+		return dbresolver.QueryTypeUnknown
+	}
+	if writeStatements[strings.ToUpper(fields[0])] {
+		return dbresolver.QueryTypeWrite
+	}
+	return dbresolver.QueryTypeRead
+}
+
+// skipLeadingComments returns the query after its leading spaces, parentheses and comments,
+// false when a comment is unterminated
+func skipLeadingComments(query string) (string, bool) {
+	for {
+		query = strings.TrimLeftFunc(query, func(r rune) bool { return unicode.IsSpace(r) || r == '(' })
+		switch {
+		case strings.HasPrefix(query, "--"), strings.HasPrefix(query, "#"):
+			end := strings.IndexByte(query, '\n')
+			if end < 0 {
+				return "", true
+			}
+			query = query[end+1:]
+		case strings.HasPrefix(query, "/*"):
+			end := strings.Index(query[2:], "*/")
+			if end < 0 {
+				return "", false
+			}
+			query = query[end+4:]
+		default:
+			return query, true
+		}
+	}
+}
+
+type clickhouseDB struct {
+	dbresolver.DB
+}
+
+// New creates the resolver of a ClickHouse cluster, sending the writes to the insertNodes
+// and balancing the reads across the nodes, the insert nodes can be part of the nodes.
+// The options can override the load balancer or the query type checker.
+func New(insertNodes []*sql.DB, nodes []*sql.DB, opts ...dbresolver.OptionFunc) dbresolver.DB {
+	opts = append([]dbresolver.OptionFunc{
+		dbresolver.WithPrimaryDBs(insertNodes...),
+		dbresolver.WithReplicaDBs(nodes...),
+		dbresolver.WithQueryTypeChecker(QueryTypeChecker{}),
+	}, opts...)
+	return &clickhouseDB{DB: dbresolver.New(opts...)}
+}
+
+// Begin returns an *UnsupportedError, ClickHouse has no transactions
+func (db *clickhouseDB) Begin() (dbresolver.Tx, error) {
+	return nil, &UnsupportedError{Operation: "transaction"}
+}
+
+// BeginTx returns an *UnsupportedError, ClickHouse has no transactions
+func (db *clickhouseDB) BeginTx(context.Context, *sql.TxOptions) (dbresolver.Tx, error) {
+	return nil, &UnsupportedError{Operation: "transaction"}
+}
+
+// Conn returns a single connection to an insert node
+func (db *clickhouseDB) Conn(ctx context.Context) (dbresolver.Conn, error) {
+	return clickhouseConnOf(db.DB.Conn(ctx))
+}
+
+// PrimaryConn returns a single connection to an insert node resolved by the load balancer
+func (db *clickhouseDB) PrimaryConn(ctx context.Context) (dbresolver.Conn, error) {
+	return clickhouseConnOf(db.DB.PrimaryConn(ctx))
+}
+
+// ReplicaConn returns a single connection to a node resolved by the load balancer, like the reads
+func (db *clickhouseDB) ReplicaConn(ctx context.Context) (dbresolver.Conn, error) {
+	return clickhouseConnOf(db.DB.ReplicaConn(ctx))
+}
+
+// clickhouseConnOf wraps the connection, its transactions are unsupported
+func clickhouseConnOf(c dbresolver.Conn, err error) (dbresolver.Conn, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &clickhouseConn{Conn: c}, nil
+}
+
+type clickhouseConn struct {
+	dbresolver.Conn
+}
+
+// BeginTx returns an *UnsupportedError, ClickHouse has no transactions
+func (c *clickhouseConn) BeginTx(context.Context, *sql.TxOptions) (dbresolver.Tx, error) {
+	return nil, &UnsupportedError{Operation: "transaction"}
+}
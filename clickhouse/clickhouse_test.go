@@ -0,0 +1,102 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+func createMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	return db, mock
+}
+
+func TestClickHouse(t *testing.T) {
+	insertNode, insertMock := createMock(t)
+	node, nodeMock := createMock(t)
+	db := New([]*sql.DB{insertNode}, []*sql.DB{node})
+
+	insertMock.ExpectExec("INSERT INTO events (id) VALUES (?)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	insertMock.ExpectQuery("INSERT INTO events_daily SELECT * FROM events").WillReturnRows(sqlmock.NewRows(nil))
+	nodeMock.ExpectQuery("SELECT count() FROM events").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	if _, err := db.Exec("INSERT INTO events (id) VALUES (?)", 1); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query("INSERT INTO events_daily SELECT * FROM events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	var count int
+	if err := db.QueryRow("SELECT count() FROM events").Scan(&count); err != nil || count != 1 {
+		t.Fatalf("want 1, got %v %v", count, err)
+	}
+
+	if _, err := db.Begin(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("want unsupported error, got %v", err)
+	}
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var unsupported *UnsupportedError
+	if _, err := conn.BeginTx(context.Background(), nil); !errors.As(err, &unsupported) {
+		t.Errorf("want *UnsupportedError, got %v", err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{insertMock, nodeMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestQueryTypeChecker(t *testing.T) {
+	tests := map[string]dbresolver.QueryType{
+		"SELECT * FROM events":                             dbresolver.QueryTypeRead,
+		"WITH t AS (SELECT 1) SELECT * FROM t":             dbresolver.QueryTypeRead,
+		"  insert into events SELECT * FROM events_buffer": dbresolver.QueryTypeWrite,
+		"ALTER TABLE events DELETE WHERE id = 1":           dbresolver.QueryTypeWrite,
+		"OPTIMIZE TABLE events FINAL":                      dbresolver.QueryTypeWrite,
+		"":                                                 dbresolver.QueryTypeUnknown,
+		"/* batch */ INSERT INTO events VALUES (1)":        dbresolver.QueryTypeWrite,
+		"-- nightly\n# job\n(SELECT 1)":                    dbresolver.QueryTypeRead,
+		"/* unterminated SELECT 1":                         dbresolver.QueryTypeWrite,
+	}
+	for query, want := range tests {
+		if got := (QueryTypeChecker{}).Check(query); got != want {
+			t.Errorf("Check(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func FuzzQueryTypeChecker(f *testing.F) {
+	f.Add("INSERT INTO events VALUES (1)", "batch")
+	f.Add("(SELECT 1)", "")
+	f.Add("/* x */ OPTIMIZE TABLE events", "*")
+	f.Fuzz(func(t *testing.T, query, comment string) {
+		checker := QueryTypeChecker{}
+		want := checker.Check(query)
+		// a leading comment doesn't change the type of the query
+		if !strings.Contains(comment, "*/") {
+			if got := checker.Check("/* " + comment + " */ " + query); got != want {
+				t.Errorf("want %v for %q after a block comment, got %v", want, query, got)
+			}
+		}
+		if !strings.Contains(comment, "\n") {
+			if got := checker.Check("-- " + comment + "\n" + query); got != want {
+				t.Errorf("want %v for %q after a line comment, got %v", want, query, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package dbresolver
+
+import "time"
+
+// Clock is the source of time of the time-based subsystems of the resolver, see WithClock
+type Clock interface {
+	Now() time.Time
+	// NewTimer creates a timer sending the current time on its channel after the duration
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event timer of a Clock, like time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, it returns false when the timer already fired or was stopped
+	Stop() bool
+}
+
+// WithClock sets the clock of the cache TTLs, the adaptive concurrency limits, the saturation sampling,
+// the discovery refresh and the query durations, so the tests can advance the time deterministically
+// instead of sleeping, eg. with dbresolvertest.ManualClock. The system clock is used by default.
+func WithClock(clock Clock) OptionFunc {
+	return func(opt *Option) {
+		opt.Clock = clock
+	}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
@@ -0,0 +1,12 @@
+package dbresolver
+
+import "time"
+
+// stubClock is a clock stopped at now, its timers are the system timers
+type stubClock struct {
+	now *time.Time
+}
+
+func (c stubClock) Now() time.Time { return *c.now }
+
+func (c stubClock) NewTimer(d time.Duration) Timer { return systemClock{}.NewTimer(d) }
@@ -0,0 +1,105 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ReadCoalescing define which read queries are coalesced
+type ReadCoalescing struct {
+	// Match selects the coalesced queries, every read query when it's nil
+	Match func(query string) bool
+}
+
+// WithReadCoalescing coalesces the identical read queries running concurrently: the queries with the same text
+// and args resolved to the same node share a single round trip, eg. a thundering herd after a cache expiry.
+// Match selects the coalesced queries, every read query when it's nil.
+// The shared results are read entirely in memory, coalesce only small results.
+// A query joining a query in flight gets its result or its error, including its context error,
+// unless its own context is done first.
+func WithReadCoalescing(match func(query string) bool) OptionFunc {
+	return func(opt *Option) {
+		opt.ReadCoalescing = &ReadCoalescing{Match: match}
+	}
+}
+
+type flightKey struct {
+	node  *sql.DB
+	query string
+	args  string
+}
+
+// flight is a query in flight, its result is set before done is closed
+type flight struct {
+	done chan struct{}
+	res  *result
+	err  error
+}
+
+// coalescer runs a single query per key at a time, the other callers wait for its result
+type coalescer struct {
+	match func(query string) bool
+
+	mu      sync.Mutex
+	flights map[flightKey]*flight
+}
+
+func newCoalescer(config ReadCoalescing) *coalescer {
+	return &coalescer{match: config.Match, flights: make(map[flightKey]*flight)}
+}
+
+// do runs the query, or waits for the result of the query in flight with the same key until ctx is done
+func (c *coalescer) do(ctx context.Context, key flightKey, query func() (*result, error)) (*result, error) {
+	c.mu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.res, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &flight{done: make(chan struct{})}
+	c.flights[key] = f
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.flights, key)
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	f.res, f.err = query()
+	return f.res, f.err
+}
+
+// coalesces reports whether the query is coalesced
+func (db *sqlDB) coalesces(writeFlag bool, query string) bool {
+	return db.coalescer != nil && !writeFlag && (db.coalescer.match == nil || db.coalescer.match(query))
+}
+
+// coalescedQuery runs the query on the node, or waits for the result of the identical query in flight on the node
+func (db *sqlDB) coalescedQuery(ctx context.Context, node *sql.DB, query string, args []interface{}) (*result, error) {
+	key := flightKey{node: node, query: query, args: argsKey(args)}
+	return db.coalescer.do(ctx, key, func() (*result, error) {
+		rows, err := db.queryContext(ctx, node, query, args, false, nil)
+		if err != nil {
+			return nil, err
+		}
+		return readResult(rows)
+	})
+}
+
+// argsKey returns the fingerprint of the args, with their types so 1 and "1" differ.
+// The Go syntax representation quotes the strings, so the separator can't be forged.
+func argsKey(args []interface{}) string {
+	var key strings.Builder
+	for _, arg := range args {
+		fmt.Fprintf(&key, "%T:%#v,", arg, arg)
+	}
+	return key.String()
+}
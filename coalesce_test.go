@@ -0,0 +1,102 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReadCoalescing(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithReadCoalescing(func(query string) bool {
+		return strings.HasPrefix(query, "SELECT name FROM country")
+	}))
+
+	// a single round trip for the identical queries running concurrently
+	query := "SELECT name FROM country WHERE code = ?"
+	replicaMock.ExpectQuery(query).WithArgs("FR").WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("France"))
+	var wg sync.WaitGroup
+	names := make([]string, 10)
+	errs := make([]error, len(names))
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				errs[i] = resolver.QueryRowContext(context.Background(), query, "FR").Scan(&names[i])
+				return
+			}
+			rows, err := resolver.QueryContext(context.Background(), query, "FR")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			defer rows.Close()
+			rows.Next()
+			errs[i] = rows.Scan(&names[i])
+		}()
+	}
+	wg.Wait()
+	for i := range names {
+		if errs[i] != nil || names[i] != "France" {
+			t.Errorf("want France, got %q, %v", names[i], errs[i])
+		}
+	}
+
+	// the queries are run again once the query in flight is done
+	replicaMock.ExpectQuery(query).WithArgs("FR").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("France"))
+	if err := resolver.QueryRow(query, "FR").Scan(new(string)); err != nil {
+		t.Error(err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadCoalescingJoinerContext(t *testing.T) {
+	c := newCoalescer(ReadCoalescing{})
+	key := flightKey{query: "SELECT 1"}
+	started, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		_, _ = c.do(context.Background(), key, func() (*result, error) {
+			close(started)
+			<-done
+			return &result{}, nil
+		})
+	}()
+	<-started
+	defer close(done)
+
+	// a joiner stops waiting for the query in flight when its own context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	res, err := c.do(ctx, key, func() (*result, error) {
+		t.Error("want the joiner waiting for the query in flight")
+		return nil, nil
+	})
+	if res != nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want the context error of the joiner, got %v, %v", res, err)
+	}
+}
+
+func TestArgsKey(t *testing.T) {
+	if argsKey([]interface{}{1, "a"}) == argsKey([]interface{}{"1", "a"}) {
+		t.Error("want the arg types in the key")
+	}
+	if argsKey([]interface{}{"a", "b"}) == argsKey([]interface{}{`a",string:"b`}) {
+		t.Error("want the args separated")
+	}
+}
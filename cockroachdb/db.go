@@ -0,0 +1,151 @@
+// Package cockroachdb adapts the resolver to CockroachDB,
+// serving the reads routed to the replicas with bounded staleness follower reads,
+// and retrying the statements and transactions aborted with a serialization failure (SQLSTATE 40001).
+package cockroachdb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 10 * time.Millisecond
+)
+
+// Config define how the queries are sent to the CockroachDB cluster
+type Config struct {
+	// FollowerReads injects the AS OF SYSTEM TIME follower_read_timestamp() clause
+	// into the eligible read queries, see WithFollowerReads
+	FollowerReads bool
+	// MaxRetries is the number of retries of the statements aborted with a serialization failure,
+	// 3 by default, negative to disable the retries
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled on every retry, 10 milliseconds by default
+	RetryBackoff time.Duration
+}
+
+type crdb struct {
+	dbresolver.DB
+	config Config
+}
+
+// New wraps the resolver of a CockroachDB cluster
+func New(db dbresolver.DB, config Config) dbresolver.DB {
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+	return &crdb{DB: db, config: config}
+}
+
+// Exec executes a query without returning any rows, retrying on serialization failures
+func (db *crdb) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows, retrying on serialization failures
+func (db *crdb) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	err = db.retry(ctx, func() error {
+		res, err = db.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// Query executes a query that returns rows, using the follower reads when eligible
+func (db *crdb) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns rows, using the follower reads when eligible.
+// The query is retried on serialization failures, the errors happening while iterating the rows are not.
+func (db *crdb) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	query = db.rewrite(query)
+	err = db.retry(ctx, func() error {
+		rows, err = db.DB.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow executes a query that is expected to return at most one row, using the follower reads when eligible
+func (db *crdb) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row, using the follower reads when eligible.
+// The query is retried on serialization failures.
+func (db *crdb) QueryRowContext(ctx context.Context, query string, args ...interface{}) (row *sql.Row) {
+	query = db.rewrite(query)
+	_ = db.retry(ctx, func() error {
+		row = db.DB.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+func (db *crdb) rewrite(query string) string {
+	if db.config.FollowerReads {
+		query, _ = WithFollowerReads(query)
+	}
+	return query
+}
+
+func (db *crdb) retry(ctx context.Context, fn func() error) error {
+	return retry(ctx, db.config.MaxRetries, db.config.RetryBackoff, fn)
+}
+
+// ExecuteTx runs fn in a transaction, and runs it again in a new transaction
+// when it's aborted with a serialization failure, up to the configured retries.
+// fn must be idempotent as it can be run multiple times.
+func ExecuteTx(ctx context.Context, db dbresolver.DB, opts *sql.TxOptions, fn func(tx dbresolver.Tx) error) error {
+	config := Config{}
+	if c, ok := db.(*crdb); ok {
+		config = c.config
+	} else {
+		config.MaxRetries, config.RetryBackoff = defaultMaxRetries, defaultRetryBackoff
+	}
+
+	return retry(ctx, config.MaxRetries, config.RetryBackoff, func() error {
+		tx, err := db.BeginTx(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func retry(ctx context.Context, maxRetries int, backoff time.Duration, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(backoff << attempt)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// IsRetryable reports whether the error is a serialization failure (SQLSTATE 40001),
+// the statement or the transaction can be retried
+func IsRetryable(err error) bool {
+	var sqlStateErr interface{ SQLState() string }
+	return errors.As(err, &sqlStateErr) && sqlStateErr.SQLState() == "40001"
+}
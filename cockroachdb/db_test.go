@@ -0,0 +1,98 @@
+package cockroachdb
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+type serializationError struct{}
+
+func (serializationError) Error() string {
+	return "restart transaction: TransactionRetryWithProtoRefreshError"
+}
+func (serializationError) SQLState() string { return "40001" }
+
+func createMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	return db, mock
+}
+
+func TestFollowerReads(t *testing.T) {
+	primary, primaryMock := createMock(t)
+	replica, replicaMock := createMock(t)
+	db := New(dbresolver.New(dbresolver.WithPrimaryDBs(primary), dbresolver.WithReplicaDBs(replica)),
+		Config{FollowerReads: true})
+
+	replicaMock.ExpectQuery("SELECT name FROM users AS OF SYSTEM TIME follower_read_timestamp() WHERE id = $1").
+		WithArgs(1).
+		WillReturnError(serializationError{})
+	replicaMock.ExpectQuery("SELECT name FROM users AS OF SYSTEM TIME follower_read_timestamp() WHERE id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alice"))
+	var name string
+	if err := db.QueryRow("SELECT name FROM users WHERE id = $1", 1).Scan(&name); err != nil || name != "alice" {
+		t.Fatalf("want alice, got %q %v", name, err)
+	}
+
+	primaryMock.ExpectExec("UPDATE users SET name = $1").
+		WithArgs("bob").
+		WillReturnError(serializationError{})
+	primaryMock.ExpectExec("UPDATE users SET name = $1").
+		WithArgs("bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Exec("UPDATE users SET name = $1", "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestExecuteTx(t *testing.T) {
+	primary, mock := createMock(t)
+	db := New(dbresolver.New(dbresolver.WithPrimaryDBs(primary)), Config{MaxRetries: 1})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts SET balance = balance - 1").WillReturnError(serializationError{})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts SET balance = balance - 1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	attempts := 0
+	err := ExecuteTx(context.Background(), db, nil, func(tx dbresolver.Tx) error {
+		attempts++
+		_, err := tx.Exec("UPDATE accounts SET balance = balance - 1")
+		return err
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("want success after 2 attempts, got %v attempts %v", attempts, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts SET balance = balance - 1").WillReturnError(serializationError{})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE accounts SET balance = balance - 1").WillReturnError(serializationError{})
+	mock.ExpectRollback()
+	err = ExecuteTx(context.Background(), db, nil, func(tx dbresolver.Tx) error {
+		_, err := tx.Exec("UPDATE accounts SET balance = balance - 1")
+		return err
+	})
+	if !IsRetryable(err) {
+		t.Errorf("want the serialization failure once the retries are exhausted, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
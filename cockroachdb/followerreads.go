@@ -0,0 +1,141 @@
+package cockroachdb
+
+import (
+	"strings"
+	"unicode"
+)
+
+// FollowerReadTimestamp is the AS OF SYSTEM TIME clause of the follower reads
+const FollowerReadTimestamp = "AS OF SYSTEM TIME follower_read_timestamp()"
+
+// fromClauseEnd are the keywords ending the FROM clause of a SELECT
+var fromClauseEnd = map[string]bool{
+	"WHERE":  true,
+	"GROUP":  true,
+	"HAVING": true,
+	"WINDOW": true,
+	"ORDER":  true,
+	"LIMIT":  true,
+	"OFFSET": true,
+	"FETCH":  true,
+}
+
+// notEligible are the keywords of the SELECT queries that can't be read from the followers
+var notEligible = map[string]bool{
+	"UNION":     true,
+	"INTERSECT": true,
+	"EXCEPT":    true,
+	"FOR":       true,
+	"INTO":      true,
+	"SYSTEM":    true,
+}
+
+type word struct {
+	text string
+	// after is the end of the previous token
+	after int
+}
+
+// WithFollowerReads injects the AS OF SYSTEM TIME follower_read_timestamp() clause at the end of the FROM clause
+// of a single SELECT statement, so the query can be served by the closest replica.
+// The query is returned unmodified when it isn't eligible, eg. a locking read (FOR UPDATE),
+// a compound SELECT (UNION), a CTE, a SELECT without FROM or one already having an AS OF SYSTEM TIME clause.
+func WithFollowerReads(query string) (string, bool) {
+	words, end, ok := scan(query)
+	if !ok || len(words) == 0 || words[0].text != "SELECT" {
+		return query, false
+	}
+
+	from := -1
+	for i, w := range words {
+		if notEligible[w.text] {
+			return query, false
+		}
+		if w.text == "FROM" && from < 0 {
+			from = i
+		}
+	}
+	if from < 0 {
+		return query, false
+	}
+
+	for _, w := range words[from+1:] {
+		if fromClauseEnd[w.text] {
+			end = w.after
+			break
+		}
+	}
+	return query[:end] + " " + FollowerReadTimestamp + query[end:], true
+}
+
+// scan returns the upper cased words of the query outside of parentheses, literals and comments,
+// and the end of the statement, ignoring the trailing comments and semicolon.
+// It returns false when the query contains multiple statements, or unbalanced parentheses.
+func scan(query string) (words []word, end int, ok bool) {
+	depth := 0
+	for i := 0; i < len(query); {
+		start, c := i, query[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			next := strings.IndexByte(query[i:], '\n')
+			if next < 0 {
+				next = len(query) - i
+			}
+			i += next
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			next := strings.Index(query[i+2:], "*/")
+			if next < 0 {
+				return nil, 0, false
+			}
+			i += next + 4
+			continue
+		case c == ';':
+			if depth == 0 {
+				// the statement ends, only comments and semicolons may follow
+				if _, rest, ok := scan(query[i+1:]); !ok || rest > 0 {
+					return nil, 0, false
+				}
+				return words, end, true
+			}
+			i++
+			continue
+		case c == '\'' || c == '"':
+			next := strings.IndexByte(query[i+1:], c)
+			if next < 0 {
+				return nil, 0, false
+			}
+			i += next + 2
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return nil, 0, false
+			}
+			i++
+		case isWordChar(c):
+			for i < len(query) && isWordChar(query[i]) {
+				i++
+			}
+			if depth == 0 {
+				words = append(words, word{text: strings.ToUpper(query[start:i]), after: end})
+			}
+		default:
+			i++
+		}
+		end = i
+	}
+	if depth != 0 {
+		return nil, 0, false
+	}
+	return words, end, true
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,83 @@
+package cockroachdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithFollowerReads(t *testing.T) {
+	tests := []struct {
+		query    string
+		want     string
+		eligible bool
+	}{
+		{
+			query:    "SELECT * FROM users",
+			want:     "SELECT * FROM users AS OF SYSTEM TIME follower_read_timestamp()",
+			eligible: true,
+		},
+		{
+			query:    "select id, name from users where id = $1 order by name;",
+			want:     "select id, name from users AS OF SYSTEM TIME follower_read_timestamp() where id = $1 order by name;",
+			eligible: true,
+		},
+		{
+			query:    "SELECT u.id FROM users u JOIN orders o ON o.user_id = u.id\nLIMIT 10",
+			want:     "SELECT u.id FROM users u JOIN orders o ON o.user_id = u.id AS OF SYSTEM TIME follower_read_timestamp()\nLIMIT 10",
+			eligible: true,
+		},
+		{
+			query:    "SELECT (SELECT max(id) FROM orders WHERE 'for' = 'for') FROM users -- where\n",
+			want:     "SELECT (SELECT max(id) FROM orders WHERE 'for' = 'for') FROM users AS OF SYSTEM TIME follower_read_timestamp() -- where\n",
+			eligible: true,
+		},
+		{
+			query:    `SELECT * FROM "Users" /* hint */ WHERE id = 1`,
+			want:     `SELECT * FROM "Users" AS OF SYSTEM TIME follower_read_timestamp() /* hint */ WHERE id = 1`,
+			eligible: true,
+		},
+		{query: "SELECT * FROM users FOR UPDATE"},
+		{query: "SELECT id FROM users UNION SELECT id FROM admins"},
+		{query: "SELECT * FROM users AS OF SYSTEM TIME '-10s'"},
+		{query: "WITH u AS (SELECT * FROM users) SELECT * FROM u"},
+		{query: "SELECT 1"},
+		{query: "SELECT * FROM users; DELETE FROM users"},
+		{query: "INSERT INTO users (name) VALUES ('from')"},
+		{query: "SELECT * FROM users WHERE name = 'unterminated"},
+	}
+
+	for _, tt := range tests {
+		got, eligible := WithFollowerReads(tt.query)
+		want := tt.want
+		if !tt.eligible {
+			want = tt.query
+		}
+		if got != want || eligible != tt.eligible {
+			t.Errorf("WithFollowerReads(%q) = %q, %v, want %q, %v", tt.query, got, eligible, want, tt.eligible)
+		}
+	}
+}
+
+func FuzzWithFollowerReads(f *testing.F) {
+	f.Add("SELECT * FROM users WHERE id = $1")
+	f.Add("SELECT (SELECT 1 FROM t) FROM users -- x\n;")
+	f.Add("SELECT 'unterminated FROM users")
+	f.Add("SELECT * FROM users; )")
+	f.Add("SELECT * FROM users" + strings.Repeat(";", 64))
+	f.Fuzz(func(t *testing.T, query string) {
+		got, ok := WithFollowerReads(query)
+		if !ok {
+			if got != query {
+				t.Errorf("want the ineligible query %q unmodified, got %q", query, got)
+			}
+			return
+		}
+		// the eligible query only gains the clause
+		if strings.Replace(got, " "+FollowerReadTimestamp, "", 1) != query && !strings.Contains(query, FollowerReadTimestamp) {
+			t.Errorf("want only the clause injected into %q, got %q", query, got)
+		}
+		if _, again := WithFollowerReads(got); again {
+			t.Errorf("want the query with follower reads %q ineligible", got)
+		}
+	})
+}
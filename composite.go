@@ -0,0 +1,155 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnknownCluster is returned when a query is routed to a cluster which isn't part of the composite
+var ErrUnknownCluster = errors.New("dbresolver: unknown cluster")
+
+// CompositeConfig define the clusters of a composite and how the queries are routed between them
+type CompositeConfig struct {
+	// Clusters are the resolvers of the clusters by name, eg. "core", "analytics" and "audit"
+	Clusters map[string]DB
+	// Route returns the name of the cluster of the query, or an empty name for the default cluster.
+	// The cluster set on the context with WithCluster takes precedence.
+	Route func(ctx context.Context, query string) string
+	// Default is the name of the cluster of the queries without route
+	Default string
+}
+
+// Composite is a facade of multiple clusters, routing each query to the resolver of its cluster,
+// so the application keeps a single dependency while its data lives in multiple clusters.
+// The transactions and the statements are bound to the cluster of their context and query.
+type Composite struct {
+	config CompositeConfig
+	names  []string
+	// results holds the routing errors of QueryRowContext
+	results *sql.DB
+}
+
+// NewComposite creates the composite of the clusters
+func NewComposite(config CompositeConfig) *Composite {
+	if _, ok := config.Clusters[config.Default]; !ok && config.Default != "" {
+		panic(fmt.Sprintf("dbresolver: the default cluster %q isn't a cluster of the composite", config.Default))
+	}
+	names := make([]string, 0, len(config.Clusters))
+	for name := range config.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &Composite{config: config, names: names, results: newResultDB()}
+}
+
+type clusterKey struct{}
+
+// WithCluster returns a context routing the queries of a composite to the named cluster
+func WithCluster(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, clusterKey{}, name)
+}
+
+// ClusterFromContext returns the cluster of the context, set by WithCluster
+func ClusterFromContext(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(clusterKey{}).(string)
+	return name, ok
+}
+
+// Cluster returns the resolver of the named cluster
+func (c *Composite) Cluster(name string) (DB, bool) {
+	db, ok := c.config.Clusters[name]
+	return db, ok
+}
+
+// Clusters returns the sorted names of the clusters
+func (c *Composite) Clusters() []string {
+	return append([]string(nil), c.names...)
+}
+
+// Resolve returns the resolver of the cluster of the query
+func (c *Composite) Resolve(ctx context.Context, query string) (DB, error) {
+	name, ok := ClusterFromContext(ctx)
+	if !ok && c.config.Route != nil {
+		name = c.config.Route(ctx, query)
+	}
+	if name == "" {
+		name = c.config.Default
+	}
+	db, ok := c.config.Clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownCluster, name)
+	}
+	return db, nil
+}
+
+// ExecContext executes the query on the resolver of its cluster
+func (c *Composite) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	db, err := c.Resolve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs the query on the resolver of its cluster
+func (c *Composite) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	db, err := c.Resolve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs the query on the resolver of its cluster.
+// The routing error is deferred until Row's Scan method is called.
+func (c *Composite) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	db, err := c.Resolve(ctx, query)
+	if err != nil {
+		return resultRow(ctx, c.results, nil, err)
+	}
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// PrepareContext prepares the statement on the resolver of its cluster
+func (c *Composite) PrepareContext(ctx context.Context, query string) (Stmt, error) {
+	db, err := c.Resolve(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return db.PrepareContext(ctx, query)
+}
+
+// BeginTx starts a transaction on the cluster of the context, see WithCluster, the default cluster otherwise.
+// The route function is called with an empty query.
+func (c *Composite) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
+	db, err := c.Resolve(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return db.BeginTx(ctx, opts)
+}
+
+// PingContext pings every cluster
+func (c *Composite) PingContext(ctx context.Context) error {
+	var errs []error
+	for _, name := range c.names {
+		if err := c.config.Clusters[name].PingContext(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every cluster
+func (c *Composite) Close() error {
+	errs := []error{c.results.Close()}
+	for _, name := range c.names {
+		if err := c.config.Clusters[name].Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
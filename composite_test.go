@@ -0,0 +1,95 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestComposite(t *testing.T) {
+	core, coreMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	analytics, analyticsMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	composite := NewComposite(CompositeConfig{
+		Clusters: map[string]DB{
+			"core":      New(WithPrimaryDBs(core)),
+			"analytics": New(WithPrimaryDBs(analytics)),
+		},
+		Route: func(_ context.Context, query string) string {
+			if strings.Contains(query, "FROM events") {
+				return "analytics"
+			}
+			return ""
+		},
+		Default: "core",
+	})
+
+	analyticsMock.ExpectQuery("SELECT count(*) FROM events").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+	var count int
+	if err := composite.QueryRowContext(context.Background(), "SELECT count(*) FROM events").Scan(&count); err != nil || count != 42 {
+		t.Errorf("want the query routed to analytics, got %d, %v", count, err)
+	}
+	coreMock.ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := composite.ExecContext(context.Background(), "DELETE FROM book"); err != nil {
+		t.Errorf("want the query routed to the default cluster: %v", err)
+	}
+
+	// the cluster of the context takes precedence
+	analyticsMock.ExpectBegin()
+	analyticsMock.ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	analyticsMock.ExpectCommit()
+	ctx := WithCluster(context.Background(), "analytics")
+	tx, err := composite.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM book"); err != nil {
+		t.Error(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Error(err)
+	}
+
+	if names := composite.Clusters(); len(names) != 2 || names[0] != "analytics" || names[1] != "core" {
+		t.Errorf("want the sorted clusters, got %v", names)
+	}
+
+	coreMock.ExpectClose()
+	analyticsMock.ExpectClose()
+	if err := composite.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{coreMock, analyticsMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCompositeUnknownCluster(t *testing.T) {
+	core, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	composite := NewComposite(CompositeConfig{Clusters: map[string]DB{"core": New(WithPrimaryDBs(core))}})
+
+	// there is no default cluster
+	if _, err := composite.QueryContext(context.Background(), "SELECT 1"); !errors.Is(err, ErrUnknownCluster) {
+		t.Errorf("want ErrUnknownCluster, got %v", err)
+	}
+	ctx := WithCluster(context.Background(), "audit")
+	if err := composite.QueryRowContext(ctx, "SELECT 1").Scan(new(int)); !errors.Is(err, ErrUnknownCluster) {
+		t.Errorf("want ErrUnknownCluster from the row, got %v", err)
+	}
+	if _, err := composite.BeginTx(ctx, nil); !errors.Is(err, ErrUnknownCluster) {
+		t.Errorf("want ErrUnknownCluster, got %v", err)
+	}
+}
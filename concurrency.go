@@ -0,0 +1,209 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Defaults of the adaptive concurrency limits
+const (
+	defaultConcurrencyInitialLimit     = 10
+	defaultConcurrencyMinLimit         = 1
+	defaultConcurrencyLatencyThreshold = 100 * time.Millisecond
+	defaultConcurrencyBackoff          = 0.5
+)
+
+// AdaptiveConcurrency define the adaptive concurrency limit of each node.
+// The limit grows by one per limit of queries completed without congestion, and is multiplied by Backoff
+// when a query completes slower than LatencyThreshold or after the node waited for a connection,
+// at most once per LatencyThreshold.
+type AdaptiveConcurrency struct {
+	// InitialLimit is the limit of each node at start, 10 by default
+	InitialLimit int
+	// MinLimit is the lowest limit, 1 by default
+	MinLimit int
+	// MaxLimit is the highest limit, the MaxOpenConnections of the node by default, unbounded without connection limit
+	MaxLimit int
+	// LatencyThreshold is the query latency above which the node is considered queueing, 100ms by default
+	LatencyThreshold time.Duration
+	// Backoff is the multiplicative decrease of the limit, in (0, 1), 0.5 by default
+	Backoff float64
+}
+
+// WithAdaptiveConcurrency bounds the queries running concurrently on each node by a limit adapting,
+// AIMD-style, to the latency and the connection waits of the node, so the resolver stops piling work
+// onto a node that is already queueing internally. A query waits for a slot until its context is done.
+// The current limits are exposed by DB.Nodes, the limit of a node is dropped when it leaves the topology.
+//
+// A slot is held until Exec returns, until the rows of Query are closed, or until the row of QueryRow is scanned,
+// like WithWorkloadPartitions: the latency of a read covers reading its rows.
+func WithAdaptiveConcurrency(config AdaptiveConcurrency) OptionFunc {
+	return func(opt *Option) {
+		opt.AdaptiveConcurrency = &config
+	}
+}
+
+// concurrencyLimit is the adaptive limit of a node, changed is closed and replaced when a slot may be free
+type concurrencyLimit struct {
+	limit        float64
+	inFlight     int
+	waitCount    int64
+	lastDecrease time.Time
+	changed      chan struct{}
+}
+
+// concurrencyLimiter holds the adaptive concurrency limit of each node
+type concurrencyLimiter struct {
+	config AdaptiveConcurrency
+	clock  Clock
+
+	mu     sync.Mutex
+	limits map[*sql.DB]*concurrencyLimit
+}
+
+func newConcurrencyLimiter(config AdaptiveConcurrency, clock Clock) *concurrencyLimiter {
+	if config.InitialLimit <= 0 {
+		config.InitialLimit = defaultConcurrencyInitialLimit
+	}
+	if config.MinLimit <= 0 {
+		config.MinLimit = defaultConcurrencyMinLimit
+	}
+	if config.LatencyThreshold <= 0 {
+		config.LatencyThreshold = defaultConcurrencyLatencyThreshold
+	}
+	if config.Backoff <= 0 || config.Backoff >= 1 {
+		config.Backoff = defaultConcurrencyBackoff
+	}
+	return &concurrencyLimiter{config: config, clock: clock, limits: make(map[*sql.DB]*concurrencyLimit)}
+}
+
+// nodeLimit returns the limit of the node, must be called with the lock held
+func (l *concurrencyLimiter) nodeLimit(node *sql.DB) *concurrencyLimit {
+	limit, ok := l.limits[node]
+	if !ok {
+		limit = &concurrencyLimit{
+			limit:     l.clamp(node, float64(l.config.InitialLimit)),
+			waitCount: node.Stats().WaitCount,
+			changed:   make(chan struct{}),
+		}
+		l.limits[node] = limit
+	}
+	return limit
+}
+
+// clamp bounds the limit of the node
+func (l *concurrencyLimiter) clamp(node *sql.DB, limit float64) float64 {
+	maxLimit := l.config.MaxLimit
+	if maxLimit == 0 {
+		maxLimit = node.Stats().MaxOpenConnections
+	}
+	if maxLimit > 0 {
+		limit = min(limit, float64(maxLimit))
+	}
+	return max(limit, float64(l.config.MinLimit))
+}
+
+// acquire waits for a slot on the node, the release function frees it and adapts the limit
+func (l *concurrencyLimiter) acquire(ctx context.Context, node *sql.DB) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	for {
+		l.mu.Lock()
+		limit := l.nodeLimit(node)
+		if limit.inFlight < int(limit.limit) {
+			limit.inFlight++
+			l.mu.Unlock()
+			start := l.clock.Now()
+			return func() { l.release(node, limit, l.clock.Now().Sub(start)) }, nil
+		}
+		changed := limit.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (l *concurrencyLimiter) release(node *sql.DB, limit *concurrencyLimit, latency time.Duration) {
+	waitCount := node.Stats().WaitCount
+	now := l.clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit.inFlight--
+	waited := waitCount > limit.waitCount
+	limit.waitCount = waitCount
+	switch {
+	case latency > l.config.LatencyThreshold || waited:
+		if now.Sub(limit.lastDecrease) >= l.config.LatencyThreshold {
+			limit.limit = l.clamp(node, limit.limit*l.config.Backoff)
+			limit.lastDecrease = now
+		}
+	default:
+		limit.limit = l.clamp(node, limit.limit+1/limit.limit)
+	}
+	close(limit.changed)
+	limit.changed = make(chan struct{})
+}
+
+// limit returns the current limit of the node, zero when the limits aren't adaptive
+func (l *concurrencyLimiter) limit(node *sql.DB) int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.nodeLimit(node).limit)
+}
+
+// forget drops the limits of the nodes removed from the topology, the queries holding a slot of a node
+// release it as usual, and the queries waiting for one are woken up
+func (l *concurrencyLimiter) forget(nodes []*sql.DB) {
+	if l == nil || len(nodes) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, node := range nodes {
+		if limit, ok := l.limits[node]; ok {
+			close(limit.changed)
+			limit.changed = make(chan struct{})
+			delete(l.limits, node)
+		}
+	}
+}
+
+// acquire waits for an admission slot of the resolver, then for a slot of the workload class of the context
+// on the node, then for a slot of the node
+func (db *sqlDB) acquire(ctx context.Context, node *sql.DB) (release func(), err error) {
+	if db.concurrency == nil && db.admission == nil {
+		// the release isn't wrapped, most resolvers don't limit the nodes
+		return db.partitions.acquire(ctx, node)
+	}
+	releaseAdmission, err := db.admission.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	releasePartition, err := db.partitions.acquire(ctx, node)
+	if err != nil {
+		releaseAdmission()
+		return nil, err
+	}
+	releaseLimit, err := db.concurrency.acquire(ctx, node)
+	if err != nil {
+		releasePartition()
+		releaseAdmission()
+		return nil, err
+	}
+	return func() {
+		releaseLimit()
+		releasePartition()
+		releaseAdmission()
+	}, nil
+}
@@ -0,0 +1,165 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	now := time.Now()
+	resolver := New(WithPrimaryDBs(primary), WithAdaptiveConcurrency(AdaptiveConcurrency{
+		InitialLimit:     2,
+		MaxLimit:         3,
+		LatencyThreshold: time.Second,
+	}), WithClock(stubClock{now: &now})).(*sqlDB)
+
+	if limit := resolver.Nodes()[0].ConcurrencyLimit; limit != 2 {
+		t.Errorf("want the initial limit, got %d", limit)
+	}
+
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := resolver.concurrency.acquire(context.Background(), primary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := resolver.ExecContext(ctx, "DELETE FROM book"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want the query waiting for a slot, got %v", err)
+	}
+
+	// a slow query halves the limit, once per latency threshold
+	now = now.Add(2 * time.Second)
+	releases[0]()
+	releases[1]()
+	if limit := resolver.Nodes()[0].ConcurrencyLimit; limit != 1 {
+		t.Errorf("want the limit decreased, got %d", limit)
+	}
+
+	// the fast queries grow the limit up to the max limit
+	for i := 0; i < 10; i++ {
+		primaryMock.ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+		if _, err := resolver.Exec("DELETE FROM book"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if limit := resolver.Nodes()[0].ConcurrencyLimit; limit != 3 {
+		t.Errorf("want the limit increased to the max, got %d", limit)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAdaptiveConcurrencyRows(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	now := time.Now()
+	resolver := New(WithPrimaryDBs(primary), WithAdaptiveConcurrency(AdaptiveConcurrency{
+		InitialLimit:     2,
+		LatencyThreshold: time.Second,
+	}), WithClock(stubClock{now: &now})).(*sqlDB)
+
+	// the open rows hold their slot, and their latency is sampled when they're closed
+	primaryMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	rows, err := resolver.Query("SELECT id FROM book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.concurrency.mu.Lock()
+	inFlight := resolver.concurrency.limits[primary].inFlight
+	resolver.concurrency.mu.Unlock()
+	if inFlight != 1 {
+		t.Errorf("want the slot held by the open rows, got %d in flight", inFlight)
+	}
+	now = now.Add(2 * time.Second)
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if limit := resolver.Nodes()[0].ConcurrencyLimit; limit != 1 {
+		t.Errorf("want the limit decreased by the rows streamed slower than the threshold, got %d", limit)
+	}
+}
+
+func TestAdaptiveConcurrencyRemovedNode(t *testing.T) {
+	nodes := make([]*sql.DB, 3)
+	for i := range nodes {
+		node, _, err := createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+		nodes[i] = node
+	}
+	primary, replica1, replica2 := nodes[0], nodes[1], nodes[2]
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica1, replica2),
+		WithAdaptiveConcurrency(AdaptiveConcurrency{InitialLimit: 1})).(*sqlDB)
+
+	release, err := resolver.concurrency.acquire(context.Background(), replica1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver.Nodes()
+	if n := len(resolver.concurrency.limits); n != 3 {
+		t.Fatalf("want the limit of each node, got %d", n)
+	}
+
+	// the limit of a node is dropped when it leaves the topology, its slots are released as usual
+	resolver.RemoveReplica(replica1)
+	resolver.AddPrimary(replica2)
+	if _, ok := resolver.concurrency.limits[replica1]; ok || len(resolver.concurrency.limits) != 2 {
+		t.Errorf("want the limit of the removed node dropped, got %v", resolver.concurrency.limits)
+	}
+	release()
+	if _, ok := resolver.concurrency.limits[replica1]; ok {
+		t.Error("want the limit of the removed node not recreated by the release")
+	}
+}
+
+func TestAdaptiveConcurrencyWaitingSlot(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	limiter := newConcurrencyLimiter(AdaptiveConcurrency{InitialLimit: 1}, systemClock{})
+	release, err := limiter.acquire(context.Background(), primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		release, err := limiter.acquire(context.Background(), primary)
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- release
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("want the second query waiting for the slot")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	(<-acquired)()
+
+	if limit := limiter.limit(primary); limit != 2 {
+		t.Errorf("want the limit increased by the fast queries, got %d", limit)
+	}
+	if got := (*concurrencyLimiter)(nil).limit(primary); got != 0 {
+		t.Errorf("want no limit without adaptive concurrency, got %d", got)
+	}
+}
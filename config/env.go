@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables when none is given
+const DefaultEnvPrefix = "DBRESOLVER"
+
+// Environment variables of the configuration, prefixed with the prefix and an underscore
+const (
+	EnvDriver                = "DRIVER"
+	EnvPrimaryDSNs           = "PRIMARY_DSNS"
+	EnvReplicaDSNs           = "REPLICA_DSNS"
+	EnvLoadBalancer          = "LB"
+	EnvMaxOpenConns          = "MAX_OPEN_CONNS"
+	EnvMaxIdleConns          = "MAX_IDLE_CONNS"
+	EnvConnMaxLifetime       = "CONN_MAX_LIFETIME"
+	EnvConnMaxIdleTime       = "CONN_MAX_IDLE_TIME"
+	EnvConnMaxLifetimeJitter = "CONN_MAX_LIFETIME_JITTER"
+	EnvHealthCheckOnStart    = "HEALTH_CHECK_ON_START"
+	EnvHealthCheckTimeout    = "HEALTH_CHECK_TIMEOUT"
+	EnvWriteKeywords         = "WRITE_KEYWORDS"
+)
+
+// FromEnv reads the configuration from the environment variables, eg. with the DBRESOLVER prefix:
+//
+//	DBRESOLVER_DRIVER=postgres
+//	DBRESOLVER_PRIMARY_DSNS=postgres://primary:5432/app
+//	DBRESOLVER_REPLICA_DSNS=postgres://replica-1:5432/app,postgres://replica-2:5432/app
+//	DBRESOLVER_LB=round_robin
+//	DBRESOLVER_MAX_OPEN_CONNS=20
+//	DBRESOLVER_MAX_IDLE_CONNS=5
+//	DBRESOLVER_CONN_MAX_LIFETIME=30m
+//	DBRESOLVER_CONN_MAX_IDLE_TIME=5m
+//	DBRESOLVER_CONN_MAX_LIFETIME_JITTER=0.1
+//	DBRESOLVER_HEALTH_CHECK_ON_START=true
+//	DBRESOLVER_HEALTH_CHECK_TIMEOUT=5s
+//	DBRESOLVER_WRITE_KEYWORDS=RETURNING,FOR UPDATE
+//
+// The DSNs and the keywords are comma separated.
+func FromEnv(prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	env := func(name string) string {
+		return strings.TrimSpace(os.Getenv(prefix + "_" + name))
+	}
+	list := func(name string) []string {
+		var values []string
+		for _, value := range strings.Split(env(name), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+
+	cfg := &Config{
+		Driver:       env(EnvDriver),
+		LoadBalancer: env(EnvLoadBalancer),
+	}
+	for _, dsn := range list(EnvPrimaryDSNs) {
+		cfg.Nodes = append(cfg.Nodes, Node{Role: dbresolver.RolePrimary, DSN: dsn})
+	}
+	for _, dsn := range list(EnvReplicaDSNs) {
+		cfg.Nodes = append(cfg.Nodes, Node{Role: dbresolver.RoleReplica, DSN: dsn})
+	}
+	cfg.Routing.WriteKeywords = list(EnvWriteKeywords)
+
+	var err error
+	for name, value := range map[string]*int{
+		EnvMaxOpenConns: &cfg.Pool.MaxOpenConns,
+		EnvMaxIdleConns: &cfg.Pool.MaxIdleConns,
+	} {
+		if v := env(name); v != "" {
+			if *value, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("dbresolver/config: %s_%s: %w", prefix, name, err)
+			}
+		}
+	}
+	for name, value := range map[string]*Duration{
+		EnvConnMaxLifetime:    &cfg.Pool.ConnMaxLifetime,
+		EnvConnMaxIdleTime:    &cfg.Pool.ConnMaxIdleTime,
+		EnvHealthCheckTimeout: &cfg.HealthCheck.Timeout,
+	} {
+		if v := env(name); v != "" {
+			if err = value.UnmarshalText([]byte(v)); err != nil {
+				return nil, fmt.Errorf("dbresolver/config: %s_%s: %w", prefix, name, err)
+			}
+		}
+	}
+	if v := env(EnvConnMaxLifetimeJitter); v != "" {
+		if cfg.Pool.ConnMaxLifetimeJitter, err = strconv.ParseFloat(v, 64); err != nil {
+			return nil, fmt.Errorf("dbresolver/config: %s_%s: %w", prefix, EnvConnMaxLifetimeJitter, err)
+		}
+	}
+	if v := env(EnvHealthCheckOnStart); v != "" {
+		if cfg.HealthCheck.OnStart, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("dbresolver/config: %s_%s: %w", prefix, EnvHealthCheckOnStart, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// NewFromEnv reads the configuration from the environment variables with the prefix,
+// DBRESOLVER when empty, and builds the resolver. See FromEnv for the variables.
+func NewFromEnv(ctx context.Context, prefix string, opts ...dbresolver.OptionFunc) (dbresolver.DB, error) {
+	cfg, err := FromEnv(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Build(ctx, nil, opts...)
+}
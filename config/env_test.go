@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("APP_DB_DRIVER", "postgres")
+	t.Setenv("APP_DB_PRIMARY_DSNS", "postgres://primary:5432/app")
+	t.Setenv("APP_DB_REPLICA_DSNS", "postgres://replica-1:5432/app, postgres://replica-2:5432/app,")
+	t.Setenv("APP_DB_LB", "random")
+	t.Setenv("APP_DB_MAX_OPEN_CONNS", "20")
+	t.Setenv("APP_DB_CONN_MAX_LIFETIME", "30m")
+	t.Setenv("APP_DB_CONN_MAX_LIFETIME_JITTER", "0.1")
+	t.Setenv("APP_DB_HEALTH_CHECK_ON_START", "true")
+	t.Setenv("APP_DB_WRITE_KEYWORDS", "RETURNING,FOR UPDATE")
+
+	cfg, err := FromEnv("APP_DB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Driver != "postgres" || cfg.LoadBalancer != "random" || len(cfg.Nodes) != 3 ||
+		cfg.Nodes[2].DSN != "postgres://replica-2:5432/app" {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.Pool.MaxOpenConns != 20 || time.Duration(cfg.Pool.ConnMaxLifetime) != 30*time.Minute || cfg.Pool.ConnMaxLifetimeJitter != 0.1 ||
+		!cfg.HealthCheck.OnStart || len(cfg.Routing.WriteKeywords) != 2 {
+		t.Errorf("unexpected settings %+v %+v %+v", cfg.Pool, cfg.HealthCheck, cfg.Routing)
+	}
+}
+
+func TestFromEnvInvalid(t *testing.T) {
+	t.Setenv("DBRESOLVER_DRIVER", "postgres")
+	if _, err := FromEnv(""); err == nil {
+		t.Error("want error without primary")
+	}
+
+	t.Setenv("DBRESOLVER_PRIMARY_DSNS", "postgres://primary:5432/app")
+	t.Setenv("DBRESOLVER_MAX_IDLE_CONNS", "five")
+	if _, err := FromEnv(""); err == nil {
+		t.Error("want error for invalid number")
+	}
+}
@@ -3,7 +3,6 @@ package dbresolver
 import (
 	"context"
 	"database/sql"
-	"strings"
 )
 
 // Conn is a *sql.Conn wrapper.
@@ -11,10 +10,13 @@ import (
 type Conn interface {
 	Close() error
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 	PingContext(ctx context.Context) error
 	PrepareContext(ctx context.Context, query string) (Stmt, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 	Raw(f func(driverConn interface{}) error) (err error)
 }
@@ -22,6 +24,15 @@ type Conn interface {
 type conn struct {
 	sourceDB *sql.DB
 	conn     *sql.Conn
+	// route is the route of the queries of the connection, a replica for the connections of ReplicaConn
+	route Route
+	hooks []Hooks
+	// defaultTxOptions are the options of the transactions begun with nil options
+	defaultTxOptions *sql.TxOptions
+	// retry retries the queries failing with a transient error, nil without retry policy
+	retry *retrier
+	// results serves the rows of the statements which couldn't be prepared in the transactions
+	results *sql.DB
 }
 
 func (c *conn) Close() error {
@@ -29,7 +40,7 @@ func (c *conn) Close() error {
 }
 
 func (c *conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
-	stx, err := c.conn.BeginTx(ctx, opts)
+	stx, err := c.conn.BeginTx(ctx, txOptions(opts, c.defaultTxOptions))
 	if err != nil {
 		return nil, err
 	}
@@ -37,11 +48,32 @@ func (c *conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
 	return &tx{
 		sourceDB: c.sourceDB,
 		tx:       stx,
+		route:    c.route,
+		hooks:    c.hooks,
+		results:  c.results,
 	}, nil
 }
 
-func (c *conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return c.conn.ExecContext(ctx, query, args...)
+// txOptions returns the options of a transaction, the default options when they're nil
+func txOptions(opts, defaults *sql.TxOptions) *sql.TxOptions {
+	if opts == nil && defaults != nil {
+		opts = &sql.TxOptions{Isolation: defaults.Isolation, ReadOnly: defaults.ReadOnly}
+	}
+	return opts
+}
+
+func (c *conn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), query, args...)
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	err = c.retry.do(ctx, true, func() error {
+		res, err = execWithHooks(ctx, c.hooks, c.route.to(c.sourceDB), query, args, func(ctx context.Context) (sql.Result, error) {
+			return c.conn.ExecContext(ctx, query, args...)
+		})
+		return err
+	})
+	return res, err
 }
 
 func (c *conn) PingContext(ctx context.Context) error {
@@ -54,18 +86,35 @@ func (c *conn) PrepareContext(ctx context.Context, query string) (Stmt, error) {
 		return nil, err
 	}
 
-	_query := strings.ToUpper(query)
-	writeFlag := strings.Contains(_query, "RETURNING")
+	return newSingleDBStmt(c.sourceDB, pstmt, isReturning(query), query, c.hooks, c.retry), nil
+}
+
+func (c *conn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.QueryContext(context.Background(), query, args...)
+}
 
-	return newSingleDBStmt(c.sourceDB, pstmt, writeFlag), nil
+func (c *conn) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	err = c.retry.do(ctx, isReturning(query), func() error {
+		rows, err = queryWithHooks(ctx, c.hooks, c.route.to(c.sourceDB), query, args, func(ctx context.Context) (*sql.Rows, error) {
+			return c.conn.QueryContext(ctx, query, args...)
+		})
+		return err
+	})
+	return rows, err
 }
 
-func (c *conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return c.conn.QueryContext(ctx, query, args...)
+func (c *conn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.QueryRowContext(context.Background(), query, args...)
 }
 
-func (c *conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return c.conn.QueryRowContext(ctx, query, args...)
+func (c *conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) (row *sql.Row) {
+	_ = c.retry.do(ctx, isReturning(query), func() error {
+		row = queryRowWithHooks(ctx, c.hooks, c.route.to(c.sourceDB), query, args, func(ctx context.Context) *sql.Row {
+			return c.conn.QueryRowContext(ctx, query, args...)
+		})
+		return row.Err()
+	})
+	return row
 }
 
 func (c *conn) Raw(f func(driverConn interface{}) error) (err error) {
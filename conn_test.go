@@ -0,0 +1,93 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRoleConn(t *testing.T) {
+	primary1, primary1Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	primary2, primary2Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, replica1Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, replica2Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	logger := &recordingLogger{}
+	resolver := New(WithPrimaryDBs(primary1, primary2), WithReplicaDBs(replica1, replica2), WithLogger(logger))
+	ctx := context.Background()
+
+	// the connections are balanced across the nodes of the role
+	seen := map[*sql.DB]int{}
+	for _, connOf := range []func(context.Context) (Conn, error){resolver.PrimaryConn, resolver.ReplicaConn} {
+		for i := 0; i < 2; i++ {
+			c, err := connOf(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen[c.(*conn).sourceDB]++
+			c.Close()
+		}
+	}
+	if seen[primary1] != 1 || seen[primary2] != 1 || seen[replica1] != 1 || seen[replica2] != 1 {
+		t.Errorf("want a connection of every node, got %v", seen)
+	}
+
+	// the statements prepared on the connection are bound to its transactions, on the replica
+	c, err := resolver.ReplicaConn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	replicaMock := replica1Mock
+	if c.(*conn).sourceDB == replica2 {
+		replicaMock = replica2Mock
+	}
+	query := "SELECT name FROM users"
+	replicaMock.ExpectPrepare(query)
+	replicaMock.ExpectBegin()
+	replicaMock.ExpectPrepare(query).ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo"))
+	replicaMock.ExpectCommit()
+	stmt, err := c.PrepareContext(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := c.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := tx.StmtContext(ctx, stmt).QueryRowContext(ctx).Scan(&name); err != nil || name != "foo" {
+		t.Errorf("want the row of the replica, got %q %v", name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	replicaMock.ExpectExec("SET search_path TO reports").WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := c.ExecContext(ctx, "SET search_path TO reports"); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.entries) == 0 || !strings.Contains(logger.entries[len(logger.entries)-1], "{role replica}") {
+		t.Errorf("want the queries of the connection routed to the replica, got %q", logger.entries)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primary1Mock, primary2Mock, replica1Mock, replica2Mock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// Connector is a driver.Connector backed by the resolver, so the libraries accepting only a *sql.DB,
+// like sqlx, ent or the migration tools, route their queries with the resolver:
+//
+//	connectionDB := dbresolver.New(dbresolver.WithPrimaryDBs(primary), dbresolver.WithReplicaDBs(replica))
+//	db := dbresolver.NewConnector(connectionDB).OpenDB()
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM book") // will use the replica
+//
+// The connections of the *sql.DB are virtual: each query is sent to the resolver, which routes it
+// like its own queries and returns its connection to its pools after the query. A transaction is pinned
+// to a connection until it ends, on a primary, or on a replica for the read-only transactions like DB.BeginTx.
+// The statements prepared with the *sql.DB aren't prepared
+// on the nodes, they are sent as queries. The resolver isn't closed with the *sql.DB.
+type Connector struct {
+	db DB
+}
+
+var (
+	_ driver.Connector = (*Connector)(nil)
+	_ driver.Driver    = (*Connector)(nil)
+)
+
+// NewConnector creates the connector of the resolver
+func NewConnector(db DB) *Connector {
+	return &Connector{db: db}
+}
+
+// OpenDB opens the *sql.DB backed by the connector
+func (c *Connector) OpenDB() *sql.DB {
+	return sql.OpenDB(c)
+}
+
+// Connect returns a virtual connection of the resolver
+func (c *Connector) Connect(context.Context) (driver.Conn, error) {
+	return &resolverConn{db: c.db}, nil
+}
+
+// Driver returns the connector itself, opening the virtual connections whatever the name
+func (c *Connector) Driver() driver.Driver {
+	return c
+}
+
+// Open returns a virtual connection of the resolver, the name is ignored
+func (c *Connector) Open(string) (driver.Conn, error) {
+	return c.Connect(context.Background())
+}
+
+// resolverConn is a virtual connection sending its queries to the resolver, or to its transaction
+type resolverConn struct {
+	db DB
+	// tx is the transaction in progress, nil outside a transaction
+	tx Tx
+}
+
+var (
+	_ driver.Conn               = (*resolverConn)(nil)
+	_ driver.ConnBeginTx        = (*resolverConn)(nil)
+	_ driver.ExecerContext      = (*resolverConn)(nil)
+	_ driver.QueryerContext     = (*resolverConn)(nil)
+	_ driver.Pinger             = (*resolverConn)(nil)
+	_ driver.NamedValueChecker  = (*resolverConn)(nil)
+	_ driver.ConnPrepareContext = (*resolverConn)(nil)
+)
+
+func (c *resolverConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext returns a statement sending the query to the resolver on every execution
+func (c *resolverConn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
+	return &resolverStmt{conn: c, query: query}, nil
+}
+
+// Close rolls back the transaction in progress, if any
+func (c *resolverConn) Close() error {
+	if c.tx == nil {
+		return nil
+	}
+	err := c.tx.Rollback()
+	c.tx = nil
+	return err
+}
+
+func (c *resolverConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx begins a transaction like DB.BeginTx, on a primary or on a replica when it's read-only,
+// the queries of the connection go to the transaction until it ends
+func (c *resolverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("dbresolver: the connection already has a transaction in progress")
+	}
+	var txOpts *sql.TxOptions
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		txOpts = &sql.TxOptions{Isolation: sql.IsolationLevel(opts.Isolation), ReadOnly: opts.ReadOnly}
+	}
+	tx, err := c.db.BeginTx(ctx, txOpts)
+	if err != nil {
+		return nil, err
+	}
+	c.tx = tx
+	return resolverTx{conn: c}, nil
+}
+
+func (c *resolverConn) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// CheckNamedValue accepts every arg, they're converted by the drivers of the nodes
+func (c *resolverConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *resolverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.tx != nil {
+		return c.tx.ExecContext(ctx, query, namedArgs(args)...)
+	}
+	return c.db.ExecContext(ctx, query, namedArgs(args)...)
+}
+
+func (c *resolverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var rows *sql.Rows
+	var err error
+	if c.tx != nil {
+		rows, err = c.tx.QueryContext(ctx, query, namedArgs(args)...)
+	} else {
+		rows, err = c.db.QueryContext(ctx, query, namedArgs(args)...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &resolverRows{rows: rows, columns: columns}, nil
+}
+
+// namedArgs returns the args of the resolver, the named args are passed as sql.NamedArg
+func namedArgs(args []driver.NamedValue) []interface{} {
+	res := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			res[i] = sql.Named(arg.Name, arg.Value)
+			continue
+		}
+		res[i] = arg.Value
+	}
+	return res
+}
+
+// resolverTx ends the transaction of the connection
+type resolverTx struct {
+	conn *resolverConn
+}
+
+func (t resolverTx) Commit() error {
+	tx := t.conn.tx
+	t.conn.tx = nil
+	return tx.Commit()
+}
+
+func (t resolverTx) Rollback() error {
+	tx := t.conn.tx
+	t.conn.tx = nil
+	return tx.Rollback()
+}
+
+// resolverStmt sends its query to the connection on every execution
+type resolverStmt struct {
+	conn  *resolverConn
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*resolverStmt)(nil)
+	_ driver.StmtQueryContext = (*resolverStmt)(nil)
+)
+
+func (s *resolverStmt) Close() error { return nil }
+
+// NumInput returns -1, the number of args is checked by the drivers of the nodes
+func (s *resolverStmt) NumInput() int { return -1 }
+
+func (s *resolverStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valueArgs(args))
+}
+
+func (s *resolverStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valueArgs(args))
+}
+
+func (s *resolverStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *resolverStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+// valueArgs returns the ordinal args of the values
+func valueArgs(args []driver.Value) []driver.NamedValue {
+	res := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		res[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return res
+}
+
+// resolverRows reads the rows of the resolver
+type resolverRows struct {
+	rows    *sql.Rows
+	columns []string
+	values  []interface{}
+	dest    []interface{}
+}
+
+func (r *resolverRows) Columns() []string { return r.columns }
+func (r *resolverRows) Close() error      { return r.rows.Close() }
+
+func (r *resolverRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	if r.dest == nil {
+		r.values = make([]interface{}, len(r.columns))
+		r.dest = make([]interface{}, len(r.columns))
+		for i := range r.values {
+			r.dest[i] = &r.values[i]
+		}
+	}
+	if err := r.rows.Scan(r.dest...); err != nil {
+		return err
+	}
+	for i, value := range r.values {
+		dest[i] = value
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package dbresolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConnector(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	db := NewConnector(New(WithPrimaryDBs(primary), WithReplicaDBs(replica))).OpenDB()
+	defer db.Close()
+	ctx := context.Background()
+
+	// the reads go to the replica, the writes to the primary
+	replicaMock.ExpectQuery("SELECT name FROM users WHERE id = $1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo").AddRow("bar"))
+	rows, err := db.QueryContext(ctx, "SELECT name FROM users WHERE id = $1", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil || len(names) != 2 || names[1] != "bar" {
+		t.Errorf("want the rows of the replica, got %v %v", names, err)
+	}
+	rows.Close()
+
+	primaryMock.ExpectExec("UPDATE users SET name = $1").WithArgs("baz").WillReturnResult(sqlmock.NewResult(0, 3))
+	res, err := db.ExecContext(ctx, "UPDATE users SET name = $1", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected, _ := res.RowsAffected(); affected != 3 {
+		t.Errorf("want the result of the primary, got %d", affected)
+	}
+
+	// the prepared statements are sent as queries
+	stmt, err := db.PrepareContext(ctx, "SELECT count(*) FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicaMock.ExpectQuery("SELECT count(*) FROM users").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	var count int
+	if err := stmt.QueryRowContext(ctx).Scan(&count); err != nil || count != 7 {
+		t.Errorf("want the count of the replica, got %d %v", count, err)
+	}
+	stmt.Close()
+
+	// the queries of a transaction go to its primary
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo"))
+	primaryMock.ExpectCommit()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := tx.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name); err != nil || name != "foo" {
+		t.Errorf("want the row of the primary, got %q %v", name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package consuldiscovery is a dbresolver.Discoverer backed by the Consul service catalog.
+//
+// The database nodes are registered as instances of a Consul service, tagged with their role.
+// The discoverer watches the healthy instances with Consul blocking queries,
+// so the resolver is updated as soon as an instance is registered, deregistered, or fails its health checks.
+package consuldiscovery
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/hashicorp/consul/api"
+)
+
+// Default tags marking the role of the service instances
+const (
+	DefaultPrimaryTag = "primary"
+	DefaultReplicaTag = "replica"
+)
+
+const defaultWaitTime = 5 * time.Minute
+
+// Config define the watched Consul service
+type Config struct {
+	// Service is the name of the Consul service of the database nodes
+	Service string
+	// Datacenter of the service, the datacenter of the agent is used when it's empty
+	Datacenter string
+	// PrimaryTag marks the primary instances, "primary" by default
+	PrimaryTag string
+	// ReplicaTag marks the replica instances, "replica" by default.
+	// Instances without any role tag are ignored.
+	ReplicaTag string
+	// WaitTime is the maximum duration of the blocking queries, 5 minutes by default
+	WaitTime time.Duration
+}
+
+// Discoverer discovers the database nodes from the healthy instances of a Consul service.
+//
+// The other tags of the instances, formatted as key=value (eg. zone=us-east-1a),
+// and the service metadata are exposed as the endpoint tags.
+type Discoverer struct {
+	client    *api.Client
+	config    Config
+	lastIndex uint64
+}
+
+var _ dbresolver.Discoverer = (*Discoverer)(nil)
+
+// New creates the discoverer for the given Consul client
+func New(client *api.Client, config Config) *Discoverer {
+	if config.PrimaryTag == "" {
+		config.PrimaryTag = DefaultPrimaryTag
+	}
+	if config.ReplicaTag == "" {
+		config.ReplicaTag = DefaultReplicaTag
+	}
+	if config.WaitTime <= 0 {
+		config.WaitTime = defaultWaitTime
+	}
+	return &Discoverer{
+		client: client,
+		config: config,
+	}
+}
+
+// Discover returns the healthy instances of the service.
+// Except for the first call, it blocks until the instances change or the wait time elapsed.
+func (d *Discoverer) Discover(ctx context.Context) ([]dbresolver.Endpoint, error) {
+	opts := &api.QueryOptions{
+		Datacenter: d.config.Datacenter,
+		WaitIndex:  d.lastIndex,
+		WaitTime:   d.config.WaitTime,
+	}
+	entries, meta, err := d.client.Health().Service(d.config.Service, "", true, opts.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	// the index is reset when it goes backwards, eg. after a Consul snapshot restore
+	if meta.LastIndex < d.lastIndex {
+		d.lastIndex = 0
+	} else {
+		d.lastIndex = meta.LastIndex
+	}
+
+	endpoints := make([]dbresolver.Endpoint, 0, len(entries))
+	for _, entry := range entries {
+		if endpoint, ok := d.endpoint(entry); ok {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints, nil
+}
+
+func (d *Discoverer) endpoint(entry *api.ServiceEntry) (dbresolver.Endpoint, bool) {
+	endpoint := dbresolver.Endpoint{Tags: map[string]string{}}
+	for k, v := range entry.Service.Meta {
+		endpoint.Tags[k] = v
+	}
+	for _, tag := range entry.Service.Tags {
+		switch tag {
+		case d.config.PrimaryTag:
+			endpoint.Role = dbresolver.RolePrimary
+		case d.config.ReplicaTag:
+			if endpoint.Role == "" {
+				endpoint.Role = dbresolver.RoleReplica
+			}
+		default:
+			if k, v, ok := strings.Cut(tag, "="); ok {
+				endpoint.Tags[k] = v
+			}
+		}
+	}
+	if endpoint.Role == "" {
+		return endpoint, false
+	}
+
+	host := entry.Service.Address
+	if host == "" {
+		host = entry.Node.Address
+	}
+	endpoint.Addr = net.JoinHostPort(host, strconv.Itoa(entry.Service.Port))
+	return endpoint, true
+}
@@ -0,0 +1,72 @@
+package consuldiscovery_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/consuldiscovery"
+	"github.com/hashicorp/consul/api"
+)
+
+func TestDiscover(t *testing.T) {
+	var gotIndexes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/postgres" || r.URL.Query().Get("passing") != "1" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		gotIndexes = append(gotIndexes, r.URL.Query().Get("index"))
+
+		entries := []*api.ServiceEntry{
+			{
+				Node:    &api.Node{Address: "10.0.0.1"},
+				Service: &api.AgentService{Port: 5432, Tags: []string{"primary", "zone=us-east-1a"}},
+			},
+			{
+				Node: &api.Node{Address: "10.0.0.2"},
+				Service: &api.AgentService{Address: "db-2.internal", Port: 5433, Tags: []string{"replica"},
+					Meta: map[string]string{"zone": "us-east-1b"}},
+			},
+			{
+				Node:    &api.Node{Address: "10.0.0.3"},
+				Service: &api.AgentService{Port: 5432, Tags: []string{"backup"}},
+			},
+		}
+		w.Header().Set("X-Consul-Index", "42")
+		_ = json.NewEncoder(w).Encode(entries)
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	discoverer := consuldiscovery.New(client, consuldiscovery.Config{Service: "postgres"})
+
+	for i := 0; i < 2; i++ {
+		endpoints, err := discoverer.Discover(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(endpoints) != 2 {
+			t.Fatalf("want %v, got %v", 2, endpoints)
+		}
+		assertEndpoint(t, endpoints[0], "10.0.0.1:5432", dbresolver.RolePrimary, "us-east-1a")
+		assertEndpoint(t, endpoints[1], "db-2.internal:5433", dbresolver.RoleReplica, "us-east-1b")
+	}
+
+	// the second call is a blocking query from the last index
+	if len(gotIndexes) != 2 || gotIndexes[0] != "" || gotIndexes[1] != "42" {
+		t.Errorf("want indexes %v, got %v", []string{"", "42"}, gotIndexes)
+	}
+}
+
+func assertEndpoint(t *testing.T, endpoint dbresolver.Endpoint, addr string, role dbresolver.Role, zone string) {
+	t.Helper()
+	if endpoint.Addr != addr || endpoint.Role != role || endpoint.Tags["zone"] != zone {
+		t.Errorf("want %s %s %s, got %+v", addr, role, zone, endpoint)
+	}
+}
@@ -0,0 +1,138 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials are the credentials used to connect to a database node
+type Credentials struct {
+	Username string
+	Password string
+	// ExpiresAt is the expiry of the credentials, they are fetched again once expired.
+	// The zero value never expires.
+	ExpiresAt time.Time
+}
+
+// CredentialsProvider fetches the credentials of the database nodes, eg. from Vault or AWS Secrets Manager
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc is a function implementing CredentialsProvider
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials calls f(ctx)
+func (f CredentialsProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// CredentialsConnector is a driver.Connector opening every new connection with the latest credentials.
+//
+// The credentials are cached until they expire, or until the database rejects them,
+// in which case they are fetched again and the connection is retried once.
+// The pool of the *sql.DB is kept, the existing connections are not affected by the rotation.
+type CredentialsConnector struct {
+	driver      driver.Driver
+	provider    CredentialsProvider
+	dsn         func(Credentials) string
+	isAuthError func(error) bool
+
+	mu     sync.Mutex
+	cached *Credentials
+}
+
+// NewCredentialsConnector creates the connector of the given driver,
+// building the DSN of every new connection from the latest credentials.
+//
+//	connector := dbresolver.NewCredentialsConnector(&pq.Driver{}, vaultProvider, func(c dbresolver.Credentials) string {
+//		return fmt.Sprintf("postgres://%s:%s@primary:5432/app", c.Username, c.Password)
+//	})
+//	dbPrimary := sql.OpenDB(connector)
+func NewCredentialsConnector(drv driver.Driver, provider CredentialsProvider,
+	dsn func(Credentials) string) *CredentialsConnector {
+	return &CredentialsConnector{
+		driver:      drv,
+		provider:    provider,
+		dsn:         dsn,
+		isAuthError: IsAuthError,
+	}
+}
+
+// WithAuthErrorClassifier overrides how the authentication errors are detected, IsAuthError by default
+func (c *CredentialsConnector) WithAuthErrorClassifier(isAuthError func(error) bool) *CredentialsConnector {
+	c.isAuthError = isAuthError
+	return c
+}
+
+// OpenDB opens the *sql.DB backed by the connector
+func (c *CredentialsConnector) OpenDB() *sql.DB {
+	return sql.OpenDB(c)
+}
+
+// Connect opens a new connection with the cached credentials,
+// the credentials are fetched again when the database rejects them.
+func (c *CredentialsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	creds, err := c.credentials(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := c.connect(ctx, creds)
+	if err == nil || !c.isAuthError(err) {
+		return conn, err
+	}
+
+	creds, err = c.credentials(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	return c.connect(ctx, creds)
+}
+
+// Driver returns the underlying driver
+func (c *CredentialsConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func (c *CredentialsConnector) connect(ctx context.Context, creds Credentials) (driver.Conn, error) {
+	dsn := c.dsn(creds)
+	if drv, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := drv.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(dsn)
+}
+
+func (c *CredentialsConnector) credentials(ctx context.Context, refresh bool) (Credentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !refresh && c.cached != nil && (c.cached.ExpiresAt.IsZero() || time.Now().Before(c.cached.ExpiresAt)) {
+		return *c.cached, nil
+	}
+	creds, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+	c.cached = &creds
+	return creds, nil
+}
+
+// IsAuthError reports whether the error is an authentication error,
+// ie. an error with a SQLSTATE of class 28 "Invalid Authorization Specification",
+// as returned by lib/pq and pgx.
+func IsAuthError(err error) bool {
+	var sqlStateErr interface{ SQLState() string }
+	if errors.As(err, &sqlStateErr) {
+		return strings.HasPrefix(sqlStateErr.SQLState(), "28")
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type authError struct{}
+
+func (authError) Error() string    { return "password authentication failed" }
+func (authError) SQLState() string { return "28P01" }
+
+// passwordDriver only accepts the connections with its current password
+type passwordDriver struct {
+	mu       sync.Mutex
+	password string
+	dsns     []string
+}
+
+func (d *passwordDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dsns = append(d.dsns, dsn)
+	if !strings.HasSuffix(dsn, ":"+d.password) {
+		return nil, authError{}
+	}
+	return nil, nil
+}
+
+type rotatingProvider struct {
+	password string
+	calls    int
+}
+
+func (p *rotatingProvider) Credentials(_ context.Context) (Credentials, error) {
+	p.calls++
+	return Credentials{Username: "app", Password: p.password}, nil
+}
+
+func TestCredentialsConnector(t *testing.T) {
+	drv := &passwordDriver{password: "v1"}
+	provider := &rotatingProvider{password: "v1"}
+	connector := NewCredentialsConnector(drv, provider, func(c Credentials) string {
+		return c.Username + ":" + c.Password
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := connector.Connect(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("want cached credentials, got %v calls", provider.calls)
+	}
+
+	// the password is rotated, the cached credentials are rejected once
+	drv.password, provider.password = "v2", "v2"
+	if _, err := connector.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("want credentials fetched again, got %v calls", provider.calls)
+	}
+	want := []string{"app:v1", "app:v1", "app:v1", "app:v2"}
+	if strings.Join(drv.dsns, ",") != strings.Join(want, ",") {
+		t.Errorf("want %v, got %v", want, drv.dsns)
+	}
+
+	// the provider is not rotated yet, the auth error is returned
+	drv.password = "v3"
+	if _, err := connector.Connect(context.Background()); !IsAuthError(err) {
+		t.Errorf("want auth error, got %v", err)
+	}
+}
+
+func TestCredentialsConnectorExpiry(t *testing.T) {
+	calls := 0
+	provider := CredentialsProviderFunc(func(_ context.Context) (Credentials, error) {
+		calls++
+		return Credentials{Password: "v1", ExpiresAt: time.Now().Add(-time.Second)}, nil
+	})
+	connector := NewCredentialsConnector(&passwordDriver{password: "v1"}, provider, func(c Credentials) string {
+		return ":" + c.Password
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := connector.Connect(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("want expired credentials fetched again, got %v calls", calls)
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	if !IsAuthError(authError{}) {
+		t.Error("want true for SQLSTATE 28P01")
+	}
+	if IsAuthError(errors.New("other error")) {
+		t.Error("want false for non-SQL error")
+	}
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
-	"strings"
+	"errors"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"go.uber.org/multierr"
 )
 
 // DB interface is a contract that supported by this library.
@@ -20,8 +20,14 @@ type DB interface {
 	Begin() (Tx, error)
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error)
 	Close() error
+	// Shutdown stops routing the new queries, waits for the connections in use to be released, then closes the DBs
+	Shutdown(ctx context.Context) error
 	// Conn only available for the primary db or the first primary db (if using multi-primary)
 	Conn(ctx context.Context) (Conn, error)
+	// PrimaryConn returns a single connection of a primary, resolved by the load balancer
+	PrimaryConn(ctx context.Context) (Conn, error)
+	// ReplicaConn returns a single connection of a replica, resolved by the load balancer
+	ReplicaConn(ctx context.Context) (Conn, error)
 	Driver() driver.Driver
 	Exec(query string, args ...interface{}) (sql.Result, error)
 	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
@@ -29,6 +35,7 @@ type DB interface {
 	PingContext(ctx context.Context) error
 	Prepare(query string) (Stmt, error)
 	PrepareContext(ctx context.Context, query string) (Stmt, error)
+	PrepareAsync(ctx context.Context, query string) (AsyncStmt, error)
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 	QueryRow(query string, args ...interface{}) *sql.Row
@@ -39,10 +46,56 @@ type DB interface {
 	SetMaxOpenConns(n int)
 	PrimaryDBs() []*sql.DB
 	ReplicaDBs() []*sql.DB
+	// AddReplica adds a replica into the rotation while queries are in flight
+	AddReplica(replica *sql.DB)
+	// RemoveReplica removes a replica from the rotation while queries are in flight
+	RemoveReplica(replica *sql.DB)
+	// AddPrimary adds a primary into the rotation while queries are in flight
+	AddPrimary(primary *sql.DB)
+	// RemovePrimary removes a primary from the rotation while queries are in flight.
+	// The last primary can't be removed.
+	RemovePrimary(primary *sql.DB) error
+	// ReadOnly returns the DB a read query would be routed to, resolved by the load balancer
+	ReadOnly() *sql.DB
+	// ReadWrite returns the DB a write query would be routed to, resolved by the load balancer
+	ReadWrite() *sql.DB
+	// Nodes returns the current nodes with their role and labels
+	Nodes() []NodeInfo
+	// ResolveRead resolves the node of a read query and describes it, the load balancer moves to the next node
+	ResolveRead(ctx context.Context) (NodeInfo, error)
+	// ResolveWrite resolves the node of a write query and describes it, the load balancer moves to the next node
+	ResolveWrite(ctx context.Context) (NodeInfo, error)
+	// ValidateTopology checks that every primary is writable and every replica is read-only
+	ValidateTopology(ctx context.Context) (*TopologyReport, error)
+	// Repoll probes the role of every node and promotes or demotes the nodes whose role changed
+	Repoll(ctx context.Context) ([]TopologyChange, error)
+	// SwapNodeDSN replaces the pool of the named node by a new pool opened with the DSN, see WithNodeName
+	SwapNodeDSN(ctx context.Context, name, dsn string) error
+	// DiffTopology returns the changes between the current and the desired topology
+	DiffTopology(desired Topology) ([]TopologyChange, error)
+	// ApplyTopology applies the changes between the current and the desired topology
+	ApplyTopology(ctx context.Context, desired Topology) ([]TopologyChange, error)
+	// ExecAllPrimaries executes the query on every primary concurrently
+	ExecAllPrimaries(ctx context.Context, opts *ExecAllOptions, query string, args ...interface{}) (*ExecAllResult, error)
+	// Prewarm prepares the queries on the nodes matching all the filters, every node without filter
+	Prewarm(ctx context.Context, queries []string, filters ...NodeFilter) error
+	// Listener receives the notifications of the channel on a dedicated primary connection, see WithNotificationWaiter
+	Listener(ctx context.Context, channel string) (*Listener, error)
+	// ExplainRoute reports where the query is routed and why, without running it
+	ExplainRoute(ctx context.Context, query string, args ...interface{}) (RouteDecision, error)
+	// SetNodeWeight changes the weight of the node at runtime, see WithReplicaWeights and WithPrimaryWeights
+	SetNodeWeight(node *sql.DB, weight int) error
+	// SetReadMode switches the nodes the reads go to at runtime, eg. to send every read to the primaries
+	SetReadMode(mode ReadMode) error
+	// BulkInsert loads the rows into the table on a primary, see WithBulkLoad
+	BulkInsert(ctx context.Context, table string, columns []string, rows [][]interface{}) (int64, error)
 	// Stats only available for the primary db or the first primary db (if using multi-primary)
 	Stats() sql.DBStats
 }
 
+// ErrLastPrimary is returned when removing the only primary db of the resolver
+var ErrLastPrimary = errors.New("dbresolver: can't remove the last primary db")
+
 // DBLoadBalancer is loadbalancer for physical DBs
 type DBLoadBalancer LoadBalancer[*sql.DB]
 
@@ -54,32 +107,274 @@ type StmtLoadBalancer LoadBalancer[*sql.Stmt]
 // Reads and writes are automatically directed to the correct db connection
 
 type sqlDB struct {
-	primaries        []*sql.DB
-	replicas         []*sql.DB
+	// topologyLock serializes the topology changes,
+	// the queries read the current nodes and their labels without locking
+	topologyLock     sync.Mutex
+	nodes            atomic.Pointer[nodeSet]
 	loadBalancer     DBLoadBalancer
 	stmtLoadBalancer StmtLoadBalancer
 	queryTypeChecker QueryTypeChecker
+	discoveries      []*discovery
+	saturation       *saturationMonitor
+	// nearest probes the latency of the replicas, nil without nearest read preference
+	nearest *nearestProber
+	// latency observes the query latency of the nodes, nil without the latency load balancer
+	latency latencyObserver[*sql.DB]
+	// affinity resolves the reads of the affinity keys, nil without the affinity load balancer
+	affinity keyResolver[*sql.DB]
+	// health checks the nodes, nil without health checks
+	health *healthChecker
+	// lag measures the replication lag of the replicas, nil without lag monitor
+	lag *lagMonitor
+	// roles probes the roles of the nodes, nil without role detection
+	roles *roleMonitor
+	// shutdown is set by Shutdown, the new queries aren't routed anymore
+	shutdown atomic.Bool
+	// repollLock serializes the probes of the roles of the nodes
+	repollLock sync.Mutex
+	// stickyWindow is the window of the reads of a session sticking to a primary after a write,
+	// zero without sticky primary
+	stickyWindow time.Duration
+	// retry retries the queries failing with a transient error, nil without retry policy
+	retry *retrier
+	// logger logs the queries and the changes of the rotation, nil without logger
+	logger Logger
+	// breaker holds the circuits of the nodes, nil without circuit breaker
+	breaker    *breaker
+	hooks      []Hooks
+	queryHooks []QueryHook
+	// labels are the labels of the nodes, copied on write like the topology
+	labels           atomic.Pointer[map[*sql.DB]map[string]string]
+	readOnlyDetector ReadOnlyDetector
+	lifetimeJitter   float64
+	// poolSettings are the pool settings set through the resolver, applied to the swapped pools
+	poolSettings poolSettings
+	redactor     Redactor
+	parallelism  int
+	// prepareConcurrency is the parallelism of Prepare
+	prepareConcurrency int
+	// prepared are the prewarmed statements, see Prewarm
+	prepared   atomic.Pointer[map[preparedKey]*sql.Stmt]
+	readCache  *readCache
+	partitions *partitions
+	// concurrency is the adaptive concurrency limit of each node, nil when the limits aren't adaptive
+	concurrency *concurrencyLimiter
+	// admission bounds the queries in flight across the nodes, nil without admission control
+	admission *admission
+	// notificationWaiter waits for the notifications of the listeners
+	notificationWaiter NotificationWaiter
+	listeners          listeners
+	bulkLoad           BulkLoad
+	// defaultTxOptions are the options of the transactions begun with nil options
+	defaultTxOptions *sql.TxOptions
+	// readOnlyTxOnPrimary begins the read-only transactions on a primary instead of a replica
+	readOnlyTxOnPrimary bool
+	// stmtCache holds the statements of the queries by node, nil without statement cache
+	stmtCache *stmtCache
+	// lazyPrepare prepares the statements on each node on their first execution there, see WithLazyPrepare
+	lazyPrepare bool
+	// readMode is which nodes the reads go to, ReplicaPreferred when unset, see SetReadMode
+	readMode atomic.Pointer[ReadMode]
+	// primaryQueryTimeout and replicaQueryTimeout bound the queries without deadline by role, see WithPrimaryQueryTimeout
+	primaryQueryTimeout time.Duration
+	replicaQueryTimeout time.Duration
+	// readFallback is where the reads go when their replica is unreachable
+	readFallback ReadFallback
+	// classifier classifies the errors of the queries, see WithErrorClassifier
+	classifier Classifier
+	// writeStrategy is how the writes are sent to the primaries
+	writeStrategy WriteStrategy
+	coalescer     *coalescer
+	// results serves the results read in memory, by the read cache and the coalesced queries,
+	// and the rows holding an error, eg. the admission errors or the classified errors
+	results *sql.DB
+	clock   Clock
+	// shards are the resolvers of the shards by name, nil when the resolver isn't sharded
+	shards        map[string]*sqlDB
+	shardNames    []string
+	shardResolver ShardResolver
+}
+
+// nodeSet is a snapshot of the topology. It's never mutated, a change stores a new snapshot.
+type nodeSet struct {
+	primaries      []*sql.DB
+	replicas       []*sql.DB
+	primaryWeights map[*sql.DB]int
+	replicaWeights map[*sql.DB]int
+	// primaryRotation and replicaRotation are the nodes repeated by weight, resolved by the load balancer
+	primaryRotation []*sql.DB
+	replicaRotation []*sql.DB
 }
 
 // PrimaryDBs return all the active primary DB
 func (db *sqlDB) PrimaryDBs() []*sql.DB {
-	return db.primaries
+	primaries, _ := db.topology()
+	return primaries
 }
 
 // ReplicaDBs return all the active replica DB
 func (db *sqlDB) ReplicaDBs() []*sql.DB {
-	return db.replicas
+	_, replicas := db.topology()
+	return replicas
+}
+
+// AddReplica adds the replica DB into the rotation, it's a no-op if the replica is already registered.
+// Statements prepared before the replica was added keep using the previous replicas.
+func (db *sqlDB) AddReplica(replica *sql.DB) {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+	set := *db.nodes.Load()
+	set.replicas = appendDB(set.replicas, replica)
+	db.storeNodes(set)
+}
+
+// RemoveReplica removes the replica DB from the rotation, the queries in flight on the replica complete.
+// The replica is not closed, closing it is the responsibility of the caller: Close waits for the queries
+// started on the replica, draining it.
+func (db *sqlDB) RemoveReplica(replica *sql.DB) {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+	set := *db.nodes.Load()
+	set.replicas = removeDB(set.replicas, replica)
+	db.storeNodes(set)
+}
+
+// AddPrimary adds the primary DB into the rotation, it's a no-op if the primary is already registered.
+// Statements prepared before the primary was added keep using the previous primaries.
+func (db *sqlDB) AddPrimary(primary *sql.DB) {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+	set := *db.nodes.Load()
+	set.primaries = appendDB(set.primaries, primary)
+	db.storeNodes(set)
+}
+
+// RemovePrimary removes the primary DB from the rotation.
+// It returns ErrLastPrimary when removing the only primary, add the new primary first when replacing it.
+// The primary is not closed, closing it is the responsibility of the caller, like RemoveReplica.
+func (db *sqlDB) RemovePrimary(primary *sql.DB) error {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+
+	set := *db.nodes.Load()
+	set.primaries = removeDB(set.primaries, primary)
+	if len(set.primaries) == 0 {
+		return ErrLastPrimary
+	}
+	db.storeNodes(set)
+	return nil
+}
+
+// appendDB returns a copy of dbs with the db appended, unless it's already in dbs.
+// The slices are copied on write, so the slices returned by topology are never mutated.
+func appendDB(dbs []*sql.DB, db *sql.DB) []*sql.DB {
+	for _, d := range dbs {
+		if d == db {
+			return dbs
+		}
+	}
+	res := make([]*sql.DB, 0, len(dbs)+1)
+	return append(append(res, dbs...), db)
+}
+
+// removeDB returns a copy of dbs without the db
+func removeDB(dbs []*sql.DB, db *sql.DB) []*sql.DB {
+	res := make([]*sql.DB, 0, len(dbs))
+	for _, d := range dbs {
+		if d != db {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// topology returns the current primaries and replicas.
+// The returned slices are never mutated, they are safe to use after a topology change.
+func (db *sqlDB) topology() (primaries, replicas []*sql.DB) {
+	set := db.nodes.Load()
+	return set.primaries, set.replicas
+}
+
+// storeNodes computes the rotations of the set and stores it as the current snapshot,
+// then drops the concurrency limits of the nodes removed from the topology.
+// The caller holds the topologyLock, so concurrent changes aren't lost.
+func (db *sqlDB) storeNodes(set nodeSet) {
+	set.primaryRotation = weightedRotation(set.primaries, func(i int) int {
+		return nodeWeight(set.primaryWeights, set.primaries[i])
+	})
+	set.replicaRotation = weightedRotation(set.replicas, func(i int) int {
+		return nodeWeight(set.replicaWeights, set.replicas[i])
+	})
+	previous := db.nodes.Swap(&set)
+	if previous != nil && db.concurrency != nil {
+		db.concurrency.forget(removedNodes(*previous, set))
+	}
+}
+
+// removedNodes returns the nodes of previous missing from set
+func removedNodes(previous, set nodeSet) []*sql.DB {
+	var removed []*sql.DB
+	for _, node := range distinct(append(append([]*sql.DB(nil), previous.primaries...), previous.replicas...)) {
+		if !containsDB(set.primaries, node) && !containsDB(set.replicas, node) {
+			removed = append(removed, node)
+		}
+	}
+	return removed
+}
+
+// weights returns the current primary and replica weights, the maps are never mutated
+func (db *sqlDB) weights() (primaryWeights, replicaWeights map[*sql.DB]int) {
+	set := db.nodes.Load()
+	return set.primaryWeights, set.replicaWeights
+}
+
+// readTopology returns the current primaries and the current replicas repeated by weight,
+// for the load balancers
+func (db *sqlDB) readTopology() (primaries, replicaRotation []*sql.DB) {
+	set := db.nodes.Load()
+	return set.primaries, set.replicaRotation
 }
 
 // Close closes all physical databases concurrently, releasing any open resources.
+// The replica discovery and the saturation sampling are stopped before closing the databases.
 func (db *sqlDB) Close() error {
-	errPrimaries := doParallely(len(db.primaries), func(i int) error {
-		return db.primaries[i].Close()
+	db.listeners.close()
+	for _, d := range db.discoveries {
+		d.close()
+	}
+	if db.saturation != nil {
+		db.saturation.close()
+	}
+	if db.nearest != nil {
+		db.nearest.close()
+	}
+	if db.health != nil {
+		db.health.close()
+	}
+	if db.lag != nil {
+		db.lag.close()
+	}
+	if db.roles != nil {
+		db.roles.close()
+	}
+	errPrepared := db.closePrepared()
+	if db.stmtCache != nil {
+		errPrepared = errors.Join(errPrepared, db.stmtCache.close())
+	}
+	errPrepared = errors.Join(errPrepared, db.results.Close())
+	primaries, replicas := db.topology()
+	ctx := context.Background()
+	errPrimaries := doParallely(ctx, db.parallelism, len(primaries), func(i int) error {
+		return primaries[i].Close()
 	})
-	errReplicas := doParallely(len(db.replicas), func(i int) error {
-		return db.replicas[i].Close()
+	errReplicas := doParallely(ctx, db.parallelism, len(replicas), func(i int) error {
+		return replicas[i].Close()
 	})
-	return multierr.Combine(errPrimaries, errReplicas)
+	errs := []error{errPrepared, errPrimaries, errReplicas}
+	for _, name := range db.shardNames {
+		errs = append(errs, db.shards[name].Close())
+	}
+	return errors.Join(errs...)
 }
 
 // Driver returns the physical database's underlying driver.
@@ -93,22 +388,59 @@ func (db *sqlDB) Begin() (Tx, error) {
 }
 
 // BeginTx starts a transaction with the provided context on the RW-db.
+// The read-only transactions are started on a replica, chosen like the replica of a read query,
+// unless the context forces the primary or sticks to it, or with WithReadOnlyTxOnPrimary.
+// A read-only transaction falls back when the replica is unreachable, see WithReadFallback.
 //
-// The provided TxOptions is optional and may be nil if defaults should be used.
-// If a non-default isolation level is used that the driver doesn't support,
+// The provided TxOptions is optional and may be nil if defaults should be used,
+// see WithDefaultTxOptions. If a non-default isolation level is used that the driver doesn't support,
 // an error will be returned.
 func (db *sqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (Tx, error) {
-	sourceDB := db.ReadWrite()
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db = shard
+	opts = txOptions(opts, db.defaultTxOptions)
+	sourceDB, route := db.ReadWrite(), primaryRoute
+	if db.txOnReplica(ctx, opts) {
+		sourceDB, route = db.readOnly(ctx)
+	}
 
 	stx, err := sourceDB.BeginTx(ctx, opts)
+	if route.Role == RoleReplica && db.isConnectionError(err) {
+		if node, fallback, ok := db.fallback(ctx, sourceDB); ok {
+			sourceDB, route = node, fallback
+			stx, err = sourceDB.BeginTx(ctx, opts)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	return &tx{
+	t := &tx{
 		sourceDB: sourceDB,
 		tx:       stx,
-	}, nil
+		route:    route,
+		hooks:    db.hooks,
+		results:  db.results,
+	}
+	if db.stickyWindow > 0 && (opts == nil || !opts.ReadOnly) {
+		t.onCommit = func() { db.recordWrite(ctx) }
+	}
+	return t, nil
+}
+
+// txOnReplica reports whether the transaction is started on a replica: a read-only transaction,
+// unless the context forces the primary or its session sticks to it
+func (db *sqlDB) txOnReplica(ctx context.Context, opts *sql.TxOptions) bool {
+	if opts == nil || !opts.ReadOnly || db.readOnlyTxOnPrimary {
+		return false
+	}
+	if role, ok := RoleOverrideFromContext(ctx); ok {
+		return role == RoleReplica
+	}
+	return !db.sticksToPrimary(ctx)
 }
 
 // Exec executes a query without returning any rows.
@@ -120,9 +452,37 @@ func (db *sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
 
 // ExecContext executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
-// Exec uses the RW-database as the underlying db connection
-func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return db.ReadWrite().ExecContext(ctx, query, args...)
+// Exec uses the RW-database as the underlying db connection, a replica with WithReplica
+func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db = shard
+	role, ok := RoleOverrideFromContext(ctx)
+	onReplica := ok && role == RoleReplica
+	if !onReplica {
+		defer db.recordWrite(ctx)
+		if db.writeStrategy.fansOut() {
+			return db.execFanOut(ctx, query, args)
+		}
+	}
+	err = db.retry.do(ctx, true, func() error {
+		if !onReplica && db.writeStrategy.Policy == WriteFailover {
+			res, err = db.execFailover(ctx, query, args)
+			return db.classifyRouted(err, primaryRoute, true)
+		}
+		curDB, route := db.ReadWrite(), primaryRoute
+		if onReplica {
+			curDB, route = db.readOnly(ctx)
+		}
+		res, err = execWithHooks(ctx, db.hooks, route.to(curDB), query, args, func(ctx context.Context) (sql.Result, error) {
+			return db.timedExecContext(ctx, route, curDB, query, args)
+		})
+		err = db.nodeError(curDB, db.classifyRouted(err, route, true))
+		return err
+	})
+	return res, err
 }
 
 // Ping verifies if a connection to each physical database is still alive,
@@ -134,13 +494,18 @@ func (db *sqlDB) Ping() error {
 // PingContext verifies if a connection to each physical database is still
 // alive, establishing a connection if necessary.
 func (db *sqlDB) PingContext(ctx context.Context) error {
-	errPrimaries := doParallely(len(db.primaries), func(i int) error {
-		return db.primaries[i].PingContext(ctx)
+	primaries, replicas := db.topology()
+	errPrimaries := doParallely(ctx, db.parallelism, len(primaries), func(i int) error {
+		return primaries[i].PingContext(ctx)
 	})
-	errReplicas := doParallely(len(db.replicas), func(i int) error {
-		return db.replicas[i].PingContext(ctx)
+	errReplicas := doParallely(ctx, db.parallelism, len(replicas), func(i int) error {
+		return replicas[i].PingContext(ctx)
 	})
-	return multierr.Combine(errPrimaries, errReplicas)
+	errs := []error{errPrimaries, errReplicas}
+	for _, name := range db.shardNames {
+		errs = append(errs, db.shards[name].PingContext(ctx))
+	}
+	return errors.Join(errs...)
 }
 
 // Prepare creates a prepared statement for later queries or executions
@@ -154,50 +519,107 @@ func (db *sqlDB) Prepare(query string) (_stmt Stmt, err error) {
 //
 // The provided context is used for the preparation of the statement, not for
 // the execution of the statement.
-func (db *sqlDB) PrepareContext(ctx context.Context, query string) (_stmt Stmt, err error) {
+func (db *sqlDB) PrepareContext(ctx context.Context, query string) (Stmt, error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return shard.prepareWithQueryHooks(ctx, query, func(ctx context.Context) (Stmt, error) {
+		return shard.prepareContext(ctx, query)
+	})
+}
+
+// prepareContext prepares the query on every node of the resolver, or on first use with lazy prepare
+func (db *sqlDB) prepareContext(ctx context.Context, query string) (_stmt Stmt, err error) {
+	if db.lazyPrepare {
+		return newLazyStmt(db, query), nil
+	}
+	primaries, replicas := db.topology()
 	dbStmt := map[*sql.DB]*sql.Stmt{}
 	var dbStmtLock sync.Mutex
-	roStmts := make([]*sql.Stmt, len(db.replicas))
-	primaryStmts := make([]*sql.Stmt, len(db.primaries))
-	errPrimaries := doParallely(len(db.primaries), func(i int) (err error) {
-		primaryStmts[i], err = db.primaries[i].PrepareContext(ctx, query)
+	primaryStmts, errPrimaries := db.prepareNodes(ctx, primaries, query, dbStmt, &dbStmtLock)
+	var fallback *sql.Stmt
+	if len(primaryStmts) > 0 {
+		fallback = primaryStmts[0]
+	}
+	roStmts, errReplicas := db.prepareReplicas(ctx, replicas, query, fallback, dbStmt, &dbStmtLock)
+
+	err = errors.Join(errPrimaries, errReplicas)
+	if err != nil {
+		return //nolint: nakedret
+	}
+
+	_stmt = &stmt{
+		loadBalancer: db.stmtLoadBalancer,
+		primaryStmts: db.weightedPrimaryStmts(primaries, primaryStmts),
+		replicaStmts: roStmts,
+		dbStmt:       dbStmt,
+		writeFlag:    isReturning(query),
+		query:        query,
+		hooks:        db.hooks,
+		parallelism:  db.parallelism,
+		retry:        db.retry,
+		classifier:   db.classifier,
+		readMode:     &db.readMode,
+		health:       db.health,
+	}
+	return _stmt, nil
+}
+
+// isReturning reports whether the prepared query returns the rows it writes
+func isReturning(query string) bool {
+	return containsReturning(query)
+}
+
+// prepareNodes prepares the query on the nodes concurrently, and records the statement of each node into dbStmt
+func (db *sqlDB) prepareNodes(ctx context.Context, nodes []*sql.DB, query string,
+	dbStmt map[*sql.DB]*sql.Stmt, dbStmtLock *sync.Mutex) ([]*sql.Stmt, error) {
+	stmts := make([]*sql.Stmt, len(nodes))
+	err := doParallely(ctx, db.prepareConcurrency, len(nodes), func(i int) (err error) {
+		stmts[i], err = nodes[i].PrepareContext(ctx, query)
 		dbStmtLock.Lock()
-		dbStmt[db.primaries[i]] = primaryStmts[i]
+		dbStmt[nodes[i]] = stmts[i]
 		dbStmtLock.Unlock()
 		return
 	})
+	return stmts, err
+}
 
-	errReplicas := doParallely(len(db.replicas), func(i int) (err error) {
-		roStmts[i], err = db.replicas[i].PrepareContext(ctx, query)
+// prepareReplicas prepares the query on the replicas, and returns their statements repeated by weight.
+// The fallback statement replaces the statement of the unreachable replicas.
+func (db *sqlDB) prepareReplicas(ctx context.Context, replicas []*sql.DB, query string, fallback *sql.Stmt,
+	dbStmt map[*sql.DB]*sql.Stmt, dbStmtLock *sync.Mutex) ([]*sql.Stmt, error) {
+	roStmts := make([]*sql.Stmt, len(replicas))
+	err := doParallely(ctx, db.prepareConcurrency, len(replicas), func(i int) (err error) {
+		roStmts[i], err = replicas[i].PrepareContext(ctx, query)
 		dbStmtLock.Lock()
-		dbStmt[db.replicas[i]] = roStmts[i]
+		dbStmt[replicas[i]] = roStmts[i]
 		dbStmtLock.Unlock()
 
 		// if connection error happens on RO connection,
 		// ignore and fallback to RW connection
-		if isDBConnectionError(err) {
-			roStmts[i] = primaryStmts[0]
+		if db.isConnectionError(err) {
+			roStmts[i] = fallback
 			return nil
 		}
 		return err
 	})
-
-	err = multierr.Combine(errPrimaries, errReplicas)
 	if err != nil {
-		return //nolint: nakedret
+		return roStmts, err
 	}
 
-	_query := strings.ToUpper(query)
-	writeFlag := strings.Contains(_query, "RETURNING")
+	_, weights := db.weights()
+	return weightedRotation(roStmts, func(i int) int {
+		return nodeWeight(weights, replicas[i])
+	}), nil
+}
 
-	_stmt = &stmt{
-		loadBalancer: db.stmtLoadBalancer,
-		primaryStmts: primaryStmts,
-		replicaStmts: roStmts,
-		dbStmt:       dbStmt,
-		writeFlag:    writeFlag,
-	}
-	return _stmt, nil
+// weightedPrimaryStmts returns the statements of the primaries repeated by weight
+func (db *sqlDB) weightedPrimaryStmts(primaries []*sql.DB, stmts []*sql.Stmt) []*sql.Stmt {
+	weights, _ := db.weights()
+	return weightedRotation(stmts, func(i int) int {
+		return nodeWeight(weights, primaries[i])
+	})
 }
 
 // Query executes a query that returns rows, typically a SELECT.
@@ -208,21 +630,60 @@ func (db *sqlDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
 
 // QueryContext executes a query that returns rows, typically a SELECT.
 // The args are for any placeholder parameters in the query.
-func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
-	var curDB *sql.DB
-	writeFlag := db.queryTypeChecker.Check(query) == QueryTypeWrite
+func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db = shard
+	writeFlag := db.isWrite(ctx, query)
+	if key, ok := db.readCacheKey(writeFlag, query, args); ok {
+		res, err := db.cachedResult(ctx, key, query, args)
+		if err != nil {
+			return nil, err
+		}
+		return resultRows(ctx, db.results, res)
+	}
+	return db.query(ctx, query, args, writeFlag)
+}
+
+// query routes the query, retried with the retry policy
+func (db *sqlDB) query(ctx context.Context, query string, args []interface{}, writeFlag bool) (rows *sql.Rows, err error) {
+	if writeFlag {
+		defer db.recordWriteQuery(ctx, query)
+	}
+	err = db.retry.do(ctx, writeFlag, func() error {
+		rows, err = db.routeQuery(ctx, query, args, writeFlag)
+		return err
+	})
+	return rows, err
+}
 
+// routeQuery routes the query, and falls back when a replica is unreachable, see WithReadFallback
+func (db *sqlDB) routeQuery(ctx context.Context, query string, args []interface{}, writeFlag bool) (rows *sql.Rows, err error) {
+	var curDB *sql.DB
+	route := primaryRoute
 	if writeFlag {
 		curDB = db.ReadWrite()
 	} else {
-		curDB = db.ReadOnly()
+		curDB, route = db.readOnly(ctx)
 	}
 
-	rows, err = curDB.QueryContext(ctx, query, args...)
-	if isDBConnectionError(err) && !writeFlag {
-		rows, err = db.ReadWrite().QueryContext(ctx, query, args...)
+	coalesce := db.coalesces(writeFlag, query)
+	var queryErr error
+	rows, err = queryWithHooks(ctx, db.hooks, route.to(curDB), query, args, func(ctx context.Context) (*sql.Rows, error) {
+		rows, queryErr = db.timedQueryContext(ctx, route, curDB, query, args, coalesce)
+		return rows, queryErr
+	})
+	if route.Role == RoleReplica && db.isConnectionError(queryErr) {
+		if node, fallback, ok := db.fallback(ctx, curDB); ok {
+			curDB, route = node, fallback
+			rows, err = queryWithHooks(ctx, db.hooks, route.to(curDB), query, args, func(ctx context.Context) (*sql.Rows, error) {
+				return db.timedQueryContext(ctx, route, curDB, query, args, coalesce)
+			})
+		}
 	}
-	return
+	return rows, db.nodeError(curDB, db.classifyRouted(err, route, writeFlag))
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -236,35 +697,210 @@ func (db *sqlDB) QueryRow(query string, args ...interface{}) *sql.Row {
 // QueryRowContext always return a non-nil value.
 // Errors are deferred until Row's Scan method is called.
 func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	var curDB *sql.DB
-	writeFlag := db.queryTypeChecker.Check(query) == QueryTypeWrite
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return resultRow(ctx, db.results, nil, err)
+	}
+	db = shard
+	writeFlag := db.isWrite(ctx, query)
+	if key, ok := db.readCacheKey(writeFlag, query, args); ok {
+		res, err := db.cachedResult(ctx, key, query, args)
+		return resultRow(ctx, db.results, res, err)
+	}
 
+	if writeFlag {
+		defer db.recordWriteQuery(ctx, query)
+	}
+	var row *sql.Row
+	_ = db.retry.do(ctx, writeFlag, func() error {
+		row = db.routeQueryRow(ctx, query, args, writeFlag)
+		return row.Err()
+	})
+	return row
+}
+
+// routeQueryRow routes the query, and falls back when a replica is unreachable, see WithReadFallback
+func (db *sqlDB) routeQueryRow(ctx context.Context, query string, args []interface{}, writeFlag bool) *sql.Row {
+	var curDB *sql.DB
+	route := primaryRoute
 	if writeFlag {
 		curDB = db.ReadWrite()
 	} else {
-		curDB = db.ReadOnly()
+		curDB, route = db.readOnly(ctx)
 	}
 
-	row := curDB.QueryRowContext(ctx, query, args...)
-	if isDBConnectionError(row.Err()) && !writeFlag {
-		row = db.ReadWrite().QueryRowContext(ctx, query, args...)
+	coalesce := db.coalesces(writeFlag, query)
+	row := queryRowWithHooks(ctx, db.hooks, route.to(curDB), query, args, func(ctx context.Context) *sql.Row {
+		return db.timedQueryRowContext(ctx, route, curDB, query, args, coalesce)
+	})
+	if route.Role == RoleReplica && db.isConnectionError(row.Err()) {
+		if node, fallback, ok := db.fallback(ctx, curDB); ok {
+			curDB, route = node, fallback
+			row = queryRowWithHooks(ctx, db.hooks, route.to(curDB), query, args, func(ctx context.Context) *sql.Row {
+				return db.timedQueryRowContext(ctx, route, curDB, query, args, coalesce)
+			})
+		}
+	}
+	if err := row.Err(); err != nil {
+		// the row holds the error classified for the route, see classifyRouted
+		if classified := db.classifyRouted(err, route, writeFlag); classified != err {
+			return resultRow(ctx, db.results, nil, classified)
+		}
 	}
-
 	return row
 }
 
+// execContext executes the query on the node, with its prewarmed or cached statement if any,
+// once the workload partition of the node has a free slot
+func (db *sqlDB) execContext(ctx context.Context, node *sql.DB, query string, args []interface{}) (_ sql.Result, err error) {
+	release, err := db.acquire(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if db.latency != nil {
+		defer db.observeLatency(node, db.clock.Now(), &err)
+	}
+	if db.breaker != nil {
+		defer func() { db.breaker.record(node, err) }()
+	}
+	if stmt := db.preparedStmt(node, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	if db.stmtCache != nil {
+		if entry := db.stmtCache.acquire(ctx, node, query); entry != nil {
+			defer db.stmtCache.release(entry)
+			return entry.stmt.ExecContext(ctx, args...)
+		}
+	}
+	return node.ExecContext(ctx, query, args...)
+}
+
+// queryContext runs the query on the node, like execContext. The cancel function of the context of the query,
+// if not nil, is called when the rows are closed.
+// A coalesced query joins the identical query in flight on the node, see WithReadCoalescing.
+func (db *sqlDB) queryContext(ctx context.Context, node *sql.DB, query string, args []interface{},
+	coalesce bool, cancel context.CancelFunc) (_ *sql.Rows, err error) {
+	if coalesce {
+		res, err := db.coalescedQuery(ctx, node, query, args)
+		ctx = releaseResultContext(ctx, cancel)
+		if err != nil {
+			return nil, err
+		}
+		return resultRows(ctx, db.results, res)
+	}
+	release, err := db.acquire(ctx, node)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+	ctx, returned := db.holdUntilClosed(ctx, release, cancel)
+	defer returned()
+	if db.latency != nil {
+		defer db.observeLatency(node, db.clock.Now(), &err)
+	}
+	if db.breaker != nil {
+		defer func() { db.breaker.record(node, err) }()
+	}
+	if stmt := db.preparedStmt(node, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	if db.stmtCache != nil {
+		if entry := db.stmtCache.acquire(ctx, node, query); entry != nil {
+			defer db.stmtCache.release(entry)
+			return entry.stmt.QueryContext(ctx, args...)
+		}
+	}
+	return node.QueryContext(ctx, query, args...)
+}
+
+// queryRowContext runs the query on the node, like queryContext.
+// The context is done when no slot is free, so the row holds the context error,
+// or the row holds the admission error.
+func (db *sqlDB) queryRowContext(ctx context.Context, node *sql.DB, query string, args []interface{},
+	coalesce bool, cancel context.CancelFunc) (row *sql.Row) {
+	if coalesce {
+		res, err := db.coalescedQuery(ctx, node, query, args)
+		return resultRow(releaseResultContext(ctx, cancel), db.results, res, err)
+	}
+	release, err := db.acquire(ctx, node)
+	if err != nil && db.admission != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return resultRow(ctx, db.results, nil, err)
+	}
+	ctx, returned := db.holdUntilClosed(ctx, release, cancel)
+	defer returned()
+	if db.latency != nil {
+		// the error of the row is deferred to Scan
+		defer db.observeLatency(node, db.clock.Now(), new(error))
+	}
+	if db.breaker != nil {
+		defer func() { db.breaker.record(node, row.Err()) }()
+	}
+	if stmt := db.preparedStmt(node, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	if db.stmtCache != nil {
+		if entry := db.stmtCache.acquire(ctx, node, query); entry != nil {
+			defer db.stmtCache.release(entry)
+			return entry.stmt.QueryRowContext(ctx, args...)
+		}
+	}
+	return node.QueryRowContext(ctx, query, args...)
+}
+
+// holdUntilClosed returns the context of the query holding the slot of the node and the context of its timeout
+// until the rows are closed, see withRowsRelease, and the function to call once the query returned.
+// The release and cancel functions may be nil. The context is returned as is without limits nor timeout.
+func (db *sqlDB) holdUntilClosed(ctx context.Context, release func(),
+	cancel context.CancelFunc) (context.Context, func()) {
+	if cancel == nil && db.concurrency == nil && db.admission == nil && db.partitions == nil {
+		if release == nil {
+			return ctx, func() {}
+		}
+		return ctx, release
+	}
+	return withRowsRelease(ctx, func() {
+		if release != nil {
+			release()
+		}
+		if cancel != nil {
+			cancel()
+		}
+	})
+}
+
+// releaseResultContext calls the cancel function, if not nil, once the coalesced query read its result in memory,
+// and returns the context of the in-memory rows, which isn't canceled: the deadline doesn't cover them
+func releaseResultContext(ctx context.Context, cancel context.CancelFunc) context.Context {
+	if cancel == nil {
+		return ctx
+	}
+	cancel()
+	return context.WithoutCancel(ctx)
+}
+
 // SetMaxIdleConns sets the maximum number of connections in the idle
 // connection pool for each underlying db connection
 // If MaxOpenConns is greater than 0 but less than the new MaxIdleConns then the
 // new MaxIdleConns will be reduced to match the MaxOpenConns limit
 // If n <= 0, no idle connections are retained.
 func (db *sqlDB) SetMaxIdleConns(n int) {
-	for i := range db.primaries {
-		db.primaries[i].SetMaxIdleConns(n)
+	db.poolSettings.setMaxIdleConns(n)
+	primaries, replicas := db.topology()
+	for i := range primaries {
+		primaries[i].SetMaxIdleConns(n)
 	}
 
-	for i := range db.replicas {
-		db.replicas[i].SetMaxIdleConns(n)
+	for i := range replicas {
+		replicas[i].SetMaxIdleConns(n)
+	}
+	for _, shard := range db.shards {
+		shard.SetMaxIdleConns(n)
 	}
 }
 
@@ -275,67 +911,168 @@ func (db *sqlDB) SetMaxIdleConns(n int) {
 // the new MaxOpenConns limit. If n <= 0, then there is no limit on the number
 // of open connections. The default is 0 (unlimited).
 func (db *sqlDB) SetMaxOpenConns(n int) {
-	for i := range db.primaries {
-		db.primaries[i].SetMaxOpenConns(n)
+	primaries, replicas := db.topology()
+	for i := range primaries {
+		primaries[i].SetMaxOpenConns(n)
+	}
+	for i := range replicas {
+		replicas[i].SetMaxOpenConns(n)
 	}
-	for i := range db.replicas {
-		db.replicas[i].SetMaxOpenConns(n)
+	for _, shard := range db.shards {
+		shard.SetMaxOpenConns(n)
 	}
 }
 
 // SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
 // Expired connections may be closed lazily before reuse.
 // If d <= 0, connections are reused forever.
+// With WithConnMaxLifetimeJitter, the lifetime of each node is shortened by a random jitter.
 func (db *sqlDB) SetConnMaxLifetime(d time.Duration) {
-	for i := range db.primaries {
-		db.primaries[i].SetConnMaxLifetime(d)
+	db.poolSettings.setConnMaxLifetime(d)
+	primaries, replicas := db.topology()
+	for i := range primaries {
+		primaries[i].SetConnMaxLifetime(db.jitterLifetime(d))
+	}
+	for i := range replicas {
+		replicas[i].SetConnMaxLifetime(db.jitterLifetime(d))
 	}
-	for i := range db.replicas {
-		db.replicas[i].SetConnMaxLifetime(d)
+	for _, shard := range db.shards {
+		shard.SetConnMaxLifetime(d)
+	}
+}
+
+// jitterLifetime returns a random lifetime between (1-lifetimeJitter)*d and d
+func (db *sqlDB) jitterLifetime(d time.Duration) time.Duration {
+	if d <= 0 || db.lifetimeJitter == 0 {
+		return d
 	}
+	return d - time.Duration(rand.Float64()*db.lifetimeJitter*float64(d))
 }
 
 // SetConnMaxIdleTime sets the maximum amount of time a connection may be idle.
 // Expired connections may be closed lazily before reuse.
 // If d <= 0, connections are not closed due to a connection's idle time.
 func (db *sqlDB) SetConnMaxIdleTime(d time.Duration) {
-	for i := range db.primaries {
-		db.primaries[i].SetConnMaxIdleTime(d)
+	db.poolSettings.setConnMaxIdleTime(d)
+	primaries, replicas := db.topology()
+	for i := range primaries {
+		primaries[i].SetConnMaxIdleTime(d)
 	}
 
-	for i := range db.replicas {
-		db.replicas[i].SetConnMaxIdleTime(d)
+	for i := range replicas {
+		replicas[i].SetConnMaxIdleTime(d)
+	}
+	for _, shard := range db.shards {
+		shard.SetConnMaxIdleTime(d)
 	}
 }
 
 // ReadOnly returns the readonly database
 func (db *sqlDB) ReadOnly() *sql.DB {
-	if len(db.replicas) == 0 {
-		return db.loadBalancer.Resolve(db.primaries)
+	curDB, _ := db.readOnly(context.Background())
+	return curDB
+}
+
+// readOnly returns the readonly database and its route, a primary when there is no replica.
+// The replica is one of the nearest replicas with the nearest read preference.
+// The unhealthy replicas and the replicas lagging too much for the context are skipped,
+// the reads go to a primary when every replica is skipped.
+func (db *sqlDB) readOnly(ctx context.Context) (*sql.DB, Route) {
+	mode := db.currentReadMode()
+	if mode != PrimaryOnly {
+		rotation := db.replicaRotation(ctx)
+		if len(rotation) == 0 && mode == ReplicaOnly {
+			rotation = db.nodes.Load().replicaRotation
+		}
+		if len(rotation) > 0 {
+			return db.resolveRead(ctx, rotation), replicaRoute
+		}
 	}
-	return db.loadBalancer.Resolve(db.replicas)
+	return resolve(db.loadBalancer, db.healthyPrimaries(db.nodes.Load().primaryRotation)), primaryRoute
 }
 
-// ReadWrite returns the primary database
+// replicaRotation returns the replicas the reads of the context are balanced across: the healthy ones,
+// with a closed circuit and fresh enough, the nearest ones with WithNearestReads
+func (db *sqlDB) replicaRotation(ctx context.Context) []*sql.DB {
+	set := db.nodes.Load()
+	if len(set.replicaRotation) == 0 {
+		return nil
+	}
+	return db.lag.fresh(ctx, db.breaker.closed(db.health.healthy(db.nearest.rotation(set))))
+}
+
+// ReadWrite returns the primary database, a healthy one with the health checks,
+// the first healthy one in order with WriteFailover
 func (db *sqlDB) ReadWrite() *sql.DB {
-	return db.loadBalancer.Resolve(db.primaries)
+	set := db.nodes.Load()
+	if db.writeStrategy.Policy == WriteFailover {
+		return db.healthyPrimaries(set.primaries)[0]
+	}
+	return resolve(db.loadBalancer, db.healthyPrimaries(set.primaryRotation))
 }
 
 // Conn returns a single connection by either opening a new connection or returning an existing connection from the
-// connection pool of the first primary db.
+// connection pool of the first primary db, the first healthy one with the health checks.
+// PrimaryConn and ReplicaConn balance the connections across the nodes of a role.
 func (db *sqlDB) Conn(ctx context.Context) (Conn, error) {
-	c, err := db.primaries[0].Conn(ctx)
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db = shard
+	primaries, _ := db.topology()
+	primaries = db.healthyPrimaries(primaries)
+	return db.conn(ctx, primaries[0], primaryRoute)
+}
+
+// PrimaryConn returns a single connection of a primary resolved by the load balancer, like the writes
+func (db *sqlDB) PrimaryConn(ctx context.Context) (Conn, error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return shard.conn(ctx, shard.ReadWrite(), primaryRoute)
+}
+
+// ReplicaConn returns a single connection of a replica resolved by the load balancer, like the reads:
+// the unhealthy replicas and the replicas lagging too much for the context are skipped,
+// the connection is of a primary when every replica is skipped. The connection falls back
+// when the replica is unreachable, see WithReadFallback.
+func (db *sqlDB) ReplicaConn(ctx context.Context) (Conn, error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	node, route := shard.readOnly(ctx)
+	c, err := shard.conn(ctx, node, route)
+	if route.Role == RoleReplica && shard.isConnectionError(err) {
+		if node, fallback, ok := shard.fallback(ctx, node); ok {
+			return shard.conn(ctx, node, fallback)
+		}
+	}
+	return c, err
+}
+
+// conn returns a single connection of the node, its queries are reported with the route
+func (db *sqlDB) conn(ctx context.Context, node *sql.DB, route Route) (Conn, error) {
+	c, err := node.Conn(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	return &conn{
-		sourceDB: db.primaries[0],
-		conn:     c,
+		sourceDB:         node,
+		conn:             c,
+		route:            route,
+		hooks:            db.hooks,
+		defaultTxOptions: db.defaultTxOptions,
+		retry:            db.retry,
+		results:          db.results,
 	}, nil
 }
 
 // Stats returns database statistics for the first primary db
 func (db *sqlDB) Stats() sql.DBStats {
-	return db.primaries[0].Stats()
+	primaries, _ := db.topology()
+	return primaries[0].Stats()
 }
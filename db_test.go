@@ -3,11 +3,16 @@ package dbresolver
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/bxcodec/dbresolver/v2/stubdriver"
 )
 
 type DBConfig struct {
@@ -373,3 +378,276 @@ type QueryMatcher struct {
 func (*QueryMatcher) Match(expectedSQL, actualSQL string) error {
 	return nil
 }
+
+func TestDynamicReplicas(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := New(WithPrimaryDBs(primary))
+
+	if got := resolver.ReadOnly(); got != primary {
+		t.Errorf("want primary without replicas, got %v", got)
+	}
+
+	resolver.AddReplica(replica)
+	resolver.AddReplica(replica)
+	if len(resolver.ReplicaDBs()) != 1 {
+		t.Errorf("want %v, got %v", 1, len(resolver.ReplicaDBs()))
+	}
+	if got := resolver.ReadOnly(); got != replica {
+		t.Errorf("want added replica, got %v", got)
+	}
+
+	resolver.RemoveReplica(replica)
+	if len(resolver.ReplicaDBs()) != 0 {
+		t.Errorf("want %v, got %v", 0, len(resolver.ReplicaDBs()))
+	}
+	if got := resolver.ReadOnly(); got != primary {
+		t.Errorf("want primary after removing the replica, got %v", got)
+	}
+}
+
+func TestDynamicReplicasConcurrently(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replicas := make([]*sql.DB, 4)
+	for i := range replicas {
+		replicas[i], _, err = createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+	}
+
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicas[0]))
+
+	err = doParallely(context.Background(), 0, len(replicas)*2, func(i int) error {
+		replica := replicas[i%len(replicas)]
+		for j := 0; j < 100; j++ {
+			if i%2 == 0 {
+				resolver.AddReplica(replica)
+			} else {
+				resolver.RemoveReplica(replica)
+			}
+			if resolver.ReadOnly() == nil {
+				return fmt.Errorf("resolved nil replica")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTopologyChangesConcurrently(t *testing.T) {
+	primary := &sql.DB{}
+	replicas := make([]*sql.DB, 16)
+	for i := range replicas {
+		replicas[i] = &sql.DB{}
+	}
+	resolver := New(WithPrimaryDBs(primary)).(*sqlDB)
+
+	// the readers run while every replica is added and reweighted concurrently
+	done := make(chan struct{})
+	readers := make(chan error)
+	for r := 0; r < 4; r++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					readers <- nil
+					return
+				default:
+				}
+				if resolver.ReadOnly() == nil || resolver.ReadWrite() != primary {
+					readers <- fmt.Errorf("resolved an invalid node")
+					return
+				}
+				resolver.Nodes()
+			}
+		}()
+	}
+	err := doParallely(context.Background(), 0, len(replicas), func(i int) error {
+		resolver.AddReplica(replicas[i])
+		resolver.setWeight(replicas[i], RoleReplica, 2)
+		resolver.setLabels(replicas[i], map[string]string{"index": fmt.Sprint(i)})
+		return nil
+	})
+	close(done)
+	for r := 0; r < 4; r++ {
+		if readerErr := <-readers; readerErr != nil {
+			t.Error(readerErr)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no change is lost
+	if got := resolver.ReplicaDBs(); len(got) != len(replicas) {
+		t.Fatalf("want %d replicas, got %d", len(replicas), len(got))
+	}
+	for _, node := range resolver.Nodes()[1:] {
+		if node.Weight != 2 || node.Labels["index"] == "" {
+			t.Errorf("want the weight and the labels of every replica, got %+v", node)
+		}
+	}
+}
+
+func TestDynamicPrimaries(t *testing.T) {
+	primary1, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	primary2, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := New(WithPrimaryDBs(primary1))
+
+	if err := resolver.RemovePrimary(primary1); err != ErrLastPrimary {
+		t.Errorf("want %v, got %v", ErrLastPrimary, err)
+	}
+
+	resolver.AddPrimary(primary2)
+	if err := resolver.RemovePrimary(primary1); err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+	if got := resolver.PrimaryDBs(); len(got) != 1 || got[0] != primary2 {
+		t.Errorf("want %v, got %v", []*sql.DB{primary2}, got)
+	}
+	if got := resolver.ReadWrite(); got != primary2 {
+		t.Errorf("want the added primary, got %v", got)
+	}
+}
+
+func TestConnMaxLifetimeJitter(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := New(WithPrimaryDBs(primary), WithConnMaxLifetimeJitter(0.2)).(*sqlDB)
+	resolver.SetConnMaxLifetime(time.Hour)
+
+	lifetimes := map[time.Duration]struct{}{}
+	for i := 0; i < 100; i++ {
+		lifetime := resolver.jitterLifetime(time.Hour)
+		if lifetime <= 48*time.Minute || lifetime > time.Hour {
+			t.Fatalf("want a lifetime between 48m and 1h, got %s", lifetime)
+		}
+		lifetimes[lifetime] = struct{}{}
+	}
+	if len(lifetimes) < 2 {
+		t.Error("want the lifetimes spread")
+	}
+	if lifetime := resolver.jitterLifetime(0); lifetime != 0 {
+		t.Errorf("want the unlimited lifetime kept, got %s", lifetime)
+	}
+}
+
+// prepareConnector opens connections counting the concurrent prepares across its nodes
+type prepareConnector struct {
+	running, maxRunning *atomic.Int32
+}
+
+func (c prepareConnector) Connect(context.Context) (driver.Conn, error) { return prepareConn(c), nil }
+func (c prepareConnector) Driver() driver.Driver                        { return nil }
+
+type prepareConn prepareConnector
+
+func (c prepareConn) Prepare(string) (driver.Stmt, error) {
+	n := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		current := c.maxRunning.Load()
+		if n <= current || c.maxRunning.CompareAndSwap(current, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return prepareStmt{}, nil
+}
+func (c prepareConn) Close() error              { return nil }
+func (c prepareConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type prepareStmt struct{}
+
+func (prepareStmt) Close() error  { return nil }
+func (prepareStmt) NumInput() int { return -1 }
+func (prepareStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (prepareStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestPrepareConcurrency(t *testing.T) {
+	connector := prepareConnector{running: &atomic.Int32{}, maxRunning: &atomic.Int32{}}
+	replicas := make([]*sql.DB, 8)
+	for i := range replicas {
+		replicas[i] = sql.OpenDB(connector)
+	}
+	primary := sql.OpenDB(connector)
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicas...), WithPrepareConcurrency(2))
+	defer resolver.Close()
+
+	stmt, err := resolver.Prepare("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if got := connector.maxRunning.Load(); got != 2 {
+		t.Errorf("want 2 nodes preparing concurrently, got %d", got)
+	}
+
+	// a node with its pool exhausted holds the prepare until the context is done
+	primary.SetMaxOpenConns(1)
+	conn, err := primary.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := resolver.PrepareContext(ctx, "SELECT 2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want the deadline error of the exhausted node, got %v", err)
+	}
+}
+
+func TestDefaultTxOptions(t *testing.T) {
+	// the stub driver supports the default transactions only
+	primary, err := sql.Open(stubdriver.DriverName, "primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := New(WithPrimaryDBs(primary), WithDefaultTxOptions(sql.TxOptions{ReadOnly: true}))
+	defer resolver.Close()
+
+	if _, err := resolver.Begin(); err == nil {
+		t.Error("want the default read-only options applied to Begin")
+	}
+	tx, err := resolver.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		t.Fatalf("want the explicit options to take precedence, got %v", err)
+	}
+	_ = tx.Rollback()
+
+	conn, err := resolver.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.BeginTx(context.Background(), nil); err == nil {
+		t.Error("want the default read-only options applied to the connections")
+	}
+}
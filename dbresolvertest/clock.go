@@ -0,0 +1,89 @@
+package dbresolvertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// ManualClock is a dbresolver.Clock advanced by the test, see dbresolver.WithClock
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*manualTimer
+	// armed is closed and replaced when a timer is created
+	armed chan struct{}
+}
+
+// NewManualClock creates a clock stopped at now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now, armed: make(chan struct{})}
+}
+
+// Now returns the current time of the clock
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer firing once the clock is advanced by the duration
+func (c *ManualClock) NewTimer(d time.Duration) dbresolver.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &manualTimer{clock: c, deadline: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, t)
+	close(c.armed)
+	c.armed = make(chan struct{})
+	return t
+}
+
+// Advance moves the clock forward, and fires the timers expiring in the meantime
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.timers = pending
+}
+
+// BlockUntil waits until n timers are pending, eg. until a background loop waits for its next iteration
+func (c *ManualClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		pending, armed := len(c.timers), c.armed
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		<-armed
+	}
+}
+
+type manualTimer struct {
+	clock    *ManualClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *manualTimer) C() <-chan time.Time { return t.c }
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package dbresolvertest_test
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+)
+
+type countingDiscoverer struct {
+	calls atomic.Int32
+}
+
+func (d *countingDiscoverer) Discover(context.Context) ([]dbresolver.Endpoint, error) {
+	d.calls.Add(1)
+	return nil, nil
+}
+
+func TestManualClock(t *testing.T) {
+	clock := dbresolvertest.NewManualClock(time.Now())
+	discoverer := &countingDiscoverer{}
+	dbresolvertest.NewFake(t, 1, 0, dbresolver.WithClock(clock),
+		dbresolver.WithDiscoverer(discoverer, time.Minute, func(dbresolver.Endpoint) (*sql.DB, error) {
+			return nil, nil
+		}))
+
+	// the discovery refreshes once the clock is advanced by the refresh interval
+	for i := 1; i <= 3; i++ {
+		clock.BlockUntil(1)
+		if calls := discoverer.calls.Load(); calls != int32(i) {
+			t.Fatalf("want %d discoveries, got %d", i, calls)
+		}
+		clock.Advance(59 * time.Second)
+		clock.Advance(time.Second)
+	}
+}
+
+func TestManualClockTimer(t *testing.T) {
+	start := time.Now()
+	clock := dbresolvertest.NewManualClock(start)
+	timer := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Error("want the pending timer stopped")
+	}
+
+	clock.Advance(time.Second)
+	if now := <-timer.C(); !now.Equal(start.Add(time.Second)) {
+		t.Errorf("want the timer fired at the advanced time, got %v", now)
+	}
+	if timer.Stop() {
+		t.Error("want the fired timer not stopped")
+	}
+	select {
+	case <-stopped.C():
+		t.Error("want the stopped timer not fired")
+	default:
+	}
+}
@@ -0,0 +1,93 @@
+// Package dbresolvertest provides a resolver backed by sqlmock, to test the code using the resolver
+// and the routing of its queries without a database.
+package dbresolvertest
+
+import (
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+var dsnCounter atomic.Uint64
+
+// Resolver is a resolver of sqlmock primaries and replicas.
+//
+// The nodes of a role share the expectations of the role, so the expectations don't depend on
+// the node chosen by the load balancer. The reads are expected on the primaries when there is no replica.
+type Resolver struct {
+	dbresolver.DB
+	// Primary holds the expectations of the primaries
+	Primary sqlmock.Sqlmock
+	// Replica holds the expectations of the replicas, it is nil when there is no replica
+	Replica sqlmock.Sqlmock
+}
+
+// NewForTest creates a resolver of sqlmock primaries and replicas, configured with the options.
+// The queries are matched with sqlmock.QueryMatcherEqual.
+// The expectations are checked and the resolver is closed when the test completes.
+func NewForTest(t testing.TB, primaries, replicas int, opts ...dbresolver.OptionFunc) *Resolver {
+	t.Helper()
+	if primaries < 1 {
+		t.Fatal("dbresolvertest: at least one primary is required")
+	}
+
+	primaryDBs, primaryMock := openMocks(t, "primary", primaries)
+	replicaDBs, replicaMock := openMocks(t, "replica", replicas)
+	r := &Resolver{
+		DB: dbresolver.New(append([]dbresolver.OptionFunc{
+			dbresolver.WithPrimaryDBs(primaryDBs...),
+			dbresolver.WithReplicaDBs(replicaDBs...),
+		}, opts...)...),
+		Primary: primaryMock,
+		Replica: replicaMock,
+	}
+
+	t.Cleanup(func() {
+		if err := r.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		// sqlmock fails the Close calls not expected by the test
+		_ = r.Close()
+	})
+	return r
+}
+
+// ExpectationsWereMet checks the expectations of the primaries and replicas
+func (r *Resolver) ExpectationsWereMet() error {
+	if err := r.Primary.ExpectationsWereMet(); err != nil {
+		return fmt.Errorf("primary: %w", err)
+	}
+	if r.Replica != nil {
+		if err := r.Replica.ExpectationsWereMet(); err != nil {
+			return fmt.Errorf("replica: %w", err)
+		}
+	}
+	return nil
+}
+
+// openMocks opens n dbs sharing the same sqlmock connection
+func openMocks(t testing.TB, role string, n int) ([]*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	if n == 0 {
+		return nil, nil
+	}
+
+	dsn := fmt.Sprintf("dbresolvertest_%s_%d", role, dsnCounter.Add(1))
+	db, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("dbresolvertest: creating of mock failed", err)
+	}
+	dbs := []*sql.DB{db}
+	for i := 1; i < n; i++ {
+		db, err := sql.Open("sqlmock", dsn)
+		if err != nil {
+			t.Fatal("dbresolvertest: opening of mock failed", err)
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs, mock
+}
@@ -0,0 +1,64 @@
+package dbresolvertest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+)
+
+func TestNewForTest(t *testing.T) {
+	db := dbresolvertest.NewForTest(t, 2, 3, dbresolver.WithLoadBalancer(dbresolver.RandomLB))
+	if len(db.PrimaryDBs()) != 2 || len(db.ReplicaDBs()) != 3 {
+		t.Fatalf("want 2 primaries and 3 replicas, got %d and %d", len(db.PrimaryDBs()), len(db.ReplicaDBs()))
+	}
+
+	for i := 0; i < 3; i++ {
+		db.Replica.ExpectQuery("SELECT title FROM book WHERE id = $1").
+			WithArgs(i).
+			WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Dune"))
+	}
+	db.Primary.ExpectQuery("INSERT INTO book (title) VALUES ($1) RETURNING id").
+		WithArgs("Emma").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	db.Primary.ExpectBegin()
+	db.Primary.ExpectExec("DELETE FROM book WHERE id = $1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	db.Primary.ExpectCommit()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		var title string
+		if err := db.QueryRowContext(ctx, "SELECT title FROM book WHERE id = $1", i).Scan(&title); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var id int
+	if err := db.QueryRowContext(ctx, "INSERT INTO book (title) VALUES ($1) RETURNING id", "Emma").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM book WHERE id = $1", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewForTestWithoutReplicas(t *testing.T) {
+	db := dbresolvertest.NewForTest(t, 1, 0)
+	if db.Replica != nil {
+		t.Error("want no replica expectations")
+	}
+
+	db.Primary.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	var one int
+	if err := db.QueryRow("SELECT 1").Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+}
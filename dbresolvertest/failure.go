@@ -0,0 +1,41 @@
+package dbresolvertest
+
+import (
+	"database/sql/driver"
+)
+
+// ErrTimeout is a net.Error timing out, the error of a node killed with Kill(node, ErrTimeout).
+// The resolver falls back to a primary when a replica returns a net.Error.
+var ErrTimeout error = timeoutError{}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "dbresolvertest: i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// Kill makes the named node fail its connections, pings and queries with the error until it's revived,
+// driver.ErrBadConn when err is nil, to exercise the fallback, retry and eviction paths deterministically.
+// The queries sent to a killed node over an already open connection are still recorded in the calls.
+func (f *FakeDB) Kill(node string, err error) {
+	if err == nil {
+		err = driver.ErrBadConn
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.killed[node] = err
+}
+
+// Revive makes the killed node answer again
+func (f *FakeDB) Revive(node string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.killed, node)
+}
+
+// killedErr returns the error of the node when it's killed
+func (f *FakeDB) killedErr(node string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.killed[node]
+}
@@ -0,0 +1,46 @@
+package dbresolvertest_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+)
+
+func TestKill(t *testing.T) {
+	db := dbresolvertest.NewFake(t, 1, 1)
+	query := "SELECT title FROM book WHERE id = $1"
+	db.On(query).Rows([]string{"title"}, []driver.Value{"Dune"})
+	db.On("DELETE FROM book").Result(0, 1)
+
+	// the read falls back to the primary while the replica times out
+	db.Kill("replica-0", dbresolvertest.ErrTimeout)
+	var title string
+	if err := db.QueryRow(query, 1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if calls := db.Calls(); len(calls) != 1 || calls[0].Node != "primary-0" {
+		t.Errorf("want the read answered by the primary, got %v", calls)
+	}
+
+	db.Kill("primary-0", nil)
+	if _, err := db.Exec("DELETE FROM book"); !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("want the killed primary failing, got %v", err)
+	}
+	if err := db.Ping(); !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("want the ping of the killed primary failing, got %v", err)
+	}
+
+	db.Revive("primary-0")
+	db.Revive("replica-0")
+	if _, err := db.Exec("DELETE FROM book"); err != nil {
+		t.Errorf("want the revived primary answering, got %v", err)
+	}
+	if err := db.QueryRow(query, 1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if calls := db.Calls(); calls[len(calls)-1].Node != "replica-0" {
+		t.Errorf("want the read answered by the revived replica, got %v", calls)
+	}
+}
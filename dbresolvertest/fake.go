@@ -0,0 +1,268 @@
+package dbresolvertest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// FakeDB is a resolver of in-memory nodes answering the queries with scripted responses,
+// to unit test the code using the resolver without scripting the expectations of every node.
+//
+// The queries are routed by the resolver, so the reads reach the replicas and the writes the primaries.
+// The nodes are named "primary-0", "replica-0", etc. A response scripted for a node with OnNode
+// takes precedence over the response of the query, eg. to make a replica unreachable with a *net.OpError.
+type FakeDB struct {
+	dbresolver.DB
+
+	mu sync.Mutex
+	// responses are the versions of the responses of each key, see ReplicaLag
+	responses map[responseKey][]*Response
+	calls     []Call
+	lag       replicaLag
+	// killed are the errors of the killed nodes, see Kill
+	killed map[string]error
+}
+
+// Call is a query answered by a node of the FakeDB
+type Call struct {
+	// Node is the name of the node answering the query
+	Node  string
+	Query string
+	Args  []driver.Value
+}
+
+// Response is the scripted response of a query, its methods can be chained.
+// A query returns the rows when they're set, the error when it's set, an empty result otherwise.
+type Response struct {
+	fake *FakeDB
+	// version is the sequence number of the response, scripted at the time
+	version uint64
+	at      time.Time
+
+	columns []string
+	rows    [][]driver.Value
+	result  driver.Result
+	err     error
+}
+
+type responseKey struct {
+	node  string
+	query string
+}
+
+// NewFake creates a FakeDB of primaries and replicas, configured with the options.
+// The resolver is closed when the test completes.
+func NewFake(t testing.TB, primaries, replicas int, opts ...dbresolver.OptionFunc) *FakeDB {
+	t.Helper()
+	if primaries < 1 {
+		t.Fatal("dbresolvertest: at least one primary is required")
+	}
+
+	f := &FakeDB{responses: make(map[responseKey][]*Response), lag: newReplicaLag(),
+		killed: make(map[string]error)}
+	var nodeOpts []dbresolver.OptionFunc
+	openNodes := func(role string, n int) []*sql.DB {
+		dbs := make([]*sql.DB, n)
+		for i := range dbs {
+			name := fmt.Sprintf("%s-%d", role, i)
+			dbs[i] = sql.OpenDB(fakeConnector{fake: f, node: name, replica: role == "replica"})
+			nodeOpts = append(nodeOpts, dbresolver.WithNodeName(dbs[i], name))
+		}
+		return dbs
+	}
+	primaryDBs, replicaDBs := openNodes("primary", primaries), openNodes("replica", replicas)
+	f.DB = dbresolver.New(append(append([]dbresolver.OptionFunc{
+		dbresolver.WithPrimaryDBs(primaryDBs...),
+		dbresolver.WithReplicaDBs(replicaDBs...),
+	}, nodeOpts...), opts...)...)
+
+	t.Cleanup(func() {
+		_ = f.Close()
+	})
+	return f
+}
+
+// On scripts the response of the query on every node
+func (f *FakeDB) On(query string) *Response {
+	return f.OnNode("", query)
+}
+
+// OnNode scripts the response of the query on the named node.
+// The response replaces the previous response of the query on the node, the lagging replicas
+// keep answering the previous one until they catch up, see ReplicaLag.
+func (f *FakeDB) OnNode(node, query string) *Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := responseKey{node: node, query: query}
+	response := &Response{fake: f, version: f.lag.next(), at: f.lag.now(), result: driver.ResultNoRows}
+	f.responses[key] = append(f.responses[key], response)
+	return response
+}
+
+// Rows sets the rows returned by the query
+func (r *Response) Rows(columns []string, rows ...[]driver.Value) *Response {
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	r.columns, r.rows = columns, rows
+	return r
+}
+
+// Result sets the result of the query executed
+func (r *Response) Result(lastInsertID, rowsAffected int64) *Response {
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	r.result = fakeResult{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+	return r
+}
+
+// Err sets the error returned by the query
+func (r *Response) Err(err error) *Response {
+	r.fake.mu.Lock()
+	defer r.fake.mu.Unlock()
+	r.err = err
+	return r
+}
+
+// Calls returns the queries answered by the nodes, in order
+func (f *FakeDB) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// answer records the call and returns a copy of the response visible on the node
+func (f *FakeDB) answer(node string, replica bool, query string, args []driver.NamedValue) (Response, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, Call{Node: node, Query: query, Args: values})
+	if err, ok := f.killed[node]; ok {
+		return Response{}, err
+	}
+	response, ok := f.visible(node, replica, responseKey{node: node, query: query})
+	if !ok {
+		response, ok = f.visible(node, replica, responseKey{query: query})
+	}
+	if !ok {
+		return Response{}, fmt.Errorf("dbresolvertest: unexpected query %q on %s", query, node)
+	}
+	return *response, nil
+}
+
+type fakeResult struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeConnector struct {
+	fake    *FakeDB
+	node    string
+	replica bool
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	if err := c.fake.killedErr(c.node); err != nil {
+		return nil, err
+	}
+	return fakeConn(c), nil
+}
+
+func (c fakeConnector) Driver() driver.Driver { return nil }
+
+// fakeConn answers the queries of a node, the transactions and the statements run on the connection
+type fakeConn fakeConnector
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{conn: c, query: query}, nil
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+// Ping fails when the node is killed
+func (c fakeConn) Ping(context.Context) error { return c.fake.killedErr(c.node) }
+
+// CheckNamedValue accepts any arg, the args are recorded as is
+func (c fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	response, err := c.fake.answer(c.node, c.replica, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if response.err != nil {
+		return nil, response.err
+	}
+	return response.result, nil
+}
+
+func (c fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	response, err := c.fake.answer(c.node, c.replica, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if response.err != nil {
+		return nil, response.err
+	}
+	return &fakeRows{columns: response.columns, rows: response.rows}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeStmt struct {
+	conn  fakeConn
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next == len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
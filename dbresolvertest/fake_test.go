@@ -0,0 +1,82 @@
+package dbresolvertest_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+)
+
+func TestFakeDB(t *testing.T) {
+	db := dbresolvertest.NewFake(t, 1, 2)
+	db.On("SELECT title FROM book WHERE id = $1").Rows([]string{"title"}, []driver.Value{"Dune"})
+	db.On("DELETE FROM book WHERE id = $1").Result(0, 1)
+	db.On("UPDATE book SET title = $1").Err(errors.New("permission denied"))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		var title string
+		if err := db.QueryRowContext(ctx, "SELECT title FROM book WHERE id = $1", 1).Scan(&title); err != nil {
+			t.Fatal(err)
+		}
+		if title != "Dune" {
+			t.Errorf("want the scripted title, got %q", title)
+		}
+	}
+	res, err := db.ExecContext(ctx, "DELETE FROM book WHERE id = $1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Errorf("want 1 row affected, got %d", n)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE book SET title = $1", "Emma"); err == nil || err.Error() != `dbresolver: primary "primary-0": permission denied` {
+		t.Errorf("want the scripted error, got %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "TRUNCATE book"); err == nil || !strings.Contains(err.Error(), `unexpected query "TRUNCATE book" on primary-0`) {
+		t.Errorf("want the unscripted query reported, got %v", err)
+	}
+
+	calls := db.Calls()
+	if len(calls) != 5 {
+		t.Fatalf("want 5 calls, got %v", calls)
+	}
+	// the reads are load balanced between the replicas
+	if nodes := calls[0].Node + "," + calls[1].Node; nodes != "replica-0,replica-1" && nodes != "replica-1,replica-0" {
+		t.Errorf("want the reads answered by both replicas, got %s", nodes)
+	}
+	want := []dbresolvertest.Call{
+		{Node: "primary-0", Query: "DELETE FROM book WHERE id = $1", Args: []driver.Value{1}},
+		{Node: "primary-0", Query: "UPDATE book SET title = $1", Args: []driver.Value{"Emma"}},
+		{Node: "primary-0", Query: "TRUNCATE book", Args: []driver.Value{}},
+	}
+	if !reflect.DeepEqual(calls[2:], want) {
+		t.Errorf("want the writes %v, got %v", want, calls[2:])
+	}
+}
+
+func TestFakeDBNodeResponse(t *testing.T) {
+	db := dbresolvertest.NewFake(t, 1, 1)
+	query := "SELECT title FROM book WHERE id = $1"
+	db.On(query).Rows([]string{"title"}, []driver.Value{"Dune"})
+	db.OnNode("replica-0", query).Err(&net.OpError{Op: "dial", Err: errors.New("connection refused")})
+
+	// the unreachable replica falls back to the primary
+	rows, err := db.Query(query, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		t.Fatal("want the row of the primary")
+	}
+	calls := db.Calls()
+	if len(calls) != 2 || calls[0].Node != "replica-0" || calls[1].Node != "primary-0" {
+		t.Errorf("want the query answered by the primary after the replica, got %v", calls)
+	}
+}
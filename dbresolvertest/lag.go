@@ -0,0 +1,71 @@
+package dbresolvertest
+
+import (
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// replicaLag is the simulated replication lag of the replicas of a FakeDB
+type replicaLag struct {
+	lag     time.Duration
+	now     func() time.Time
+	version uint64
+	// caughtUp is the last version replayed by each replica, by CatchUp
+	caughtUp map[string]uint64
+}
+
+func newReplicaLag() replicaLag {
+	return replicaLag{now: time.Now, caughtUp: make(map[string]uint64)}
+}
+
+// next returns the version of a new response
+func (l *replicaLag) next() uint64 {
+	l.version++
+	return l.version
+}
+
+// replays reports whether the replica has replayed the response
+func (l *replicaLag) replays(node string, response *Response) bool {
+	return response.version <= l.caughtUp[node] || l.now().Sub(response.at) >= l.lag
+}
+
+// ReplicaLag simulates the replication lag of the replicas: a replica answers the response of a query
+// scripted at least lag ago, a stale snapshot, until the response is older than the lag or the replica
+// catches up with CatchUp. The primaries answer the last response. A replica answers the queries
+// scripted after its snapshot as unexpected queries. There is no lag by default.
+//
+// It tests the read-after-write and the staleness of the reads without a replicated cluster,
+// a lag of hours simulates replicas replaying only on CatchUp.
+func (f *FakeDB) ReplicaLag(lag time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lag.lag = lag
+}
+
+// CatchUp makes the replicas replay the responses scripted so far, every replica when none is named
+func (f *FakeDB) CatchUp(replicas ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(replicas) == 0 {
+		for _, node := range f.Nodes() {
+			if node.Role == dbresolver.RoleReplica {
+				replicas = append(replicas, node.Name())
+			}
+		}
+	}
+	for _, replica := range replicas {
+		f.lag.caughtUp[replica] = f.lag.version
+	}
+}
+
+// visible returns the last response of the key visible on the node, must be called with the lock held
+func (f *FakeDB) visible(node string, replica bool, key responseKey) (*Response, bool) {
+	versions := f.responses[key]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !replica || f.lag.replays(node, versions[i]) {
+			return versions[i], true
+		}
+	}
+	return nil, false
+}
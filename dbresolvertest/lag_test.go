@@ -0,0 +1,69 @@
+package dbresolvertest_test
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+)
+
+func TestReplicaLag(t *testing.T) {
+	db := dbresolvertest.NewFake(t, 1, 1)
+	db.ReplicaLag(time.Hour)
+	query := "SELECT title FROM book WHERE id = $1"
+	db.On(query).Rows([]string{"title"}, []driver.Value{"Dune"})
+
+	var title string
+	if err := db.QueryRow(query, 1).Scan(&title); err == nil || !strings.Contains(err.Error(), "unexpected query") {
+		t.Errorf("want the query unknown to the lagging replica, got %v", err)
+	}
+	db.CatchUp()
+
+	// the write is visible on the primary, the replica answers the stale snapshot until it catches up
+	db.On("UPDATE book SET title = $1 WHERE id = $2").Result(0, 1)
+	if _, err := db.Exec("UPDATE book SET title = $1 WHERE id = $2", "Dune Messiah", 1); err != nil {
+		t.Fatal(err)
+	}
+	db.On(query).Rows([]string{"title"}, []driver.Value{"Dune Messiah"})
+	for _, tc := range []struct {
+		read func() error
+		want string
+	}{
+		{func() error { return db.QueryRow(query, 1).Scan(&title) }, "Dune"},
+		{func() error { return db.ReadWrite().QueryRow(query, 1).Scan(&title) }, "Dune Messiah"},
+		{func() error { db.CatchUp("replica-0"); return db.QueryRow(query, 1).Scan(&title) }, "Dune Messiah"},
+	} {
+		if err := tc.read(); err != nil {
+			t.Fatal(err)
+		}
+		if title != tc.want {
+			t.Errorf("want %q, got %q", tc.want, title)
+		}
+	}
+}
+
+func TestReplicaLagElapsed(t *testing.T) {
+	db := dbresolvertest.NewFake(t, 1, 1)
+	query := "SELECT title FROM book WHERE id = $1"
+	db.On(query).Rows([]string{"title"}, []driver.Value{"Dune"})
+	db.ReplicaLag(100 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	db.On(query).Rows([]string{"title"}, []driver.Value{"Dune Messiah"})
+
+	var title string
+	if err := db.QueryRow(query, 1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if title != "Dune" {
+		t.Errorf("want the stale title, got %q", title)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := db.QueryRow(query, 1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	if title != "Dune Messiah" {
+		t.Errorf("want the replicated title, got %q", title)
+	}
+}
@@ -0,0 +1,89 @@
+package dbresolvertest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// Routing is a routing decision of the resolver
+type Routing struct {
+	Query string
+	// Route is where the query was sent, a fallback read is recorded as a second routing
+	Route dbresolver.Route
+	// Err is the error of the query
+	Err error
+}
+
+// Recorder records the routing decisions of a resolver, to assert the routing contracts of the application,
+// eg. a handler never touching the primaries. Attach it with dbresolver.WithHooks(recorder).
+type Recorder struct {
+	mu       sync.Mutex
+	routings []Routing
+}
+
+type routingKey struct{}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Before records the route of the query
+func (r *Recorder) Before(ctx context.Context, query string, _ ...interface{}) (context.Context, error) {
+	route, _ := dbresolver.RouteFromContext(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routings = append(r.routings, Routing{Query: query, Route: route})
+	return context.WithValue(ctx, routingKey{}, len(r.routings)-1), nil
+}
+
+// After is a no-op
+func (r *Recorder) After(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	return ctx, nil
+}
+
+// OnError records the error of the query
+func (r *Recorder) OnError(ctx context.Context, err error, _ string, _ ...interface{}) error {
+	i, ok := ctx.Value(routingKey{}).(int)
+	if !ok {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i < len(r.routings) {
+		r.routings[i].Err = err
+	}
+	return err
+}
+
+// Routings returns the routing decisions recorded, in order
+func (r *Recorder) Routings() []Routing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Routing(nil), r.routings...)
+}
+
+// Reset forgets the routing decisions recorded
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routings = nil
+}
+
+// AssertNoRole fails the test when a query was sent to a node of the role, eg. dbresolver.RolePrimary
+func (r *Recorder) AssertNoRole(t testing.TB, role dbresolver.Role) {
+	t.Helper()
+	var queries []string
+	for _, routing := range r.Routings() {
+		if routing.Route.Role == role {
+			queries = append(queries, routing.Query)
+		}
+	}
+	if len(queries) > 0 {
+		t.Errorf("dbresolvertest: want no query sent to a %s, got %q", role, strings.Join(queries, `", "`))
+	}
+}
@@ -0,0 +1,63 @@
+package dbresolvertest_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+)
+
+// recordingTB records the errors of the test
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	recorder := dbresolvertest.NewRecorder()
+	db := dbresolvertest.NewFake(t, 1, 1, dbresolver.WithHooks(recorder))
+	query := "SELECT title FROM book WHERE id = $1"
+	db.On(query).Rows([]string{"title"}, []driver.Value{"Dune"})
+
+	var title string
+	if err := db.QueryRow(query, 1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	recorder.AssertNoRole(t, dbresolver.RolePrimary)
+
+	db.Kill("replica-0", dbresolvertest.ErrTimeout)
+	if err := db.QueryRow(query, 1).Scan(&title); err != nil {
+		t.Fatal(err)
+	}
+	routings := recorder.Routings()
+	if len(routings) != 3 {
+		t.Fatalf("want 3 routings, got %v", routings)
+	}
+	replica, fallback := routings[1], routings[2]
+	if replica.Route.Role != dbresolver.RoleReplica || !errors.Is(replica.Err, dbresolvertest.ErrTimeout) {
+		t.Errorf("want the read failing on the replica, got %+v", replica)
+	}
+	if !fallback.Route.Fallback || fallback.Route.Node != db.PrimaryDBs()[0] || fallback.Err != nil {
+		t.Errorf("want the read falling back to the primary, got %+v", fallback)
+	}
+
+	tb := &recordingTB{}
+	recorder.AssertNoRole(tb, dbresolver.RolePrimary)
+	if len(tb.errors) != 1 {
+		t.Errorf("want the fallback to the primary reported, got %v", tb.errors)
+	}
+
+	recorder.Reset()
+	if routings := recorder.Routings(); len(routings) != 0 {
+		t.Errorf("want no routing after reset, got %v", routings)
+	}
+}
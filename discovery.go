@@ -0,0 +1,163 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Role is the role of a database node in the topology
+type Role string
+
+// Supported node roles
+const (
+	RolePrimary Role = "primary"
+	RoleReplica Role = "replica"
+)
+
+// Endpoint is a database node found by a Discoverer
+type Endpoint struct {
+	// Addr is the network address of the node, eg. host:port
+	Addr string
+	// Role is the role of the node in the topology
+	Role Role
+	// Tags are the free form metadata of the node, eg. zone
+	Tags map[string]string
+}
+
+// Discoverer discovers the database nodes of the topology, eg. from DNS or a service registry.
+//
+// Discover returns the current endpoints.
+// Implementations supporting change notifications may block until the endpoints change,
+// or until the context is done.
+type Discoverer interface {
+	Discover(ctx context.Context) ([]Endpoint, error)
+}
+
+// Discovery define a Discoverer and how to connect to the discovered endpoints
+type Discovery struct {
+	Discoverer Discoverer
+	// Open opens the connection to a discovered endpoint
+	Open func(endpoint Endpoint) (*sql.DB, error)
+	// RefreshInterval is the delay between two Discover calls.
+	// It can be zero for discoverers blocking until the endpoints change.
+	RefreshInterval time.Duration
+}
+
+// discoveryRetryDelay is the minimum delay before calling a failing Discoverer again
+const discoveryRetryDelay = time.Second
+
+type endpointKey struct {
+	addr string
+	role Role
+}
+
+// discovery runs the Discoverer in the background and reconciles the topology of the resolver
+type discovery struct {
+	db     *sqlDB
+	config Discovery
+	nodes  map[endpointKey]*sql.DB
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newDiscovery(db *sqlDB, config Discovery) *discovery {
+	return &discovery{
+		db:     db,
+		config: config,
+		nodes:  map[endpointKey]*sql.DB{},
+		done:   make(chan struct{}),
+	}
+}
+
+// start discovers the endpoints once synchronously, then keeps refreshing them in the background
+func (d *discovery) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	err := d.refresh(ctx)
+
+	go func() {
+		defer close(d.done)
+		for {
+			delay := d.config.RefreshInterval
+			if err != nil && delay < discoveryRetryDelay {
+				delay = discoveryRetryDelay
+			}
+			if delay > 0 {
+				timer := d.db.clock.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C():
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			err = d.refresh(ctx)
+		}
+	}()
+}
+
+// close stops the background refresh, the discovered nodes are closed with the resolver
+func (d *discovery) close() {
+	d.cancel()
+	<-d.done
+}
+
+func (d *discovery) refresh(ctx context.Context) error {
+	endpoints, err := d.config.Discoverer.Discover(ctx)
+	if err != nil {
+		// keep the current nodes, a failed discovery is not a removal
+		return err
+	}
+	d.reconcile(endpoints)
+	return nil
+}
+
+// reconcile adds the new endpoints into the resolver, then removes the endpoints which are gone.
+// A node changing its role is reopened with its new role.
+func (d *discovery) reconcile(endpoints []Endpoint) {
+	desired := make(map[endpointKey]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		key := endpointKey{addr: endpoint.Addr, role: endpoint.Role}
+		desired[key] = struct{}{}
+		if _, ok := d.nodes[key]; ok {
+			continue
+		}
+		node, err := d.config.Open(endpoint)
+		if err != nil {
+			continue
+		}
+		d.nodes[key] = node
+		if len(endpoint.Tags) > 0 {
+			d.db.setLabels(node, endpoint.Tags)
+		}
+		if endpoint.Role == RolePrimary {
+			d.db.AddPrimary(node)
+		} else {
+			d.db.AddReplica(node)
+		}
+	}
+
+	for key, node := range d.nodes {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		// remove it from the rotation first, so closing only waits for the in-flight queries
+		if key.role == RolePrimary {
+			if err := d.db.RemovePrimary(node); errors.Is(err, ErrLastPrimary) {
+				// keep it until a new primary is discovered
+				continue
+			}
+		} else {
+			d.db.RemoveReplica(node)
+		}
+		delete(d.nodes, key)
+		d.db.setLabels(node, nil)
+		_ = node.Close()
+	}
+}
@@ -0,0 +1,84 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type fakeDiscoverer struct {
+	mu        sync.Mutex
+	endpoints []Endpoint
+}
+
+func (f *fakeDiscoverer) set(endpoints ...Endpoint) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.endpoints = endpoints
+}
+
+func (f *fakeDiscoverer) Discover(ctx context.Context) ([]Endpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.endpoints, nil
+}
+
+func TestDiscoveryReconcile(t *testing.T) {
+	bootstrap, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	discoverer := &fakeDiscoverer{}
+	discoverer.set(
+		Endpoint{Addr: "db-1:5432", Role: RolePrimary},
+		Endpoint{Addr: "db-2:5432", Role: RoleReplica, Tags: map[string]string{"zone": "a"}},
+	)
+	opened := map[endpointKey]sqlmock.Sqlmock{}
+	dbs := map[string]*sql.DB{}
+	open := func(endpoint Endpoint) (*sql.DB, error) {
+		db, mock, err := createMock()
+		if err != nil {
+			return nil, err
+		}
+		mock.ExpectClose()
+		opened[endpointKey{addr: endpoint.Addr, role: endpoint.Role}] = mock
+		dbs[string(endpoint.Role)+endpoint.Addr] = db
+		return db, nil
+	}
+
+	resolver := New(WithPrimaryDBs(bootstrap), WithDiscoverer(discoverer, 0, open)).(*sqlDB)
+	// the background refresh is racing with the test, stop it
+	resolver.discoveries[0].close()
+
+	if len(resolver.PrimaryDBs()) != 2 || len(resolver.ReplicaDBs()) != 1 {
+		t.Fatalf("want 2 primaries and 1 replica, got %v and %v", resolver.PrimaryDBs(), resolver.ReplicaDBs())
+	}
+	if nodes := resolver.Nodes(); nodes[2].Labels["zone"] != "a" {
+		t.Errorf("want the endpoint tags as labels, got %+v", nodes)
+	}
+
+	// db-2 is promoted, db-1 is gone
+	discoverer.set(Endpoint{Addr: "db-2:5432", Role: RolePrimary})
+	if err := resolver.discoveries[0].refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolver.ReplicaDBs(); len(got) != 0 {
+		t.Errorf("want no replicas, got %v", got)
+	}
+	primaries := resolver.PrimaryDBs()
+	if len(primaries) != 2 || primaries[0] != bootstrap || primaries[1] != dbs["primarydb-2:5432"] {
+		t.Errorf("want the bootstrap and the promoted primaries, got %v", primaries)
+	}
+	if resolver.nodeLabels(dbs["replicadb-2:5432"]) != nil {
+		t.Error("want the labels of the removed node deleted")
+	}
+	for _, endpoint := range []endpointKey{{"db-1:5432", RolePrimary}, {"db-2:5432", RoleReplica}} {
+		if err := opened[endpoint].ExpectationsWereMet(); err != nil {
+			t.Errorf("%v should be closed: %s", endpoint, err)
+		}
+	}
+}
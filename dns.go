@@ -0,0 +1,111 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDNSLookupTimeout   = 5 * time.Second
+	defaultDNSRefreshInterval = 30 * time.Second
+)
+
+// DNSDiscovery define the DNS record resolved into the replicas of the resolver
+type DNSDiscovery struct {
+	// Host is the DNS name to resolve.
+	// Names starting with an underscore are resolved as SRV records, eg. _postgres._tcp.replicas.svc
+	// Other names are resolved as A/AAAA records, with an optional port, eg. reader.rds.amazonaws.com:5432
+	Host string
+	// RefreshInterval is the interval between two resolutions, 30 seconds by default
+	RefreshInterval time.Duration
+	// Open opens the replica connection for a resolved address (ip, or ip:port)
+	Open func(addr string) (*sql.DB, error)
+
+	// lookups are swappable for testing
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+	lookupSRV  func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// dnsDiscoverer resolves the DNS record into replica endpoints
+type dnsDiscoverer struct {
+	config DNSDiscovery
+}
+
+func newDNSDiscoverer(config DNSDiscovery) *dnsDiscoverer {
+	if config.lookupHost == nil {
+		config.lookupHost = net.DefaultResolver.LookupHost
+	}
+	if config.lookupSRV == nil {
+		config.lookupSRV = net.DefaultResolver.LookupSRV
+	}
+	return &dnsDiscoverer{config: config}
+}
+
+// discovery returns the DNS discovery, refreshed on every refresh interval
+func (d *dnsDiscoverer) discovery() Discovery {
+	interval := d.config.RefreshInterval
+	if interval <= 0 {
+		interval = defaultDNSRefreshInterval
+	}
+	return Discovery{
+		Discoverer: d,
+		Open: func(endpoint Endpoint) (*sql.DB, error) {
+			return d.config.Open(endpoint.Addr)
+		},
+		RefreshInterval: interval,
+	}
+}
+
+// Discover resolves the record into replica endpoints
+func (d *dnsDiscoverer) Discover(ctx context.Context) ([]Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultDNSLookupTimeout)
+	defer cancel()
+
+	addrs, err := d.lookup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]Endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		endpoints = append(endpoints, Endpoint{Addr: addr, Role: RoleReplica})
+	}
+	return endpoints, nil
+}
+
+// lookup resolves the record into the sorted replica addresses
+func (d *dnsDiscoverer) lookup(ctx context.Context) ([]string, error) {
+	var addrs []string
+	if strings.HasPrefix(d.config.Host, "_") {
+		_, records, err := d.config.lookupSRV(ctx, "", "", d.config.Host)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			target := strings.TrimSuffix(record.Target, ".")
+			addrs = append(addrs, net.JoinHostPort(target, strconv.Itoa(int(record.Port))))
+		}
+	} else {
+		host, port, err := net.SplitHostPort(d.config.Host)
+		if err != nil {
+			host, port = d.config.Host, ""
+		}
+		ips, err := d.config.lookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if port == "" {
+				addrs = append(addrs, ip)
+				continue
+			}
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
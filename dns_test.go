@@ -0,0 +1,127 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type fakeDNS struct {
+	mu      sync.Mutex
+	records []string
+	err     error
+}
+
+func (f *fakeDNS) set(err error, records ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records, f.err = records, err
+}
+
+func (f *fakeDNS) lookupHost(_ context.Context, _ string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.records, f.err
+}
+
+func (f *fakeDNS) lookupSRV(_ context.Context, _, _, _ string) (string, []*net.SRV, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var records []*net.SRV
+	for _, target := range f.records {
+		records = append(records, &net.SRV{Target: target + ".", Port: 5432})
+	}
+	return "", records, f.err
+}
+
+func withFakeDNS(host string, dns *fakeDNS, opened map[string]sqlmock.Sqlmock) OptionFunc {
+	return func(opt *Option) {
+		opt.DNSDiscovery = &DNSDiscovery{
+			Host:            host,
+			RefreshInterval: time.Hour,
+			Open: func(addr string) (*sql.DB, error) {
+				db, mock, err := createMock()
+				if err != nil {
+					return nil, err
+				}
+				mock.ExpectClose()
+				opened[addr] = mock
+				return db, nil
+			},
+			lookupHost: dns.lookupHost,
+			lookupSRV:  dns.lookupSRV,
+		}
+	}
+}
+
+func TestDNSDiscovery(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	dns := &fakeDNS{}
+	dns.set(nil, "10.0.0.2", "10.0.0.1")
+	opened := map[string]sqlmock.Sqlmock{}
+	resolver := New(WithPrimaryDBs(primary), withFakeDNS("reader.example.com:5432", dns, opened)).(*sqlDB)
+
+	if len(resolver.ReplicaDBs()) != 2 {
+		t.Fatalf("want %v, got %v", 2, len(resolver.ReplicaDBs()))
+	}
+	if _, ok := opened["10.0.0.1:5432"]; !ok {
+		t.Errorf("want replica opened with the host port, got %v", opened)
+	}
+
+	// failed resolution keeps the current replicas
+	dns.set(errors.New("timeout"))
+	_ = resolver.discoveries[0].refresh(context.Background())
+	if len(resolver.ReplicaDBs()) != 2 {
+		t.Errorf("want %v, got %v", 2, len(resolver.ReplicaDBs()))
+	}
+
+	dns.set(nil, "10.0.0.2", "10.0.0.3")
+	_ = resolver.discoveries[0].refresh(context.Background())
+	if len(resolver.ReplicaDBs()) != 2 {
+		t.Errorf("want %v, got %v", 2, len(resolver.ReplicaDBs()))
+	}
+	if err := opened["10.0.0.1:5432"].ExpectationsWereMet(); err != nil {
+		t.Errorf("removed replica should be closed: %s", err)
+	}
+
+	primaryMock.ExpectClose()
+	if err := resolver.Close(); err != nil {
+		t.Error(err)
+	}
+	for addr, mock := range opened {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("replica %s should be closed: %s", addr, err)
+		}
+	}
+}
+
+func TestDNSDiscoverySRV(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	dns := &fakeDNS{}
+	dns.set(nil, "replica-0.db.svc", "replica-1.db.svc")
+	opened := map[string]sqlmock.Sqlmock{}
+	resolver := New(WithPrimaryDBs(primary), withFakeDNS("_postgres._tcp.db.svc", dns, opened))
+
+	if len(resolver.ReplicaDBs()) != 2 {
+		t.Fatalf("want %v, got %v", 2, len(resolver.ReplicaDBs()))
+	}
+	for _, addr := range []string{"replica-0.db.svc:5432", "replica-1.db.svc:5432"} {
+		if _, ok := opened[addr]; !ok {
+			t.Errorf("want %s opened, got %v", addr, opened)
+		}
+	}
+}
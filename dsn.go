@@ -0,0 +1,75 @@
+package dbresolver
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DSNParams are the parameters of a node DSN built from a DSNTemplate
+type DSNParams struct {
+	Host     string `yaml:"host" json:"host"`
+	Port     int    `yaml:"port" json:"port"`
+	User     string `yaml:"user" json:"user"`
+	Password string `yaml:"password" json:"password"`
+	Database string `yaml:"database" json:"database"`
+	// Params are the other parameters, eg. {{index .Params "sslmode"}}
+	Params map[string]string `yaml:"params" json:"params"`
+}
+
+// merge returns the params overridden by the non-zero fields of the overrides
+func (p DSNParams) merge(overrides DSNParams) DSNParams {
+	merged := p
+	if overrides.Host != "" {
+		merged.Host = overrides.Host
+	}
+	if overrides.Port != 0 {
+		merged.Port = overrides.Port
+	}
+	if overrides.User != "" {
+		merged.User = overrides.User
+	}
+	if overrides.Password != "" {
+		merged.Password = overrides.Password
+	}
+	if overrides.Database != "" {
+		merged.Database = overrides.Database
+	}
+	merged.Params = make(map[string]string, len(p.Params)+len(overrides.Params))
+	for key, value := range p.Params {
+		merged.Params[key] = value
+	}
+	for key, value := range overrides.Params {
+		merged.Params[key] = value
+	}
+	return merged
+}
+
+// DSNTemplate builds the DSNs of nearly identical nodes from a base DSN and per-node overrides.
+// The template is a text/template of the DSNParams, so any driver DSN format can be templated:
+//
+//	tmpl, err := dbresolver.NewDSNTemplate("postgres://{{.User}}:{{.Password}}@{{.Host}}:{{.Port}}/{{.Database}}",
+//		dbresolver.DSNParams{User: "app", Port: 5432, Database: "app"})
+//	replicaDSN, err := tmpl.DSN(dbresolver.DSNParams{Host: "replica-1"})
+type DSNTemplate struct {
+	tmpl     *template.Template
+	defaults DSNParams
+}
+
+// NewDSNTemplate parses the DSN template, the defaults are the parameters shared by the nodes
+func NewDSNTemplate(text string, defaults DSNParams) (*DSNTemplate, error) {
+	tmpl, err := template.New("dsn").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("dbresolver: invalid DSN template: %w", err)
+	}
+	return &DSNTemplate{tmpl: tmpl, defaults: defaults}, nil
+}
+
+// DSN builds the DSN of a node, the non-zero overrides replace the defaults
+func (t *DSNTemplate) DSN(overrides DSNParams) (string, error) {
+	var dsn strings.Builder
+	if err := t.tmpl.Execute(&dsn, t.defaults.merge(overrides)); err != nil {
+		return "", fmt.Errorf("dbresolver: executing the DSN template: %w", err)
+	}
+	return dsn.String(), nil
+}
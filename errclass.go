@@ -0,0 +1,208 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Errors matched with errors.Is by the errors of Exec, Query and QueryRow of the resolver, see Classifier.
+// The errors keep their message, and still match the errors of the driver.
+var (
+	// ErrNodeUnhealthy is matched by the connection errors: the node is unreachable or its connection broke
+	ErrNodeUnhealthy = errors.New("dbresolver: node unhealthy")
+	// ErrNoPrimary is matched by the errors of the queries sent to a primary which is unreachable
+	// or doesn't accept the writes anymore, eg. a primary demoted by a failover, see DB.Repoll
+	ErrNoPrimary = errors.New("dbresolver: no writable primary")
+	// ErrAllReplicasDown is matched by the errors of the reads sent to a primary because no replica
+	// was in rotation: every replica is unhealthy, has an open circuit or lags too much
+	ErrAllReplicasDown = errors.New("dbresolver: all replicas down")
+)
+
+// ErrorClass is the class of the error of a query, see Classifier
+type ErrorClass string
+
+// Supported error classes
+const (
+	// ErrorClassConnection is the class of the errors of an unreachable node or a broken connection,
+	// the reads fall back to a primary and are retried
+	ErrorClassConnection ErrorClass = "connection"
+	// ErrorClassConflict is the class of the serialization failures and the deadlocks, the queries are retried
+	ErrorClassConflict ErrorClass = "conflict"
+	// ErrorClassReadOnly is the class of the writes refused by a read-only node
+	ErrorClassReadOnly ErrorClass = "read_only"
+	// ErrorClassCanceled is the class of the errors of the queries whose context is done
+	ErrorClassCanceled ErrorClass = "canceled"
+	// ErrorClassOther is the class of the other errors, eg. a syntax error or a constraint violation
+	ErrorClassOther ErrorClass = "other"
+)
+
+// Classifier classifies the errors of the queries, the retries, the fallbacks to a primary,
+// the circuit breakers and the write failover decide with it. The error isn't nil.
+type Classifier interface {
+	Classify(err error) ErrorClass
+}
+
+// ClassifierFunc is a function implementing Classifier
+type ClassifierFunc func(err error) ErrorClass
+
+// Classify calls the function
+func (f ClassifierFunc) Classify(err error) ErrorClass {
+	return f(err)
+}
+
+// Classifiers of the common drivers
+var (
+	// PostgresClassifier classifies the errors of the drivers exposing the SQLSTATE, like pgx and lib/pq:
+	// the connection exceptions (class 08) and the server shutdowns (57P01 to 57P03) are connection errors,
+	// the serialization failures and the deadlocks (40001 and 40P01) conflicts, and the writes
+	// in a read-only transaction (25006) read-only errors
+	PostgresClassifier Classifier = ClassifierFunc(func(err error) ErrorClass {
+		if class, ok := classifyCommon(err); ok {
+			return class
+		}
+		return classifyPostgres(err)
+	})
+	// MySQLClassifier classifies the errors of go-sql-driver/mysql by their number: the deadlocks (1213)
+	// are conflicts, the writes refused by the read_only servers (1290 and 1792) read-only errors,
+	// and the invalid connections and the server shutdowns (1053) connection errors
+	MySQLClassifier Classifier = ClassifierFunc(func(err error) ErrorClass {
+		if class, ok := classifyCommon(err); ok {
+			return class
+		}
+		return classifyMySQL(err)
+	})
+	// DefaultClassifier classifies the errors like PostgresClassifier, then like MySQLClassifier,
+	// it's the default classifier
+	DefaultClassifier Classifier = ClassifierFunc(func(err error) ErrorClass {
+		if class, ok := classifyCommon(err); ok {
+			return class
+		}
+		if class := classifyPostgres(err); class != ErrorClassOther {
+			return class
+		}
+		return classifyMySQL(err)
+	})
+)
+
+// WithErrorClassifier sets how the errors of the queries are classified, DefaultClassifier by default
+func WithErrorClassifier(classifier Classifier) OptionFunc {
+	if classifier == nil {
+		panic("dbresolver: invalid nil error classifier")
+	}
+	return func(opt *Option) {
+		opt.Classifier = classifier
+	}
+}
+
+// classifyCommon classifies the context errors and the connection errors of every driver
+func classifyCommon(err error) (ErrorClass, bool) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassCanceled, true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return ErrorClassConnection, true
+	}
+	return "", false
+}
+
+func classifyPostgres(err error) ErrorClass {
+	var sqlStateErr interface{ SQLState() string }
+	if !errors.As(err, &sqlStateErr) {
+		return ErrorClassOther
+	}
+	switch state := sqlStateErr.SQLState(); {
+	case state == "40001", state == "40P01":
+		return ErrorClassConflict
+	case state == "25006":
+		return ErrorClassReadOnly
+	case strings.HasPrefix(state, "08"), state == "57P01", state == "57P02", state == "57P03":
+		return ErrorClassConnection
+	}
+	return ErrorClassOther
+}
+
+// mysqlErrorPattern matches the message of the errors of go-sql-driver/mysql, eg. Error 1213 (40001): Deadlock found,
+// the driver doesn't expose the number of the error through an interface
+var mysqlErrorPattern = regexp.MustCompile(`^Error (\d+)(?: \([0-9A-Z]{5}\))?: `)
+
+func classifyMySQL(err error) ErrorClass {
+	msg := err.Error()
+	if msg == "invalid connection" {
+		// mysql.ErrInvalidConn
+		return ErrorClassConnection
+	}
+	match := mysqlErrorPattern.FindStringSubmatch(msg)
+	if match == nil {
+		return ErrorClassOther
+	}
+	switch number, _ := strconv.Atoi(match[1]); number {
+	case 1213:
+		return ErrorClassConflict
+	case 1290, 1792:
+		return ErrorClassReadOnly
+	case 1053:
+		return ErrorClassConnection
+	}
+	return ErrorClassOther
+}
+
+// isConnectionError reports whether the error is a connection error, see ErrorClassConnection
+func isConnectionError(classifier Classifier, err error) bool {
+	return err != nil && classifier.Classify(err) == ErrorClassConnection
+}
+
+func (db *sqlDB) isConnectionError(err error) bool {
+	return isConnectionError(db.classifier, err)
+}
+
+// classifiedError matches the sentinels of its class with errors.Is, with the message of the error
+type classifiedError struct {
+	err       error
+	sentinels []error
+}
+
+func (e *classifiedError) Error() string { return e.err.Error() }
+
+func (e *classifiedError) Unwrap() error { return e.err }
+
+func (e *classifiedError) Is(target error) bool {
+	for _, sentinel := range e.sentinels {
+		if target == sentinel {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyRouted wraps the error of a query sent to the route, so it matches ErrNodeUnhealthy, ErrNoPrimary
+// and ErrAllReplicasDown. A read sent to a primary without falling back from a replica found no replica in rotation.
+func (db *sqlDB) classifyRouted(err error, route Route, writeFlag bool) error {
+	if err == nil {
+		return nil
+	}
+	class := db.classifier.Classify(err)
+	var sentinels []error
+	if class == ErrorClassConnection {
+		sentinels = append(sentinels, ErrNodeUnhealthy)
+	}
+	switch {
+	case route.Role != RolePrimary || route.Fallback:
+	case writeFlag && (class == ErrorClassConnection || class == ErrorClassReadOnly):
+		sentinels = append(sentinels, ErrNoPrimary)
+	case !writeFlag && class == ErrorClassConnection && len(db.nodes.Load().replicas) > 0 && db.currentReadMode() != PrimaryOnly:
+		sentinels = append(sentinels, ErrAllReplicasDown)
+	}
+	if len(sentinels) == 0 {
+		return err
+	}
+	return &classifiedError{err: err, sentinels: sentinels}
+}
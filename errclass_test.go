@@ -0,0 +1,135 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestClassifier(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want ErrorClass
+	}{
+		{&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{IsTimeout: true}}, ErrorClassConnection},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network error")}, ErrorClassConnection},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), ErrorClassConnection},
+		{io.ErrUnexpectedEOF, ErrorClassConnection},
+		{syscall.ECONNRESET, ErrorClassConnection},
+		{context.Canceled, ErrorClassCanceled},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), ErrorClassCanceled},
+		{sqlStateError("40001"), ErrorClassConflict},
+		{sqlStateError("40P01"), ErrorClassConflict},
+		{sqlStateError("25006"), ErrorClassReadOnly},
+		{sqlStateError("08006"), ErrorClassConnection},
+		{sqlStateError("57P01"), ErrorClassConnection},
+		{sqlStateError("23505"), ErrorClassOther},
+		{errors.New("Error 1213 (40001): Deadlock found when trying to get lock"), ErrorClassConflict},
+		{errors.New("Error 1290 (HY000): The MySQL server is running with the --read-only option"), ErrorClassReadOnly},
+		{errors.New("Error 1792: Cannot execute statement in a READ ONLY transaction."), ErrorClassReadOnly},
+		{errors.New("invalid connection"), ErrorClassConnection},
+		{errors.New("Error 1062 (23000): Duplicate entry"), ErrorClassOther},
+		{errors.New("other error"), ErrorClassOther},
+	} {
+		if got := DefaultClassifier.Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v): want %v, got %v", tt.err, tt.want, got)
+		}
+	}
+
+	if got := PostgresClassifier.Classify(errors.New("Error 1213: Deadlock found")); got != ErrorClassOther {
+		t.Errorf("want the MySQL errors unknown to PostgresClassifier, got %v", got)
+	}
+	if got := MySQLClassifier.Classify(sqlStateError("40001")); got != ErrorClassOther {
+		t.Errorf("want the SQLSTATE unknown to MySQLClassifier, got %v", got)
+	}
+}
+
+func TestClassifiedErrors(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica))
+
+	// the write refused by a demoted primary
+	readOnly := sqlStateError("25006")
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(readOnly)
+	if _, err := resolver.Exec("DELETE FROM users"); !errors.Is(err, ErrNoPrimary) || !errors.Is(err, readOnly) {
+		t.Errorf("want ErrNoPrimary matching the driver error, got %v", err)
+	}
+
+	// the read falling back from an unreachable replica to an unreachable primary
+	unreachable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	replicaMock.ExpectQuery("SELECT 1").WillReturnError(unreachable)
+	primaryMock.ExpectQuery("SELECT 1").WillReturnError(unreachable)
+	_, err = resolver.Query("SELECT 1")
+	if !errors.Is(err, ErrNodeUnhealthy) || errors.Is(err, ErrAllReplicasDown) {
+		t.Errorf("want ErrNodeUnhealthy only, got %v", err)
+	}
+	if err == nil || err.Error() != unreachable.Error() {
+		t.Errorf("want the message of the driver error, got %v", err)
+	}
+
+	// the other errors are returned as is
+	syntax := errors.New("syntax error")
+	replicaMock.ExpectQuery("SELEC 1").WillReturnError(syntax)
+	var one int
+	if err := resolver.QueryRow("SELEC 1").Scan(&one); err != syntax {
+		t.Errorf("want the driver error, got %v", err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestClassifiedErrorsAllReplicasDown(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithCircuitBreaker(CircuitBreaker{FailureThreshold: 1}))
+
+	// the unreachable replica opens its circuit
+	replicaMock.ExpectQuery("SELECT 1").WillReturnError(&net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	primaryMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	var one int
+	if err := resolver.QueryRow("SELECT 1").Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+
+	// the read sent to the primary without replica in rotation
+	primaryMock.ExpectQuery("SELECT 1").WillReturnError(io.ErrUnexpectedEOF)
+	err = resolver.QueryRow("SELECT 1").Scan(&one)
+	if !errors.Is(err, ErrAllReplicasDown) || !errors.Is(err, ErrNodeUnhealthy) {
+		t.Errorf("want ErrAllReplicasDown, got %v", err)
+	}
+
+	// a custom classifier
+	custom := New(WithPrimaryDBs(primary), WithErrorClassifier(ClassifierFunc(func(error) ErrorClass {
+		return ErrorClassReadOnly
+	})))
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(errors.New("read only"))
+	if _, err := custom.Exec("DELETE FROM users"); !errors.Is(err, ErrNoPrimary) {
+		t.Errorf("want ErrNoPrimary, got %v", err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
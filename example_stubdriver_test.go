@@ -0,0 +1,47 @@
+package dbresolver_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/stubdriver"
+)
+
+func ExampleNew_stubDriver() {
+	// the stub databases answer the canned rows, the replica and the primary answer differently
+	query := "SELECT title FROM book WHERE id=$1"
+	stubdriver.SetRows("replica", query, []string{"title"}, []driver.Value{"Dune (from the replica)"})
+	stubdriver.SetRows("primary", query+" RETURNING title", []string{"title"}, []driver.Value{"Dune (from the primary)"})
+
+	dbPrimary, err := sql.Open(stubdriver.DriverName, "primary")
+	if err != nil {
+		log.Fatal(err)
+	}
+	dbReadOnlyReplica, err := sql.Open(stubdriver.DriverName, "replica")
+	if err != nil {
+		log.Fatal(err)
+	}
+	connectionDB := dbresolver.New(
+		dbresolver.WithPrimaryDBs(dbPrimary),
+		dbresolver.WithReplicaDBs(dbReadOnlyReplica))
+	defer connectionDB.Close()
+
+	ctx := context.Background()
+	var title string
+	if err := connectionDB.QueryRowContext(ctx, query, 1).Scan(&title); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(title)
+	if err := connectionDB.QueryRowContext(ctx, query+" RETURNING title", 1).Scan(&title); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(title)
+
+	// Output:
+	// Dune (from the replica)
+	// Dune (from the primary)
+}
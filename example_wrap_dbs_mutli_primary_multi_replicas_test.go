@@ -3,68 +3,66 @@ package dbresolver_test
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log"
 
 	"github.com/bxcodec/dbresolver/v2"
-	_ "github.com/lib/pq"
+	"github.com/bxcodec/dbresolver/v2/stubdriver"
 )
 
 func ExampleNew_multiPrimaryMultiReplicas() {
-	var (
-		host1     = "localhost"
-		port1     = 5432
-		user1     = "postgresrw"
-		password1 = "<password>"
-		host2     = "localhost"
-		port2     = 5433
-		user2     = "postgresro"
-		password2 = "<password>"
-		dbname    = "<dbname>"
-	)
-	// connection string
-	rwPrimary := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host1, port1, user1, password1, dbname)
-	readOnlyReplica := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host2, port2, user2, password2, dbname)
+	// the stub databases stand for the PostgreSQL nodes, opened in production with their connection string, eg.
+	// sql.Open("postgres", "host=localhost port=5432 user=postgresrw password=<password> dbname=<dbname>")
+	query := "SELECT title FROM book WHERE id=$1"
+	for _, dsn := range []string{"multi-replica-1", "multi-replica-2"} {
+		stubdriver.SetRows(dsn, query, []string{"title"}, []driver.Value{"Dune (from " + dsn + ")"})
+	}
 
-	// open database for primary
-	dbPrimary1, err := sql.Open("postgres", rwPrimary)
+	// open database for the primaries
+	dbPrimary1, err := sql.Open(stubdriver.DriverName, "multi-primary-1")
 	if err != nil {
-		log.Print("go error when connecting to the DB")
+		log.Fatal(err)
 	}
-	// open database for primary
-	dbPrimary2, err := sql.Open("postgres", rwPrimary)
+	dbPrimary2, err := sql.Open(stubdriver.DriverName, "multi-primary-2")
 	if err != nil {
-		log.Print("go error when connecting to the DB")
+		log.Fatal(err)
 	}
 
 	// configure the DBs for other setup eg, tracing, etc
 	// eg, tracing.Postgres(dbPrimary)
 
-	// open database for replica
-	dbReadOnlyReplica1, err := sql.Open("postgres", readOnlyReplica)
+	// open database for the replicas
+	dbReadOnlyReplica1, err := sql.Open(stubdriver.DriverName, "multi-replica-1")
 	if err != nil {
-		log.Print("go error when connecting to the DB")
+		log.Fatal(err)
 	}
-	// open database for replica
-	dbReadOnlyReplica2, err := sql.Open("postgres", readOnlyReplica)
+	dbReadOnlyReplica2, err := sql.Open(stubdriver.DriverName, "multi-replica-2")
 	if err != nil {
-		log.Print("go error when connecting to the DB")
+		log.Fatal(err)
 	}
-	// configure the DBs for other setup eg, tracing, etc
-	// eg, tracing.Postgres(dbReadOnlyReplica)
 
 	connectionDB := dbresolver.New(
 		dbresolver.WithPrimaryDBs(dbPrimary1, dbPrimary2),
 		dbresolver.WithReplicaDBs(dbReadOnlyReplica1, dbReadOnlyReplica2),
 		dbresolver.WithLoadBalancer(dbresolver.RoundRobinLB))
+	defer connectionDB.Close()
 
 	// now you can use the connection for all DB operation
-	_, err = connectionDB.ExecContext(context.Background(), "DELETE FROM book WHERE id=$1") // will use primaryDB
-	if err != nil {
-		log.Print("go error when executing the query to the DB", err)
+	ctx := context.Background()
+	if _, err := connectionDB.ExecContext(ctx, "DELETE FROM book WHERE id=$1", 1); err != nil { // will use a primary
+		log.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		var title string
+		// will use the replicas in turn
+		if err := connectionDB.QueryRowContext(ctx, query, 1).Scan(&title); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(title)
 	}
-	_ = connectionDB.QueryRowContext(context.Background(), "SELECT * FROM book WHERE id=$1") // will use replicaReadOnlyDB
 
 	// Output:
-	//
+	// Dune (from multi-replica-1)
+	// Dune (from multi-replica-2)
 }
@@ -0,0 +1,142 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// RouteDecision explains where the resolver routes a query, see DB.ExplainRoute
+type RouteDecision struct {
+	// Route is where the query is sent. Its node is nil when the load balancer can't tell the next node
+	// in advance, eg. RandomLB, or when the query is served by the read cache.
+	Route
+	// Shard is the shard of the query, empty without shards or shard key
+	Shard string
+	// QueryType is the type of the query returned by the query type checker
+	QueryType QueryType
+	// Candidates are the nodes the load balancer chooses from
+	Candidates []*sql.DB
+	// Cached is set when the result of the query is in the read cache, no node is queried
+	Cached bool
+	// Reasons explain each step of the routing, in order
+	Reasons []string
+}
+
+// ExplainRoute routes the query like QueryContext and QueryRowContext without running it: it resolves the shard,
+// runs the query type checker, looks up the read cache and the nearest replicas, and reports which node
+// the load balancer chooses next. The load balancer doesn't move, the next query may still go to another node
+// when the queries run concurrently. ExecContext and Conn always use a primary, BeginTx too except
+// for the read-only transactions.
+func (db *sqlDB) ExplainRoute(ctx context.Context, query string, args ...interface{}) (RouteDecision, error) {
+	var decision RouteDecision
+	shard, name, err := db.namedShard(ctx)
+	if err != nil {
+		return decision, err
+	}
+	if name != "" {
+		decision.Shard = name
+		decision.explain("the shard key resolves to the shard %q", name)
+	}
+	db = shard
+
+	decision.QueryType = db.queryTypeChecker.Check(query)
+	writeFlag := db.isWrite(ctx, query)
+	if role, ok := RoleOverrideFromContext(ctx); ok {
+		decision.explain("the context forces the %s", role)
+	} else if decision.QueryType == QueryTypeWrite {
+		decision.explain("the query type checker classifies the query as a write")
+	} else if writeFlag {
+		decision.explain("the session wrote within the sticky primary window, the read goes to a primary")
+	} else {
+		decision.explain("the query type checker classifies the query as a read")
+	}
+	if key, ok := db.readCacheKey(writeFlag, query, args); ok {
+		if db.readCache.contains(key) {
+			decision.Cached = true
+			decision.explain("the read cache holds the result of the query")
+			return decision, nil
+		}
+		decision.explain("the result of the query isn't in the read cache, it's cached once read")
+	}
+
+	// nodes are the nodes the load balancer resolves, the replica rotation repeats the replicas by weight
+	var nodes []*sql.DB
+	set := db.nodes.Load()
+	healthy := db.breaker.closed(db.health.healthy(db.nearest.rotation(set)))
+	rotation := db.lag.fresh(ctx, healthy)
+	switch {
+	case writeFlag:
+		decision.Role, nodes = RolePrimary, db.healthyPrimaries(set.primaryRotation)
+		decision.explain("the query goes to the primaries")
+	case db.currentReadMode() == PrimaryOnly:
+		decision.Role, nodes = RolePrimary, db.healthyPrimaries(set.primaryRotation)
+		decision.explain("the read mode is %s, the reads go to the primaries", PrimaryOnly)
+	case len(set.replicaRotation) == 0:
+		decision.Role, nodes = RolePrimary, db.healthyPrimaries(set.primaryRotation)
+		decision.explain("the resolver has no replica, the reads go to the primaries")
+	case len(rotation) == 0 && db.currentReadMode() == ReplicaOnly:
+		decision.Role, nodes = RoleReplica, set.replicaRotation
+		decision.explain("no replica is in rotation, the read mode is %s, the reads go to every replica", ReplicaOnly)
+	case len(healthy) == 0:
+		decision.Role, nodes = RolePrimary, db.healthyPrimaries(set.primaryRotation)
+		decision.explain("every replica is unhealthy or has an open circuit, the reads go to the primaries")
+	case len(rotation) == 0:
+		decision.Role, nodes = RolePrimary, db.healthyPrimaries(set.primaryRotation)
+		decision.explain("every replica lags more than the maximum staleness, the reads go to the primaries")
+	default:
+		decision.Role, nodes = RoleReplica, rotation
+		if replicas := len(distinct(set.replicas)); db.nearest != nil && len(distinct(db.nearest.rotation(set))) < replicas {
+			decision.explain("the nearest reads restrict the replicas to %d of %d",
+				len(distinct(db.nearest.rotation(set))), replicas)
+		} else {
+			decision.explain("the reads go to the replicas")
+		}
+		if lagging := len(distinct(healthy)) - len(distinct(rotation)); lagging > 0 {
+			decision.explain("the replication lag excludes %d replicas", lagging)
+		}
+		decision.explain("a read failing with a connection error falls back to a primary")
+	}
+	unhealthy, open := 0, 0
+	for _, role := range [][]*sql.DB{distinct(set.primaries), distinct(set.replicas)} {
+		unhealthy += len(role) - len(db.health.healthy(role))
+		open += len(role) - len(db.breaker.closed(role))
+	}
+	if unhealthy > 0 {
+		decision.explain("the health checks took %d nodes out of rotation", unhealthy)
+	}
+	if open > 0 {
+		decision.explain("the circuit breakers took %d nodes out of rotation", open)
+	}
+	decision.Candidates = distinct(nodes)
+	if db.coalesces(writeFlag, query) {
+		decision.explain("the identical queries in flight on the node are coalesced")
+	}
+
+	if decision.Role == RolePrimary && db.writeStrategy.Policy == WriteFailover {
+		decision.Node = db.ReadWrite()
+		decision.explain("the failover write strategy chooses the first healthy primary")
+		return decision, nil
+	}
+
+	idx := 0
+	if len(nodes) > 1 {
+		idx = db.loadBalancer.peek(len(nodes))
+	}
+	if key, ok := AffinityKeyFromContext(ctx); ok && db.affinity != nil && decision.Role == RoleReplica && len(nodes) > 1 {
+		decision.Node = db.affinity.resolveKey(nodes, key, db.affinityID())
+		decision.explain("the %s load balancer chooses the node of the affinity key", db.loadBalancer.Name())
+		return decision, nil
+	}
+	if idx < 0 {
+		decision.explain("the %s load balancer chooses one of the %d candidates", db.loadBalancer.Name(), len(decision.Candidates))
+		return decision, nil
+	}
+	decision.Node = nodes[idx]
+	decision.explain("the %s load balancer chooses the node %d of %d", db.loadBalancer.Name(), idx+1, len(nodes))
+	return decision, nil
+}
+
+func (d *RouteDecision) explain(format string, args ...interface{}) {
+	d.Reasons = append(d.Reasons, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,87 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExplainRoute(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replicas := make([]*sql.DB, 2)
+	replicaMocks := make(map[*sql.DB]sqlmock.Sqlmock, 2)
+	for i := range replicas {
+		replica, replicaMock, err := createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+		replicas[i], replicaMocks[replica] = replica, replicaMock
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicas...))
+
+	decision, err := resolver.ExplainRoute(context.Background(), "INSERT INTO book (title) VALUES ($1) RETURNING id", "Dune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Role != RolePrimary || decision.Node != primary || decision.QueryType != QueryTypeWrite {
+		t.Errorf("want the write routed to the primary, got %+v", decision)
+	}
+
+	// the explained node is the node of the next query, the load balancer doesn't move
+	for i := 0; i < 3; i++ {
+		decision, err := resolver.ExplainRoute(context.Background(), "SELECT title FROM book")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision.Role != RoleReplica || len(decision.Candidates) != 2 || len(decision.Reasons) == 0 {
+			t.Fatalf("want the read routed to the replicas, got %+v", decision)
+		}
+		again, _ := resolver.ExplainRoute(context.Background(), "SELECT title FROM book")
+		if again.Node != decision.Node {
+			t.Fatal("want the dry run to keep the load balancer")
+		}
+		replicaMocks[decision.Node].ExpectQuery("SELECT title FROM book").WillReturnRows(sqlmock.NewRows([]string{"title"}))
+		rows, err := resolver.Query("SELECT title FROM book")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rows.Close()
+	}
+	for _, replicaMock := range replicaMocks {
+		if err := replicaMock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExplainRouteRandom(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica1, replica2), WithLoadBalancer(RandomLB))
+
+	decision, err := resolver.ExplainRoute(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Node != nil || len(decision.Candidates) != 2 {
+		t.Errorf("want the random choice between the replicas unknown, got %+v", decision)
+	}
+}
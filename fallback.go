@@ -0,0 +1,65 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ReadFallback define where a read goes when its replica is unreachable
+type ReadFallback string
+
+// Supported read fallbacks
+const (
+	// FallbackPrimary sends the read again to a healthy primary
+	FallbackPrimary ReadFallback = "PRIMARY"
+	// FallbackReplica sends the read again to another replica in rotation: healthy, with a closed circuit
+	// and fresh enough for the context, and to a healthy primary when there's none
+	FallbackReplica ReadFallback = "REPLICA"
+	// FallbackNone returns the connection error of the replica
+	FallbackNone ReadFallback = "NONE"
+)
+
+// WithReadFallback sets where the reads of the resolver and its connections go when their replica is unreachable,
+// FallbackPrimary by default: the Query and QueryRow of the resolver and its lazy prepared statements,
+// the read-only transactions and ReplicaConn failing to connect to the replica.
+// The statements prepared on the replicas fall back to a primary.
+func WithReadFallback(fallback ReadFallback) OptionFunc {
+	switch fallback {
+	case "", FallbackPrimary, FallbackReplica, FallbackNone:
+	default:
+		panic(fmt.Sprintf("dbresolver: invalid read fallback %q", fallback))
+	}
+	return func(opt *Option) {
+		opt.ReadFallback = fallback
+	}
+}
+
+// replicaFallbackRoute is the route of the reads sent again to another replica
+var replicaFallbackRoute = Route{Role: RoleReplica, Fallback: true}
+
+// fallback returns the node a read goes to after the connection error of the replica, false without fallback.
+// With ReplicaOnly the read only falls back to another replica.
+func (db *sqlDB) fallback(ctx context.Context, failed *sql.DB) (*sql.DB, Route, bool) {
+	replicaOnly := db.currentReadMode() == ReplicaOnly
+	if db.readFallback == FallbackNone {
+		return nil, Route{}, false
+	}
+	if db.readFallback == FallbackReplica || replicaOnly {
+		rotation := db.replicaRotation(ctx)
+		others := make([]*sql.DB, 0, len(rotation))
+		for _, node := range rotation {
+			if node != failed {
+				others = append(others, node)
+			}
+		}
+		if len(others) > 0 {
+			return db.resolveRead(ctx, others), replicaFallbackRoute, true
+		}
+		if replicaOnly {
+			// the reads don't go to the primaries, see ReplicaOnly
+			return nil, Route{}, false
+		}
+	}
+	return db.ReadWrite(), fallbackRoute, true
+}
@@ -0,0 +1,88 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// unreachableConnector fails to connect like an unreachable node
+type unreachableConnector struct{}
+
+func (unreachableConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+}
+
+func (unreachableConnector) Driver() driver.Driver { return nil }
+
+func TestReadFallback(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	unreachable := sql.OpenDB(unreachableConnector{})
+
+	// the read goes again to the other replica
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(unreachable, replica), WithReadFallback(FallbackReplica))
+	// round robin, one of the reads is routed to the unreachable replica
+	for i := 0; i < 2; i++ {
+		replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		var one int
+		if err := resolver.QueryRow("SELECT 1").Scan(&one); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the connections go to the other replica
+	for i := 0; i < 2; i++ {
+		conn, err := resolver.ReplicaConn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the read fails without fallback
+	none := New(WithPrimaryDBs(primary), WithReplicaDBs(unreachable), WithReadFallback(FallbackNone))
+	if _, err := none.Query("SELECT 1"); !errors.Is(err, ErrNodeUnhealthy) {
+		t.Errorf("want the replica connection error, got %v", err)
+	}
+	if _, err := none.ReplicaConn(context.Background()); err == nil {
+		t.Errorf("want the replica connection error, got %v", err)
+	}
+
+	// the connection goes to the primary by default
+	fallback := New(WithPrimaryDBs(primary), WithReplicaDBs(unreachable))
+	conn, err := fallback.ReplicaConn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWithReadFallbackInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic for an unknown read fallback")
+		}
+	}()
+	WithReadFallback("SOMETIMES")
+}
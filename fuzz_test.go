@@ -1,9 +1,12 @@
 package dbresolver
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"testing"
 
+	"github.com/bxcodec/dbresolver/v2/stubdriver"
 	fuzz "github.com/google/gofuzz"
 )
 
@@ -56,3 +59,58 @@ func FuzzMultiWrite(f *testing.F) {
 
 }
 */
+
+// routeHooks records the route of the last query
+type routeHooks struct {
+	route Route
+}
+
+func (h *routeHooks) Before(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	h.route, _ = RouteFromContext(ctx)
+	return ctx, nil
+}
+
+func (h *routeHooks) After(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	return ctx, nil
+}
+
+func FuzzQueryRouting(f *testing.F) {
+	primary, err := sql.Open(stubdriver.DriverName, "fuzz-primary")
+	if err != nil {
+		f.Fatal(err)
+	}
+	replica, err := sql.Open(stubdriver.DriverName, "fuzz-replica")
+	if err != nil {
+		f.Fatal(err)
+	}
+	hooks := &routeHooks{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithHooks(hooks))
+	f.Cleanup(func() { resolver.Close() })
+
+	f.Add("SELECT * FROM book")
+	f.Add("insert into book (title) values ($1) returning id")
+	f.Add("UPDATE book SET title = 'ReTuRnInG'")
+	f.Fuzz(func(t *testing.T, query string) {
+		// the reads classified as writes are sent to a primary
+		want := RoleReplica
+		if (DefaultQueryTypeChecker{}).Check(query) == QueryTypeWrite {
+			want = RolePrimary
+		}
+		rows, err := resolver.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		if hooks.route.Role != want || hooks.route.Fallback {
+			t.Errorf("want %q routed to a %s, got %+v", query, want, hooks.route)
+		}
+
+		// the writes are always sent to a primary
+		if _, err := resolver.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+		if hooks.route.Role != RolePrimary || hooks.route.Node != primary {
+			t.Errorf("want %q executed on the primary, got %+v", query, hooks.route)
+		}
+	})
+}
@@ -4,12 +4,18 @@ go 1.22
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/Masterminds/squirrel v1.5.4
+	github.com/georgysavva/scany/v2 v2.1.3
 	github.com/google/gofuzz v1.2.0
-	github.com/lib/pq v1.10.9
-	go.uber.org/multierr v1.11.0
+	go.uber.org/mock v0.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require github.com/stretchr/testify v1.8.1 // indirect
+require (
+	github.com/lann/builder v0.0.0-20180802200727-47ae307949d0 // indirect
+	github.com/lann/ps v0.0.0-20150810152359-62de8c46ede0 // indirect
+	github.com/lib/pq v1.10.9 // indirect
+)
 
 retract (
 	// below versions doesn't support Update,Insert queries with "RETURNING CLAUSE"
@@ -0,0 +1,23 @@
+module github.com/bxcodec/dbresolver/v2/gormresolver
+
+go 1.22
+
+require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/bxcodec/dbresolver/v2 v2.2.1
+	gorm.io/driver/postgres v1.5.9
+	gorm.io/gorm v1.31.2
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/pgx/v5 v5.6.0 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/crypto v0.31.0 // indirect
+	golang.org/x/sync v0.10.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
@@ -0,0 +1,37 @@
+package gormresolver
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Operation is a gorm clause used to override the routing of a single statement.
+//
+//	db.Clauses(gormresolver.Write).First(&user) // read from the primary
+type Operation string
+
+// Supported routing overrides
+const (
+	Read  Operation = "read"
+	Write Operation = "write"
+)
+
+const operationSetting = "dbresolver:operation"
+
+// ModifyStatement stores the operation in the statement settings,
+// it will be consumed by the resolver callbacks.
+func (op Operation) ModifyStatement(stmt *gorm.Statement) {
+	stmt.Settings.Store(operationSetting, op)
+}
+
+// Build implements clause.Expression, the operation doesn't write any SQL.
+func (op Operation) Build(clause.Builder) {}
+
+func statementOperation(stmt *gorm.Statement) (Operation, bool) {
+	v, ok := stmt.Settings.Load(operationSetting)
+	if !ok {
+		return "", false
+	}
+	op, ok := v.(Operation)
+	return op, ok
+}
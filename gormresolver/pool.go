@@ -0,0 +1,66 @@
+package gormresolver
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// connPool is a gorm.ConnPool backed by the resolver.
+// Reads and writes are routed by the resolver itself,
+// while prepared statements and transactions are pinned to a primary.
+type connPool struct {
+	db dbresolver.DB
+}
+
+// PrepareContext prepares the statement on a primary db, since gorm
+// doesn't tell whether the prepared statement will be used for reading or writing.
+func (p *connPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.ReadWrite().PrepareContext(ctx, query)
+}
+
+func (p *connPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, query, args...)
+}
+
+func (p *connPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, query, args...)
+}
+
+func (p *connPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts the transaction on a primary db.
+// The returned *sql.Tx is used by gorm as the ConnPool of the whole transaction.
+func (p *connPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.db.ReadWrite().BeginTx(ctx, opts)
+}
+
+// GetDBConn returns the first primary db, aligned with dbresolver.DB Conn and Stats.
+func (p *connPool) GetDBConn() (*sql.DB, error) {
+	return p.db.PrimaryDBs()[0], nil
+}
+
+// rolePool is a gorm.ConnPool pinned to a single role,
+// used when the routing is overridden with the Read or Write clause.
+type rolePool struct {
+	resolve func() *sql.DB
+}
+
+func (p *rolePool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.resolve().PrepareContext(ctx, query)
+}
+
+func (p *rolePool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.resolve().ExecContext(ctx, query, args...)
+}
+
+func (p *rolePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.resolve().QueryContext(ctx, query, args...)
+}
+
+func (p *rolePool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.resolve().QueryRowContext(ctx, query, args...)
+}
@@ -0,0 +1,117 @@
+// Package gormresolver provides a gorm plugin backed by dbresolver.
+//
+// Reads are routed to the replicas, while writes and transactions are routed to the primaries.
+// The routing of a single statement can be overridden with the Read and Write clauses.
+package gormresolver
+
+import (
+	"strings"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"gorm.io/gorm"
+)
+
+const pluginName = "bxcodec:dbresolver"
+
+// Resolver is a gorm plugin routing the gorm statements through a dbresolver.DB
+type Resolver struct {
+	db        dbresolver.DB
+	pool      *connPool
+	readPool  *rolePool
+	writePool *rolePool
+}
+
+// New creates the gorm plugin for the given resolver
+//
+//	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: resolver.ConnPool()}))
+//	err = gdb.Use(resolver)
+func New(db dbresolver.DB) *Resolver {
+	return &Resolver{
+		db:        db,
+		pool:      &connPool{db: db},
+		readPool:  &rolePool{resolve: db.ReadOnly},
+		writePool: &rolePool{resolve: db.ReadWrite},
+	}
+}
+
+// ConnPool returns the gorm.ConnPool backed by the resolver.
+// It can be passed to the gorm dialector, eg. postgres.Config{Conn: resolver.ConnPool()}
+func (r *Resolver) ConnPool() gorm.ConnPool {
+	return r.pool
+}
+
+// Name return the plugin name
+func (r *Resolver) Name() string {
+	return pluginName
+}
+
+// Initialize replaces the gorm connection pool with the resolver
+// and registers the callbacks handling the routing of each statement.
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	if prepared, ok := db.ConnPool.(*gorm.PreparedStmtDB); ok {
+		prepared.ConnPool = r.pool
+	} else {
+		db.ConnPool = r.pool
+	}
+	db.Statement.ConnPool = db.ConnPool
+
+	callbacks := []struct {
+		name     string
+		register func(name string, fn func(*gorm.DB)) error
+		fn       func(*gorm.DB)
+	}{
+		{"gorm:create", db.Callback().Create().Before("gorm:create").Register, r.switchWrite},
+		{"gorm:update", db.Callback().Update().Before("gorm:update").Register, r.switchWrite},
+		{"gorm:delete", db.Callback().Delete().Before("gorm:delete").Register, r.switchWrite},
+		{"gorm:query", db.Callback().Query().Before("gorm:query").Register, r.switchRead},
+		{"gorm:row", db.Callback().Row().Before("gorm:row").Register, r.switchRead},
+		{"gorm:raw", db.Callback().Raw().Before("gorm:raw").Register, r.switchRaw},
+	}
+	for _, cb := range callbacks {
+		if err := cb.register(pluginName+":"+cb.name, cb.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) switchWrite(db *gorm.DB) {
+	r.switchConn(db, Write)
+}
+
+// switchRead routes to the replicas, unless the statement is locking the rows (SELECT ... FOR UPDATE)
+func (r *Resolver) switchRead(db *gorm.DB) {
+	if _, ok := db.Statement.Clauses["FOR"]; ok {
+		r.switchConn(db, Write)
+		return
+	}
+	r.switchConn(db, Read)
+}
+
+// switchRaw routes raw SQL to the replicas only when it is a SELECT statement
+func (r *Resolver) switchRaw(db *gorm.DB) {
+	query := strings.TrimSpace(db.Statement.SQL.String())
+	if len(query) >= len("SELECT") && strings.EqualFold(query[:len("SELECT")], "SELECT") {
+		r.switchConn(db, Read)
+		return
+	}
+	r.switchConn(db, Write)
+}
+
+func (r *Resolver) switchConn(db *gorm.DB, op Operation) {
+	stmt := db.Statement
+	// transactions are already pinned to their own connection
+	if _, ok := stmt.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+	if override, ok := statementOperation(stmt); ok {
+		op = override
+	}
+
+	switch op {
+	case Write:
+		stmt.ConnPool = r.writePool
+	case Read:
+		stmt.ConnPool = r.readPool
+	}
+}
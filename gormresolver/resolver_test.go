@@ -0,0 +1,150 @@
+package gormresolver_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/gormresolver"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type User struct {
+	ID   int
+	Name string
+}
+
+func createMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	return db, mock
+}
+
+func openGorm(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, sqlmock.Sqlmock) {
+	primary, primaryMock := createMock(t)
+	replica, replicaMock := createMock(t)
+
+	resolver := gormresolver.New(dbresolver.New(
+		dbresolver.WithPrimaryDBs(primary),
+		dbresolver.WithReplicaDBs(replica),
+	))
+
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: resolver.ConnPool()}), &gorm.Config{
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal("gorm open failed", err)
+	}
+	if err := gdb.Use(resolver); err != nil {
+		t.Fatal("gorm use failed", err)
+	}
+	return gdb, primaryMock, replicaMock
+}
+
+func assertExpectations(t *testing.T, mocks ...sqlmock.Sqlmock) {
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("sqlmock:unmet expectations: %s", err)
+		}
+	}
+}
+
+func TestResolverRouting(t *testing.T) {
+	t.Run("query on replica", func(t *testing.T) {
+		gdb, primaryMock, replicaMock := openGorm(t)
+		replicaMock.ExpectQuery(`SELECT \* FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Hiro"))
+
+		var users []User
+		if err := gdb.Find(&users).Error; err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primaryMock, replicaMock)
+	})
+
+	t.Run("create on primary", func(t *testing.T) {
+		gdb, primaryMock, replicaMock := openGorm(t)
+		primaryMock.ExpectQuery(`INSERT INTO "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+		if err := gdb.Create(&User{Name: "Hiro"}).Error; err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primaryMock, replicaMock)
+	})
+
+	t.Run("raw exec on primary", func(t *testing.T) {
+		gdb, primaryMock, replicaMock := openGorm(t)
+		primaryMock.ExpectExec(`UPDATE users`).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		if err := gdb.Exec("UPDATE users SET name = ?", "Hiro").Error; err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primaryMock, replicaMock)
+	})
+
+	t.Run("locking read on primary", func(t *testing.T) {
+		gdb, primaryMock, replicaMock := openGorm(t)
+		primaryMock.ExpectQuery(`SELECT \* FROM "users" FOR UPDATE`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		var users []User
+		if err := gdb.Clauses(clauseLocking()).Find(&users).Error; err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primaryMock, replicaMock)
+	})
+
+	t.Run("write clause override", func(t *testing.T) {
+		gdb, primaryMock, replicaMock := openGorm(t)
+		primaryMock.ExpectQuery(`SELECT \* FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		var users []User
+		if err := gdb.Clauses(gormresolver.Write).Find(&users).Error; err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primaryMock, replicaMock)
+	})
+
+	t.Run("read clause override", func(t *testing.T) {
+		gdb, primaryMock, replicaMock := openGorm(t)
+		replicaMock.ExpectQuery(`WITH recent AS`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		var users []User
+		err := gdb.Clauses(gormresolver.Read).
+			Raw("WITH recent AS (SELECT * FROM users) SELECT * FROM recent").
+			Scan(&users).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primaryMock, replicaMock)
+	})
+
+	t.Run("transaction on primary", func(t *testing.T) {
+		gdb, primaryMock, replicaMock := openGorm(t)
+		primaryMock.ExpectBegin()
+		primaryMock.ExpectQuery(`SELECT \* FROM "users"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		primaryMock.ExpectCommit()
+
+		err := gdb.Transaction(func(tx *gorm.DB) error {
+			var users []User
+			return tx.Find(&users).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primaryMock, replicaMock)
+	})
+}
+
+func clauseLocking() clause.Locking {
+	return clause.Locking{Strength: clause.LockingStrengthUpdate}
+}
@@ -0,0 +1,278 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the health checks
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultFailureThreshold    = 3
+)
+
+// HealthCheck define how the nodes are checked, see WithHealthCheckInterval
+type HealthCheck struct {
+	// Interval between two checks of the nodes, 10 seconds by default. It's the timeout of a check too.
+	Interval time.Duration
+	// FailureThreshold is the number of consecutive failed checks marking a node unhealthy, 3 by default
+	FailureThreshold int
+	// Check checks the node, PingContext by default
+	Check func(ctx context.Context, node *sql.DB) error
+}
+
+// WithHealthCheckInterval checks every primary and replica in the background on every interval.
+// A node failing FailureThreshold consecutive checks, see WithFailureThreshold, is out of rotation:
+// ReadOnly, ReadWrite and the reads of the prepared statements skip it, the reads go to the primaries
+// when every replica is unhealthy.
+// The node is back in rotation after its first successful check. The checks stop when the resolver is closed.
+func WithHealthCheckInterval(interval time.Duration) OptionFunc {
+	if interval <= 0 {
+		panic(fmt.Sprintf("dbresolver: invalid health check interval %v", interval))
+	}
+	return func(opt *Option) {
+		opt.HealthCheck = healthCheck(opt)
+		opt.HealthCheck.Interval = interval
+	}
+}
+
+// WithFailureThreshold sets the number of consecutive failed checks marking a node unhealthy,
+// it enables the health checks, see WithHealthCheckInterval
+func WithFailureThreshold(n int) OptionFunc {
+	if n < 1 {
+		panic(fmt.Sprintf("dbresolver: invalid failure threshold %d", n))
+	}
+	return func(opt *Option) {
+		opt.HealthCheck = healthCheck(opt)
+		opt.HealthCheck.FailureThreshold = n
+	}
+}
+
+// WithHealthCheck checks the nodes with the check function, it enables the health checks,
+// see WithHealthCheckInterval
+func WithHealthCheck(check func(ctx context.Context, node *sql.DB) error) OptionFunc {
+	return func(opt *Option) {
+		opt.HealthCheck = healthCheck(opt)
+		opt.HealthCheck.Check = check
+	}
+}
+
+// healthCheck returns a copy of the health check of the options, so the options of the shards don't share it
+func healthCheck(opt *Option) *HealthCheck {
+	if opt.HealthCheck == nil {
+		return &HealthCheck{}
+	}
+	config := *opt.HealthCheck
+	return &config
+}
+
+// healthChecker checks the nodes of the resolver
+type healthChecker struct {
+	db     *sqlDB
+	config HealthCheck
+
+	// failures are the consecutive failed checks of each node, only used by the checks
+	failures map[*sql.DB]int
+	// unhealthy are the nodes out of rotation, the map is replaced on every change
+	unhealthy atomic.Pointer[map[*sql.DB]struct{}]
+	// failedChecks are the failed checks of each node since the start, the map is replaced on every check
+	failedChecks atomic.Pointer[map[*sql.DB]int64]
+	mu           sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newHealthChecker(db *sqlDB, config HealthCheck) *healthChecker {
+	if config.Interval <= 0 {
+		config.Interval = defaultHealthCheckInterval
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+	if config.Check == nil {
+		config.Check = func(ctx context.Context, node *sql.DB) error {
+			return node.PingContext(ctx)
+		}
+	}
+	return &healthChecker{
+		db:       db,
+		config:   config,
+		failures: map[*sql.DB]int{},
+		done:     make(chan struct{}),
+	}
+}
+
+// start checks the nodes now, then on every interval until close
+func (h *healthChecker) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	go func() {
+		defer close(h.done)
+		for {
+			h.check(ctx)
+			timer := h.db.clock.NewTimer(h.config.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+}
+
+func (h *healthChecker) close() {
+	h.cancel()
+	<-h.done
+}
+
+// check checks every node concurrently, and updates the nodes out of rotation
+func (h *healthChecker) check(ctx context.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	set := h.db.nodes.Load()
+	nodes := distinct(append(append([]*sql.DB(nil), set.primaries...), set.replicas...))
+	failed := make([]bool, len(nodes))
+	_ = doParallely(ctx, h.db.parallelism, len(nodes), func(i int) error {
+		checkCtx, cancel := context.WithTimeout(ctx, h.config.Interval)
+		defer cancel()
+		failed[i] = h.config.Check(checkCtx, nodes[i]) != nil
+		return nil
+	})
+	if ctx.Err() != nil {
+		return
+	}
+
+	failures := make(map[*sql.DB]int, len(nodes))
+	unhealthy := make(map[*sql.DB]struct{})
+	failedChecks := make(map[*sql.DB]int64, len(nodes))
+	if previous := h.failedChecks.Load(); previous != nil {
+		for _, node := range nodes {
+			if n, ok := (*previous)[node]; ok {
+				failedChecks[node] = n
+			}
+		}
+	}
+	for i, node := range nodes {
+		if !failed[i] {
+			continue
+		}
+		failedChecks[node]++
+		failures[node] = h.failures[node] + 1
+		if failures[node] >= h.config.FailureThreshold {
+			unhealthy[node] = struct{}{}
+		}
+	}
+	h.failures = failures
+	h.logChanges(ctx, unhealthy)
+	h.unhealthy.Store(&unhealthy)
+	h.failedChecks.Store(&failedChecks)
+}
+
+// logChanges logs the nodes going out of rotation and back, it's called before storing the unhealthy nodes
+func (h *healthChecker) logChanges(ctx context.Context, unhealthy map[*sql.DB]struct{}) {
+	if h.db.logger == nil {
+		return
+	}
+	previous := map[*sql.DB]struct{}{}
+	if p := h.unhealthy.Load(); p != nil {
+		previous = *p
+	}
+	for node := range unhealthy {
+		if _, ok := previous[node]; !ok {
+			h.db.log(ctx, LogLevelWarn, "dbresolver: node unhealthy, out of rotation", h.db.nodeFields(node)...)
+		}
+	}
+	for node := range previous {
+		if _, ok := unhealthy[node]; !ok {
+			h.db.log(ctx, LogLevelInfo, "dbresolver: node healthy, back in rotation", h.db.nodeFields(node)...)
+		}
+	}
+}
+
+// failedCheckCount returns the failed checks of the node since the start, zero without health checks
+func (h *healthChecker) failedCheckCount(node *sql.DB) int64 {
+	if h == nil {
+		return 0
+	}
+	failedChecks := h.failedChecks.Load()
+	if failedChecks == nil {
+		return 0
+	}
+	return (*failedChecks)[node]
+}
+
+// healthy returns the nodes in rotation, the nodes themselves when they're all healthy or without health checks
+func (h *healthChecker) healthy(nodes []*sql.DB) []*sql.DB {
+	if h == nil {
+		return nodes
+	}
+	unhealthy := h.unhealthy.Load()
+	if unhealthy == nil || len(*unhealthy) == 0 {
+		return nodes
+	}
+	res := make([]*sql.DB, 0, len(nodes))
+	for _, node := range nodes {
+		if _, ok := (*unhealthy)[node]; !ok {
+			res = append(res, node)
+		}
+	}
+	return res
+}
+
+// healthyStmts returns the statements in rotation, skipping the statements of the unhealthy nodes of nodeStmts,
+// the statements themselves when the nodes are all healthy or without health checks
+func (h *healthChecker) healthyStmts(stmts []*sql.Stmt, nodeStmts map[*sql.DB]*sql.Stmt) []*sql.Stmt {
+	if h == nil {
+		return stmts
+	}
+	unhealthy := h.unhealthy.Load()
+	if unhealthy == nil || len(*unhealthy) == 0 {
+		return stmts
+	}
+	skipped := make(map[*sql.Stmt]struct{}, len(*unhealthy))
+	for node := range *unhealthy {
+		if st, ok := nodeStmts[node]; ok && st != nil {
+			skipped[st] = struct{}{}
+		}
+	}
+	if len(skipped) == 0 {
+		return stmts
+	}
+	res := make([]*sql.Stmt, 0, len(stmts))
+	for _, st := range stmts {
+		if _, ok := skipped[st]; !ok {
+			res = append(res, st)
+		}
+	}
+	return res
+}
+
+// isHealthy reports whether the node is in rotation
+func (h *healthChecker) isHealthy(node *sql.DB) bool {
+	if h == nil {
+		return true
+	}
+	unhealthy := h.unhealthy.Load()
+	if unhealthy == nil {
+		return true
+	}
+	_, ok := (*unhealthy)[node]
+	return !ok
+}
+
+// healthyPrimaries returns the primaries in rotation, healthy and with a closed circuit,
+// every primary when none is in rotation
+func (db *sqlDB) healthyPrimaries(primaries []*sql.DB) []*sql.DB {
+	if healthy := db.breaker.closed(db.health.healthy(primaries)); len(healthy) > 0 {
+		return healthy
+	}
+	return primaries
+}
@@ -0,0 +1,140 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// awaitFirstCheck waits for the check of the nodes on start, the next check is after the interval
+func awaitFirstCheck(t *testing.T, h *healthChecker) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for h.unhealthy.Load() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the nodes weren't checked on start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	primaries := make([]*sql.DB, 2)
+	replicas := make([]*sql.DB, 2)
+	for _, nodes := range [][]*sql.DB{primaries, replicas} {
+		for i := range nodes {
+			node, _, err := createMock()
+			if err != nil {
+				t.Fatal("creating of mock failed")
+			}
+			nodes[i] = node
+		}
+	}
+	down := map[*sql.DB]bool{}
+	resolver := New(WithPrimaryDBs(primaries...), WithReplicaDBs(replicas...),
+		WithHealthCheckInterval(time.Hour), WithFailureThreshold(2),
+		WithHealthCheck(func(_ context.Context, node *sql.DB) error {
+			if down[node] {
+				return errors.New("unreachable")
+			}
+			return nil
+		})).(*sqlDB)
+	defer resolver.stop()
+	awaitFirstCheck(t, resolver.health)
+
+	// a node is out of rotation after the failure threshold
+	down[primaries[0]], down[replicas[0]] = true, true
+	resolver.health.check(context.Background())
+	if seen := readNodes(resolver, 4); seen[replicas[0]] == 0 {
+		t.Errorf("want the replica in rotation below the failure threshold, got %v", seen)
+	}
+	resolver.health.check(context.Background())
+	for i := 0; i < 4; i++ {
+		if resolver.ReadOnly() != replicas[1] || resolver.ReadWrite() != primaries[1] {
+			t.Fatal("want the unhealthy nodes skipped")
+		}
+	}
+	for _, node := range resolver.Nodes() {
+		if node.Unhealthy != (node.DB == primaries[0] || node.DB == replicas[0]) {
+			t.Errorf("want the unhealthy nodes reported, got %+v", node)
+		}
+	}
+
+	// the reads go to the primaries when every replica is unhealthy
+	down[replicas[1]] = true
+	resolver.health.check(context.Background())
+	resolver.health.check(context.Background())
+	if replica := resolver.ReadOnly(); replica != primaries[1] {
+		t.Error("want the reads on the healthy primary")
+	}
+
+	// a node is back in rotation after a successful check
+	down = map[*sql.DB]bool{}
+	resolver.health.check(context.Background())
+	if seen := readNodes(resolver, 4); seen[replicas[0]] != 2 || seen[replicas[1]] != 2 {
+		t.Errorf("want the recovered replicas in rotation, got %v", seen)
+	}
+	failed := map[*sql.DB]int64{primaries[0]: 4, primaries[1]: 0, replicas[0]: 4, replicas[1]: 2}
+	for _, node := range resolver.Nodes() {
+		if node.FailedHealthChecks != failed[node.DB] {
+			t.Errorf("want %d failed health checks, got %+v", failed[node.DB], node)
+		}
+	}
+}
+
+func readNodes(resolver *sqlDB, n int) map[*sql.DB]int {
+	seen := map[*sql.DB]int{}
+	for i := 0; i < n; i++ {
+		seen[resolver.ReadOnly()]++
+	}
+	return seen
+}
+
+func TestHealthCheckStmt(t *testing.T) {
+	const query = "SELECT title FROM book"
+	nodes := make([]*sql.DB, 3)
+	for i := range nodes {
+		node, mock, err := createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+		mock.ExpectPrepare(query)
+		nodes[i] = node
+	}
+	primary, replicas := nodes[0], nodes[1:]
+	down := map[*sql.DB]bool{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicas...),
+		WithHealthCheckInterval(time.Hour), WithFailureThreshold(1),
+		WithHealthCheck(func(_ context.Context, node *sql.DB) error {
+			if down[node] {
+				return errors.New("unreachable")
+			}
+			return nil
+		})).(*sqlDB)
+	defer resolver.stop()
+	awaitFirstCheck(t, resolver.health)
+
+	prepared, err := resolver.Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := prepared.(*stmt)
+
+	// the statement of an unhealthy replica is out of rotation
+	down[replicas[0]] = true
+	resolver.health.check(context.Background())
+	for i := 0; i < 4; i++ {
+		if st.ROStmt() != st.dbStmt[replicas[1]] {
+			t.Fatal("want the statement of the unhealthy replica skipped")
+		}
+	}
+
+	// the reads go to the primary when every replica is unhealthy
+	down[replicas[1]] = true
+	resolver.health.check(context.Background())
+	if st.ROStmt() != st.dbStmt[primary] {
+		t.Error("want the reads on the statement of the primary")
+	}
+}
@@ -1,45 +1,99 @@
 package dbresolver
 
 import (
-	"net"
+	"context"
+	"database/sql"
+	"errors"
 	"sync"
-
-	"go.uber.org/multierr"
+	"sync/atomic"
 )
 
-func doParallely(n int, fn func(i int) error) error {
-	errors := make(chan error, n)
-	wg := &sync.WaitGroup{}
-	wg.Add(n)
-	for i := 0; i < n; i++ {
-		go func(i int) {
-			errors <- fn(i)
-			wg.Done()
-		}(i)
-	}
-
-	go func(wg *sync.WaitGroup) {
-		wg.Wait()
-		close(errors)
-	}(wg)
-
-	var arrErrs []error
-	for err := range errors {
-		if err != nil {
-			arrErrs = append(arrErrs, err)
+// DefaultMaxParallelism is the default number of nodes called concurrently by Ping, Prepare and Close
+const DefaultMaxParallelism = 16
+
+// parallel is the reusable state of a doParallely call
+type parallel struct {
+	wg       sync.WaitGroup
+	errs     []error
+	next     atomic.Int64
+	canceled atomic.Bool
+}
+
+// maxPooledParallelism bounds the errors slice kept in the pool,
+// so a single large call doesn't pin its memory
+const maxPooledParallelism = 64
+
+var parallelPool = sync.Pool{
+	New: func() any { return &parallel{} },
+}
+
+// doParallely calls fn for each index from 0 to n-1 concurrently, at most limit calls at a time
+// when the limit is positive, and combines the returned errors.
+// The calls not started yet when the context is done are skipped, and the context error is returned.
+// The calling goroutine runs calls too, so a single node doesn't spawn any goroutine.
+func doParallely(ctx context.Context, limit, n int, fn func(i int) error) error {
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		if err := ctx.Err(); err != nil {
+			return err
 		}
+		return fn(0)
 	}
 
-	return multierr.Combine(arrErrs...)
-}
+	workers := n
+	if limit > 0 && limit < n {
+		workers = limit
+	}
+	p := parallelPool.Get().(*parallel)
+	if cap(p.errs) < n {
+		p.errs = make([]error, n)
+	}
+	errs := p.errs[:n]
+	work := func() {
+		for {
+			i := int(p.next.Add(1) - 1)
+			if i >= n {
+				return
+			}
+			if ctx.Err() != nil {
+				p.canceled.Store(true)
+				return
+			}
+			errs[i] = fn(i)
+		}
+	}
+	p.wg.Add(workers - 1)
+	for w := 1; w < workers; w++ {
+		go func() {
+			defer p.wg.Done()
+			work()
+		}()
+	}
+	work()
+	p.wg.Wait()
 
-func isDBConnectionError(err error) bool {
-	if _, ok := err.(net.Error); ok {
-		return ok
+	// errors.Join copies the non nil errors, and returns nil without allocating when there are none
+	err := errors.Join(errs...)
+	if p.canceled.Load() {
+		err = errors.Join(err, ctx.Err())
+	}
+	clear(errs)
+	p.next.Store(0)
+	p.canceled.Store(false)
+	if cap(p.errs) <= maxPooledParallelism {
+		parallelPool.Put(p)
 	}
+	return err
+}
 
-	if _, ok := err.(*net.OpError); ok {
-		return ok
+// indexOf returns the index of the node among the nodes, -1 when it's missing
+func indexOf(nodes []*sql.DB, node *sql.DB) int {
+	for i, n := range nodes {
+		if n == node {
+			return i
+		}
 	}
-	return false
+	return -1
 }
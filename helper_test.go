@@ -1,17 +1,18 @@
 package dbresolver
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"net"
 	"runtime"
+	"sync/atomic"
 	"testing"
 )
 
 func TestParallelFunction(t *testing.T) {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	seq := []int{1, 2, 3, 4, 5, 6, 7, 8}
-	err := doParallely(len(seq), func(i int) error {
+	err := doParallely(context.Background(), 0, len(seq), func(i int) error {
 		if seq[i]%2 == 1 {
 			seq[i] *= seq[i]
 			return nil
@@ -32,22 +33,63 @@ func TestParallelFunction(t *testing.T) {
 	}
 }
 
-func TestIsDBConnectionError(t *testing.T) {
-	// test connection timeout error
-	timeoutError := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{IsTimeout: true}}
-	if !isDBConnectionError(timeoutError) {
-		t.Error("Expected true for timeout error")
+func TestParallelFunctionSmallN(t *testing.T) {
+	if err := doParallely(context.Background(), 0, 0, func(int) error { return errors.New("called") }); err != nil {
+		t.Errorf("want nil for no calls, got %v", err)
 	}
 
-	// test general network error
-	networkError := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network error")}
-	if !isDBConnectionError(networkError) {
-		t.Error("Expected true for network error")
+	errSingle := errors.New("single")
+	if err := doParallely(context.Background(), 0, 1, func(i int) error { return errSingle }); err != errSingle {
+		t.Errorf("want the error of the single call, got %v", err)
 	}
 
-	// test non-network error
-	otherError := errors.New("other error")
-	if isDBConnectionError(otherError) {
-		t.Error("Expected false for non-network error")
+	// the pooled state must not leak the errors of a previous call
+	_ = doParallely(context.Background(), 0, 3, func(i int) error { return fmt.Errorf("call %d", i) })
+	if err := doParallely(context.Background(), 0, 3, func(int) error { return nil }); err != nil {
+		t.Errorf("want nil, got %v", err)
+	}
+}
+
+func TestParallelFunctionLimit(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	err := doParallely(context.Background(), 3, 30, func(int) error {
+		n := running.Add(1)
+		for {
+			current := maxRunning.Load()
+			if n <= current || maxRunning.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		runtime.Gosched()
+		running.Add(-1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxRunning.Load() > 3 {
+		t.Errorf("want at most 3 concurrent calls, got %d", maxRunning.Load())
+	}
+}
+
+func TestParallelFunctionCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	err := doParallely(ctx, 1, 10, func(i int) error {
+		calls.Add(1)
+		if i == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want the context error, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("want the calls after the cancellation skipped, got %d calls", calls.Load())
+	}
+
+	if err := doParallely(ctx, 0, 1, func(int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("want the context error for a single call, got %v", err)
 	}
 }
@@ -0,0 +1,135 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Hooks are called around every query sent by the resolver, its transactions, connections and statements.
+//
+// The interface is the one of github.com/qustavo/sqlhooks, existing sqlhooks implementations can be attached
+// to the resolver as is. The context of the hooks holds the Route of the query, see RouteFromContext.
+type Hooks interface {
+	// Before is called before the query, an error aborts the query.
+	// The returned context is passed to the query and to the other hooks.
+	Before(ctx context.Context, query string, args ...interface{}) (context.Context, error)
+	// After is called after a successful query, an error is returned to the caller
+	After(ctx context.Context, query string, args ...interface{}) (context.Context, error)
+}
+
+// OnErrorer is implemented by the Hooks called when the query fails instead of After,
+// the returned error is returned to the caller
+type OnErrorer interface {
+	OnError(ctx context.Context, err error, query string, args ...interface{}) error
+}
+
+// Route describes where the resolver sent a query
+type Route struct {
+	// Role is the role of the db the query is sent to
+	Role Role
+	// Fallback is true when the read query is sent again after a replica connection error,
+	// to a primary db or to another replica, see WithReadFallback
+	Fallback bool
+	// Node is the db the query is sent to, it's nil for the prepared statements
+	Node *sql.DB
+}
+
+// to returns the route to the node
+func (r Route) to(node *sql.DB) Route {
+	r.Node = node
+	return r
+}
+
+type routeKey struct{}
+
+// RouteFromContext returns the Route of the query in the context of the Hooks
+func RouteFromContext(ctx context.Context) (Route, bool) {
+	route, ok := ctx.Value(routeKey{}).(Route)
+	return route, ok
+}
+
+var (
+	primaryRoute  = Route{Role: RolePrimary}
+	replicaRoute  = Route{Role: RoleReplica}
+	fallbackRoute = Route{Role: RolePrimary, Fallback: true}
+)
+
+// runHooks runs fn between the hooks, the context of the hooks is passed to fn
+func runHooks(ctx context.Context, hooks []Hooks, route Route, query string, args []interface{},
+	fn func(ctx context.Context) error) (err error) {
+	if len(hooks) == 0 {
+		return fn(ctx)
+	}
+
+	ctx = context.WithValue(ctx, routeKey{}, route)
+	for _, hook := range hooks {
+		hookCtx, err := hook.Before(ctx, query, args...)
+		if err != nil {
+			return onError(ctx, hooks, err, query, args)
+		}
+		ctx = hookCtx
+	}
+	if err = fn(ctx); err != nil {
+		return onError(ctx, hooks, err, query, args)
+	}
+	for _, hook := range hooks {
+		if ctx, err = hook.After(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func onError(ctx context.Context, hooks []Hooks, err error, query string, args []interface{}) error {
+	for _, hook := range hooks {
+		if onErrorer, ok := hook.(OnErrorer); ok {
+			err = onErrorer.OnError(ctx, err, query, args...)
+		}
+	}
+	return err
+}
+
+func execWithHooks(ctx context.Context, hooks []Hooks, route Route, query string, args []interface{},
+	exec func(ctx context.Context) (sql.Result, error)) (res sql.Result, err error) {
+	err = runHooks(ctx, hooks, route, query, args, func(ctx context.Context) error {
+		res, err = exec(ctx)
+		return err
+	})
+	return res, err
+}
+
+func queryWithHooks(ctx context.Context, hooks []Hooks, route Route, query string, args []interface{},
+	queryRows func(ctx context.Context) (*sql.Rows, error)) (rows *sql.Rows, err error) {
+	err = runHooks(ctx, hooks, route, query, args, func(ctx context.Context) error {
+		rows, err = queryRows(ctx)
+		return err
+	})
+	if err != nil && rows != nil {
+		// an After hook failed
+		_ = rows.Close()
+		rows = nil
+	}
+	return rows, err
+}
+
+// queryRowWithHooks runs the QueryRow function between the hooks.
+// A *sql.Row can't hold an arbitrary error: when a Before hook fails,
+// the query is run with a canceled context and its Scan returns context.Canceled,
+// the errors returned by the After and OnError hooks are ignored.
+func queryRowWithHooks(ctx context.Context, hooks []Hooks, route Route, query string, args []interface{},
+	queryRow func(ctx context.Context) *sql.Row) (row *sql.Row) {
+	if len(hooks) == 0 {
+		return queryRow(ctx)
+	}
+
+	err := runHooks(ctx, hooks, route, query, args, func(ctx context.Context) error {
+		row = queryRow(ctx)
+		return row.Err()
+	})
+	if row == nil {
+		canceledCtx, cancel := context.WithCancelCause(ctx)
+		cancel(err)
+		row = queryRow(canceledCtx)
+	}
+	return row
+}
@@ -0,0 +1,149 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type ctxKey struct{}
+
+// recordingHooks is a sqlhooks style implementation recording the hooks calls
+type recordingHooks struct {
+	calls     []string
+	beforeErr error
+}
+
+func (h *recordingHooks) Before(ctx context.Context, query string, _ ...interface{}) (context.Context, error) {
+	route, _ := RouteFromContext(ctx)
+	h.calls = append(h.calls, fmt.Sprintf("before %s %s fallback=%v", route.Role, query, route.Fallback))
+	return context.WithValue(ctx, ctxKey{}, "started"), h.beforeErr
+}
+
+func (h *recordingHooks) After(ctx context.Context, query string, _ ...interface{}) (context.Context, error) {
+	h.calls = append(h.calls, fmt.Sprintf("after %s %v", query, ctx.Value(ctxKey{})))
+	return ctx, nil
+}
+
+func (h *recordingHooks) OnError(ctx context.Context, err error, query string, _ ...interface{}) error {
+	h.calls = append(h.calls, fmt.Sprintf("error %s %v", query, ctx.Value(ctxKey{})))
+	return fmt.Errorf("hooked: %w", err)
+}
+
+func TestHooks(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	hooks := &recordingHooks{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithHooks(hooks))
+
+	connErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	replicaMock.ExpectQuery("SELECT 1").WillReturnError(connErr)
+	primaryMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(errors.New("deadlock"))
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectExec("UPDATE users SET name = ?").WithArgs("bob").WillReturnResult(sqlmock.NewResult(0, 1))
+	primaryMock.ExpectCommit()
+
+	rows, err := resolver.Query("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := resolver.Exec("DELETE FROM users"); err == nil || !strings.HasPrefix(err.Error(), "hooked: ") {
+		t.Errorf("want the error returned by OnError, got %v", err)
+	}
+	tx, err := resolver.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("UPDATE users SET name = ?", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"before replica SELECT 1 fallback=false",
+		"error SELECT 1 started",
+		"before primary SELECT 1 fallback=true",
+		"after SELECT 1 started",
+		"before primary DELETE FROM users fallback=false",
+		"error DELETE FROM users started",
+		"before primary UPDATE users SET name = ? fallback=false",
+		"after UPDATE users SET name = ? started",
+	}
+	if strings.Join(hooks.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want hooks calls:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(hooks.calls, "\n"))
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestHooksStmt(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	hooks := &recordingHooks{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithHooks(hooks))
+
+	primaryMock.ExpectPrepare("SELECT name FROM users WHERE id = ?")
+	replicaMock.ExpectPrepare("SELECT name FROM users WHERE id = ?").
+		ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("alice"))
+
+	stmt, err := resolver.Prepare("SELECT name FROM users WHERE id = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	if err := stmt.QueryRow(1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"before replica SELECT name FROM users WHERE id = ? fallback=false",
+		"after SELECT name FROM users WHERE id = ? started",
+	}
+	if strings.Join(hooks.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("want hooks calls:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(hooks.calls, "\n"))
+	}
+}
+
+func TestHooksBeforeError(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	hooks := &recordingHooks{beforeErr: errors.New("rate limited")}
+	resolver := New(WithPrimaryDBs(primary), WithHooks(hooks))
+
+	if _, err := resolver.Exec("DELETE FROM users"); err == nil || err.Error() != "hooked: rate limited" {
+		t.Errorf("want the Before error, got %v", err)
+	}
+	var name string
+	if err := resolver.QueryRow("SELECT name FROM users").Scan(&name); !errors.Is(err, context.Canceled) {
+		t.Errorf("want the QueryRow canceled, got %v", err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,242 @@
+// Package integrationtest runs the resolver against a real PostgreSQL cluster,
+// a primary with streaming replicas started with testcontainers.
+//
+// The tests require a Docker daemon, and are skipped in short mode or when Docker is not available:
+//
+//	cd integrationtest && go test ./...
+//
+// The tests built with the compose tag run against the cluster of docker-compose.yml instead,
+// see ConnectCluster:
+//
+//	docker compose -f integrationtest/docker-compose.yml up -d --wait
+//	cd integrationtest && go test -tags compose -run Compose ./...
+package integrationtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+	_ "github.com/lib/pq" // the postgres driver
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/network"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Image is the PostgreSQL image of the cluster, configuring the streaming replication from its environment
+const Image = "bitnami/postgresql:16"
+
+const (
+	primaryAlias        = "primary"
+	password            = "dbresolver"
+	replicationUser     = "replicator"
+	replicationPassword = "replicator"
+	database            = "dbresolver"
+	startupTimeout      = 2 * time.Minute
+)
+
+// Cluster is a PostgreSQL primary with its streaming replicas
+type Cluster struct {
+	// Primary is the connection to the primary
+	Primary *sql.DB
+	// Replicas are the connections to the replicas
+	Replicas []*sql.DB
+	// DB is the resolver of the cluster
+	DB dbresolver.DB
+
+	network    *testcontainers.DockerNetwork
+	replicas   []testcontainers.Container
+	containers []testcontainers.Container
+}
+
+// StartCluster starts a primary and the given number of replicas streaming from it,
+// and opens the resolver of the cluster with the given options
+func StartCluster(ctx context.Context, replicas int, opts ...dbresolver.OptionFunc) (_ *Cluster, err error) {
+	c := &Cluster{}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, c.Terminate(context.Background()))
+		}
+	}()
+
+	if c.network, err = network.New(ctx); err != nil {
+		return nil, fmt.Errorf("creating the network: %w", err)
+	}
+
+	_, c.Primary, err = c.start(ctx, []string{primaryAlias}, map[string]string{
+		"POSTGRESQL_REPLICATION_MODE": "master",
+	}, "database system is ready to accept connections")
+	if err != nil {
+		return nil, fmt.Errorf("starting the primary: %w", err)
+	}
+
+	for i := 0; i < replicas; i++ {
+		container, db, err := c.start(ctx, nil, map[string]string{
+			"POSTGRESQL_REPLICATION_MODE":   "slave",
+			"POSTGRESQL_MASTER_HOST":        primaryAlias,
+			"POSTGRESQL_MASTER_PORT_NUMBER": "5432",
+		}, "database system is ready to accept read-only connections")
+		if err != nil {
+			return nil, fmt.Errorf("starting the replica %d: %w", i, err)
+		}
+		c.replicas = append(c.replicas, container)
+		c.Replicas = append(c.Replicas, db)
+	}
+
+	opts = append([]dbresolver.OptionFunc{
+		dbresolver.WithPrimaryDBs(c.Primary),
+		dbresolver.WithReplicaDBs(c.Replicas...),
+	}, opts...)
+	c.DB = dbresolver.New(opts...)
+	return c, nil
+}
+
+// ConnectCluster connects to a running cluster, eg. the cluster of docker-compose.yml,
+// and opens the resolver of the cluster with the given options.
+// The replicas of the cluster aren't containers, StopReplica fails.
+func ConnectCluster(ctx context.Context, primaryDSN string, replicaDSNs []string,
+	opts ...dbresolver.OptionFunc) (_ *Cluster, err error) {
+	c := &Cluster{}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, c.Terminate(context.Background()))
+		}
+	}()
+
+	if c.Primary, err = connect(ctx, primaryDSN); err != nil {
+		return nil, fmt.Errorf("connecting to the primary: %w", err)
+	}
+	for i, dsn := range replicaDSNs {
+		db, err := connect(ctx, dsn)
+		if db != nil {
+			c.Replicas = append(c.Replicas, db)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("connecting to the replica %d: %w", i, err)
+		}
+	}
+
+	opts = append([]dbresolver.OptionFunc{
+		dbresolver.WithPrimaryDBs(c.Primary),
+		dbresolver.WithReplicaDBs(c.Replicas...),
+	}, opts...)
+	c.DB = dbresolver.New(opts...)
+	return c, nil
+}
+
+// connect opens the database and pings it, the database is returned to be closed even on error
+func connect(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return db, db.PingContext(ctx)
+}
+
+func (c *Cluster) start(ctx context.Context, aliases []string, env map[string]string,
+	readyLog string) (testcontainers.Container, *sql.DB, error) {
+	env["POSTGRESQL_PASSWORD"] = password
+	env["POSTGRESQL_DATABASE"] = database
+	env["POSTGRESQL_REPLICATION_USER"] = replicationUser
+	env["POSTGRESQL_REPLICATION_PASSWORD"] = replicationPassword
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:          Image,
+			Env:            env,
+			ExposedPorts:   []string{"5432/tcp"},
+			Networks:       []string{c.network.Name},
+			NetworkAliases: map[string][]string{c.network.Name: aliases},
+			WaitingFor: wait.ForAll(
+				wait.ForLog(readyLog),
+				wait.ForListeningPort("5432/tcp"),
+			).WithDeadline(startupTimeout),
+		},
+		Started: true,
+	})
+	if container != nil {
+		c.containers = append(c.containers, container)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := connect(ctx, fmt.Sprintf("host=%s port=%s user=postgres password=%s dbname=%s sslmode=disable",
+		host, port.Port(), password, database))
+	return container, db, err
+}
+
+// StopReplica stops the container of the replica, its connection is kept in the resolver
+func (c *Cluster) StopReplica(ctx context.Context, i int) error {
+	if i >= len(c.replicas) {
+		return fmt.Errorf("the replica %d isn't a container of the cluster", i)
+	}
+	timeout := 10 * time.Second
+	return c.replicas[i].Stop(ctx, &timeout)
+}
+
+// ReplicationLag returns the replay lag of the replica, the time since the last transaction it replayed
+func (c *Cluster) ReplicationLag(ctx context.Context, i int) (time.Duration, error) {
+	var lag float64
+	err := c.Replicas[i].QueryRowContext(ctx,
+		"SELECT COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)").Scan(&lag)
+	return time.Duration(lag * float64(time.Second)), err
+}
+
+// WaitReplication waits until the replicas replayed the current WAL position of the primary
+func (c *Cluster) WaitReplication(ctx context.Context) error {
+	var lsn string
+	if err := c.Primary.QueryRowContext(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return err
+	}
+	for i, replica := range c.Replicas {
+		for {
+			var replayed bool
+			err := replica.QueryRowContext(ctx,
+				"SELECT pg_last_wal_replay_lsn() >= $1::pg_lsn", lsn).Scan(&replayed)
+			if err != nil {
+				return fmt.Errorf("replica %d: %w", i, err)
+			}
+			if replayed {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+	}
+	return nil
+}
+
+// Terminate closes the resolver and removes the containers of the cluster
+func (c *Cluster) Terminate(ctx context.Context) (err error) {
+	if c.DB != nil {
+		err = errors.Join(err, c.DB.Close())
+	} else {
+		for _, db := range append([]*sql.DB{c.Primary}, c.Replicas...) {
+			if db != nil {
+				err = errors.Join(err, db.Close())
+			}
+		}
+	}
+	for _, container := range c.containers {
+		err = errors.Join(err, container.Terminate(ctx))
+	}
+	if c.network != nil {
+		err = errors.Join(err, c.network.Remove(ctx))
+	}
+	return err
+}
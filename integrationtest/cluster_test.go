@@ -0,0 +1,125 @@
+package integrationtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func startCluster(t *testing.T, replicas int) *Cluster {
+	if testing.Short() {
+		t.Skip("skipping the integration test in short mode")
+	}
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	cluster, err := StartCluster(ctx, replicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := cluster.Terminate(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if _, err := cluster.Primary.ExecContext(ctx,
+		"CREATE TABLE book (id SERIAL PRIMARY KEY, title TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.WaitReplication(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return cluster
+}
+
+func TestRouting(t *testing.T) {
+	cluster := startCluster(t, 2)
+	ctx := context.Background()
+
+	if _, err := cluster.DB.ExecContext(ctx, "INSERT INTO book (title) VALUES ($1)", "Dune"); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	err := cluster.DB.QueryRowContext(ctx, "INSERT INTO book (title) VALUES ($1) RETURNING id", "Emma").Scan(&id)
+	if err != nil {
+		t.Fatalf("want the RETURNING query routed to the primary: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		var inRecovery bool
+		if err := cluster.DB.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+			t.Fatal(err)
+		}
+		if !inRecovery {
+			t.Error("want the reads routed to the replicas")
+		}
+	}
+
+	tx, err := cluster.DB.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+	var inRecovery bool
+	if err := tx.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		t.Fatal(err)
+	}
+	if inRecovery {
+		t.Error("want the transactions on the primary")
+	}
+}
+
+func TestReplicationLag(t *testing.T) {
+	cluster := startCluster(t, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := cluster.DB.ExecContext(ctx, "INSERT INTO book (title) VALUES ($1)", "Dune"); err != nil {
+		t.Fatal(err)
+	}
+	// the write is visible on the replica once replayed
+	if err := cluster.WaitReplication(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := cluster.DB.QueryRowContext(ctx, "SELECT count(*) FROM book").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("want the replicated row, got %d rows", count)
+	}
+
+	lag, err := cluster.ReplicationLag(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lag < 0 || lag > time.Minute {
+		t.Errorf("unexpected replication lag %v", lag)
+	}
+}
+
+func TestReplicaFailover(t *testing.T) {
+	cluster := startCluster(t, 1)
+	ctx := context.Background()
+
+	if err := cluster.StopReplica(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	cluster.Replicas[0].SetMaxIdleConns(0) // drop the connections to the stopped replica
+
+	// the reads fall back to the primary on the replica connection errors
+	var inRecovery bool
+	if err := cluster.DB.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		t.Fatalf("want the read served by the primary: %v", err)
+	}
+	if inRecovery {
+		t.Error("want the read served by the primary")
+	}
+	if _, err := cluster.DB.ExecContext(ctx, "INSERT INTO book (title) VALUES ($1)", "Dune"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,159 @@
+//go:build compose
+
+package integrationtest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/lib/pq"
+)
+
+// The DSNs of the cluster of docker-compose.yml, they can be overridden from the environment
+var (
+	composePrimaryDSN = getenv("DBRESOLVER_PRIMARY_DSN",
+		"host=localhost port=55432 user=postgres password=dbresolver dbname=dbresolver sslmode=disable")
+	composeReplicaDSN = getenv("DBRESOLVER_REPLICA_DSN",
+		"host=localhost port=55433 user=postgres password=dbresolver dbname=dbresolver sslmode=disable")
+)
+
+func getenv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// composeCluster connects to the cluster of docker-compose.yml, and recreates the book table
+func composeCluster(t *testing.T, opts ...dbresolver.OptionFunc) *Cluster {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cluster, err := ConnectCluster(ctx, composePrimaryDSN, []string{composeReplicaDSN}, opts...)
+	if err != nil {
+		t.Fatalf("connecting to the compose cluster, is it up? %v", err)
+	}
+	t.Cleanup(func() {
+		if err := cluster.Terminate(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if _, err := cluster.Primary.ExecContext(ctx, "DROP TABLE IF EXISTS book"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cluster.Primary.ExecContext(ctx,
+		"CREATE TABLE book (id SERIAL PRIMARY KEY, title TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.WaitReplication(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return cluster
+}
+
+// compose runs docker compose on docker-compose.yml
+func compose(t *testing.T, args ...string) {
+	t.Helper()
+	out, err := exec.Command("docker", append([]string{"compose", "-f", "docker-compose.yml"}, args...)...).
+		CombinedOutput()
+	if err != nil {
+		t.Fatalf("docker compose %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestComposeReadOnlyTransaction(t *testing.T) {
+	cluster := composeCluster(t)
+	ctx := context.Background()
+
+	// the standby rejects the writes, sqlmock can't reproduce the error
+	_, err := cluster.Replicas[0].ExecContext(ctx, "INSERT INTO book (title) VALUES ($1)", "Dune")
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code.Name() != "read_only_sql_transaction" {
+		t.Fatalf("want a read_only_sql_transaction error on the replica, got %v", err)
+	}
+
+	// the resolver routes the writes, including the RETURNING queries, to the primary
+	if _, err := cluster.DB.ExecContext(ctx, "INSERT INTO book (title) VALUES ($1)", "Dune"); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	err = cluster.DB.QueryRowContext(ctx, "INSERT INTO book (title) VALUES ($1) RETURNING id", "Emma").Scan(&id)
+	if err != nil {
+		t.Fatalf("want the RETURNING query routed to the primary: %v", err)
+	}
+
+	report, err := cluster.DB.ValidateTopology(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); err != nil {
+		t.Errorf("want a valid topology: %v", err)
+	}
+
+	// a standby configured as a primary is reported
+	swapped := dbresolver.New(dbresolver.WithPrimaryDBs(cluster.Replicas[0]), dbresolver.WithReplicaDBs(cluster.Primary))
+	report, err = swapped.ValidateTopology(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); !errors.Is(err, dbresolver.ErrRoleMismatch) {
+		t.Errorf("want ErrRoleMismatch for the swapped roles, got %v", err)
+	}
+}
+
+func TestComposeReplicationLag(t *testing.T) {
+	cluster := composeCluster(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if _, err := cluster.DB.ExecContext(ctx, "INSERT INTO book (title) VALUES ($1)", "Dune"); err != nil {
+		t.Fatal(err)
+	}
+	// the write is visible on the replica once replayed
+	if err := cluster.WaitReplication(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := cluster.DB.QueryRowContext(ctx, "SELECT count(*) FROM book").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("want the replicated row, got %d rows", count)
+	}
+
+	lag, err := cluster.ReplicationLag(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lag < 0 || lag > time.Minute {
+		t.Errorf("unexpected replication lag %v", lag)
+	}
+}
+
+func TestComposeReplicaFailover(t *testing.T) {
+	cluster := composeCluster(t)
+	ctx := context.Background()
+
+	compose(t, "stop", "replica")
+	t.Cleanup(func() {
+		compose(t, "up", "-d", "--wait", "replica")
+	})
+	cluster.Replicas[0].SetMaxIdleConns(0) // drop the connections to the stopped replica
+
+	// the reads fall back to the primary on the replica connection errors
+	var inRecovery bool
+	if err := cluster.DB.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		t.Fatalf("want the read served by the primary: %v", err)
+	}
+	if inRecovery {
+		t.Error("want the read served by the primary")
+	}
+	if _, err := cluster.DB.ExecContext(ctx, "INSERT INTO book (title) VALUES ($1)", "Dune"); err != nil {
+		t.Fatal(err)
+	}
+}
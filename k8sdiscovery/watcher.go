@@ -0,0 +1,177 @@
+// Package k8sdiscovery keeps the replicas of a dbresolver.DB in sync with
+// the EndpointSlices of a Kubernetes Service.
+//
+// Scaling the read-replica StatefulSet behind the Service automatically
+// adds and removes the replicas from the resolver rotation.
+package k8sdiscovery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listersv1 "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Config define the watched Service and how to connect to its endpoints
+type Config struct {
+	// Namespace of the Service
+	Namespace string
+	// Service is the name of the Service exposing the replicas
+	Service string
+	// PortName selects the EndpointSlice port, the first port is used when it's empty
+	PortName string
+	// Open opens the replica connection for a discovered address (host:port)
+	Open func(addr string) (*sql.DB, error)
+	// OnError is called when a discovered replica can't be opened or closed, it's optional
+	OnError func(addr string, err error)
+	// ResyncPeriod of the EndpointSlice informer, zero disables the resync
+	ResyncPeriod time.Duration
+}
+
+// Watcher watches the EndpointSlices of a Service and reconciles the replicas of the resolver
+type Watcher struct {
+	client kubernetes.Interface
+	db     dbresolver.DB
+	cfg    Config
+
+	mu       sync.Mutex
+	replicas map[string]*sql.DB
+}
+
+// NewWatcher creates the watcher for the given resolver
+func NewWatcher(client kubernetes.Interface, db dbresolver.DB, cfg Config) *Watcher {
+	return &Watcher{
+		client:   client,
+		db:       db,
+		cfg:      cfg,
+		replicas: map[string]*sql.DB{},
+	}
+}
+
+// Run watches the EndpointSlices until the context is done.
+// The replicas discovered so far are kept in the resolver once it returns.
+func (w *Watcher) Run(ctx context.Context) error {
+	if w.cfg.Open == nil {
+		return errors.New("k8sdiscovery: Config.Open is required")
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: w.cfg.Service})
+	factory := informers.NewSharedInformerFactoryWithOptions(w.client, w.cfg.ResyncPeriod,
+		informers.WithNamespace(w.cfg.Namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = selector.String()
+		}))
+	defer factory.Shutdown()
+
+	informer := factory.Discovery().V1().EndpointSlices()
+	lister := informer.Lister()
+	reconcile := func(interface{}) { w.reconcile(lister, selector) }
+	_, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    reconcile,
+		UpdateFunc: func(_, obj interface{}) { reconcile(obj) },
+		DeleteFunc: reconcile,
+	})
+	if err != nil {
+		return err
+	}
+
+	factory.Start(ctx.Done())
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.New("k8sdiscovery: failed to sync the informer cache for " + typ.String())
+		}
+	}
+	w.reconcile(lister, selector)
+
+	<-ctx.Done()
+	return nil
+}
+
+// reconcile adds the newly ready endpoints into the resolver, and removes the endpoints which are gone
+func (w *Watcher) reconcile(lister listersv1.EndpointSliceLister, selector labels.Selector) {
+	slices, err := lister.EndpointSlices(w.cfg.Namespace).List(selector)
+	if err != nil {
+		w.onError("", err)
+		return
+	}
+	desired := map[string]struct{}{}
+	for _, slice := range slices {
+		for _, addr := range w.addresses(slice) {
+			desired[addr] = struct{}{}
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for addr := range desired {
+		if _, ok := w.replicas[addr]; ok {
+			continue
+		}
+		replica, err := w.cfg.Open(addr)
+		if err != nil {
+			w.onError(addr, err)
+			continue
+		}
+		w.replicas[addr] = replica
+		w.db.AddReplica(replica)
+	}
+
+	for addr, replica := range w.replicas {
+		if _, ok := desired[addr]; ok {
+			continue
+		}
+		w.db.RemoveReplica(replica)
+		delete(w.replicas, addr)
+		if err := replica.Close(); err != nil {
+			w.onError(addr, err)
+		}
+	}
+}
+
+// addresses returns the host:port of every ready endpoint of the slice
+func (w *Watcher) addresses(slice *discoveryv1.EndpointSlice) []string {
+	var port *int32
+	for i := range slice.Ports {
+		p := slice.Ports[i]
+		if w.cfg.PortName == "" || (p.Name != nil && *p.Name == w.cfg.PortName) {
+			port = p.Port
+			break
+		}
+	}
+	if port == nil {
+		return nil
+	}
+
+	var addrs []string
+	for _, endpoint := range slice.Endpoints {
+		if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+			continue
+		}
+		for _, host := range endpoint.Addresses {
+			addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(int(*port))))
+		}
+	}
+	return addrs
+}
+
+func (w *Watcher) onError(addr string, err error) {
+	if w.cfg.OnError != nil {
+		w.cfg.OnError(addr, err)
+	}
+}
@@ -0,0 +1,111 @@
+package k8sdiscovery_test
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/k8sdiscovery"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func endpointSlice(addrs ...string) *discoveryv1.EndpointSlice {
+	port, portName := int32(5432), "postgres"
+	ready, notReady := true, false
+
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db-replicas-abc",
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "db-replicas"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+	}
+	for _, addr := range addrs {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{addr},
+			Conditions: discoveryv1.EndpointConditions{Ready: &ready},
+		})
+	}
+	// not ready endpoints are never routed to
+	slice.Endpoints = append(slice.Endpoints, discoveryv1.Endpoint{
+		Addresses:  []string{"10.0.0.99"},
+		Conditions: discoveryv1.EndpointConditions{Ready: &notReady},
+	})
+	return slice
+}
+
+func waitForReplicas(t *testing.T, db dbresolver.DB, n int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(db.ReplicaDBs()) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v replicas, got %v", n, len(db.ReplicaDBs()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcherReconcile(t *testing.T) {
+	primary, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	db := dbresolver.New(dbresolver.WithPrimaryDBs(primary))
+
+	var mu sync.Mutex
+	opened := map[string]sqlmock.Sqlmock{}
+	client := fake.NewSimpleClientset(endpointSlice("10.0.0.1", "10.0.0.2"))
+	watcher := k8sdiscovery.NewWatcher(client, db, k8sdiscovery.Config{
+		Namespace: "default",
+		Service:   "db-replicas",
+		PortName:  "postgres",
+		Open: func(addr string) (*sql.DB, error) {
+			replica, mock, err := sqlmock.New()
+			if err != nil {
+				return nil, err
+			}
+			mock.ExpectClose()
+			mu.Lock()
+			opened[addr] = mock
+			mu.Unlock()
+			return replica, nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- watcher.Run(ctx)
+	}()
+
+	waitForReplicas(t, db, 2)
+
+	_, err = client.DiscoveryV1().EndpointSlices("default").
+		Update(ctx, endpointSlice("10.0.0.2"), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForReplicas(t, db, 1)
+
+	mu.Lock()
+	if _, ok := opened["10.0.0.99:5432"]; ok {
+		t.Error("not ready endpoint should not be opened")
+	}
+	if err := opened["10.0.0.1:5432"].ExpectationsWereMet(); err != nil {
+		t.Errorf("removed replica should be closed: %s", err)
+	}
+	mu.Unlock()
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
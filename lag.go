@@ -0,0 +1,253 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultLagInterval is the default interval between two measures of the replication lag
+const defaultLagInterval = 5 * time.Second
+
+// LagFunc measures the replication lag of a replica
+type LagFunc func(ctx context.Context, replica *sql.DB) (time.Duration, error)
+
+// Replication lag measures of the common databases
+var (
+	// PostgresLag measures the time since the last transaction replayed by the standby, zero when the standby
+	// has replayed everything it received, so an idle primary doesn't look like a lag. It's the default LagFunc.
+	PostgresLag LagFunc = func(ctx context.Context, replica *sql.DB) (time.Duration, error) {
+		var seconds float64
+		err := replica.QueryRowContext(ctx, `SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END`).Scan(&seconds)
+		return time.Duration(seconds * float64(time.Second)), err
+	}
+	// MySQLLag reads the Seconds_Behind_Master column of SHOW SLAVE STATUS,
+	// it fails when the replication is stopped or the server isn't a replica
+	MySQLLag LagFunc = func(ctx context.Context, replica *sql.DB) (time.Duration, error) {
+		return showStatusLag(ctx, replica, "SHOW SLAVE STATUS", "Seconds_Behind_Master")
+	}
+)
+
+// LagMonitor define how the replication lag of the replicas is measured, see WithLagMonitor
+type LagMonitor struct {
+	// Interval between two measures of the replicas, 5 seconds by default. It's the timeout of a measure too.
+	Interval time.Duration
+	// MaxLag is the lag above which a replica is excluded from the reads, zero keeps every replica.
+	// The reads go to the primaries when every replica is excluded.
+	MaxLag time.Duration
+	// Lag measures the lag of a replica, PostgresLag by default
+	Lag LagFunc
+}
+
+// WithLagMonitor measures the replication lag of each replica in the background on every interval,
+// and excludes from the reads the replicas lagging more than MaxLag. A replica whose lag can't be measured
+// is excluded until its next measure, the reads go to every replica until the first measure.
+// The measured lags are exposed by DB.Nodes. The prepared statements keep their rotation of every replica.
+// The measures stop when the resolver is closed.
+func WithLagMonitor(config LagMonitor) OptionFunc {
+	return func(opt *Option) {
+		opt.LagMonitor = &config
+	}
+}
+
+type maxStalenessKey struct{}
+
+// WithMaxStaleness returns a context whose reads only go to the replicas lagging at most d,
+// or to a primary when no replica qualifies, eg. to read fresher data than MaxLag allows.
+// Without WithLagMonitor the lag of the replicas is unknown, the reads go to a primary.
+func WithMaxStaleness(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, maxStalenessKey{}, d)
+}
+
+// MaxStalenessFromContext returns the maximum staleness set by WithMaxStaleness
+func MaxStalenessFromContext(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(maxStalenessKey{}).(time.Duration)
+	return d, ok
+}
+
+// lagMonitor measures the replication lag of the replicas of the resolver
+type lagMonitor struct {
+	db     *sqlDB
+	config LagMonitor
+
+	// mu serializes the measures
+	mu sync.Mutex
+	// lags are the last measured lags, the replicas failing their measure are missing.
+	// The map is replaced on every measure, nil until the first one.
+	lags atomic.Pointer[map[*sql.DB]time.Duration]
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newLagMonitor(db *sqlDB, config LagMonitor) *lagMonitor {
+	if config.Interval <= 0 {
+		config.Interval = defaultLagInterval
+	}
+	if config.Lag == nil {
+		config.Lag = PostgresLag
+	}
+	return &lagMonitor{
+		db:     db,
+		config: config,
+		done:   make(chan struct{}),
+	}
+}
+
+// start measures the replicas now, then on every interval until close
+func (m *lagMonitor) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	go func() {
+		defer close(m.done)
+		for {
+			m.measure(ctx)
+			timer := m.db.clock.NewTimer(m.config.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+}
+
+func (m *lagMonitor) close() {
+	m.cancel()
+	<-m.done
+}
+
+// measure measures the lag of every replica concurrently
+func (m *lagMonitor) measure(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replicas := distinct(m.db.nodes.Load().replicas)
+	measured := make([]time.Duration, len(replicas))
+	_ = doParallely(ctx, m.db.parallelism, len(replicas), func(i int) error {
+		measureCtx, cancel := context.WithTimeout(ctx, m.config.Interval)
+		defer cancel()
+		lag, err := m.config.Lag(measureCtx, replicas[i])
+		if err != nil {
+			lag = -1
+		}
+		measured[i] = lag
+		return nil
+	})
+	if ctx.Err() != nil {
+		return
+	}
+
+	lags := make(map[*sql.DB]time.Duration, len(replicas))
+	for i, replica := range replicas {
+		if measured[i] >= 0 {
+			lags[replica] = measured[i]
+		}
+	}
+	m.lags.Store(&lags)
+}
+
+// fresh returns the replicas lagging at most MaxLag, and at most the maximum staleness of the context.
+// It returns the replicas themselves without lag monitor or limit, none when the context sets
+// a maximum staleness without lag monitor.
+func (m *lagMonitor) fresh(ctx context.Context, replicas []*sql.DB) []*sql.DB {
+	staleness, limited := MaxStalenessFromContext(ctx)
+	if m == nil {
+		if limited {
+			return nil
+		}
+		return replicas
+	}
+	maxLag := m.config.MaxLag
+	if limited && (maxLag == 0 || staleness < maxLag) {
+		maxLag = staleness
+	} else if maxLag == 0 {
+		return replicas
+	}
+	lags := m.lags.Load()
+	if lags == nil {
+		if limited {
+			return nil
+		}
+		return replicas
+	}
+
+	isFresh := func(replica *sql.DB) bool {
+		lag, ok := (*lags)[replica]
+		return ok && lag <= maxLag
+	}
+	for i, replica := range replicas {
+		if isFresh(replica) {
+			continue
+		}
+		// the replicas are copied from the first stale one
+		res := append(make([]*sql.DB, 0, len(replicas)), replicas[:i]...)
+		for _, replica := range replicas[i+1:] {
+			if isFresh(replica) {
+				res = append(res, replica)
+			}
+		}
+		return res
+	}
+	return replicas
+}
+
+// lag returns the last measured lag of the replica, zero when it isn't measured or the monitor is nil
+func (m *lagMonitor) lag(replica *sql.DB) time.Duration {
+	if m == nil {
+		return 0
+	}
+	lags := m.lags.Load()
+	if lags == nil {
+		return 0
+	}
+	return (*lags)[replica]
+}
+
+// showStatusLag reads the lag in seconds from the column of the replication status query
+func showStatusLag(ctx context.Context, replica *sql.DB, query, column string) (time.Duration, error) {
+	rows, err := replica.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, errors.New("dbresolver: the server isn't a replica")
+	}
+	values := make([]sql.NullInt64, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range columns {
+		if columns[i] == column {
+			dest[i] = &values[i]
+			continue
+		}
+		dest[i] = new(sql.RawBytes)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+	for i := range columns {
+		if columns[i] != column {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, errors.New("dbresolver: the replication is stopped")
+		}
+		return time.Duration(values[i].Int64) * time.Second, rows.Err()
+	}
+	return 0, fmt.Errorf("dbresolver: no %s column in the replication status", column)
+}
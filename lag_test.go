@@ -0,0 +1,127 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// awaitFirstMeasure waits for the measure of the replicas on start, the next measure is after the interval
+func awaitFirstMeasure(t *testing.T, m *lagMonitor) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for m.lags.Load() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the replicas weren't measured on start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLagMonitor(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replicas := make([]*sql.DB, 2)
+	for i := range replicas {
+		if replicas[i], _, err = createMock(); err != nil {
+			t.Fatal("creating of mock failed")
+		}
+	}
+	lags := map[*sql.DB]time.Duration{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicas...),
+		WithLagMonitor(LagMonitor{Interval: time.Hour, MaxLag: 10 * time.Second,
+			Lag: func(_ context.Context, replica *sql.DB) (time.Duration, error) {
+				lag, ok := lags[replica]
+				if !ok {
+					return 0, errors.New("unreachable")
+				}
+				return lag, nil
+			}})).(*sqlDB)
+	defer resolver.stop()
+	awaitFirstMeasure(t, resolver.lag)
+
+	// the replicas failing their measure are excluded
+	resolver.lag.measure(context.Background())
+	if replica := resolver.ReadOnly(); replica != primary {
+		t.Error("want the reads on the primary when no replica is measured")
+	}
+
+	// the replicas lagging more than MaxLag are excluded
+	lags[replicas[0]], lags[replicas[1]] = time.Second, time.Minute
+	resolver.lag.measure(context.Background())
+	if seen := readNodes(resolver, 4); seen[replicas[0]] != 4 {
+		t.Errorf("want the lagging replica excluded, got %v", seen)
+	}
+	for _, node := range resolver.Nodes() {
+		if node.DB == replicas[1] && node.ReplicationLag != time.Minute {
+			t.Errorf("want the replication lag reported, got %v", node.ReplicationLag)
+		}
+	}
+
+	// the maximum staleness of the context is stricter than MaxLag
+	ctx := WithMaxStaleness(context.Background(), 5*time.Second)
+	if replica, route := resolver.readOnly(ctx); replica != replicas[0] || route.Role != RoleReplica {
+		t.Error("want the fresh replica within the maximum staleness")
+	}
+	ctx = WithMaxStaleness(context.Background(), 500*time.Millisecond)
+	if replica, route := resolver.readOnly(ctx); replica != primary || route.Role != RolePrimary {
+		t.Error("want the reads on the primary when every replica is staler than the context allows")
+	}
+	decision, err := resolver.ExplainRoute(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Role != RolePrimary {
+		t.Errorf("want the explained reads on the primary, got %+v", decision)
+	}
+}
+
+func TestMaxStalenessWithoutLagMonitor(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica)).(*sqlDB)
+
+	if got, _ := resolver.readOnly(context.Background()); got != replica {
+		t.Error("want the reads on the replica")
+	}
+	// the lag of the replica is unknown
+	if got, _ := resolver.readOnly(WithMaxStaleness(context.Background(), time.Minute)); got != primary {
+		t.Error("want the reads on the primary")
+	}
+}
+
+func TestMySQLLag(t *testing.T) {
+	replica, mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"Slave_IO_State", "Seconds_Behind_Master"}).AddRow("Waiting", 3))
+	if lag, err := MySQLLag(context.Background(), replica); err != nil || lag != 3*time.Second {
+		t.Errorf("want a lag of 3s, got %v %v", lag, err)
+	}
+
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(
+		sqlmock.NewRows([]string{"Slave_IO_State", "Seconds_Behind_Master"}).AddRow("", nil))
+	if _, err := MySQLLag(context.Background(), replica); err == nil {
+		t.Error("want an error when the replication is stopped")
+	}
+
+	mock.ExpectQuery("SHOW SLAVE STATUS").WillReturnRows(sqlmock.NewRows([]string{"Seconds_Behind_Master"}))
+	if _, err := MySQLLag(context.Background(), replica); err == nil {
+		t.Error("want an error when the server isn't a replica")
+	}
+}
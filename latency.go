@@ -0,0 +1,169 @@
+package dbresolver
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the latency load balancer
+const (
+	defaultLatencyTolerance    = time.Millisecond
+	defaultLatencySmoothing    = 0.3
+	defaultLatencyExploreEvery = 100
+)
+
+// LatencyBalancing define how the latency load balancer prefers the fastest nodes
+type LatencyBalancing struct {
+	// Tolerance is the band above the lowest average latency within which the nodes are the fastest, 1ms by default
+	Tolerance time.Duration
+	// Threshold is the average latency above which a node is excluded, it only takes the spillover
+	// when every node is above the threshold. Zero disables the threshold.
+	Threshold time.Duration
+	// Smoothing is the weight of the last latency in the moving average, in (0, 1], 0.3 by default
+	Smoothing float64
+	// ExploreEvery sends one out of ExploreEvery queries to every node round robin,
+	// so the average latency of the excluded nodes is refreshed, 100 by default
+	ExploreEvery int
+}
+
+// WithLatencyLoadBalancer configure the latency load balancer for the resolver, see LatencyLoadBalancer
+func WithLatencyLoadBalancer(config LatencyBalancing) OptionFunc {
+	return func(opt *Option) {
+		opt.DBLB = NewLatencyLoadBalancer[*sql.DB](config)
+		opt.StmtLB = NewLatencyLoadBalancer[*sql.Stmt](config)
+	}
+}
+
+// latencyObserver is satisfied by the load balancers resolving by latency
+type latencyObserver[T DBConnection] interface {
+	Observe(db T, latency time.Duration)
+	Latency(db T) time.Duration
+}
+
+// LatencyLoadBalancer represent for Latency LB policy.
+// It tracks a moving average of the query latency of each node, and resolves round robin the fastest nodes:
+// the nodes within the tolerance of the lowest average latency. The nodes not observed yet are the fastest,
+// so every node is observed, eg. the cross-AZ replicas only take the spillover once they are measured slower.
+//
+// The resolver observes the queries of its nodes, the prepared statements and the connections of the other
+// resolvers, eg. the pgx pools, are resolved round robin unless their latency is reported with Observe.
+// It must not be copied after first use.
+type LatencyLoadBalancer[T DBConnection] struct {
+	config     LatencyBalancing
+	roundRobin RoundRobinLoadBalancer[T]
+	resolves   atomic.Uint64
+
+	mu        sync.RWMutex
+	latencies map[T]time.Duration
+}
+
+// NewLatencyLoadBalancer creates the latency load balancer with the configuration
+func NewLatencyLoadBalancer[T DBConnection](config LatencyBalancing) *LatencyLoadBalancer[T] {
+	if config.Tolerance <= 0 {
+		config.Tolerance = defaultLatencyTolerance
+	}
+	if config.Smoothing <= 0 || config.Smoothing > 1 {
+		config.Smoothing = defaultLatencySmoothing
+	}
+	if config.ExploreEvery <= 0 {
+		config.ExploreEvery = defaultLatencyExploreEvery
+	}
+	return &LatencyLoadBalancer[T]{config: config, latencies: make(map[T]time.Duration)}
+}
+
+// Name return the LB policy name
+func (lb *LatencyLoadBalancer[T]) Name() LoadBalancerPolicy {
+	return LatencyLB
+}
+
+// Observe adds the latency of a query to the moving average of the node
+func (lb *LatencyLoadBalancer[T]) Observe(db T, latency time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if previous, ok := lb.latencies[db]; ok {
+		latency = time.Duration(lb.config.Smoothing*float64(latency) + (1-lb.config.Smoothing)*float64(previous))
+	}
+	lb.latencies[db] = latency
+}
+
+// Latency returns the average latency of the node, zero when it isn't observed yet
+func (lb *LatencyLoadBalancer[T]) Latency(db T) time.Duration {
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	return lb.latencies[db]
+}
+
+// Resolve return the resolved option for Latency LB
+func (lb *LatencyLoadBalancer[T]) Resolve(dbs []T) T {
+	if len(dbs) <= 1 || lb.resolves.Add(1)%uint64(lb.config.ExploreEvery) == 0 {
+		return lb.roundRobin.Resolve(dbs)
+	}
+
+	lb.mu.RLock()
+	defer lb.mu.RUnlock()
+	// the lowest latency below the threshold, the lowest latency when every node is above it
+	lowest, spillover := time.Duration(-1), true
+	for _, db := range dbs {
+		latency := lb.latencies[db]
+		below := lb.config.Threshold == 0 || latency <= lb.config.Threshold
+		if below && spillover {
+			lowest, spillover = latency, false
+		}
+		if (below || spillover) && (lowest < 0 || latency < lowest) {
+			lowest = latency
+		}
+	}
+
+	fastest := func(db T) bool {
+		latency := lb.latencies[db]
+		below := spillover || lb.config.Threshold == 0 || latency <= lb.config.Threshold
+		return below && latency <= lowest+lb.config.Tolerance
+	}
+	count := 0
+	for _, db := range dbs {
+		if fastest(db) {
+			count++
+		}
+	}
+	idx := lb.roundRobin.predict(count)
+	for _, db := range dbs {
+		if fastest(db) {
+			if idx == 0 {
+				return db
+			}
+			idx--
+		}
+	}
+	return dbs[0]
+}
+
+func (lb *LatencyLoadBalancer[T]) peek(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	// the next index depends on the latencies when resolving
+	return -1
+}
+
+func (lb *LatencyLoadBalancer[T]) predict(n int) int {
+	return lb.roundRobin.predict(n)
+}
+
+// observeLatency reports the latency of the query on the node to the latency load balancer.
+// The failed queries aren't observed, a node failing fast isn't the fastest.
+func (db *sqlDB) observeLatency(node *sql.DB, start time.Time, err *error) {
+	if *err == nil {
+		db.latency.Observe(node, db.clock.Now().Sub(start))
+	}
+}
+
+// nodeLatency returns the probed latency of the node with the nearest read preference,
+// its average query latency with the latency load balancer
+func (db *sqlDB) nodeLatency(node *sql.DB) time.Duration {
+	if db.nearest == nil && db.latency != nil {
+		return db.latency.Latency(node)
+	}
+	return db.nearest.latency(node)
+}
@@ -0,0 +1,106 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLatencyLoadBalancer(t *testing.T) {
+	local1, local2, remote := &sql.DB{}, &sql.DB{}, &sql.DB{}
+	dbs := []*sql.DB{local1, remote, local2}
+	lb := NewLatencyLoadBalancer[*sql.DB](LatencyBalancing{Threshold: 10 * time.Millisecond, ExploreEvery: 1000})
+
+	resolveCounts := func(n int) map[*sql.DB]int {
+		counts := map[*sql.DB]int{}
+		for i := 0; i < n; i++ {
+			counts[lb.Resolve(dbs)]++
+		}
+		return counts
+	}
+
+	// the nodes not observed yet are the fastest
+	if counts := resolveCounts(30); counts[local1] != 10 || counts[remote] != 10 || counts[local2] != 10 {
+		t.Errorf("want every node resolved before the first observation, got %v", counts)
+	}
+
+	// the fastest nodes within the tolerance are resolved round robin
+	lb.Observe(local1, 2*time.Millisecond)
+	lb.Observe(local2, 2500*time.Microsecond)
+	lb.Observe(remote, 5*time.Millisecond)
+	if counts := resolveCounts(30); counts[local1] != 15 || counts[local2] != 15 {
+		t.Errorf("want the fastest nodes resolved, got %v", counts)
+	}
+
+	// the moving average follows the latency of the queries
+	for i := 0; i < 20; i++ {
+		lb.Observe(local1, 8*time.Millisecond)
+		lb.Observe(local2, 8*time.Millisecond)
+	}
+	if latency := lb.Latency(local1); latency < 7*time.Millisecond || latency > 8*time.Millisecond {
+		t.Errorf("want the moving average close to the last latencies, got %v", latency)
+	}
+	if counts := resolveCounts(30); counts[remote] != 30 {
+		t.Errorf("want the spillover to the faster node, got %v", counts)
+	}
+
+	// the nodes above the threshold are excluded, unless every node is above it
+	lb.Observe(remote, time.Second)
+	if counts := resolveCounts(30); counts[remote] != 0 {
+		t.Errorf("want the node above the threshold excluded, got %v", counts)
+	}
+	for i := 0; i < 20; i++ {
+		lb.Observe(local1, time.Second)
+		lb.Observe(local2, 500*time.Millisecond)
+		lb.Observe(remote, time.Second)
+	}
+	if counts := resolveCounts(30); counts[local2] != 30 {
+		t.Errorf("want the fastest node when every node is above the threshold, got %v", counts)
+	}
+}
+
+func TestLatencyLoadBalancerExplore(t *testing.T) {
+	fast, slow := &sql.DB{}, &sql.DB{}
+	lb := NewLatencyLoadBalancer[*sql.DB](LatencyBalancing{ExploreEvery: 10})
+	lb.Observe(fast, time.Millisecond)
+	lb.Observe(slow, time.Second)
+
+	slowCount := 0
+	for i := 0; i < 100; i++ {
+		if lb.Resolve([]*sql.DB{fast, slow}) == slow {
+			slowCount++
+		}
+	}
+	if slowCount == 0 || slowCount > 10 {
+		t.Errorf("want the slow node explored once every 10 queries at most, got %d", slowCount)
+	}
+}
+
+func TestLatencyLoadBalancerObservesQueries(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithLoadBalancer(LatencyLB))
+
+	replicaMock.ExpectQuery("SELECT 1").WillDelayFor(5 * time.Millisecond).WillReturnRows(sqlmock.NewRows([]string{"1"}))
+	rows, err := resolver.QueryContext(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	if latency := resolver.Nodes()[1].Latency; latency < 5*time.Millisecond {
+		t.Errorf("want the latency of the query observed, got %v", latency)
+	}
+	if latency := resolver.Nodes()[0].Latency; latency != 0 {
+		t.Errorf("want no latency for the primary without query, got %v", latency)
+	}
+}
@@ -0,0 +1,215 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// errLazyStmtClosed is returned by the lazy statements used after Close, like sql.Stmt
+var errLazyStmtClosed = errors.New("sql: statement is closed")
+
+// WithLazyPrepare makes Prepare and PrepareContext return at once, the statement is prepared on a node
+// the first time it runs there, and reused by the next executions on the node.
+// The statements used only for reads or writes aren't prepared on every node, and an unreachable replica
+// doesn't fail Prepare. The errors preparing the statement are returned by its executions.
+// The statements run on the nodes resolved like the queries of the resolver, the ones added later too.
+func WithLazyPrepare() OptionFunc {
+	return func(opt *Option) {
+		opt.LazyPrepare = true
+	}
+}
+
+// lazyStmt is a statement prepared on each node on its first execution there, see WithLazyPrepare
+type lazyStmt struct {
+	db        *sqlDB
+	query     string
+	writeFlag bool
+
+	// mu guards stmts and closed, it isn't held while preparing
+	mu     sync.Mutex
+	stmts  map[*sql.DB]*sql.Stmt
+	closed bool
+}
+
+func newLazyStmt(db *sqlDB, query string) *lazyStmt {
+	return &lazyStmt{
+		db:        db,
+		query:     query,
+		writeFlag: isReturning(query),
+		stmts:     map[*sql.DB]*sql.Stmt{},
+	}
+}
+
+// stmtFor returns the statement of the node, prepared on its first use
+func (s *lazyStmt) stmtFor(ctx context.Context, node *sql.DB) (*sql.Stmt, error) {
+	s.mu.Lock()
+	st, ok := s.stmts[node]
+	closed := s.closed
+	s.mu.Unlock()
+	if ok {
+		return st, nil
+	}
+	if closed {
+		return nil, errLazyStmtClosed
+	}
+
+	prepared, err := node.PrepareContext(ctx, s.query)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.stmts[node]; ok || s.closed {
+		// prepared concurrently, or closed meanwhile
+		_ = prepared.Close()
+		if s.closed {
+			return nil, errLazyStmtClosed
+		}
+		return st, nil
+	}
+	s.stmts[node] = prepared
+	return prepared, nil
+}
+
+// Close closes the statements prepared on the nodes concurrently
+func (s *lazyStmt) Close() error {
+	s.mu.Lock()
+	stmts := make([]*sql.Stmt, 0, len(s.stmts))
+	for _, st := range s.stmts {
+		stmts = append(stmts, st)
+	}
+	s.stmts, s.closed = map[*sql.DB]*sql.Stmt{}, true
+	s.mu.Unlock()
+
+	return doParallely(context.Background(), s.db.parallelism, len(stmts), func(i int) error {
+		return stmts[i].Close()
+	})
+}
+
+func (s *lazyStmt) Exec(args ...interface{}) (sql.Result, error) {
+	return s.ExecContext(context.Background(), args...)
+}
+
+// ExecContext executes the statement on a primary
+func (s *lazyStmt) ExecContext(ctx context.Context, args ...interface{}) (res sql.Result, err error) {
+	err = s.db.retry.do(ctx, true, func() error {
+		node := s.db.ReadWrite()
+		res, err = execWithHooks(ctx, s.db.hooks, primaryRoute.to(node), s.query, args, func(ctx context.Context) (sql.Result, error) {
+			st, err := s.stmtFor(ctx, node)
+			if err != nil {
+				return nil, err
+			}
+			return st.ExecContext(ctx, args...)
+		})
+		return err
+	})
+	return res, err
+}
+
+func (s *lazyStmt) Query(args ...interface{}) (*sql.Rows, error) {
+	return s.QueryContext(context.Background(), args...)
+}
+
+// QueryContext executes the statement on a replica, or a primary when the query returns the rows it writes
+func (s *lazyStmt) QueryContext(ctx context.Context, args ...interface{}) (rows *sql.Rows, err error) {
+	err = s.db.retry.do(ctx, s.writeFlag, func() error {
+		rows, err = s.routeQuery(ctx, args)
+		return err
+	})
+	return rows, err
+}
+
+// routeQuery routes the query, and falls back when a replica is unreachable, see WithReadFallback
+func (s *lazyStmt) routeQuery(ctx context.Context, args []interface{}) (*sql.Rows, error) {
+	node, route := s.db.ReadWrite(), primaryRoute
+	if !s.writeFlag {
+		node, route = s.db.readOnly(ctx)
+	}
+	query := func(node *sql.DB) func(ctx context.Context) (*sql.Rows, error) {
+		return func(ctx context.Context) (*sql.Rows, error) {
+			st, err := s.stmtFor(ctx, node)
+			if err != nil {
+				return nil, err
+			}
+			return st.QueryContext(ctx, args...)
+		}
+	}
+
+	rows, err := queryWithHooks(ctx, s.db.hooks, route.to(node), s.query, args, query(node))
+	if route.Role == RoleReplica && s.db.isConnectionError(err) {
+		if node, fallback, ok := s.db.fallback(ctx, node); ok {
+			rows, err = queryWithHooks(ctx, s.db.hooks, fallback.to(node), s.query, args, query(node))
+		}
+	}
+	return rows, err
+}
+
+func (s *lazyStmt) QueryRow(args ...interface{}) *sql.Row {
+	return s.QueryRowContext(context.Background(), args...)
+}
+
+// QueryRowContext executes the statement like QueryContext, the errors preparing the statement
+// are returned by the Scan of the row
+func (s *lazyStmt) QueryRowContext(ctx context.Context, args ...interface{}) (row *sql.Row) {
+	_ = s.db.retry.do(ctx, s.writeFlag, func() error {
+		row = s.routeQueryRow(ctx, args)
+		return row.Err()
+	})
+	return row
+}
+
+// routeQueryRow routes the query, and falls back when a replica is unreachable, see WithReadFallback
+func (s *lazyStmt) routeQueryRow(ctx context.Context, args []interface{}) *sql.Row {
+	node, route := s.db.ReadWrite(), primaryRoute
+	if !s.writeFlag {
+		node, route = s.db.readOnly(ctx)
+	}
+	queryRow := func(node *sql.DB) func(ctx context.Context) *sql.Row {
+		return func(ctx context.Context) *sql.Row {
+			st, err := s.stmtFor(ctx, node)
+			if err != nil {
+				return resultRow(ctx, s.db.results, nil, err)
+			}
+			return st.QueryRowContext(ctx, args...)
+		}
+	}
+
+	row := queryRowWithHooks(ctx, s.db.hooks, route.to(node), s.query, args, queryRow(node))
+	if route.Role == RoleReplica && s.db.isConnectionError(row.Err()) {
+		if node, fallback, ok := s.db.fallback(ctx, node); ok {
+			row = queryRowWithHooks(ctx, s.db.hooks, fallback.to(node), s.query, args, queryRow(node))
+		}
+	}
+	return row
+}
+
+// failedStmt is a statement which couldn't be prepared, its executions return the error
+type failedStmt struct {
+	err error
+	// results serves the row holding the error
+	results *sql.DB
+}
+
+func (s *failedStmt) Close() error { return nil }
+
+func (s *failedStmt) Exec(...interface{}) (sql.Result, error) { return nil, s.err }
+
+func (s *failedStmt) ExecContext(context.Context, ...interface{}) (sql.Result, error) {
+	return nil, s.err
+}
+
+func (s *failedStmt) Query(...interface{}) (*sql.Rows, error) { return nil, s.err }
+
+func (s *failedStmt) QueryContext(context.Context, ...interface{}) (*sql.Rows, error) {
+	return nil, s.err
+}
+
+func (s *failedStmt) QueryRow(...interface{}) *sql.Row {
+	return s.QueryRowContext(context.Background())
+}
+
+func (s *failedStmt) QueryRowContext(ctx context.Context, _ ...interface{}) *sql.Row {
+	return resultRow(ctx, s.results, nil, s.err)
+}
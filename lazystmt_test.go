@@ -0,0 +1,142 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLazyPrepare(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithLazyPrepare())
+
+	// nothing is prepared until the statement runs
+	query := "SELECT name FROM users WHERE id = ?"
+	st, err := resolver.Prepare(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// the reads prepare the statement on the replica once
+	prepared := replicaMock.ExpectPrepare(query)
+	prepared.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo"))
+	prepared.ExpectQuery().WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("bar"))
+	prepared.WillBeClosed()
+	var name string
+	if err := st.QueryRow(1).Scan(&name); err != nil || name != "foo" {
+		t.Fatalf("want foo, got %q, %v", name, err)
+	}
+	rows, err := st.Query(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("want nothing prepared on the primary, got %v", err)
+	}
+
+	// the writes prepare the statement on the primary
+	insert := "INSERT INTO users(name) VALUES (?)"
+	primaryMock.ExpectPrepare(insert).WillBeClosed().ExpectExec().WithArgs("baz").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	write, err := resolver.Prepare(insert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := write.Exec("baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := write.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := st.QueryRow(1).Scan(&name); err == nil {
+		t.Error("want the error of the closed statement")
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestLazyPrepareFallback(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithLazyPrepare())
+
+	// the unreachable replica doesn't fail Prepare, its reads fall back to the primary
+	query := "SELECT 1"
+	st, err := resolver.PrepareContext(context.Background(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicaMock.ExpectPrepare(query).WillReturnError(&net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	primaryMock.ExpectPrepare(query).ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	var one int
+	if err := st.QueryRowContext(context.Background()).Scan(&one); err != nil || one != 1 {
+		t.Fatalf("want the read on the primary, got %d, %v", one, err)
+	}
+
+	// the errors preparing the statement are returned by its executions
+	invalid, err := resolver.Prepare("SELEC 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	syntax := errors.New("syntax error")
+	primaryMock.ExpectPrepare("SELEC 1").WillReturnError(syntax)
+	if _, err := invalid.Exec(); !errors.Is(err, syntax) {
+		t.Errorf("want the prepare error, got %v", err)
+	}
+
+	// the transactions prepare the statement on their primary
+	update := "UPDATE users SET name = ?"
+	primaryMock.ExpectBegin()
+	// once on the primary, then on the connection of the transaction by database/sql
+	primaryMock.ExpectPrepare(update)
+	primaryMock.ExpectPrepare(update).ExpectExec().WithArgs("qux").WillReturnResult(sqlmock.NewResult(0, 2))
+	primaryMock.ExpectCommit()
+	lazy, err := resolver.Prepare(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := resolver.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Stmt(lazy).Exec("qux"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
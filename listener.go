@@ -0,0 +1,207 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// listenerRetryInterval is the delay between two failed subscriptions of a listener
+const listenerRetryInterval = time.Second
+
+// ErrNoNotificationWaiter is returned by DB.Listener when the resolver has no notification waiter
+var ErrNoNotificationWaiter = errors.New("dbresolver: no notification waiter, see WithNotificationWaiter")
+
+// Notification is a notification received by a Listener
+type Notification struct {
+	Channel string
+	Payload string
+	// Reconnected is set on the first notification after the listener subscribed again, on a new connection:
+	// the notifications sent while it was reconnecting are lost, eg. the state they signal must be read again.
+	// The notification has no payload.
+	Reconnected bool
+}
+
+// NotificationWaiter waits for the next notification on the driver connection of a primary, see sql.Conn.Raw.
+// It's specific to the driver, eg. pgxv5.WaitForNotification for the pgx stdlib driver.
+type NotificationWaiter func(ctx context.Context, driverConn any) (Notification, error)
+
+// WithNotificationWaiter sets how the listeners wait for the notifications, see DB.Listener
+func WithNotificationWaiter(waiter NotificationWaiter) OptionFunc {
+	return func(opt *Option) {
+		opt.NotificationWaiter = waiter
+	}
+}
+
+// Listener receives the notifications of a channel, eg. PostgreSQL LISTEN/NOTIFY, on a dedicated connection
+// to a primary. When the connection fails, eg. on a failover, the listener subscribes again on a primary
+// of the current topology. It's closed when the resolver is closed.
+type Listener struct {
+	db            *sqlDB
+	channel       string
+	waiter        NotificationWaiter
+	notifications chan Notification
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Listener subscribes to the channel on a dedicated connection to a primary, with the notification waiter
+// of the resolver. The context bounds the first subscription, the listener runs until it's closed.
+func (db *sqlDB) Listener(ctx context.Context, channel string) (*Listener, error) {
+	shard, err := db.shard(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db = shard
+	if db.notificationWaiter == nil {
+		return nil, ErrNoNotificationWaiter
+	}
+	l := &Listener{db: db, channel: channel, waiter: db.notificationWaiter,
+		notifications: make(chan Notification), done: make(chan struct{})}
+	conn, err := l.subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	db.listeners.add(l)
+	go l.run(runCtx, conn)
+	return l, nil
+}
+
+// Notifications returns the notifications of the channel, it's closed once the listener is closed
+func (l *Listener) Notifications() <-chan Notification {
+	return l.notifications
+}
+
+// Close unsubscribes, the connection of the listener is closed
+func (l *Listener) Close() error {
+	l.db.listeners.remove(l)
+	l.cancel()
+	<-l.done
+	return nil
+}
+
+// subscribe takes a connection to a primary, and listens to the channel on it
+func (l *Listener) subscribe(ctx context.Context) (*sql.Conn, error) {
+	conn, err := l.db.ReadWrite().Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "LISTEN "+quoteIdentifier(l.channel)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// run delivers the notifications, and subscribes again when the connection fails until the listener is closed
+func (l *Listener) run(ctx context.Context, conn *sql.Conn) {
+	defer close(l.done)
+	defer close(l.notifications)
+	for {
+		reconnected := l.wait(ctx, conn)
+		discard(conn)
+		if !reconnected {
+			return
+		}
+		for {
+			var err error
+			if conn, err = l.subscribe(ctx); err == nil {
+				break
+			}
+			timer := l.db.clock.NewTimer(listenerRetryInterval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+		if !l.deliver(ctx, Notification{Channel: l.channel, Reconnected: true}) {
+			discard(conn)
+			return
+		}
+	}
+}
+
+// wait delivers the notifications received on the connection, until the connection fails or the context is done.
+// It reports whether the listener must subscribe again.
+func (l *Listener) wait(ctx context.Context, conn *sql.Conn) bool {
+	for {
+		var notification Notification
+		err := conn.Raw(func(driverConn any) (err error) {
+			notification, err = l.waiter(ctx, driverConn)
+			return err
+		})
+		if ctx.Err() != nil {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+		if !l.deliver(ctx, notification) {
+			return false
+		}
+	}
+}
+
+// discard closes the connection without returning it to the pool, it's still listening
+func discard(conn *sql.Conn) {
+	_ = conn.Raw(func(any) error {
+		return driver.ErrBadConn
+	})
+	_ = conn.Close()
+}
+
+func (l *Listener) deliver(ctx context.Context, notification Notification) bool {
+	select {
+	case l.notifications <- notification:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// quoteIdentifier quotes the PostgreSQL identifier, eg. a channel name
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// listeners are the open listeners of a resolver, closed with the resolver
+type listeners struct {
+	mu  sync.Mutex
+	set map[*Listener]struct{}
+}
+
+func (ls *listeners) add(l *Listener) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.set == nil {
+		ls.set = make(map[*Listener]struct{})
+	}
+	ls.set[l] = struct{}{}
+}
+
+func (ls *listeners) remove(l *Listener) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	delete(ls.set, l)
+}
+
+// close closes every listener
+func (ls *listeners) close() {
+	ls.mu.Lock()
+	set := ls.set
+	ls.set = nil
+	ls.mu.Unlock()
+	for l := range set {
+		_ = l.Close()
+	}
+}
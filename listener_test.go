@@ -0,0 +1,116 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2/stubdriver"
+)
+
+type waitResult struct {
+	notification Notification
+	err          error
+}
+
+// channelWaiter returns the notification waiter receiving the results of the channel
+func channelWaiter(results chan waitResult) NotificationWaiter {
+	return func(ctx context.Context, _ any) (Notification, error) {
+		select {
+		case result := <-results:
+			return result.notification, result.err
+		case <-ctx.Done():
+			return Notification{}, ctx.Err()
+		}
+	}
+}
+
+func receive(t *testing.T, l *Listener) Notification {
+	t.Helper()
+	select {
+	case notification := <-l.Notifications():
+		return notification
+	case <-time.After(time.Second):
+		t.Fatal("no notification")
+		return Notification{}
+	}
+}
+
+func TestListener(t *testing.T) {
+	primary, err := sql.Open(stubdriver.DriverName, "listener-primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan waitResult)
+	resolver := New(WithPrimaryDBs(primary), WithNotificationWaiter(channelWaiter(results)))
+	defer resolver.Close()
+
+	l, err := resolver.Listener(context.Background(), "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results <- waitResult{notification: Notification{Channel: "events", Payload: "book:1"}}
+	if notification := receive(t, l); notification.Payload != "book:1" || notification.Reconnected {
+		t.Errorf("want the notification, got %+v", notification)
+	}
+
+	// the listener subscribes again when the connection fails
+	results <- waitResult{err: driver.ErrBadConn}
+	if notification := receive(t, l); !notification.Reconnected || notification.Channel != "events" {
+		t.Errorf("want the reconnection notified, got %+v", notification)
+	}
+	results <- waitResult{notification: Notification{Channel: "events", Payload: "book:2"}}
+	if notification := receive(t, l); notification.Payload != "book:2" {
+		t.Errorf("want the notification after the reconnection, got %+v", notification)
+	}
+	if inUse := primary.Stats().InUse; inUse != 1 {
+		t.Errorf("want a single dedicated connection, %d in use", inUse)
+	}
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-l.Notifications(); ok {
+		t.Error("want the notifications closed")
+	}
+	if stats := primary.Stats(); stats.InUse != 0 || stats.Idle != 0 {
+		t.Errorf("want the listening connection discarded, got %+v", stats)
+	}
+}
+
+func TestListenerClosedWithResolver(t *testing.T) {
+	primary, err := sql.Open(stubdriver.DriverName, "listener-primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := New(WithPrimaryDBs(primary), WithNotificationWaiter(channelWaiter(make(chan waitResult))))
+	l, err := resolver.Listener(context.Background(), "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resolver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-l.Notifications(); ok {
+		t.Error("want the listener closed with the resolver")
+	}
+}
+
+func TestListenerWithoutWaiter(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	if _, err := New(WithPrimaryDBs(primary)).Listener(context.Background(), "events"); !errors.Is(err, ErrNoNotificationWaiter) {
+		t.Errorf("want ErrNoNotificationWaiter, got %v", err)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if quoted := quoteIdentifier(`my"channel`); quoted != `"my""channel"` {
+		t.Errorf("got %s", quoted)
+	}
+}
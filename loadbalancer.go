@@ -2,14 +2,16 @@ package dbresolver
 
 import (
 	"database/sql"
+	"fmt"
 	"math/rand"
+	"slices"
 	"sync/atomic"
-	"time"
 )
 
-// DBConnection is the generic type for DB and Stmt operation
+// DBConnection is the generic type for DB and Stmt operation.
+// It's also satisfied by the connection pools of the driver specific resolvers, eg. *pgxpool.Pool
 type DBConnection interface {
-	*sql.DB | *sql.Stmt
+	comparable
 }
 
 // LoadBalancer define the load balancer contract
@@ -17,6 +19,39 @@ type LoadBalancer[T DBConnection] interface {
 	Resolve([]T) T
 	Name() LoadBalancerPolicy
 	predict(n int) int
+	// peek returns the index the next Resolve would return without moving the load balancer,
+	// -1 when it can't tell in advance
+	peek(n int) int
+}
+
+// NewLoadBalancer creates the load balancer for the given policy.
+// It panics if the policy is not supported.
+func NewLoadBalancer[T DBConnection](policy LoadBalancerPolicy) LoadBalancer[T] {
+	switch policy {
+	case RoundRobinLB:
+		return &RoundRobinLoadBalancer[T]{}
+	case RandomLB:
+		return &RandomLoadBalancer[T]{
+			randInt: make(chan int, 1),
+		}
+	case LeastConnectionsLB:
+		return &LeastConnectionsLoadBalancer[T]{}
+	case LatencyLB:
+		return NewLatencyLoadBalancer[T](LatencyBalancing{})
+	case AffinityLB:
+		return &AffinityLoadBalancer[T]{}
+	default:
+		panic(fmt.Sprintf("LoadBalancer: %s is not supported", policy))
+	}
+}
+
+// resolve returns the only connection without load balancing it,
+// the fast path of the topologies with a single primary and up to a single replica
+func resolve[T DBConnection](lb LoadBalancer[T], dbs []T) T {
+	if len(dbs) == 1 {
+		return dbs[0]
+	}
+	return lb.Resolve(dbs)
 }
 
 // RandomLoadBalancer represent for Random LB policy
@@ -30,33 +65,61 @@ func (lb RandomLoadBalancer[T]) Name() LoadBalancerPolicy {
 }
 
 // Resolve return the resolved option for Random LB.
-// Marked with go:nosplit to prevent preemption.
-//
-//go:nosplit
+// The load balancer is shared by the rotations of different lengths, eg. the primaries and the replicas,
+// so the index drawn by predict is only used when it's in range, and each call draws its own index otherwise.
 func (lb RandomLoadBalancer[T]) Resolve(dbs []T) T {
-	if len(lb.randInt) == 0 {
-		lb.predict(len(dbs))
+	if len(dbs) == 1 {
+		return dbs[0]
+	}
+	select {
+	case idx := <-lb.randInt:
+		if idx < len(dbs) {
+			return dbs[idx]
+		}
+	default:
 	}
-	randomInt := <-lb.randInt
-	return dbs[randomInt]
+	return dbs[rand.Intn(len(dbs))]
 }
 
+// predict draws the index of the next Resolve, replacing the index drawn before if any
 func (lb RandomLoadBalancer[T]) predict(n int) int {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	max := n - 1 //nolint
-	min := 0     //nolint
-	idx := r.Intn(max-min+1) + min
-	lb.randInt <- idx
-	return idx
+	if n <= 1 {
+		// a single db is resolved without drawing, like the resolver fast path
+		return 0
+	}
+	idx := rand.Intn(n)
+	if lb.randInt == nil {
+		return idx
+	}
+	for {
+		select {
+		case lb.randInt <- idx:
+			return idx
+		default:
+		}
+		select {
+		case <-lb.randInt:
+		default:
+		}
+	}
 }
 
-// RoundRobinLoadBalancer represent for RoundRobin LB policy
+func (lb RandomLoadBalancer[T]) peek(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	// the next index is random
+	return -1
+}
+
+// RoundRobinLoadBalancer represent for RoundRobin LB policy.
+// It must not be copied after first use.
 type RoundRobinLoadBalancer[T DBConnection] struct {
-	counter uint64 // Monotonically incrementing counter on every call
+	counter atomic.Uint64 // Monotonically incrementing counter on every call
 }
 
 // Name return the LB policy name
-func (lb RoundRobinLoadBalancer[T]) Name() LoadBalancerPolicy {
+func (lb *RoundRobinLoadBalancer[T]) Name() LoadBalancerPolicy {
 	return RoundRobinLB
 }
 
@@ -66,10 +129,103 @@ func (lb *RoundRobinLoadBalancer[T]) Resolve(dbs []T) T {
 	return dbs[idx]
 }
 
+func (lb *RoundRobinLoadBalancer[T]) peek(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return int((lb.counter.Load() + 1) % uint64(n))
+}
+
 func (lb *RoundRobinLoadBalancer[T]) predict(n int) int {
 	if n <= 1 {
 		return 0
 	}
-	// counter := lb.counter
-	return int(atomic.AddUint64(&lb.counter, 1) % uint64(n))
+	return int(lb.counter.Add(1) % uint64(n))
+}
+
+// statsProvider is satisfied by the connections exposing their pool stats, eg. *sql.DB
+type statsProvider interface {
+	Stats() sql.DBStats
+}
+
+// LeastConnectionsLoadBalancer represent for LeastConnections LB policy.
+// It resolves the connection with the fewest connections in use in its sql.DBStats,
+// the fewest waits for a connection breaking the ties, then round robin.
+// A connection repeated by weight has its connections in use divided by its weight.
+// The connections without stats, eg. the prepared statements, are resolved round robin.
+// It must not be copied after first use.
+type LeastConnectionsLoadBalancer[T DBConnection] struct {
+	roundRobin RoundRobinLoadBalancer[T]
+	// distinct caches the distinct connections of the last rotation with their weights
+	distinct atomic.Pointer[distinctNodes[T]]
+}
+
+// distinctNodes are the distinct connections of a rotation with their weights
+type distinctNodes[T DBConnection] struct {
+	rotation []T
+	nodes    []T
+	weights  []int
+}
+
+// Name return the LB policy name
+func (lb *LeastConnectionsLoadBalancer[T]) Name() LoadBalancerPolicy {
+	return LeastConnectionsLB
+}
+
+// Resolve return the resolved option for LeastConnections LB.
+// The stats of each distinct connection are read once.
+func (lb *LeastConnectionsLoadBalancer[T]) Resolve(dbs []T) T {
+	if len(dbs) <= 1 {
+		return dbs[lb.roundRobin.predict(len(dbs))]
+	}
+	if _, ok := any(dbs[0]).(statsProvider); !ok {
+		return dbs[lb.roundRobin.predict(len(dbs))]
+	}
+
+	distinct := lb.distinctNodes(dbs)
+	nodes, weights := distinct.nodes, distinct.weights
+	start := lb.roundRobin.predict(len(nodes))
+	best := -1
+	var bestStats sql.DBStats
+	for i := range nodes {
+		idx := (start + i) % len(nodes)
+		stats := any(nodes[idx]).(statsProvider).Stats()
+		if best == -1 || fewerConnections(stats, weights[idx], bestStats, weights[best]) {
+			best, bestStats = idx, stats
+		}
+	}
+	return nodes[best]
+}
+
+// distinctNodes returns the distinct connections of the rotation with their weights,
+// cached until the rotation changes
+func (lb *LeastConnectionsLoadBalancer[T]) distinctNodes(dbs []T) *distinctNodes[T] {
+	if cached := lb.distinct.Load(); cached != nil && slices.Equal(cached.rotation, dbs) {
+		return cached
+	}
+	nodes, weights := weightedNodes(dbs)
+	distinct := &distinctNodes[T]{rotation: slices.Clone(dbs), nodes: nodes, weights: weights}
+	lb.distinct.Store(distinct)
+	return distinct
+}
+
+// fewerConnections reports whether a has fewer connections in use than b relatively to their weights
+func fewerConnections(a sql.DBStats, aWeight int, b sql.DBStats, bWeight int) bool {
+	aInUse, bInUse := int64(a.InUse)*int64(bWeight), int64(b.InUse)*int64(aWeight)
+	if aInUse != bInUse {
+		return aInUse < bInUse
+	}
+	return a.WaitCount*int64(bWeight) < b.WaitCount*int64(aWeight)
+}
+
+func (lb *LeastConnectionsLoadBalancer[T]) peek(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	// the next index depends on the connections in use when resolving
+	return -1
+}
+
+func (lb *LeastConnectionsLoadBalancer[T]) predict(n int) int {
+	return lb.roundRobin.predict(n)
 }
@@ -1,7 +1,10 @@
 package dbresolver
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"testing/quick"
 )
@@ -26,3 +29,196 @@ func TestReplicaRoundRobin(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestRoundRobinZeroAllocations(t *testing.T) {
+	dbs := []*sql.DB{{}, {}, {}}
+	var lb LoadBalancer[*sql.DB] = &RoundRobinLoadBalancer[*sql.DB]{}
+	if allocs := testing.AllocsPerRun(100, func() { lb.Resolve(dbs) }); allocs != 0 {
+		t.Errorf("want no allocation resolving a db, got %v", allocs)
+	}
+
+	resolver := New(WithPrimaryDBs(&sql.DB{}), WithReplicaDBs(dbs...))
+	if allocs := testing.AllocsPerRun(100, func() { resolver.ReadOnly() }); allocs != 0 {
+		t.Errorf("want no allocation resolving a replica, got %v", allocs)
+	}
+}
+
+func TestRoundRobinConcurrently(t *testing.T) {
+	dbs := []*sql.DB{{}, {}, {}, {}}
+	lb := &RoundRobinLoadBalancer[*sql.DB]{}
+	var counts [4]atomic.Int32
+	err := doParallely(context.Background(), 0, 4, func(int) error {
+		for i := 0; i < 1000; i++ {
+			resolved := lb.Resolve(dbs)
+			for j, db := range dbs {
+				if db == resolved {
+					counts[j].Add(1)
+				}
+			}
+			if lb.Name() != RoundRobinLB {
+				return fmt.Errorf("unexpected name %s", lb.Name())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range counts {
+		if counts[i].Load() != 1000 {
+			t.Errorf("want each db resolved 1000 times, got %d for db %d", counts[i].Load(), i)
+		}
+	}
+}
+
+func TestRandomConcurrently(t *testing.T) {
+	primaries, replicas := []*sql.DB{{}, {}}, []*sql.DB{{}, {}, {}}
+	lb := NewLoadBalancer[*sql.DB](RandomLB)
+	// the rotations of different lengths share the load balancer, like the primaries and the replicas
+	err := doParallely(context.Background(), 0, 8, func(i int) error {
+		for j := 0; j < 1000; j++ {
+			if i%2 == 0 {
+				lb.predict(len(replicas))
+				lb.Resolve(replicas)
+			} else {
+				lb.Resolve(primaries)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if idx := lb.predict(len(replicas)); lb.Resolve(replicas) != replicas[idx] {
+		t.Error("want the predicted replica resolved")
+	}
+}
+
+func TestNewLoadBalancer(t *testing.T) {
+	type pool struct{ name string }
+	pools := []*pool{{"p1"}, {"p2"}}
+
+	for _, policy := range []LoadBalancerPolicy{RoundRobinLB, RandomLB, LeastConnectionsLB, AffinityLB} {
+		lb := NewLoadBalancer[*pool](policy)
+		if lb.Name() != policy {
+			t.Errorf("want %v, got %v", policy, lb.Name())
+		}
+		if got := lb.Resolve(pools); got != pools[0] && got != pools[1] {
+			t.Errorf("resolved unknown pool %v", got)
+		}
+	}
+}
+
+type countingLoadBalancer struct {
+	RoundRobinLoadBalancer[*sql.DB]
+	calls int
+}
+
+func (lb *countingLoadBalancer) Resolve(dbs []*sql.DB) *sql.DB {
+	lb.calls++
+	return lb.RoundRobinLoadBalancer.Resolve(dbs)
+}
+
+func TestSingleNodeFastPath(t *testing.T) {
+	primary, replica := &sql.DB{}, &sql.DB{}
+	lb := &countingLoadBalancer{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), func(opt *Option) {
+		opt.DBLB = lb
+	})
+
+	if resolver.ReadWrite() != primary || resolver.ReadOnly() != replica {
+		t.Error("want the only primary and replica")
+	}
+	if lb.calls != 0 {
+		t.Errorf("want the load balancer skipped, got %d calls", lb.calls)
+	}
+
+	resolver.AddReplica(&sql.DB{})
+	resolver.ReadOnly()
+	if lb.calls != 1 {
+		t.Errorf("want the replicas load balanced, got %d calls", lb.calls)
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	dbs := make([]*sql.DB, 3)
+	for i := range dbs {
+		db, _, err := createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+		dbs[i] = db
+	}
+	lb := NewLoadBalancer[*sql.DB](LeastConnectionsLB)
+
+	// the ties are resolved round robin
+	counts := map[*sql.DB]int{}
+	for i := 0; i < 30; i++ {
+		counts[lb.Resolve(dbs)]++
+	}
+	for i, db := range dbs {
+		if counts[db] != 10 {
+			t.Errorf("want the idle db %d resolved 10 times, got %d", i, counts[db])
+		}
+	}
+
+	// the busy dbs are skipped
+	for _, db := range dbs[:2] {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+	}
+	for i := 0; i < 10; i++ {
+		if lb.Resolve(dbs) != dbs[2] {
+			t.Fatal("want the db with the fewest connections in use")
+		}
+	}
+
+	// the connections in use are divided by the weight
+	rotation := []*sql.DB{dbs[0], dbs[0], dbs[1], dbs[2]}
+	conn, err := dbs[2].Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for i := 0; i < 10; i++ {
+		if lb.Resolve(rotation) != dbs[0] {
+			t.Fatal("want the weighted db preferred at the same connections in use")
+		}
+	}
+
+	// the statements have no stats and are resolved round robin
+	stmts := []*sql.Stmt{{}, {}}
+	stmtLB := NewLoadBalancer[*sql.Stmt](LeastConnectionsLB)
+	if stmtLB.Resolve(stmts) == stmtLB.Resolve(stmts) {
+		t.Error("want the statements resolved round robin")
+	}
+}
+
+func TestLeastConnectionsStats(t *testing.T) {
+	replicas := []*countingStats{{}, {}}
+	rotation := weightedRotation(replicas, func(i int) int { return []int{MaxNodeWeight, 1}[i] })
+	lb := NewLoadBalancer[*countingStats](LeastConnectionsLB)
+	lb.Resolve(rotation)
+	for i, replica := range replicas {
+		if replica.calls != 1 {
+			t.Errorf("want the stats of the replica %d read once, got %d", i, replica.calls)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { lb.Resolve(rotation) }); allocs != 0 {
+		t.Errorf("want the distinct replicas of the rotation cached, got %v allocations", allocs)
+	}
+}
+
+// countingStats counts the reads of its stats
+type countingStats struct {
+	calls int
+}
+
+func (s *countingStats) Stats() sql.DBStats {
+	s.calls++
+	return sql.DBStats{}
+}
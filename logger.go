@@ -0,0 +1,162 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+)
+
+// LogLevel is the level of a log entry of the resolver
+type LogLevel int
+
+// Levels of the log entries, they match the slog levels
+const (
+	LogLevelDebug LogLevel = LogLevel(slog.LevelDebug)
+	LogLevelInfo  LogLevel = LogLevel(slog.LevelInfo)
+	LogLevelWarn  LogLevel = LogLevel(slog.LevelWarn)
+	LogLevelError LogLevel = LogLevel(slog.LevelError)
+)
+
+// String returns the name of the level
+func (l LogLevel) String() string {
+	return slog.Level(l).String()
+}
+
+// LogField is a structured field of a log entry
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Fields of the log entries
+const (
+	LogFieldRole         = "role"
+	LogFieldNode         = "node"
+	LogFieldName         = "name"
+	LogFieldFallback     = "fallback"
+	LogFieldPrepare      = "prepare"
+	LogFieldQuery        = "query"
+	LogFieldQueryDigest  = "query_digest"
+	LogFieldLatency      = "latency"
+	LogFieldError        = "error"
+	LogFieldCircuitState = "circuit_state"
+)
+
+// Logger logs the entries of the resolver, see WithLogger.
+// The zapresolver and logrusresolver modules adapt zap and logrus, NewSlogLogger adapts slog.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, fields ...LogField)
+}
+
+// WithLogger logs the queries of the resolver, its transactions, connections and statements at the debug level,
+// the failed ones at the error level, with the role and the index of their node, the digest of the query
+// (not the query itself nor its args, they can hold personal data), the latency and the error.
+// It logs the nodes going out of rotation and back too, with the health checks and the circuit breakers.
+func WithLogger(logger Logger) OptionFunc {
+	return func(opt *Option) {
+		opt.Logger = logger
+	}
+}
+
+// slogLogger adapts a *slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger adapts the slog logger, slog.Default() when nil
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return slogLogger{logger: logger}
+}
+
+func (l slogLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+	if !l.logger.Enabled(ctx, slog.Level(level)) {
+		return
+	}
+	attrs := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		attrs[i] = slog.Any(field.Key, field.Value)
+	}
+	l.logger.LogAttrs(ctx, slog.Level(level), msg, attrs...)
+}
+
+// QueryDigest returns the digest of the query logged by the resolver, the FNV-1a hash of the query in hexadecimal,
+// so the log entries of a query can be found without logging the query
+func QueryDigest(query string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(query))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// logQueryHook logs the queries of the resolver
+type logQueryHook struct {
+	logger Logger
+}
+
+func (h logQueryHook) BeforeQuery(ctx context.Context, _ *QueryEvent) context.Context {
+	return ctx
+}
+
+func (h logQueryHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	level, msg := LogLevelDebug, "dbresolver: query"
+	if event.Err != nil {
+		level, msg = LogLevelError, "dbresolver: query failed"
+	}
+	fields := make([]LogField, 0, 7)
+	if event.Prepare {
+		fields = append(fields, LogField{LogFieldPrepare, true})
+	} else {
+		fields = append(fields, LogField{LogFieldRole, string(event.Route.Role)},
+			LogField{LogFieldFallback, event.Route.Fallback})
+	}
+	if event.Index >= 0 {
+		fields = append(fields, LogField{LogFieldNode, event.Index})
+	}
+	if event.Name != "" {
+		fields = append(fields, LogField{LogFieldName, event.Name})
+	}
+	fields = append(fields, LogField{LogFieldQueryDigest, QueryDigest(event.Query)},
+		LogField{LogFieldLatency, event.Duration})
+	if event.Err != nil {
+		fields = append(fields, LogField{LogFieldError, event.Err.Error()})
+	}
+	h.logger.Log(ctx, level, msg, fields...)
+}
+
+// log logs the entry with the logger of the resolver, if any
+func (db *sqlDB) log(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+	if db.logger != nil {
+		db.logger.Log(ctx, level, msg, fields...)
+	}
+}
+
+// nodeFields returns the role, the index and the name of the node
+func (db *sqlDB) nodeFields(node *sql.DB) []LogField {
+	role, index := db.locate(node)
+	fields := []LogField{{LogFieldRole, string(role)}, {LogFieldNode, index}}
+	if name := db.nodeName(node); name != "" {
+		fields = append(fields, LogField{LogFieldName, name})
+	}
+	return fields
+}
+
+// logCircuitChange logs the state changes of the circuits, then calls next if any
+func (db *sqlDB) logCircuitChange(next func(node *sql.DB, from, to CircuitState)) func(node *sql.DB, from, to CircuitState) {
+	return func(node *sql.DB, from, to CircuitState) {
+		level, msg := LogLevelInfo, "dbresolver: circuit closed, node back in rotation"
+		switch to {
+		case CircuitOpen:
+			level, msg = LogLevelWarn, "dbresolver: circuit open, node out of rotation"
+		case CircuitHalfOpen:
+			msg = "dbresolver: circuit half-open"
+		}
+		db.log(context.Background(), level, msg, append(db.nodeFields(node), LogField{LogFieldCircuitState, string(to)})...)
+		if next != nil {
+			next(node, from, to)
+		}
+	}
+}
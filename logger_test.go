@@ -0,0 +1,73 @@
+package dbresolver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recordingLogger records the log entries
+type recordingLogger struct {
+	entries []string
+}
+
+func (l *recordingLogger) Log(_ context.Context, level LogLevel, msg string, fields ...LogField) {
+	l.entries = append(l.entries, fmt.Sprintf("%s %s %v", level, msg, fields))
+}
+
+func TestLogger(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	now := time.Now()
+	logger := &recordingLogger{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithLogger(logger),
+		WithClock(stubClock{now: &now}), WithCircuitBreaker(CircuitBreaker{FailureThreshold: 1}),
+		WithNodeLabels(replica, map[string]string{NodeNameLabel: "replica-a"})).(*sqlDB)
+
+	replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(errors.New("deadlock"))
+	rows, err := resolver.Query("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := resolver.Exec("DELETE FROM users"); err == nil {
+		t.Fatal("want the error of the query")
+	}
+	resolver.breaker.record(replica, &net.OpError{Op: "read", Err: errors.New("reset")})
+
+	want := []string{
+		fmt.Sprintf("DEBUG dbresolver: query [{role replica} {fallback false} {node 0} {name replica-a} {query_digest %s} {latency 0s}]",
+			QueryDigest("SELECT 1")),
+		fmt.Sprintf("ERROR dbresolver: query failed [{role primary} {fallback false} {node 0} {query_digest %s} {latency 0s} {error deadlock}]",
+			QueryDigest("DELETE FROM users")),
+		"WARN dbresolver: circuit open, node out of rotation [{role replica} {node 0} {name replica-a} {circuit_state open}]",
+	}
+	if fmt.Sprint(logger.entries) != fmt.Sprint(want) {
+		t.Errorf("want the entries\n%q, got\n%q", want, logger.entries)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	logger.Log(context.Background(), LogLevelDebug, "skipped")
+	logger.Log(context.Background(), LogLevelWarn, "node unhealthy", LogField{LogFieldRole, "replica"}, LogField{LogFieldNode, 1})
+	if got := buf.String(); strings.Contains(got, "skipped") || !strings.Contains(got, `level=WARN msg="node unhealthy" role=replica node=1`) {
+		t.Errorf("want the warning logged with its fields, got %q", got)
+	}
+}
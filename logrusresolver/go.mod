@@ -0,0 +1,13 @@
+module github.com/bxcodec/dbresolver/v2/logrusresolver
+
+go 1.22
+
+require (
+	github.com/bxcodec/dbresolver/v2 v2.2.1
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require (
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+)
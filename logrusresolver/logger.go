@@ -0,0 +1,42 @@
+// Package logrusresolver logs the queries and the rotation changes of the resolver with logrus.
+package logrusresolver
+
+import (
+	"context"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// Logger adapts a logrus.FieldLogger to dbresolver.Logger
+type Logger struct {
+	logger logrus.FieldLogger
+}
+
+var _ dbresolver.Logger = (*Logger)(nil)
+
+// New adapts the logrus logger or entry, attached to the resolver with dbresolver.WithLogger:
+//
+//	db := dbresolver.New(dbresolver.WithPrimaryDBs(primary), dbresolver.WithLogger(logrusresolver.New(logrus.StandardLogger())))
+func New(logger logrus.FieldLogger) *Logger {
+	return &Logger{logger: logger}
+}
+
+// Log logs the entry at the logrus level of the resolver level
+func (l *Logger) Log(ctx context.Context, level dbresolver.LogLevel, msg string, fields ...dbresolver.LogField) {
+	logrusFields := make(logrus.Fields, len(fields))
+	for _, field := range fields {
+		logrusFields[field.Key] = field.Value
+	}
+	entry := l.logger.WithFields(logrusFields).WithContext(ctx)
+	switch {
+	case level >= dbresolver.LogLevelError:
+		entry.Error(msg)
+	case level >= dbresolver.LogLevelWarn:
+		entry.Warn(msg)
+	case level >= dbresolver.LogLevelInfo:
+		entry.Info(msg)
+	default:
+		entry.Debug(msg)
+	}
+}
@@ -0,0 +1,33 @@
+package logrusresolver_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/logrusresolver"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogger(t *testing.T) {
+	logrusLogger, hook := test.NewNullLogger()
+	logrusLogger.SetLevel(logrus.InfoLevel)
+	logger := logrusresolver.New(logrusLogger)
+
+	logger.Log(context.Background(), dbresolver.LogLevelDebug, "dbresolver: query")
+	logger.Log(context.Background(), dbresolver.LogLevelWarn, "dbresolver: node unhealthy, out of rotation",
+		dbresolver.LogField{Key: dbresolver.LogFieldRole, Value: "replica"},
+		dbresolver.LogField{Key: dbresolver.LogFieldNode, Value: 1})
+
+	if len(hook.AllEntries()) != 1 {
+		t.Fatalf("want the entries above the level only, got %v", hook.AllEntries())
+	}
+	entry := hook.LastEntry()
+	if entry.Level != logrus.WarnLevel || entry.Message != "dbresolver: node unhealthy, out of rotation" {
+		t.Errorf("want the unhealthy node at the warn level, got %v", entry)
+	}
+	if entry.Data[dbresolver.LogFieldRole] != "replica" || entry.Data[dbresolver.LogFieldNode] != 1 {
+		t.Errorf("want the fields of the entry, got %v", entry.Data)
+	}
+}
@@ -0,0 +1,9 @@
+// Package mocks provides the gomock mocks of the dbresolver interfaces,
+// to unit test the applications depending on dbresolver.DB without a database.
+//
+//	ctrl := gomock.NewController(t)
+//	db := mocks.NewMockDB(ctrl)
+//	db.EXPECT().ExecContext(gomock.Any(), "DELETE FROM book WHERE id = $1", 1).Return(sqlmock.NewResult(0, 1), nil)
+package mocks
+
+//go:generate go run go.uber.org/mock/mockgen -write_package_comment=false -destination=mocks.go -package=mocks github.com/bxcodec/dbresolver/v2 DB,Tx,Stmt,Conn,QueryTypeChecker,Discoverer,CredentialsProvider,Hooks,OnErrorer,ReadOnlyDetector,Redactor
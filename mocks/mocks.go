@@ -0,0 +1,1637 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/bxcodec/dbresolver/v2 (interfaces: DB,Tx,Stmt,Conn,QueryTypeChecker,Discoverer,CredentialsProvider,Hooks,OnErrorer,ReadOnlyDetector,Redactor)
+//
+// Generated by this command:
+//
+//	mockgen -write_package_comment=false -destination=mocks.go -package=mocks github.com/bxcodec/dbresolver/v2 DB,Tx,Stmt,Conn,QueryTypeChecker,Discoverer,CredentialsProvider,Hooks,OnErrorer,ReadOnlyDetector,Redactor
+package mocks
+
+import (
+	context "context"
+	sql "database/sql"
+	driver "database/sql/driver"
+	reflect "reflect"
+	time "time"
+
+	dbresolver "github.com/bxcodec/dbresolver/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDB is a mock of DB interface.
+type MockDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockDBMockRecorder
+}
+
+// MockDBMockRecorder is the mock recorder for MockDB.
+type MockDBMockRecorder struct {
+	mock *MockDB
+}
+
+// NewMockDB creates a new mock instance.
+func NewMockDB(ctrl *gomock.Controller) *MockDB {
+	mock := &MockDB{ctrl: ctrl}
+	mock.recorder = &MockDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDB) EXPECT() *MockDBMockRecorder {
+	return m.recorder
+}
+
+// AddPrimary mocks base method.
+func (m *MockDB) AddPrimary(arg0 *sql.DB) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddPrimary", arg0)
+}
+
+// AddPrimary indicates an expected call of AddPrimary.
+func (mr *MockDBMockRecorder) AddPrimary(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPrimary", reflect.TypeOf((*MockDB)(nil).AddPrimary), arg0)
+}
+
+// AddReplica mocks base method.
+func (m *MockDB) AddReplica(arg0 *sql.DB) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddReplica", arg0)
+}
+
+// AddReplica indicates an expected call of AddReplica.
+func (mr *MockDBMockRecorder) AddReplica(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReplica", reflect.TypeOf((*MockDB)(nil).AddReplica), arg0)
+}
+
+// ApplyTopology mocks base method.
+func (m *MockDB) ApplyTopology(arg0 context.Context, arg1 dbresolver.Topology) ([]dbresolver.TopologyChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyTopology", arg0, arg1)
+	ret0, _ := ret[0].([]dbresolver.TopologyChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyTopology indicates an expected call of ApplyTopology.
+func (mr *MockDBMockRecorder) ApplyTopology(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyTopology", reflect.TypeOf((*MockDB)(nil).ApplyTopology), arg0, arg1)
+}
+
+// Begin mocks base method.
+func (m *MockDB) Begin() (dbresolver.Tx, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin")
+	ret0, _ := ret[0].(dbresolver.Tx)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockDBMockRecorder) Begin() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockDB)(nil).Begin))
+}
+
+// BeginTx mocks base method.
+func (m *MockDB) BeginTx(arg0 context.Context, arg1 *sql.TxOptions) (dbresolver.Tx, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginTx", arg0, arg1)
+	ret0, _ := ret[0].(dbresolver.Tx)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginTx indicates an expected call of BeginTx.
+func (mr *MockDBMockRecorder) BeginTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTx", reflect.TypeOf((*MockDB)(nil).BeginTx), arg0, arg1)
+}
+
+// BulkInsert mocks base method.
+func (m *MockDB) BulkInsert(arg0 context.Context, arg1 string, arg2 []string, arg3 [][]any) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkInsert", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkInsert indicates an expected call of BulkInsert.
+func (mr *MockDBMockRecorder) BulkInsert(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkInsert", reflect.TypeOf((*MockDB)(nil).BulkInsert), arg0, arg1, arg2, arg3)
+}
+
+// Close mocks base method.
+func (m *MockDB) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockDBMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockDB)(nil).Close))
+}
+
+// Conn mocks base method.
+func (m *MockDB) Conn(arg0 context.Context) (dbresolver.Conn, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Conn", arg0)
+	ret0, _ := ret[0].(dbresolver.Conn)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Conn indicates an expected call of Conn.
+func (mr *MockDBMockRecorder) Conn(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Conn", reflect.TypeOf((*MockDB)(nil).Conn), arg0)
+}
+
+// DiffTopology mocks base method.
+func (m *MockDB) DiffTopology(arg0 dbresolver.Topology) ([]dbresolver.TopologyChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiffTopology", arg0)
+	ret0, _ := ret[0].([]dbresolver.TopologyChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiffTopology indicates an expected call of DiffTopology.
+func (mr *MockDBMockRecorder) DiffTopology(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiffTopology", reflect.TypeOf((*MockDB)(nil).DiffTopology), arg0)
+}
+
+// Driver mocks base method.
+func (m *MockDB) Driver() driver.Driver {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Driver")
+	ret0, _ := ret[0].(driver.Driver)
+	return ret0
+}
+
+// Driver indicates an expected call of Driver.
+func (mr *MockDBMockRecorder) Driver() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Driver", reflect.TypeOf((*MockDB)(nil).Driver))
+}
+
+// Exec mocks base method.
+func (m *MockDB) Exec(arg0 string, arg1 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Exec", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exec indicates an expected call of Exec.
+func (mr *MockDBMockRecorder) Exec(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockDB)(nil).Exec), varargs...)
+}
+
+// ExecAllPrimaries mocks base method.
+func (m *MockDB) ExecAllPrimaries(arg0 context.Context, arg1 *dbresolver.ExecAllOptions, arg2 string, arg3 ...any) (*dbresolver.ExecAllResult, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1, arg2}
+	for _, a := range arg3 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecAllPrimaries", varargs...)
+	ret0, _ := ret[0].(*dbresolver.ExecAllResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecAllPrimaries indicates an expected call of ExecAllPrimaries.
+func (mr *MockDBMockRecorder) ExecAllPrimaries(arg0, arg1, arg2 any, arg3 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1, arg2}, arg3...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecAllPrimaries", reflect.TypeOf((*MockDB)(nil).ExecAllPrimaries), varargs...)
+}
+
+// ExecContext mocks base method.
+func (m *MockDB) ExecContext(arg0 context.Context, arg1 string, arg2 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecContext", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecContext indicates an expected call of ExecContext.
+func (mr *MockDBMockRecorder) ExecContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*MockDB)(nil).ExecContext), varargs...)
+}
+
+// ExplainRoute mocks base method.
+func (m *MockDB) ExplainRoute(arg0 context.Context, arg1 string, arg2 ...any) (dbresolver.RouteDecision, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExplainRoute", varargs...)
+	ret0, _ := ret[0].(dbresolver.RouteDecision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExplainRoute indicates an expected call of ExplainRoute.
+func (mr *MockDBMockRecorder) ExplainRoute(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExplainRoute", reflect.TypeOf((*MockDB)(nil).ExplainRoute), varargs...)
+}
+
+// Listener mocks base method.
+func (m *MockDB) Listener(arg0 context.Context, arg1 string) (*dbresolver.Listener, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Listener", arg0, arg1)
+	ret0, _ := ret[0].(*dbresolver.Listener)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Listener indicates an expected call of Listener.
+func (mr *MockDBMockRecorder) Listener(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Listener", reflect.TypeOf((*MockDB)(nil).Listener), arg0, arg1)
+}
+
+// Nodes mocks base method.
+func (m *MockDB) Nodes() []dbresolver.NodeInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Nodes")
+	ret0, _ := ret[0].([]dbresolver.NodeInfo)
+	return ret0
+}
+
+// Nodes indicates an expected call of Nodes.
+func (mr *MockDBMockRecorder) Nodes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Nodes", reflect.TypeOf((*MockDB)(nil).Nodes))
+}
+
+// Ping mocks base method.
+func (m *MockDB) Ping() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockDBMockRecorder) Ping() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockDB)(nil).Ping))
+}
+
+// PingContext mocks base method.
+func (m *MockDB) PingContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PingContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PingContext indicates an expected call of PingContext.
+func (mr *MockDBMockRecorder) PingContext(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PingContext", reflect.TypeOf((*MockDB)(nil).PingContext), arg0)
+}
+
+// Prepare mocks base method.
+func (m *MockDB) Prepare(arg0 string) (dbresolver.Stmt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Prepare", arg0)
+	ret0, _ := ret[0].(dbresolver.Stmt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Prepare indicates an expected call of Prepare.
+func (mr *MockDBMockRecorder) Prepare(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prepare", reflect.TypeOf((*MockDB)(nil).Prepare), arg0)
+}
+
+// PrepareAsync mocks base method.
+func (m *MockDB) PrepareAsync(arg0 context.Context, arg1 string) (dbresolver.AsyncStmt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrepareAsync", arg0, arg1)
+	ret0, _ := ret[0].(dbresolver.AsyncStmt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PrepareAsync indicates an expected call of PrepareAsync.
+func (mr *MockDBMockRecorder) PrepareAsync(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareAsync", reflect.TypeOf((*MockDB)(nil).PrepareAsync), arg0, arg1)
+}
+
+// PrepareContext mocks base method.
+func (m *MockDB) PrepareContext(arg0 context.Context, arg1 string) (dbresolver.Stmt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrepareContext", arg0, arg1)
+	ret0, _ := ret[0].(dbresolver.Stmt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PrepareContext indicates an expected call of PrepareContext.
+func (mr *MockDBMockRecorder) PrepareContext(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareContext", reflect.TypeOf((*MockDB)(nil).PrepareContext), arg0, arg1)
+}
+
+// Prewarm mocks base method.
+func (m *MockDB) Prewarm(arg0 context.Context, arg1 []string, arg2 ...dbresolver.NodeFilter) error {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Prewarm", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Prewarm indicates an expected call of Prewarm.
+func (mr *MockDBMockRecorder) Prewarm(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prewarm", reflect.TypeOf((*MockDB)(nil).Prewarm), varargs...)
+}
+
+// PrimaryConn mocks base method.
+func (m *MockDB) PrimaryConn(arg0 context.Context) (dbresolver.Conn, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrimaryConn", arg0)
+	ret0, _ := ret[0].(dbresolver.Conn)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PrimaryConn indicates an expected call of PrimaryConn.
+func (mr *MockDBMockRecorder) PrimaryConn(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrimaryConn", reflect.TypeOf((*MockDB)(nil).PrimaryConn), arg0)
+}
+
+// PrimaryDBs mocks base method.
+func (m *MockDB) PrimaryDBs() []*sql.DB {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrimaryDBs")
+	ret0, _ := ret[0].([]*sql.DB)
+	return ret0
+}
+
+// PrimaryDBs indicates an expected call of PrimaryDBs.
+func (mr *MockDBMockRecorder) PrimaryDBs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrimaryDBs", reflect.TypeOf((*MockDB)(nil).PrimaryDBs))
+}
+
+// Query mocks base method.
+func (m *MockDB) Query(arg0 string, arg1 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Query", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockDBMockRecorder) Query(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockDB)(nil).Query), varargs...)
+}
+
+// QueryContext mocks base method.
+func (m *MockDB) QueryContext(arg0 context.Context, arg1 string, arg2 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryContext", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryContext indicates an expected call of QueryContext.
+func (mr *MockDBMockRecorder) QueryContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*MockDB)(nil).QueryContext), varargs...)
+}
+
+// QueryRow mocks base method.
+func (m *MockDB) QueryRow(arg0 string, arg1 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRow", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRow indicates an expected call of QueryRow.
+func (mr *MockDBMockRecorder) QueryRow(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRow", reflect.TypeOf((*MockDB)(nil).QueryRow), varargs...)
+}
+
+// QueryRowContext mocks base method.
+func (m *MockDB) QueryRowContext(arg0 context.Context, arg1 string, arg2 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRowContext", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRowContext indicates an expected call of QueryRowContext.
+func (mr *MockDBMockRecorder) QueryRowContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*MockDB)(nil).QueryRowContext), varargs...)
+}
+
+// ReadOnly mocks base method.
+func (m *MockDB) ReadOnly() *sql.DB {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadOnly")
+	ret0, _ := ret[0].(*sql.DB)
+	return ret0
+}
+
+// ReadOnly indicates an expected call of ReadOnly.
+func (mr *MockDBMockRecorder) ReadOnly() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadOnly", reflect.TypeOf((*MockDB)(nil).ReadOnly))
+}
+
+// ReadWrite mocks base method.
+func (m *MockDB) ReadWrite() *sql.DB {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadWrite")
+	ret0, _ := ret[0].(*sql.DB)
+	return ret0
+}
+
+// ReadWrite indicates an expected call of ReadWrite.
+func (mr *MockDBMockRecorder) ReadWrite() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadWrite", reflect.TypeOf((*MockDB)(nil).ReadWrite))
+}
+
+// RemovePrimary mocks base method.
+func (m *MockDB) RemovePrimary(arg0 *sql.DB) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePrimary", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePrimary indicates an expected call of RemovePrimary.
+func (mr *MockDBMockRecorder) RemovePrimary(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePrimary", reflect.TypeOf((*MockDB)(nil).RemovePrimary), arg0)
+}
+
+// RemoveReplica mocks base method.
+func (m *MockDB) RemoveReplica(arg0 *sql.DB) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RemoveReplica", arg0)
+}
+
+// RemoveReplica indicates an expected call of RemoveReplica.
+func (mr *MockDBMockRecorder) RemoveReplica(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveReplica", reflect.TypeOf((*MockDB)(nil).RemoveReplica), arg0)
+}
+
+// Repoll mocks base method.
+func (m *MockDB) Repoll(arg0 context.Context) ([]dbresolver.TopologyChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Repoll", arg0)
+	ret0, _ := ret[0].([]dbresolver.TopologyChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Repoll indicates an expected call of Repoll.
+func (mr *MockDBMockRecorder) Repoll(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Repoll", reflect.TypeOf((*MockDB)(nil).Repoll), arg0)
+}
+
+// ReplicaConn mocks base method.
+func (m *MockDB) ReplicaConn(arg0 context.Context) (dbresolver.Conn, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplicaConn", arg0)
+	ret0, _ := ret[0].(dbresolver.Conn)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplicaConn indicates an expected call of ReplicaConn.
+func (mr *MockDBMockRecorder) ReplicaConn(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplicaConn", reflect.TypeOf((*MockDB)(nil).ReplicaConn), arg0)
+}
+
+// ReplicaDBs mocks base method.
+func (m *MockDB) ReplicaDBs() []*sql.DB {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplicaDBs")
+	ret0, _ := ret[0].([]*sql.DB)
+	return ret0
+}
+
+// ReplicaDBs indicates an expected call of ReplicaDBs.
+func (mr *MockDBMockRecorder) ReplicaDBs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplicaDBs", reflect.TypeOf((*MockDB)(nil).ReplicaDBs))
+}
+
+// ResolveRead mocks base method.
+func (m *MockDB) ResolveRead(arg0 context.Context) (dbresolver.NodeInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveRead", arg0)
+	ret0, _ := ret[0].(dbresolver.NodeInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveRead indicates an expected call of ResolveRead.
+func (mr *MockDBMockRecorder) ResolveRead(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveRead", reflect.TypeOf((*MockDB)(nil).ResolveRead), arg0)
+}
+
+// ResolveWrite mocks base method.
+func (m *MockDB) ResolveWrite(arg0 context.Context) (dbresolver.NodeInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveWrite", arg0)
+	ret0, _ := ret[0].(dbresolver.NodeInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveWrite indicates an expected call of ResolveWrite.
+func (mr *MockDBMockRecorder) ResolveWrite(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveWrite", reflect.TypeOf((*MockDB)(nil).ResolveWrite), arg0)
+}
+
+// SetConnMaxIdleTime mocks base method.
+func (m *MockDB) SetConnMaxIdleTime(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetConnMaxIdleTime", arg0)
+}
+
+// SetConnMaxIdleTime indicates an expected call of SetConnMaxIdleTime.
+func (mr *MockDBMockRecorder) SetConnMaxIdleTime(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConnMaxIdleTime", reflect.TypeOf((*MockDB)(nil).SetConnMaxIdleTime), arg0)
+}
+
+// SetConnMaxLifetime mocks base method.
+func (m *MockDB) SetConnMaxLifetime(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetConnMaxLifetime", arg0)
+}
+
+// SetConnMaxLifetime indicates an expected call of SetConnMaxLifetime.
+func (mr *MockDBMockRecorder) SetConnMaxLifetime(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetConnMaxLifetime", reflect.TypeOf((*MockDB)(nil).SetConnMaxLifetime), arg0)
+}
+
+// SetMaxIdleConns mocks base method.
+func (m *MockDB) SetMaxIdleConns(arg0 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxIdleConns", arg0)
+}
+
+// SetMaxIdleConns indicates an expected call of SetMaxIdleConns.
+func (mr *MockDBMockRecorder) SetMaxIdleConns(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIdleConns", reflect.TypeOf((*MockDB)(nil).SetMaxIdleConns), arg0)
+}
+
+// SetMaxOpenConns mocks base method.
+func (m *MockDB) SetMaxOpenConns(arg0 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxOpenConns", arg0)
+}
+
+// SetMaxOpenConns indicates an expected call of SetMaxOpenConns.
+func (mr *MockDBMockRecorder) SetMaxOpenConns(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxOpenConns", reflect.TypeOf((*MockDB)(nil).SetMaxOpenConns), arg0)
+}
+
+// SetNodeWeight mocks base method.
+func (m *MockDB) SetNodeWeight(arg0 *sql.DB, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNodeWeight", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetNodeWeight indicates an expected call of SetNodeWeight.
+func (mr *MockDBMockRecorder) SetNodeWeight(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNodeWeight", reflect.TypeOf((*MockDB)(nil).SetNodeWeight), arg0, arg1)
+}
+
+// SetReadMode mocks base method.
+func (m *MockDB) SetReadMode(arg0 dbresolver.ReadMode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetReadMode", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetReadMode indicates an expected call of SetReadMode.
+func (mr *MockDBMockRecorder) SetReadMode(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadMode", reflect.TypeOf((*MockDB)(nil).SetReadMode), arg0)
+}
+
+// Shutdown mocks base method.
+func (m *MockDB) Shutdown(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Shutdown", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Shutdown indicates an expected call of Shutdown.
+func (mr *MockDBMockRecorder) Shutdown(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockDB)(nil).Shutdown), arg0)
+}
+
+// Stats mocks base method.
+func (m *MockDB) Stats() sql.DBStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(sql.DBStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockDBMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockDB)(nil).Stats))
+}
+
+// SwapNodeDSN mocks base method.
+func (m *MockDB) SwapNodeDSN(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SwapNodeDSN", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SwapNodeDSN indicates an expected call of SwapNodeDSN.
+func (mr *MockDBMockRecorder) SwapNodeDSN(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SwapNodeDSN", reflect.TypeOf((*MockDB)(nil).SwapNodeDSN), arg0, arg1, arg2)
+}
+
+// ValidateTopology mocks base method.
+func (m *MockDB) ValidateTopology(arg0 context.Context) (*dbresolver.TopologyReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateTopology", arg0)
+	ret0, _ := ret[0].(*dbresolver.TopologyReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateTopology indicates an expected call of ValidateTopology.
+func (mr *MockDBMockRecorder) ValidateTopology(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateTopology", reflect.TypeOf((*MockDB)(nil).ValidateTopology), arg0)
+}
+
+// MockTx is a mock of Tx interface.
+type MockTx struct {
+	ctrl     *gomock.Controller
+	recorder *MockTxMockRecorder
+}
+
+// MockTxMockRecorder is the mock recorder for MockTx.
+type MockTxMockRecorder struct {
+	mock *MockTx
+}
+
+// NewMockTx creates a new mock instance.
+func NewMockTx(ctrl *gomock.Controller) *MockTx {
+	mock := &MockTx{ctrl: ctrl}
+	mock.recorder = &MockTxMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTx) EXPECT() *MockTxMockRecorder {
+	return m.recorder
+}
+
+// Commit mocks base method.
+func (m *MockTx) Commit() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Commit")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Commit indicates an expected call of Commit.
+func (mr *MockTxMockRecorder) Commit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockTx)(nil).Commit))
+}
+
+// Exec mocks base method.
+func (m *MockTx) Exec(arg0 string, arg1 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Exec", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exec indicates an expected call of Exec.
+func (mr *MockTxMockRecorder) Exec(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockTx)(nil).Exec), varargs...)
+}
+
+// ExecContext mocks base method.
+func (m *MockTx) ExecContext(arg0 context.Context, arg1 string, arg2 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecContext", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecContext indicates an expected call of ExecContext.
+func (mr *MockTxMockRecorder) ExecContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*MockTx)(nil).ExecContext), varargs...)
+}
+
+// Prepare mocks base method.
+func (m *MockTx) Prepare(arg0 string) (dbresolver.Stmt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Prepare", arg0)
+	ret0, _ := ret[0].(dbresolver.Stmt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Prepare indicates an expected call of Prepare.
+func (mr *MockTxMockRecorder) Prepare(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prepare", reflect.TypeOf((*MockTx)(nil).Prepare), arg0)
+}
+
+// PrepareContext mocks base method.
+func (m *MockTx) PrepareContext(arg0 context.Context, arg1 string) (dbresolver.Stmt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrepareContext", arg0, arg1)
+	ret0, _ := ret[0].(dbresolver.Stmt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PrepareContext indicates an expected call of PrepareContext.
+func (mr *MockTxMockRecorder) PrepareContext(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareContext", reflect.TypeOf((*MockTx)(nil).PrepareContext), arg0, arg1)
+}
+
+// Query mocks base method.
+func (m *MockTx) Query(arg0 string, arg1 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Query", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockTxMockRecorder) Query(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockTx)(nil).Query), varargs...)
+}
+
+// QueryContext mocks base method.
+func (m *MockTx) QueryContext(arg0 context.Context, arg1 string, arg2 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryContext", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryContext indicates an expected call of QueryContext.
+func (mr *MockTxMockRecorder) QueryContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*MockTx)(nil).QueryContext), varargs...)
+}
+
+// QueryRow mocks base method.
+func (m *MockTx) QueryRow(arg0 string, arg1 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRow", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRow indicates an expected call of QueryRow.
+func (mr *MockTxMockRecorder) QueryRow(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRow", reflect.TypeOf((*MockTx)(nil).QueryRow), varargs...)
+}
+
+// QueryRowContext mocks base method.
+func (m *MockTx) QueryRowContext(arg0 context.Context, arg1 string, arg2 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRowContext", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRowContext indicates an expected call of QueryRowContext.
+func (mr *MockTxMockRecorder) QueryRowContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*MockTx)(nil).QueryRowContext), varargs...)
+}
+
+// Rollback mocks base method.
+func (m *MockTx) Rollback() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rollback")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rollback indicates an expected call of Rollback.
+func (mr *MockTxMockRecorder) Rollback() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*MockTx)(nil).Rollback))
+}
+
+// Stmt mocks base method.
+func (m *MockTx) Stmt(arg0 dbresolver.Stmt) dbresolver.Stmt {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stmt", arg0)
+	ret0, _ := ret[0].(dbresolver.Stmt)
+	return ret0
+}
+
+// Stmt indicates an expected call of Stmt.
+func (mr *MockTxMockRecorder) Stmt(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stmt", reflect.TypeOf((*MockTx)(nil).Stmt), arg0)
+}
+
+// StmtContext mocks base method.
+func (m *MockTx) StmtContext(arg0 context.Context, arg1 dbresolver.Stmt) dbresolver.Stmt {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StmtContext", arg0, arg1)
+	ret0, _ := ret[0].(dbresolver.Stmt)
+	return ret0
+}
+
+// StmtContext indicates an expected call of StmtContext.
+func (mr *MockTxMockRecorder) StmtContext(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StmtContext", reflect.TypeOf((*MockTx)(nil).StmtContext), arg0, arg1)
+}
+
+// MockStmt is a mock of Stmt interface.
+type MockStmt struct {
+	ctrl     *gomock.Controller
+	recorder *MockStmtMockRecorder
+}
+
+// MockStmtMockRecorder is the mock recorder for MockStmt.
+type MockStmtMockRecorder struct {
+	mock *MockStmt
+}
+
+// NewMockStmt creates a new mock instance.
+func NewMockStmt(ctrl *gomock.Controller) *MockStmt {
+	mock := &MockStmt{ctrl: ctrl}
+	mock.recorder = &MockStmtMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStmt) EXPECT() *MockStmtMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockStmt) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockStmtMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStmt)(nil).Close))
+}
+
+// Exec mocks base method.
+func (m *MockStmt) Exec(arg0 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Exec", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exec indicates an expected call of Exec.
+func (mr *MockStmtMockRecorder) Exec(arg0 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockStmt)(nil).Exec), arg0...)
+}
+
+// ExecContext mocks base method.
+func (m *MockStmt) ExecContext(arg0 context.Context, arg1 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecContext", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecContext indicates an expected call of ExecContext.
+func (mr *MockStmtMockRecorder) ExecContext(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*MockStmt)(nil).ExecContext), varargs...)
+}
+
+// Query mocks base method.
+func (m *MockStmt) Query(arg0 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Query", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockStmtMockRecorder) Query(arg0 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockStmt)(nil).Query), arg0...)
+}
+
+// QueryContext mocks base method.
+func (m *MockStmt) QueryContext(arg0 context.Context, arg1 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryContext", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryContext indicates an expected call of QueryContext.
+func (mr *MockStmtMockRecorder) QueryContext(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*MockStmt)(nil).QueryContext), varargs...)
+}
+
+// QueryRow mocks base method.
+func (m *MockStmt) QueryRow(arg0 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRow", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRow indicates an expected call of QueryRow.
+func (mr *MockStmtMockRecorder) QueryRow(arg0 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRow", reflect.TypeOf((*MockStmt)(nil).QueryRow), arg0...)
+}
+
+// QueryRowContext mocks base method.
+func (m *MockStmt) QueryRowContext(arg0 context.Context, arg1 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRowContext", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRowContext indicates an expected call of QueryRowContext.
+func (mr *MockStmtMockRecorder) QueryRowContext(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*MockStmt)(nil).QueryRowContext), varargs...)
+}
+
+// MockConn is a mock of Conn interface.
+type MockConn struct {
+	ctrl     *gomock.Controller
+	recorder *MockConnMockRecorder
+}
+
+// MockConnMockRecorder is the mock recorder for MockConn.
+type MockConnMockRecorder struct {
+	mock *MockConn
+}
+
+// NewMockConn creates a new mock instance.
+func NewMockConn(ctrl *gomock.Controller) *MockConn {
+	mock := &MockConn{ctrl: ctrl}
+	mock.recorder = &MockConnMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConn) EXPECT() *MockConnMockRecorder {
+	return m.recorder
+}
+
+// BeginTx mocks base method.
+func (m *MockConn) BeginTx(arg0 context.Context, arg1 *sql.TxOptions) (dbresolver.Tx, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginTx", arg0, arg1)
+	ret0, _ := ret[0].(dbresolver.Tx)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginTx indicates an expected call of BeginTx.
+func (mr *MockConnMockRecorder) BeginTx(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTx", reflect.TypeOf((*MockConn)(nil).BeginTx), arg0, arg1)
+}
+
+// Close mocks base method.
+func (m *MockConn) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockConnMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockConn)(nil).Close))
+}
+
+// Exec mocks base method.
+func (m *MockConn) Exec(arg0 string, arg1 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Exec", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exec indicates an expected call of Exec.
+func (mr *MockConnMockRecorder) Exec(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exec", reflect.TypeOf((*MockConn)(nil).Exec), varargs...)
+}
+
+// ExecContext mocks base method.
+func (m *MockConn) ExecContext(arg0 context.Context, arg1 string, arg2 ...any) (sql.Result, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExecContext", varargs...)
+	ret0, _ := ret[0].(sql.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExecContext indicates an expected call of ExecContext.
+func (mr *MockConnMockRecorder) ExecContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecContext", reflect.TypeOf((*MockConn)(nil).ExecContext), varargs...)
+}
+
+// PingContext mocks base method.
+func (m *MockConn) PingContext(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PingContext", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PingContext indicates an expected call of PingContext.
+func (mr *MockConnMockRecorder) PingContext(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PingContext", reflect.TypeOf((*MockConn)(nil).PingContext), arg0)
+}
+
+// PrepareContext mocks base method.
+func (m *MockConn) PrepareContext(arg0 context.Context, arg1 string) (dbresolver.Stmt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PrepareContext", arg0, arg1)
+	ret0, _ := ret[0].(dbresolver.Stmt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PrepareContext indicates an expected call of PrepareContext.
+func (mr *MockConnMockRecorder) PrepareContext(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareContext", reflect.TypeOf((*MockConn)(nil).PrepareContext), arg0, arg1)
+}
+
+// Query mocks base method.
+func (m *MockConn) Query(arg0 string, arg1 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Query", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Query indicates an expected call of Query.
+func (mr *MockConnMockRecorder) Query(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Query", reflect.TypeOf((*MockConn)(nil).Query), varargs...)
+}
+
+// QueryContext mocks base method.
+func (m *MockConn) QueryContext(arg0 context.Context, arg1 string, arg2 ...any) (*sql.Rows, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryContext", varargs...)
+	ret0, _ := ret[0].(*sql.Rows)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryContext indicates an expected call of QueryContext.
+func (mr *MockConnMockRecorder) QueryContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryContext", reflect.TypeOf((*MockConn)(nil).QueryContext), varargs...)
+}
+
+// QueryRow mocks base method.
+func (m *MockConn) QueryRow(arg0 string, arg1 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0}
+	for _, a := range arg1 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRow", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRow indicates an expected call of QueryRow.
+func (mr *MockConnMockRecorder) QueryRow(arg0 any, arg1 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0}, arg1...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRow", reflect.TypeOf((*MockConn)(nil).QueryRow), varargs...)
+}
+
+// QueryRowContext mocks base method.
+func (m *MockConn) QueryRowContext(arg0 context.Context, arg1 string, arg2 ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRowContext", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRowContext indicates an expected call of QueryRowContext.
+func (mr *MockConnMockRecorder) QueryRowContext(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*MockConn)(nil).QueryRowContext), varargs...)
+}
+
+// Raw mocks base method.
+func (m *MockConn) Raw(arg0 func(any) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Raw", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Raw indicates an expected call of Raw.
+func (mr *MockConnMockRecorder) Raw(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Raw", reflect.TypeOf((*MockConn)(nil).Raw), arg0)
+}
+
+// MockQueryTypeChecker is a mock of QueryTypeChecker interface.
+type MockQueryTypeChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockQueryTypeCheckerMockRecorder
+}
+
+// MockQueryTypeCheckerMockRecorder is the mock recorder for MockQueryTypeChecker.
+type MockQueryTypeCheckerMockRecorder struct {
+	mock *MockQueryTypeChecker
+}
+
+// NewMockQueryTypeChecker creates a new mock instance.
+func NewMockQueryTypeChecker(ctrl *gomock.Controller) *MockQueryTypeChecker {
+	mock := &MockQueryTypeChecker{ctrl: ctrl}
+	mock.recorder = &MockQueryTypeCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQueryTypeChecker) EXPECT() *MockQueryTypeCheckerMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockQueryTypeChecker) Check(arg0 string) dbresolver.QueryType {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", arg0)
+	ret0, _ := ret[0].(dbresolver.QueryType)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockQueryTypeCheckerMockRecorder) Check(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockQueryTypeChecker)(nil).Check), arg0)
+}
+
+// MockDiscoverer is a mock of Discoverer interface.
+type MockDiscoverer struct {
+	ctrl     *gomock.Controller
+	recorder *MockDiscovererMockRecorder
+}
+
+// MockDiscovererMockRecorder is the mock recorder for MockDiscoverer.
+type MockDiscovererMockRecorder struct {
+	mock *MockDiscoverer
+}
+
+// NewMockDiscoverer creates a new mock instance.
+func NewMockDiscoverer(ctrl *gomock.Controller) *MockDiscoverer {
+	mock := &MockDiscoverer{ctrl: ctrl}
+	mock.recorder = &MockDiscovererMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDiscoverer) EXPECT() *MockDiscovererMockRecorder {
+	return m.recorder
+}
+
+// Discover mocks base method.
+func (m *MockDiscoverer) Discover(arg0 context.Context) ([]dbresolver.Endpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discover", arg0)
+	ret0, _ := ret[0].([]dbresolver.Endpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Discover indicates an expected call of Discover.
+func (mr *MockDiscovererMockRecorder) Discover(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discover", reflect.TypeOf((*MockDiscoverer)(nil).Discover), arg0)
+}
+
+// MockCredentialsProvider is a mock of CredentialsProvider interface.
+type MockCredentialsProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockCredentialsProviderMockRecorder
+}
+
+// MockCredentialsProviderMockRecorder is the mock recorder for MockCredentialsProvider.
+type MockCredentialsProviderMockRecorder struct {
+	mock *MockCredentialsProvider
+}
+
+// NewMockCredentialsProvider creates a new mock instance.
+func NewMockCredentialsProvider(ctrl *gomock.Controller) *MockCredentialsProvider {
+	mock := &MockCredentialsProvider{ctrl: ctrl}
+	mock.recorder = &MockCredentialsProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCredentialsProvider) EXPECT() *MockCredentialsProviderMockRecorder {
+	return m.recorder
+}
+
+// Credentials mocks base method.
+func (m *MockCredentialsProvider) Credentials(arg0 context.Context) (dbresolver.Credentials, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Credentials", arg0)
+	ret0, _ := ret[0].(dbresolver.Credentials)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Credentials indicates an expected call of Credentials.
+func (mr *MockCredentialsProviderMockRecorder) Credentials(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Credentials", reflect.TypeOf((*MockCredentialsProvider)(nil).Credentials), arg0)
+}
+
+// MockHooks is a mock of Hooks interface.
+type MockHooks struct {
+	ctrl     *gomock.Controller
+	recorder *MockHooksMockRecorder
+}
+
+// MockHooksMockRecorder is the mock recorder for MockHooks.
+type MockHooksMockRecorder struct {
+	mock *MockHooks
+}
+
+// NewMockHooks creates a new mock instance.
+func NewMockHooks(ctrl *gomock.Controller) *MockHooks {
+	mock := &MockHooks{ctrl: ctrl}
+	mock.recorder = &MockHooksMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHooks) EXPECT() *MockHooksMockRecorder {
+	return m.recorder
+}
+
+// After mocks base method.
+func (m *MockHooks) After(arg0 context.Context, arg1 string, arg2 ...any) (context.Context, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "After", varargs...)
+	ret0, _ := ret[0].(context.Context)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// After indicates an expected call of After.
+func (mr *MockHooksMockRecorder) After(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "After", reflect.TypeOf((*MockHooks)(nil).After), varargs...)
+}
+
+// Before mocks base method.
+func (m *MockHooks) Before(arg0 context.Context, arg1 string, arg2 ...any) (context.Context, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Before", varargs...)
+	ret0, _ := ret[0].(context.Context)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Before indicates an expected call of Before.
+func (mr *MockHooksMockRecorder) Before(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Before", reflect.TypeOf((*MockHooks)(nil).Before), varargs...)
+}
+
+// MockOnErrorer is a mock of OnErrorer interface.
+type MockOnErrorer struct {
+	ctrl     *gomock.Controller
+	recorder *MockOnErrorerMockRecorder
+}
+
+// MockOnErrorerMockRecorder is the mock recorder for MockOnErrorer.
+type MockOnErrorerMockRecorder struct {
+	mock *MockOnErrorer
+}
+
+// NewMockOnErrorer creates a new mock instance.
+func NewMockOnErrorer(ctrl *gomock.Controller) *MockOnErrorer {
+	mock := &MockOnErrorer{ctrl: ctrl}
+	mock.recorder = &MockOnErrorerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOnErrorer) EXPECT() *MockOnErrorerMockRecorder {
+	return m.recorder
+}
+
+// OnError mocks base method.
+func (m *MockOnErrorer) OnError(arg0 context.Context, arg1 error, arg2 string, arg3 ...any) error {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1, arg2}
+	for _, a := range arg3 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "OnError", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// OnError indicates an expected call of OnError.
+func (mr *MockOnErrorerMockRecorder) OnError(arg0, arg1, arg2 any, arg3 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1, arg2}, arg3...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnError", reflect.TypeOf((*MockOnErrorer)(nil).OnError), varargs...)
+}
+
+// MockReadOnlyDetector is a mock of ReadOnlyDetector interface.
+type MockReadOnlyDetector struct {
+	ctrl     *gomock.Controller
+	recorder *MockReadOnlyDetectorMockRecorder
+}
+
+// MockReadOnlyDetectorMockRecorder is the mock recorder for MockReadOnlyDetector.
+type MockReadOnlyDetectorMockRecorder struct {
+	mock *MockReadOnlyDetector
+}
+
+// NewMockReadOnlyDetector creates a new mock instance.
+func NewMockReadOnlyDetector(ctrl *gomock.Controller) *MockReadOnlyDetector {
+	mock := &MockReadOnlyDetector{ctrl: ctrl}
+	mock.recorder = &MockReadOnlyDetectorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReadOnlyDetector) EXPECT() *MockReadOnlyDetectorMockRecorder {
+	return m.recorder
+}
+
+// IsReadOnly mocks base method.
+func (m *MockReadOnlyDetector) IsReadOnly(arg0 context.Context, arg1 *sql.DB) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsReadOnly", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsReadOnly indicates an expected call of IsReadOnly.
+func (mr *MockReadOnlyDetectorMockRecorder) IsReadOnly(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReadOnly", reflect.TypeOf((*MockReadOnlyDetector)(nil).IsReadOnly), arg0, arg1)
+}
+
+// MockRedactor is a mock of Redactor interface.
+type MockRedactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockRedactorMockRecorder
+}
+
+// MockRedactorMockRecorder is the mock recorder for MockRedactor.
+type MockRedactorMockRecorder struct {
+	mock *MockRedactor
+}
+
+// NewMockRedactor creates a new mock instance.
+func NewMockRedactor(ctrl *gomock.Controller) *MockRedactor {
+	mock := &MockRedactor{ctrl: ctrl}
+	mock.recorder = &MockRedactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRedactor) EXPECT() *MockRedactorMockRecorder {
+	return m.recorder
+}
+
+// Redact mocks base method.
+func (m *MockRedactor) Redact(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Redact", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Redact indicates an expected call of Redact.
+func (mr *MockRedactorMockRecorder) Redact(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redact", reflect.TypeOf((*MockRedactor)(nil).Redact), arg0)
+}
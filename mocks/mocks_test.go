@@ -0,0 +1,44 @@
+package mocks_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+type bookRepository struct {
+	db dbresolver.DB
+}
+
+func (r *bookRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM book WHERE id = $1", id); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func TestMockDB(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := mocks.NewMockDB(ctrl)
+	tx := mocks.NewMockTx(ctrl)
+
+	gomock.InOrder(
+		db.EXPECT().BeginTx(gomock.Any(), nil).Return(tx, nil),
+		tx.EXPECT().ExecContext(gomock.Any(), "DELETE FROM book WHERE id = $1", 1).Return(nil, sql.ErrConnDone),
+		tx.EXPECT().Rollback().Return(nil),
+	)
+
+	repository := &bookRepository{db: db}
+	if err := repository.Delete(context.Background(), 1); err != sql.ErrConnDone {
+		t.Errorf("want %v, got %v", sql.ErrConnDone, err)
+	}
+}
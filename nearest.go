@@ -0,0 +1,192 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of the nearest read preference
+const (
+	defaultNearestInterval  = 5 * time.Second
+	defaultNearestTolerance = 5 * time.Millisecond
+	// nearestSmoothing is the weight of the last probe in the smoothed latency
+	nearestSmoothing = 0.3
+)
+
+// NearestReads define how the latency of the replicas is probed, and which replicas are the nearest
+type NearestReads struct {
+	// Interval between two probes of the replicas, 5 seconds by default. It's the timeout of a probe too.
+	Interval time.Duration
+	// Tolerance is the band above the lowest latency within which the replicas are the nearest, 5ms by default
+	Tolerance time.Duration
+	// Probe measures the round trip to the replica, PingContext by default.
+	// A failed probe excludes the replica from the nearest replicas until its next successful probe.
+	Probe func(ctx context.Context, replica *sql.DB) error
+}
+
+// WithNearestReads probes the round-trip latency of each replica in the background, and routes the reads
+// to the nearest replicas: the replicas within the tolerance of the lowest latency, load balanced by weight.
+// It fits the multi-region deployments where the zone labels don't reflect the actual latency.
+// The reads go to every replica until the first probe, and after a topology change until the next probe.
+// The prepared statements keep their rotation of every replica. The probing stops when the resolver is closed.
+func WithNearestReads(config NearestReads) OptionFunc {
+	return func(opt *Option) {
+		opt.NearestReads = &config
+	}
+}
+
+// nearestRotation is the replica rotation restricted to the nearest replicas,
+// computed from the rotation of a topology snapshot
+type nearestRotation struct {
+	from     *nodeSet
+	rotation []*sql.DB
+}
+
+// nearestProber probes the latency of the replicas of the resolver
+type nearestProber struct {
+	db     *sqlDB
+	config NearestReads
+
+	// probeMu serializes the probes, mu guards the latencies
+	probeMu   sync.Mutex
+	mu        sync.Mutex
+	latencies map[*sql.DB]time.Duration
+	nearest   atomic.Pointer[nearestRotation]
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newNearestProber(db *sqlDB, config NearestReads) *nearestProber {
+	if config.Interval <= 0 {
+		config.Interval = defaultNearestInterval
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = defaultNearestTolerance
+	}
+	if config.Probe == nil {
+		config.Probe = func(ctx context.Context, replica *sql.DB) error {
+			return replica.PingContext(ctx)
+		}
+	}
+	return &nearestProber{
+		db:        db,
+		config:    config,
+		latencies: map[*sql.DB]time.Duration{},
+		done:      make(chan struct{}),
+	}
+}
+
+// start probes the replicas now, then on every interval until close
+func (p *nearestProber) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	go func() {
+		defer close(p.done)
+		for {
+			p.probe(ctx)
+			timer := p.db.clock.NewTimer(p.config.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+		}
+	}()
+}
+
+func (p *nearestProber) close() {
+	p.cancel()
+	<-p.done
+}
+
+// probe measures the latency of every replica concurrently, and computes the nearest rotation
+func (p *nearestProber) probe(ctx context.Context) {
+	p.probeMu.Lock()
+	defer p.probeMu.Unlock()
+
+	set := p.db.nodes.Load()
+	replicas := distinct(set.replicas)
+	measured := make([]time.Duration, len(replicas))
+	_ = doParallely(ctx, p.db.parallelism, len(replicas), func(i int) error {
+		probeCtx, cancel := context.WithTimeout(ctx, p.config.Interval)
+		defer cancel()
+		start := p.db.clock.Now()
+		if err := p.config.Probe(probeCtx, replicas[i]); err != nil {
+			measured[i] = -1
+			return nil
+		}
+		measured[i] = max(p.db.clock.Now().Sub(start), 0)
+		return nil
+	})
+	if ctx.Err() != nil {
+		return
+	}
+
+	p.mu.Lock()
+	latencies := make(map[*sql.DB]time.Duration, len(replicas))
+	lowest := time.Duration(-1)
+	for i, replica := range replicas {
+		latency := measured[i]
+		if previous, ok := p.latencies[replica]; ok && latency >= 0 {
+			latency = time.Duration(nearestSmoothing*float64(latency) + (1-nearestSmoothing)*float64(previous))
+		}
+		if latency < 0 {
+			continue
+		}
+		latencies[replica] = latency
+		if lowest < 0 || latency < lowest {
+			lowest = latency
+		}
+	}
+	p.latencies = latencies
+	p.mu.Unlock()
+
+	var rotation []*sql.DB
+	for _, replica := range set.replicaRotation {
+		if latency, ok := latencies[replica]; ok && latency <= lowest+p.config.Tolerance {
+			rotation = append(rotation, replica)
+		}
+	}
+	p.nearest.Store(&nearestRotation{from: set, rotation: rotation})
+}
+
+// rotation returns the nearest replicas of the topology snapshot, every replica when they aren't probed yet
+func (p *nearestProber) rotation(set *nodeSet) []*sql.DB {
+	if p == nil {
+		return set.replicaRotation
+	}
+	nearest := p.nearest.Load()
+	if nearest == nil || nearest.from != set || len(nearest.rotation) == 0 {
+		return set.replicaRotation
+	}
+	return nearest.rotation
+}
+
+// latency returns the smoothed latency of the replica, zero when it isn't probed or the prober is nil
+func (p *nearestProber) latency(replica *sql.DB) time.Duration {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latencies[replica]
+}
+
+// distinct returns the nodes without duplicates, in order
+func distinct(nodes []*sql.DB) []*sql.DB {
+	seen := make(map[*sql.DB]struct{}, len(nodes))
+	res := make([]*sql.DB, 0, len(nodes))
+	for _, node := range nodes {
+		if _, ok := seen[node]; !ok {
+			seen[node] = struct{}{}
+			res = append(res, node)
+		}
+	}
+	return res
+}
@@ -0,0 +1,113 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// awaitFirstProbe waits for the probe of the replicas on start, the next probe is after the interval
+func awaitFirstProbe(t *testing.T, p *nearestProber) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for p.nearest.Load() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the replicas weren't probed on start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNearestReads(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replicas := make([]*sql.DB, 3)
+	for i := range replicas {
+		if replicas[i], _, err = createMock(); err != nil {
+			t.Fatal("creating of mock failed")
+		}
+	}
+	now := time.Now()
+	clock := stubClock{now: &now}
+	// the probe advances the clock by the latency of the replica, the replicas are probed one at a time
+	latencies := map[*sql.DB]time.Duration{replicas[0]: 40 * time.Millisecond, replicas[1]: 2 * time.Millisecond,
+		replicas[2]: 5 * time.Millisecond}
+	var probeErr error
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replicas...), WithClock(clock), WithMaxParallelism(1),
+		WithNearestReads(NearestReads{
+			Interval:  time.Hour,
+			Tolerance: 5 * time.Millisecond,
+			Probe: func(_ context.Context, replica *sql.DB) error {
+				now = now.Add(latencies[replica])
+				if replica == replicas[2] {
+					return probeErr
+				}
+				return nil
+			},
+		})).(*sqlDB)
+	defer resolver.stop()
+	awaitFirstProbe(t, resolver.nearest)
+
+	// the far replica is out of the tolerance band
+	seen := map[*sql.DB]int{}
+	for i := 0; i < 10; i++ {
+		seen[resolver.ReadOnly()]++
+	}
+	if seen[replicas[0]] != 0 || seen[replicas[1]] != 5 || seen[replicas[2]] != 5 {
+		t.Errorf("want the reads on the nearest replicas, got %v", seen)
+	}
+	for _, node := range resolver.Nodes() {
+		if node.DB == replicas[1] && node.Latency != 2*time.Millisecond {
+			t.Errorf("want the probed latency of the replica, got %v", node.Latency)
+		}
+	}
+
+	// a failed probe excludes the replica
+	probeErr = errors.New("unreachable")
+	resolver.nearest.probe(context.Background())
+	for i := 0; i < 4; i++ {
+		if replica := resolver.ReadOnly(); replica != replicas[1] {
+			t.Fatal("want the reads on the nearest reachable replica")
+		}
+	}
+
+	// the rotation of a new topology has every replica until the next probe
+	resolver.RemoveReplica(replicas[1])
+	seen = map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		seen[resolver.ReadOnly()]++
+	}
+	if seen[replicas[0]] != 2 || seen[replicas[2]] != 2 {
+		t.Errorf("want the reads on every replica after the topology change, got %v", seen)
+	}
+}
+
+func TestNearestReadsSmoothing(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	now := time.Now()
+	latency := 10 * time.Millisecond
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithClock(stubClock{now: &now}),
+		WithNearestReads(NearestReads{Interval: time.Hour, Probe: func(context.Context, *sql.DB) error {
+			now = now.Add(latency)
+			return nil
+		}})).(*sqlDB)
+	defer resolver.stop()
+	awaitFirstProbe(t, resolver.nearest)
+
+	latency = 20 * time.Millisecond
+	resolver.nearest.probe(context.Background())
+	if got := resolver.nearest.latency(replica); got != 13*time.Millisecond {
+		t.Errorf("want the latency smoothed, got %v", got)
+	}
+}
@@ -0,0 +1,273 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NodeInfo describes a node of the topology
+type NodeInfo struct {
+	DB   *sql.DB
+	Role Role
+	// Weight is the share of the queries sent to the node relatively to the other nodes of its role
+	Weight int
+	// Labels are the metadata of the node, eg. its region, zone, tier or shard.
+	// They are shared, they must not be modified.
+	Labels map[string]string
+	// ConcurrencyLimit is the current adaptive concurrency limit of the node, zero when the limits aren't adaptive
+	ConcurrencyLimit int
+	// Latency is the probed round-trip latency of the replica with the nearest read preference,
+	// the average query latency of the node with the latency load balancer, zero otherwise
+	Latency time.Duration
+	// Unhealthy is set when the node failed its health checks and is out of rotation, see WithHealthCheckInterval
+	Unhealthy bool
+	// FailedHealthChecks is the number of failed health checks of the node since it's in the topology
+	FailedHealthChecks int64
+	// ReplicationLag is the last measured replication lag of the replica, see WithLagMonitor
+	ReplicationLag time.Duration
+	// Circuit is the state of the circuit breaker of the node, closed without circuit breaker
+	Circuit CircuitState
+}
+
+// NodeNameLabel is the label of the node names
+const NodeNameLabel = "name"
+
+// Name returns the name of the node, its NodeNameLabel label
+func (n NodeInfo) Name() string {
+	return n.Labels[NodeNameLabel]
+}
+
+// WithNodeName names the node, the name identifies the node in DB.SwapNodeDSN.
+// It's a shortcut for the NodeNameLabel label.
+func WithNodeName(node *sql.DB, name string) OptionFunc {
+	return WithNodeLabels(node, map[string]string{NodeNameLabel: name})
+}
+
+// WithNodeLabels attaches the labels to the node, eg. {"zone": "us-east-1a"}.
+// The node is one of the primary or replica DBs, the labels are exposed by DB.Nodes.
+// It can be used multiple times, the labels of a node are merged.
+func WithNodeLabels(node *sql.DB, labels map[string]string) OptionFunc {
+	return func(opt *Option) {
+		if opt.NodeLabels == nil {
+			opt.NodeLabels = make(map[*sql.DB]map[string]string)
+		}
+		opt.NodeLabels[node] = mergeLabels(opt.NodeLabels[node], labels)
+	}
+}
+
+// mergeLabels returns a copy of the labels overridden by the overrides
+func mergeLabels(labels, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(labels)+len(overrides))
+	for key, value := range labels {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// NamedDB is a node with its name, see Named
+type NamedDB struct {
+	DB   *sql.DB
+	Name string
+}
+
+// Named returns the node with its name, eg. WithNamedReplicaDBs(Named("us-east-1a", db1), Named("us-east-1b", db2))
+func Named(name string, db *sql.DB) NamedDB {
+	return NamedDB{DB: db, Name: name}
+}
+
+// WithNamedPrimaryDBs adds the primaries to the resolver with their names, see WithNodeName
+func WithNamedPrimaryDBs(primaries ...NamedDB) OptionFunc {
+	return func(opt *Option) {
+		WithPrimaryDBs(namedDBs(primaries)...)(opt)
+		for _, primary := range primaries {
+			WithNodeName(primary.DB, primary.Name)(opt)
+		}
+	}
+}
+
+// WithNamedReplicaDBs adds the replicas to the resolver with their names, see WithNodeName
+func WithNamedReplicaDBs(replicas ...NamedDB) OptionFunc {
+	return func(opt *Option) {
+		WithReplicaDBs(namedDBs(replicas)...)(opt)
+		for _, replica := range replicas {
+			WithNodeName(replica.DB, replica.Name)(opt)
+		}
+	}
+}
+
+func namedDBs(nodes []NamedDB) []*sql.DB {
+	dbs := make([]*sql.DB, len(nodes))
+	for i, node := range nodes {
+		dbs[i] = node.DB
+	}
+	return dbs
+}
+
+// NodeError is the error of a query sent to a named node, it wraps the error of the node with its name and role
+type NodeError struct {
+	Name string
+	Role Role
+	Err  error
+}
+
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("dbresolver: %s %q: %v", e.Role, e.Name, e.Err)
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// nodeError wraps the error of the query with the name of the node, the errors of the unnamed nodes are returned as is
+func (db *sqlDB) nodeError(node *sql.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+	name := db.nodeName(node)
+	if name == "" {
+		return err
+	}
+	role, _ := db.locate(node)
+	return &NodeError{Name: name, Role: role, Err: err}
+}
+
+// Nodes returns the current primaries then the current replicas with their labels.
+// A node added multiple times to a role is only returned once for the role.
+// The nodes of the shards follow, by shard name, labeled with their ShardLabel.
+func (db *sqlDB) Nodes() []NodeInfo {
+	set := db.nodes.Load()
+	nodes := make([]NodeInfo, 0, len(set.primaries)+len(set.replicas))
+	appendNodes := func(dbs []*sql.DB, role Role) {
+		seen := make(map[*sql.DB]int, len(dbs))
+		for _, node := range dbs {
+			info := db.nodeInfo(set, node, role)
+			if i, ok := seen[node]; ok {
+				// the node was added multiple times to approximate a weight
+				nodes[i].Weight += info.Weight
+				continue
+			}
+			seen[node] = len(nodes)
+			nodes = append(nodes, info)
+		}
+	}
+	appendNodes(set.primaries, RolePrimary)
+	appendNodes(set.replicas, RoleReplica)
+	for _, name := range db.shardNames {
+		for _, node := range db.shards[name].Nodes() {
+			node.Labels = mergeLabels(node.Labels, map[string]string{ShardLabel: name})
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// nodeInfo describes the node of the role in the snapshot
+func (db *sqlDB) nodeInfo(set *nodeSet, node *sql.DB, role Role) NodeInfo {
+	weights := set.primaryWeights
+	if role == RoleReplica {
+		weights = set.replicaWeights
+	}
+	return NodeInfo{DB: node, Role: role, Weight: nodeWeight(weights, node), Labels: db.nodeLabels(node),
+		ConcurrencyLimit: db.concurrency.limit(node), Latency: db.nodeLatency(node),
+		Unhealthy: !db.health.isHealthy(node), FailedHealthChecks: db.health.failedCheckCount(node),
+		ReplicationLag: db.lag.lag(node), Circuit: db.breaker.circuitState(node)}
+}
+
+// ResolveRead resolves the node of a read query like QueryContext and describes it.
+// The context is honored: its shard key, its role override, its session and its maximum staleness.
+// Unlike ExplainRoute, the load balancer moves to the next node.
+func (db *sqlDB) ResolveRead(ctx context.Context) (NodeInfo, error) {
+	shard, name, err := db.namedShard(ctx)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+	onPrimary := shard.sticksToPrimary(ctx)
+	if role, ok := RoleOverrideFromContext(ctx); ok {
+		onPrimary = role == RolePrimary
+	}
+	if onPrimary {
+		return shard.describe(shard.ReadWrite(), RolePrimary, name), nil
+	}
+	node, route := shard.readOnly(ctx)
+	return shard.describe(node, route.Role, name), nil
+}
+
+// ResolveWrite resolves the node of a write query like ExecContext and describes it.
+// The context is honored: its shard key and its role override.
+func (db *sqlDB) ResolveWrite(ctx context.Context) (NodeInfo, error) {
+	shard, name, err := db.namedShard(ctx)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+	if role, ok := RoleOverrideFromContext(ctx); ok && role == RoleReplica {
+		node, route := shard.readOnly(ctx)
+		return shard.describe(node, route.Role, name), nil
+	}
+	return shard.describe(shard.ReadWrite(), RolePrimary, name), nil
+}
+
+// describe describes the resolved node of the role, labeled with the name of its shard if any
+func (db *sqlDB) describe(node *sql.DB, role Role, shard string) NodeInfo {
+	info := db.nodeInfo(db.nodes.Load(), node, role)
+	if shard != "" {
+		info.Labels = mergeLabels(info.Labels, map[string]string{ShardLabel: shard})
+	}
+	return info
+}
+
+// locate returns the role of the node and its index among the nodes of its role, -1 when it isn't in the topology
+func (db *sqlDB) locate(node *sql.DB) (Role, int) {
+	set := db.nodes.Load()
+	if index := indexOf(set.primaries, node); index >= 0 {
+		return RolePrimary, index
+	}
+	return RoleReplica, indexOf(set.replicas, node)
+}
+
+// nodeLabels returns the labels of the node, nil without labels
+func (db *sqlDB) nodeLabels(node *sql.DB) map[string]string {
+	if labels := db.labels.Load(); labels != nil {
+		return (*labels)[node]
+	}
+	return nil
+}
+
+// nodeName returns the name of the node, empty when it isn't named
+func (db *sqlDB) nodeName(node *sql.DB) string {
+	return db.nodeLabels(node)[NodeNameLabel]
+}
+
+// setLabels replaces the labels of the node, nil labels remove them
+func (db *sqlDB) setLabels(node *sql.DB, labels map[string]string) {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+
+	if labels != nil {
+		labels = mergeLabels(nil, labels)
+	}
+	db.storeLabels(node, labels)
+}
+
+// storeLabels stores the labels of the nodes with the labels of the node, nil labels remove them.
+// The labels are copied on write, the topology lock must be held.
+func (db *sqlDB) storeLabels(node *sql.DB, labels map[string]string) {
+	var current map[*sql.DB]map[string]string
+	if stored := db.labels.Load(); stored != nil {
+		current = *stored
+	}
+	res := make(map[*sql.DB]map[string]string, len(current)+1)
+	for n, l := range current {
+		res[n] = l
+	}
+	if labels == nil {
+		delete(res, node)
+	} else {
+		res[node] = labels
+	}
+	db.labels.Store(&res)
+}
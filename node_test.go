@@ -0,0 +1,116 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNodes(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	labels := map[string]string{"zone": "us-east-1a"}
+	resolver := New(
+		WithPrimaryDBs(primary),
+		WithReplicaDBs(replica1, replica1, replica2),
+		WithNodeLabels(replica1, labels),
+		WithNodeLabels(replica1, map[string]string{"tier": "analytics"}),
+	)
+	labels["zone"] = "modified"
+
+	nodes := resolver.Nodes()
+	want := []struct {
+		db     *sql.DB
+		role   Role
+		labels int
+	}{
+		{primary, RolePrimary, 0},
+		{replica1, RoleReplica, 2},
+		{replica2, RoleReplica, 0},
+	}
+	if len(nodes) != len(want) {
+		t.Fatalf("want %d nodes, got %+v", len(want), nodes)
+	}
+	for i, w := range want {
+		if nodes[i].DB != w.db || nodes[i].Role != w.role || len(nodes[i].Labels) != w.labels {
+			t.Errorf("node %d: want %+v, got %+v", i, w, nodes[i])
+		}
+	}
+	if nodes[1].Labels["zone"] != "us-east-1a" || nodes[1].Labels["tier"] != "analytics" {
+		t.Errorf("want the merged labels, got %v", nodes[1].Labels)
+	}
+}
+
+func TestNamedNodes(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	logger := &recordingLogger{}
+	resolver := New(
+		WithNamedPrimaryDBs(Named("primary-a", primary)),
+		WithNamedReplicaDBs(Named("us-east-1a", replica1), Named("us-east-1b", replica2)),
+		WithLogger(logger))
+	ctx := context.Background()
+
+	// the resolved nodes are described with their names, the load balancer moves
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		node, err := resolver.ResolveRead(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Role != RoleReplica {
+			t.Errorf("want a replica, got %+v", node)
+		}
+		seen[node.Name()] = true
+	}
+	if !seen["us-east-1a"] || !seen["us-east-1b"] {
+		t.Errorf("want both replicas resolved in turn, got %v", seen)
+	}
+	node, err := resolver.ResolveRead(WithPrimary(ctx))
+	if err != nil || node.Name() != "primary-a" || node.Role != RolePrimary {
+		t.Errorf("want the primary forced by the context, got %+v %v", node, err)
+	}
+	node, err = resolver.ResolveWrite(ctx)
+	if err != nil || node.DB != primary || node.Name() != "primary-a" {
+		t.Errorf("want the primary, got %+v %v", node, err)
+	}
+
+	// the errors of the named nodes are wrapped with their names, the query hooks and the logs get the names
+	deadlock := errors.New("deadlock")
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(deadlock)
+	_, err = resolver.ExecContext(ctx, "DELETE FROM users")
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.Name != "primary-a" || nodeErr.Role != RolePrimary ||
+		!errors.Is(err, deadlock) {
+		t.Fatalf("want the error wrapped with the name of the node, got %v", err)
+	}
+	if err.Error() != `dbresolver: primary "primary-a": deadlock` {
+		t.Errorf("want the name of the node in the error, got %q", err)
+	}
+	if len(logger.entries) != 1 || !strings.Contains(logger.entries[0], "{name primary-a}") {
+		t.Errorf("want the name of the node logged, got %q", logger.entries)
+	}
+}
@@ -0,0 +1,179 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Scheme is the URL scheme of the resolver connection URLs
+const Scheme = "dbresolver"
+
+// Query parameters of the resolver connection URLs
+const (
+	primaryParam      = "primary"
+	replicaParam      = "replica"
+	loadBalancerParam = "lb"
+)
+
+// Open opens the resolver described by a single connection URL, eg. from an environment variable:
+//
+//	dbresolver://postgres?primary=dsn1&replica=dsn2&replica=dsn3&lb=round_robin
+//
+// The host is the driver name passed to sql.Open and the DSNs are query escaped.
+// The lb parameter is optional, round_robin or random. The options are applied after the URL ones,
+// WithStartupCheck checks the opened nodes. See OpenDSN for a role-tagged format without escaping.
+func Open(urlstr string, opts ...OptionFunc) (DB, error) {
+	u, err := url.Parse(urlstr)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// the URL holds the DSNs query escaped
+			if unescaped, unescapeErr := url.QueryUnescape(urlErr.URL); unescapeErr == nil {
+				urlErr.URL = unescaped
+			}
+			urlErr.URL = redactorOf(opts).Redact(urlErr.URL)
+		}
+		return nil, fmt.Errorf("dbresolver: invalid connection URL: %w", err)
+	}
+	opener := &URLOpener{Options: opts}
+	return opener.OpenDBURL(context.Background(), u)
+}
+
+// URLOpener opens the resolver connection URLs, following the Go Cloud Development Kit URL openers
+type URLOpener struct {
+	// Open opens the connection of each DSN, sql.Open by default
+	Open func(driverName, dsn string) (*sql.DB, error)
+	// Options are applied after the URL ones
+	Options []OptionFunc
+}
+
+// OpenDBURL opens the resolver described by the URL
+func (o *URLOpener) OpenDBURL(ctx context.Context, u *url.URL) (DB, error) {
+	if u.Scheme != Scheme {
+		return nil, fmt.Errorf("dbresolver: invalid connection URL scheme %q, want %q", u.Scheme, Scheme)
+	}
+	driverName := u.Host
+	if driverName == "" {
+		return nil, fmt.Errorf("dbresolver: missing driver name in the connection URL")
+	}
+	return openNodes(ctx, o.Open, driverName, u.Query(), o.Options)
+}
+
+// OpenDSN opens the resolver described by a role-tagged DSN, like sql.Open:
+//
+//	dbresolver.OpenDSN("postgres", "primary=dsn1;replica=dsn2;replica=dsn3;lb=round_robin")
+//
+// Any number of nodes can be given for each role. A DSN can contain semicolons,
+// eg. a SQL Server DSN, as long as the following segment doesn't start with a role.
+// The lb segment is optional, round_robin or random. The options are applied after the DSN ones.
+func OpenDSN(driverName, dsn string, opts ...OptionFunc) (DB, error) {
+	params, err := parseRoleDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return openNodes(context.Background(), nil, driverName, params, opts)
+}
+
+// parseRoleDSN parses a role-tagged DSN into the parameters of the connection URLs
+func parseRoleDSN(dsn string) (url.Values, error) {
+	params := url.Values{}
+	var role string
+	for _, segment := range strings.Split(strings.TrimRight(dsn, "; "), ";") {
+		key, value, ok := strings.Cut(segment, "=")
+		key = strings.TrimSpace(key)
+		if ok && (key == primaryParam || key == replicaParam || key == loadBalancerParam) {
+			role = key
+			params[key] = append(params[key], value)
+			continue
+		}
+		if role != primaryParam && role != replicaParam {
+			return nil, fmt.Errorf("dbresolver: invalid DSN segment, want primary=<dsn>, replica=<dsn> or lb=<policy>")
+		}
+		// the segment is part of the previous DSN
+		last := len(params[role]) - 1
+		params[role][last] += ";" + segment
+	}
+	for _, values := range params {
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+	}
+	return params, nil
+}
+
+// redactorOf returns the redactor set by the options, DefaultRedactor when none is set
+func redactorOf(opts []OptionFunc) Redactor {
+	opt := &Option{}
+	for _, optFunc := range opts {
+		optFunc(opt)
+	}
+	if opt.Redactor == nil {
+		return DefaultRedactor
+	}
+	return opt.Redactor
+}
+
+// openNodes opens the primary and replica DSNs of the params, and builds the resolver with the options.
+// The opened DBs are closed when the startup check fails.
+func openNodes(ctx context.Context, open func(driverName, dsn string) (*sql.DB, error), driverName string, params url.Values,
+	extraOpts []OptionFunc) (_ DB, err error) {
+	if open == nil {
+		open = sql.Open
+	}
+
+	var opts []OptionFunc
+	for param := range params {
+		switch param {
+		case primaryParam, replicaParam:
+		case loadBalancerParam:
+			policy := LoadBalancerPolicy(strings.ToUpper(params.Get(param)))
+			switch policy {
+			case RoundRobinLB, RandomLB, LeastConnectionsLB, LatencyLB, AffinityLB:
+			default:
+				return nil, fmt.Errorf("dbresolver: unsupported load balancer %q", params.Get(param))
+			}
+			opts = append(opts, WithLoadBalancer(policy))
+		default:
+			return nil, fmt.Errorf("dbresolver: unknown parameter %q", param)
+		}
+	}
+	if len(params[primaryParam]) == 0 {
+		return nil, fmt.Errorf("dbresolver: missing %s", primaryParam)
+	}
+
+	var opened []*sql.DB
+	defer func() {
+		if err != nil {
+			for _, db := range opened {
+				err = errors.Join(err, db.Close())
+			}
+		}
+	}()
+	openAll := func(role string) ([]*sql.DB, error) {
+		dbs := make([]*sql.DB, 0, len(params[role]))
+		for i, dsn := range params[role] {
+			db, err := open(driverName, dsn)
+			if err != nil {
+				return nil, fmt.Errorf("dbresolver: opening %s %d: %w", role, i, RedactError(err, dsn, redactorOf(extraOpts)))
+			}
+			opened = append(opened, db)
+			dbs = append(dbs, db)
+		}
+		return dbs, nil
+	}
+	primaries, err := openAll(primaryParam)
+	if err != nil {
+		return nil, err
+	}
+	replicas, err := openAll(replicaParam)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]OptionFunc{WithPrimaryDBs(primaries...), WithReplicaDBs(replicas...)}, opts...)
+	return Connect(ctx, append(opts, extraOpts...)...)
+}
@@ -0,0 +1,169 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOpen(t *testing.T) {
+	mocks := map[string]sqlmock.Sqlmock{}
+	for _, dsn := range []string{"open_primary", "open_replica1", "open_replica2"} {
+		_, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatal("creating of mock failed", err)
+		}
+		mocks[dsn] = mock
+	}
+
+	db, err := Open("dbresolver://sqlmock?primary=open_primary&replica=open_replica1&replica=open_replica2&lb=round_robin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if len(db.PrimaryDBs()) != 1 || len(db.ReplicaDBs()) != 2 {
+		t.Fatalf("want 1 primary and 2 replicas, got %d and %d", len(db.PrimaryDBs()), len(db.ReplicaDBs()))
+	}
+
+	mocks["open_primary"].ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	mocks["open_replica1"].ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	mocks["open_replica2"].ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	if _, err := db.Exec("DELETE FROM book"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		var one int
+		if err := db.QueryRow("SELECT 1").Scan(&one); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for dsn, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: %s", dsn, err)
+		}
+	}
+}
+
+func TestOpenInvalidURL(t *testing.T) {
+	for _, urlstr := range []string{
+		"postgres://localhost?primary=dsn",
+		"dbresolver://?primary=dsn",
+		"dbresolver://postgres?replica=dsn",
+		"dbresolver://postgres?primary=dsn&lb=least_conn",
+		"dbresolver://postgres?primary=dsn&replicas=dsn",
+		"dbresolver://postgres?primary=%zz",
+	} {
+		if _, err := Open(urlstr); err == nil {
+			t.Errorf("want error for %s", urlstr)
+		}
+	}
+}
+
+func TestURLOpenerClosesOnError(t *testing.T) {
+	var opened []sqlmock.Sqlmock
+	opener := &URLOpener{
+		Open: func(driverName, dsn string) (*sql.DB, error) {
+			if dsn == "broken" {
+				return nil, errors.New("broken dsn")
+			}
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				return nil, err
+			}
+			mock.ExpectClose()
+			opened = append(opened, mock)
+			return db, nil
+		},
+	}
+	u, _ := url.Parse("dbresolver://postgres?primary=dsn1&replica=dsn2&replica=broken")
+	if _, err := opener.OpenDBURL(context.Background(), u); err == nil {
+		t.Fatal("want the open error")
+	}
+	if len(opened) != 2 {
+		t.Fatalf("want the 2 dsns opened before the error, got %d", len(opened))
+	}
+	for _, mock := range opened {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestOpenRedactsErrors(t *testing.T) {
+	_, err := Open("dbresolver://postgres?primary=postgres%3A%2F%2Fapp%3Asecret%40primary%2Fapp\x7f")
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("want the invalid URL error redacted, got %v", err)
+	}
+
+	opener := &URLOpener{
+		Open: func(driverName, dsn string) (*sql.DB, error) {
+			return nil, fmt.Errorf("cannot parse %q", dsn)
+		},
+	}
+	u, _ := url.Parse("dbresolver://postgres?primary=" + url.QueryEscape("host=primary password=secret"))
+	_, err = opener.OpenDBURL(context.Background(), u)
+	if err == nil || err.Error() != `dbresolver: opening primary 0: cannot parse "host=primary password=xxxxx"` {
+		t.Errorf("want the open error redacted, got %v", err)
+	}
+}
+
+func TestOpenDSN(t *testing.T) {
+	mocks := map[string]sqlmock.Sqlmock{}
+	for _, dsn := range []string{"opendsn_primary", "opendsn_replica1", "opendsn_replica2"} {
+		_, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+		if err != nil {
+			t.Fatal("creating of mock failed", err)
+		}
+		mocks[dsn] = mock
+	}
+
+	db, err := OpenDSN("sqlmock", "primary=opendsn_primary;replica=opendsn_replica1;replica=opendsn_replica2;lb=random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if len(db.PrimaryDBs()) != 1 || len(db.ReplicaDBs()) != 2 {
+		t.Fatalf("want 1 primary and 2 replicas, got %d and %d", len(db.PrimaryDBs()), len(db.ReplicaDBs()))
+	}
+
+	mocks["opendsn_primary"].ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := db.Exec("DELETE FROM book"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mocks["opendsn_primary"].ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := OpenDSN("sqlmock", "opendsn_primary"); err == nil {
+		t.Error("want error for a DSN without role")
+	}
+	if _, err := OpenDSN("sqlmock", "replica=opendsn_replica1"); err == nil {
+		t.Error("want error without primary")
+	}
+}
+
+func TestParseRoleDSN(t *testing.T) {
+	params, err := parseRoleDSN("primary=server=db1;user id=app;password=secret; replica=server=db2;database=app ;lb=random;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := url.Values{
+		"primary": {"server=db1;user id=app;password=secret"},
+		"replica": {"server=db2;database=app"},
+		"lb":      {"random"},
+	}
+	if len(params) != len(want) {
+		t.Fatalf("want %v, got %v", want, params)
+	}
+	for key, values := range want {
+		if len(params[key]) != 1 || params[key][0] != values[0] {
+			t.Errorf("%s: want %q, got %q", key, values, params[key])
+		}
+	}
+}
@@ -3,6 +3,7 @@ package dbresolver
 import (
 	"database/sql"
 	"fmt"
+	"time"
 )
 
 // LoadBalancerPolicy define the loadbalancer policy data type
@@ -10,17 +11,64 @@ type LoadBalancerPolicy string
 
 // Supported Loadbalancer policy
 const (
-	RoundRobinLB LoadBalancerPolicy = "ROUND_ROBIN"
-	RandomLB     LoadBalancerPolicy = "RANDOM"
+	RoundRobinLB       LoadBalancerPolicy = "ROUND_ROBIN"
+	RandomLB           LoadBalancerPolicy = "RANDOM"
+	LeastConnectionsLB LoadBalancerPolicy = "LEAST_CONNECTIONS"
+	LatencyLB          LoadBalancerPolicy = "LATENCY"
+	AffinityLB         LoadBalancerPolicy = "AFFINITY"
 )
 
 // Option define the option property
 type Option struct {
-	PrimaryDBs       []*sql.DB
-	ReplicaDBs       []*sql.DB
-	StmtLB           StmtLoadBalancer
-	DBLB             DBLoadBalancer
-	QueryTypeChecker QueryTypeChecker
+	PrimaryDBs          []*sql.DB
+	ReplicaDBs          []*sql.DB
+	StmtLB              StmtLoadBalancer
+	DBLB                DBLoadBalancer
+	QueryTypeChecker    QueryTypeChecker
+	DNSDiscovery        *DNSDiscovery
+	Discoveries         []Discovery
+	Hooks               []Hooks
+	QueryHooks          []QueryHook
+	NodeLabels          map[*sql.DB]map[string]string
+	ReadOnlyDetector    ReadOnlyDetector
+	ReplicaWeights      map[*sql.DB]int
+	PrimaryWeights      map[*sql.DB]int
+	SlowQueryLog        SlowQueryLog
+	LifetimeJitter      float64
+	Redactor            Redactor
+	StartupCheck        *StartupCheck
+	Saturation          *Saturation
+	MaxParallelism      int
+	PrepareConcurrency  int
+	ReadCache           *ReadCache
+	WorkloadPartitions  map[string]float64
+	ReadCoalescing      *ReadCoalescing
+	AdaptiveConcurrency *AdaptiveConcurrency
+	Clock               Clock
+	Shards              map[string]ShardConfig
+	ShardResolver       ShardResolver
+	NearestReads        *NearestReads
+	AdmissionControl    *AdmissionControl
+	NotificationWaiter  NotificationWaiter
+	BulkLoad            BulkLoad
+	DefaultTxOptions    *sql.TxOptions
+	QueryParsing        bool
+	HealthCheck         *HealthCheck
+	LagMonitor          *LagMonitor
+	StickyPrimary       time.Duration
+	RetryPolicy         *RetryPolicy
+	CircuitBreaker      *CircuitBreaker
+	Logger              Logger
+	ReadOnlyTxOnPrimary bool
+	WriteStrategy       WriteStrategy
+	RoleDetection       *RoleDetection
+	LazyPrepare         bool
+	StmtCache           int
+	Classifier          Classifier
+	ReadFallback        ReadFallback
+	ReadMode            ReadMode
+	PrimaryQueryTimeout time.Duration
+	ReplicaQueryTimeout time.Duration
 }
 
 // OptionFunc used for option chaining
@@ -48,23 +96,100 @@ func WithQueryTypeChecker(checker QueryTypeChecker) OptionFunc {
 	}
 }
 
+// WithDNSDiscovery resolves the host A/SRV records into the replicas of the resolver,
+// and keeps them in sync by resolving the host again on every refreshInterval.
+// The open function opens the replica connection for each resolved address.
+func WithDNSDiscovery(host string, refreshInterval time.Duration, open func(addr string) (*sql.DB, error)) OptionFunc {
+	return func(opt *Option) {
+		opt.DNSDiscovery = &DNSDiscovery{
+			Host:            host,
+			RefreshInterval: refreshInterval,
+			Open:            open,
+		}
+	}
+}
+
+// WithDiscoverer keeps the primaries and replicas of the resolver in sync with the endpoints found by the discoverer.
+// The discoverer is called again after every refreshInterval, it can be zero for discoverers
+// blocking until the endpoints change. The open function opens the connection for each discovered endpoint.
+// It can be used multiple times to combine multiple discoverers.
+func WithDiscoverer(discoverer Discoverer, refreshInterval time.Duration,
+	open func(endpoint Endpoint) (*sql.DB, error)) OptionFunc {
+	return func(opt *Option) {
+		opt.Discoveries = append(opt.Discoveries, Discovery{
+			Discoverer:      discoverer,
+			Open:            open,
+			RefreshInterval: refreshInterval,
+		})
+	}
+}
+
+// WithHooks attaches the hooks to the queries of the resolver, in the given order.
+// It can be used multiple times, eg. to attach the github.com/qustavo/sqlhooks implementations
+// of different instrumentations.
+func WithHooks(hooks ...Hooks) OptionFunc {
+	return func(opt *Option) {
+		opt.Hooks = append(opt.Hooks, hooks...)
+	}
+}
+
+// WithConnMaxLifetimeJitter spreads the connection expiries of the nodes: SetConnMaxLifetime sets
+// the lifetime of each node to a random duration between (1-fraction)*d and d, instead of expiring
+// and reconnecting the connections of every node at the same time. The fraction is in [0, 1), eg. 0.1.
+func WithConnMaxLifetimeJitter(fraction float64) OptionFunc {
+	if fraction < 0 || fraction >= 1 {
+		panic(fmt.Sprintf("dbresolver: invalid lifetime jitter %v, the fraction must be in [0, 1)", fraction))
+	}
+	return func(opt *Option) {
+		opt.LifetimeJitter = fraction
+	}
+}
+
+// WithMaxParallelism bounds the number of nodes called concurrently by Ping, Prepare, Close
+// and ValidateTopology, DefaultMaxParallelism by default. Zero calls every node concurrently.
+func WithMaxParallelism(n int) OptionFunc {
+	if n < 0 {
+		panic(fmt.Sprintf("dbresolver: invalid max parallelism %d", n))
+	}
+	return func(opt *Option) {
+		opt.MaxParallelism = n
+	}
+}
+
+// WithPrepareConcurrency bounds the number of nodes preparing a statement concurrently, instead of
+// the max parallelism. Preparing takes a connection on each node, opening it when the pool has no idle one,
+// so it limits the burst of new connections when preparing a statement across a large fleet.
+// A node waits for a free connection when its pool is at its max open connections.
+func WithPrepareConcurrency(n int) OptionFunc {
+	if n < 1 {
+		panic(fmt.Sprintf("dbresolver: invalid prepare concurrency %d", n))
+	}
+	return func(opt *Option) {
+		opt.PrepareConcurrency = n
+	}
+}
+
+// WithDefaultTxOptions sets the options of the transactions begun with nil options,
+// by Begin and BeginTx of the resolver and of its connections, eg. the REPEATABLE READ isolation level.
+func WithDefaultTxOptions(opts sql.TxOptions) OptionFunc {
+	return func(opt *Option) {
+		opt.DefaultTxOptions = &opts
+	}
+}
+
+// WithReadOnlyTxOnPrimary begins the read-only transactions on a primary, like the other transactions.
+// By default BeginTx begins them on a replica, see DB.BeginTx.
+func WithReadOnlyTxOnPrimary() OptionFunc {
+	return func(opt *Option) {
+		opt.ReadOnlyTxOnPrimary = true
+	}
+}
+
 // WithLoadBalancer configure the loadbalancer for the resolver
 func WithLoadBalancer(lb LoadBalancerPolicy) OptionFunc {
 	return func(opt *Option) {
-		switch lb {
-		case RoundRobinLB:
-			opt.DBLB = &RoundRobinLoadBalancer[*sql.DB]{}
-			opt.StmtLB = &RoundRobinLoadBalancer[*sql.Stmt]{}
-		case RandomLB:
-			opt.DBLB = &RandomLoadBalancer[*sql.DB]{
-				randInt: make(chan int, 1),
-			}
-			opt.StmtLB = &RandomLoadBalancer[*sql.Stmt]{
-				randInt: make(chan int, 1),
-			}
-		default:
-			panic(fmt.Sprintf("LoadBalancer: %s is not supported", lb))
-		}
+		opt.DBLB = NewLoadBalancer[*sql.DB](lb)
+		opt.StmtLB = NewLoadBalancer[*sql.Stmt](lb)
 	}
 }
 
@@ -73,5 +198,8 @@ func defaultOption() *Option {
 		DBLB:             &RoundRobinLoadBalancer[*sql.DB]{},
 		StmtLB:           &RoundRobinLoadBalancer[*sql.Stmt]{},
 		QueryTypeChecker: &DefaultQueryTypeChecker{},
+		Clock:            systemClock{},
+		MaxParallelism:   DefaultMaxParallelism,
+		Classifier:       DefaultClassifier,
 	}
 }
@@ -3,6 +3,7 @@ package dbresolver_test
 import (
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/dbresolver/v2"
 )
@@ -50,3 +51,15 @@ func TestOptionWithLoadBalancerNonExist(t *testing.T) {
 	opt := &dbresolver.Option{}
 	optFunc(opt)
 }
+
+func TestOptionWithDNSDiscovery(t *testing.T) {
+	optFunc := dbresolver.WithDNSDiscovery("reader.example.com:5432", time.Minute, func(addr string) (*sql.DB, error) {
+		return sql.Open("postgres", "host="+addr)
+	})
+	opt := &dbresolver.Option{}
+	optFunc(opt)
+
+	if opt.DNSDiscovery == nil || opt.DNSDiscovery.Host != "reader.example.com:5432" {
+		t.Errorf("want %v, got %v", "reader.example.com:5432", opt.DNSDiscovery)
+	}
+}
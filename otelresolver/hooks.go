@@ -0,0 +1,98 @@
+// Package otelresolver traces the queries of the resolver with OpenTelemetry.
+//
+// The spans of the resolver carry the route of the queries (the role of the db and the fallbacks).
+// The context of the span is passed down to the db, so the spans of an instrumented driver,
+// eg. the *sql.DB opened with github.com/XSAM/otelsql, are children of the resolver spans.
+// WithTracerProvider traces the preparations too, with the index of the node and the load balancer.
+package otelresolver
+
+import (
+	"context"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/bxcodec/dbresolver/v2/otelresolver"
+
+// spanKey holds the span started by Before, the span of the context can be a parent span
+// when the Before hook of another instrumentation failed first
+type spanKey struct{}
+
+// Attributes of the resolver spans
+const (
+	RoleKey      = attribute.Key("dbresolver.role")
+	FallbackKey  = attribute.Key("dbresolver.fallback")
+	StatementKey = attribute.Key("db.statement")
+)
+
+// Config define how the queries are traced
+type Config struct {
+	// TracerProvider creates the tracer, the global tracer provider by default
+	TracerProvider trace.TracerProvider
+	// SpanName is the name of the spans, "dbresolver.query" by default
+	SpanName string
+	// OmitStatement omits the query from the span attributes
+	OmitStatement bool
+}
+
+// Hooks starts a span for every query of the resolver
+type Hooks struct {
+	tracer trace.Tracer
+	config Config
+}
+
+var (
+	_ dbresolver.Hooks     = (*Hooks)(nil)
+	_ dbresolver.OnErrorer = (*Hooks)(nil)
+)
+
+// New creates the hooks, attached to the resolver with dbresolver.WithHooks
+func New(config Config) *Hooks {
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+	if config.SpanName == "" {
+		config.SpanName = "dbresolver.query"
+	}
+	return &Hooks{
+		tracer: config.TracerProvider.Tracer(instrumentationName),
+		config: config,
+	}
+}
+
+// Before starts the span of the query
+func (h *Hooks) Before(ctx context.Context, query string, _ ...interface{}) (context.Context, error) {
+	attrs := make([]attribute.KeyValue, 0, 3)
+	if route, ok := dbresolver.RouteFromContext(ctx); ok {
+		attrs = append(attrs, RoleKey.String(string(route.Role)), FallbackKey.Bool(route.Fallback))
+	}
+	if !h.config.OmitStatement {
+		attrs = append(attrs, StatementKey.String(query))
+	}
+	ctx, span := h.tracer.Start(ctx, h.config.SpanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	return context.WithValue(ctx, spanKey{}, span), nil
+}
+
+// After ends the span of the query
+func (h *Hooks) After(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	if span, ok := ctx.Value(spanKey{}).(trace.Span); ok {
+		span.End()
+	}
+	return ctx, nil
+}
+
+// OnError records the error and ends the span of the query
+func (h *Hooks) OnError(ctx context.Context, err error, _ string, _ ...interface{}) error {
+	if span, ok := ctx.Value(spanKey{}).(trace.Span); ok {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package otelresolver_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/XSAM/otelsql"
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/otelresolver"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// openMock opens a sqlmock db instrumented by otelsql
+func openMock(t *testing.T, dsn string, tp trace.TracerProvider) (*sql.DB, sqlmock.Sqlmock) {
+	_, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	db, err := otelsql.Open("sqlmock", dsn, otelsql.WithTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, mock
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan, name string) []sdktrace.ReadOnlySpan {
+	var found []sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		if span.Name() == name {
+			found = append(found, span)
+		}
+	}
+	return found
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, kv attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHooksWithOtelsql(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	primary, primaryMock := openMock(t, "otelresolver_primary", tp)
+	replica, replicaMock := openMock(t, "otelresolver_replica", tp)
+	db := dbresolver.New(
+		dbresolver.WithPrimaryDBs(primary),
+		dbresolver.WithReplicaDBs(replica),
+		dbresolver.WithHooks(otelresolver.New(otelresolver.Config{TracerProvider: tp})))
+
+	replicaMock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Hiro"))
+	primaryMock.ExpectPrepare("UPDATE users SET name = ?").
+		ExpectExec().WithArgs("Yuki").WillReturnError(errors.New("deadlock"))
+	replicaMock.ExpectPrepare("UPDATE users SET name = ?")
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "handler")
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := db.PrepareContext(ctx, "UPDATE users SET name = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.ExecContext(ctx, "Yuki"); err == nil {
+		t.Fatal("want the exec error")
+	}
+	stmt.Close()
+	parent.End()
+
+	spans := recorder.Ended()
+	resolverSpans := spansByName(spans, "dbresolver.query")
+	if len(resolverSpans) != 2 {
+		t.Fatalf("want 2 resolver spans, got %d", len(resolverSpans))
+	}
+	query, exec := resolverSpans[0], resolverSpans[1]
+	if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("want the resolver span to be a child of the caller span")
+	}
+	if !hasAttribute(query, otelresolver.RoleKey.String("replica")) ||
+		!hasAttribute(query, otelresolver.StatementKey.String("SELECT name FROM users")) {
+		t.Errorf("unexpected query span attributes %v", query.Attributes())
+	}
+	if !hasAttribute(exec, otelresolver.RoleKey.String("primary")) || exec.Status().Code != codes.Error {
+		t.Errorf("unexpected exec span %v %v", exec.Attributes(), exec.Status())
+	}
+
+	// the driver spans are children of the resolver spans
+	for name, resolverSpan := range map[string]sdktrace.ReadOnlySpan{"sql.conn.query": query, "sql.stmt.exec": exec} {
+		driverSpans := spansByName(spans, name)
+		if len(driverSpans) != 1 {
+			t.Fatalf("want 1 %s span, got %d", name, len(driverSpans))
+		}
+		if driverSpans[0].Parent().SpanID() != resolverSpan.SpanContext().SpanID() {
+			t.Errorf("want the %s span to be a child of the resolver span", name)
+		}
+	}
+	// the statements are prepared with the caller context
+	prepareSpans := spansByName(spans, "sql.conn.prepare")
+	if len(prepareSpans) != 2 {
+		t.Fatalf("want 2 sql.conn.prepare spans, got %d", len(prepareSpans))
+	}
+	for _, prepare := range prepareSpans {
+		if prepare.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Error("want the prepare span to be a child of the caller span")
+		}
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWithTracerProvider(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	primary, primaryMock := openMock(t, "otelresolver_tp_primary", tp)
+	replica1, _ := openMock(t, "otelresolver_tp_replica1", tp)
+	replica2, replica2Mock := openMock(t, "otelresolver_tp_replica2", tp)
+	db := dbresolver.New(
+		dbresolver.WithPrimaryDBs(primary),
+		dbresolver.WithNamedReplicaDBs(dbresolver.Named("replica-1", replica1), dbresolver.Named("replica-2", replica2)),
+		otelresolver.WithTracerProvider(tp))
+
+	connErr := errors.New("dial tcp: connection refused")
+	replica2Mock.ExpectQuery("SELECT name FROM users").WillReturnError(&net.OpError{Op: "dial", Net: "tcp", Err: connErr})
+	primaryMock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Hiro"))
+
+	rows, err := db.QueryContext(context.Background(), "SELECT name FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	spans := spansByName(recorder.Ended(), "dbresolver.query")
+	if len(spans) != 2 {
+		t.Fatalf("want 2 resolver spans, got %d", len(spans))
+	}
+	replicaSpan, fallbackSpan := spans[0], spans[1]
+	if !hasAttribute(replicaSpan, otelresolver.RoleKey.String("replica")) ||
+		!hasAttribute(replicaSpan, otelresolver.NodeIndexKey.Int(1)) ||
+		!hasAttribute(replicaSpan, otelresolver.NodeNameKey.String("replica-2")) ||
+		!hasAttribute(replicaSpan, otelresolver.LoadBalancerKey.String(string(dbresolver.RoundRobinLB))) ||
+		replicaSpan.Status().Code != codes.Error {
+		t.Errorf("unexpected replica span %v %v", replicaSpan.Attributes(), replicaSpan.Status())
+	}
+	if !hasAttribute(fallbackSpan, otelresolver.RoleKey.String("primary")) ||
+		!hasAttribute(fallbackSpan, otelresolver.NodeIndexKey.Int(0)) ||
+		len(fallbackSpan.Events()) != 1 || fallbackSpan.Events()[0].Name != otelresolver.FallbackEvent {
+		t.Errorf("unexpected fallback span %v %v", fallbackSpan.Attributes(), fallbackSpan.Events())
+	}
+}
@@ -0,0 +1,88 @@
+package otelresolver
+
+import (
+	"context"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of the spans of the query hook, besides the ones of the Hooks
+const (
+	NodeIndexKey    = attribute.Key("dbresolver.node_index")
+	NodeNameKey     = attribute.Key("dbresolver.node_name")
+	LoadBalancerKey = attribute.Key("dbresolver.load_balancer")
+	PrepareKey      = attribute.Key("dbresolver.prepare")
+)
+
+// FallbackEvent is the span event of the read queries sent again to a primary after a replica connection error
+const FallbackEvent = "dbresolver.fallback"
+
+// querySpanKey holds the span started by BeforeQuery
+type querySpanKey struct{}
+
+// QueryHook starts a span for every query and every preparation of the resolver, like the Hooks,
+// annotated with the index and the name of the node and the load balancer which resolved it
+type QueryHook struct {
+	tracer trace.Tracer
+	config Config
+}
+
+var _ dbresolver.QueryHook = (*QueryHook)(nil)
+
+// NewQueryHook creates the query hook, attached to the resolver with dbresolver.WithQueryHook
+func NewQueryHook(config Config) *QueryHook {
+	hooks := New(config)
+	return &QueryHook{tracer: hooks.tracer, config: hooks.config}
+}
+
+// WithTracerProvider traces the queries of the resolver with the tracer provider, see QueryHook
+func WithTracerProvider(tp trace.TracerProvider) dbresolver.OptionFunc {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return dbresolver.WithQueryHook(NewQueryHook(Config{TracerProvider: tp}))
+}
+
+// BeforeQuery starts the span of the query
+func (h *QueryHook) BeforeQuery(ctx context.Context, event *dbresolver.QueryEvent) context.Context {
+	attrs := make([]attribute.KeyValue, 0, 7)
+	if event.Prepare {
+		attrs = append(attrs, PrepareKey.Bool(true))
+	} else {
+		attrs = append(attrs, RoleKey.String(string(event.Route.Role)), FallbackKey.Bool(event.Route.Fallback))
+	}
+	if event.Index >= 0 {
+		attrs = append(attrs, NodeIndexKey.Int(event.Index))
+	}
+	if event.Name != "" {
+		attrs = append(attrs, NodeNameKey.String(event.Name))
+	}
+	attrs = append(attrs, LoadBalancerKey.String(string(event.LoadBalancer)))
+	if !h.config.OmitStatement {
+		attrs = append(attrs, StatementKey.String(event.Query))
+	}
+	ctx, span := h.tracer.Start(ctx, h.config.SpanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+	if event.Route.Fallback {
+		span.AddEvent(FallbackEvent)
+	}
+	return context.WithValue(ctx, querySpanKey{}, span)
+}
+
+// AfterQuery records the error, if any, and ends the span of the query
+func (h *QueryHook) AfterQuery(ctx context.Context, event *dbresolver.QueryEvent) {
+	span, ok := ctx.Value(querySpanKey{}).(trace.Span)
+	if !ok {
+		return
+	}
+	if event.Err != nil {
+		span.RecordError(event.Err)
+		span.SetStatus(codes.Error, event.Err.Error())
+	}
+	span.End()
+}
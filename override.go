@@ -0,0 +1,32 @@
+package dbresolver
+
+import "context"
+
+type roleOverrideKey struct{}
+
+// WithPrimary returns a context sending the queries of the resolver to a primary, eg. to read a write
+// without opening a transaction. The reads skip the read cache and the coalescing.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, roleOverrideKey{}, RolePrimary)
+}
+
+// WithReplica returns a context sending the queries of the resolver to a replica, whatever their query type.
+// ExecContext runs on a replica too, a primary when the resolver has no replica.
+func WithReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, roleOverrideKey{}, RoleReplica)
+}
+
+// RoleOverrideFromContext returns the role forced by WithPrimary or WithReplica
+func RoleOverrideFromContext(ctx context.Context) (Role, bool) {
+	role, ok := ctx.Value(roleOverrideKey{}).(Role)
+	return role, ok
+}
+
+// isWrite reports whether the query goes to a primary, the role forced by the context takes precedence
+// over the query type. The reads of a session sticking to a primary go to a primary, see WithStickyPrimary.
+func (db *sqlDB) isWrite(ctx context.Context, query string) bool {
+	if role, ok := RoleOverrideFromContext(ctx); ok {
+		return role == RolePrimary
+	}
+	return db.queryTypeChecker.Check(query) == QueryTypeWrite || db.sticksToPrimary(ctx)
+}
@@ -0,0 +1,54 @@
+package dbresolver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRoleOverride(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica))
+
+	primaryCtx := WithPrimary(context.Background())
+	primaryMock.ExpectQuery("SELECT title FROM book").WillReturnRows(sqlmock.NewRows([]string{"title"}))
+	rows, err := resolver.QueryContext(primaryCtx, "SELECT title FROM book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+	primaryMock.ExpectQuery("SELECT title FROM book").WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Dune"))
+	if err := resolver.QueryRowContext(primaryCtx, "SELECT title FROM book").Scan(new(string)); err != nil {
+		t.Error(err)
+	}
+
+	replicaCtx := WithReplica(context.Background())
+	replicaMock.ExpectQuery("SELECT title FROM book RETURNING id").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	rows, err = resolver.QueryContext(replicaCtx, "SELECT title FROM book RETURNING id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+	replicaMock.ExpectExec("SET TRANSACTION READ ONLY").WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := resolver.ExecContext(replicaCtx, "SET TRANSACTION READ ONLY"); err != nil {
+		t.Error(err)
+	}
+
+	if role, ok := RoleOverrideFromContext(primaryCtx); !ok || role != RolePrimary {
+		t.Errorf("want the primary override, got %v", role)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
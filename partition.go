@@ -0,0 +1,103 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+type workloadClassKey struct{}
+
+// WithWorkloadClass returns a copy of the context classifying its queries, eg. "batch", see WithWorkloadPartitions
+func WithWorkloadClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, workloadClassKey{}, class)
+}
+
+// WorkloadClass returns the workload class of the context, empty when it's not classified
+func WorkloadClass(ctx context.Context) string {
+	class, _ := ctx.Value(workloadClassKey{}).(string)
+	return class
+}
+
+// WithWorkloadPartitions splits the capacity of each node between the workload classes, eg. {"batch": 0.2}:
+// the queries of a class run on at most its share of the max open connections of the node, at least one,
+// so a batch job can't consume the entire pool of a replica shared with the interactive queries.
+// The queries of the classes without share, and the queries of the nodes without max open connections,
+// aren't limited. A query waits for a slot of its class until its context is done.
+//
+// A slot is held until Exec returns, until the rows of Query are closed, or until the row of QueryRow is scanned,
+// like the connection of the query.
+func WithWorkloadPartitions(shares map[string]float64) OptionFunc {
+	total := 0.0
+	for class, share := range shares {
+		if share <= 0 || share > 1 {
+			panic(fmt.Sprintf("dbresolver: invalid share %v of the workload class %q, it must be in (0, 1]", share, class))
+		}
+		total += share
+	}
+	if total > 1 {
+		panic(fmt.Sprintf("dbresolver: invalid workload partitions, the shares sum to %v", total))
+	}
+	copied := make(map[string]float64, len(shares))
+	for class, share := range shares {
+		copied[class] = share
+	}
+	return func(opt *Option) {
+		opt.WorkloadPartitions = copied
+	}
+}
+
+type partitionKey struct {
+	node  *sql.DB
+	class string
+}
+
+// partitions are the semaphores of the workload classes of each node
+type partitions struct {
+	shares map[string]float64
+
+	mu         sync.Mutex
+	semaphores map[partitionKey]chan struct{}
+}
+
+func newPartitions(shares map[string]float64) *partitions {
+	return &partitions{shares: shares, semaphores: make(map[partitionKey]chan struct{})}
+}
+
+// acquire waits for a slot of the class of the context on the node, the release function frees it
+func (p *partitions) acquire(ctx context.Context, node *sql.DB) (release func(), err error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	class := WorkloadClass(ctx)
+	share, ok := p.shares[class]
+	if !ok {
+		return func() {}, nil
+	}
+	maxOpen := node.Stats().MaxOpenConnections
+	if maxOpen == 0 {
+		return func() {}, nil
+	}
+
+	semaphore := p.semaphore(partitionKey{node: node, class: class}, max(1, int(share*float64(maxOpen))))
+	select {
+	case semaphore <- struct{}{}:
+		return func() { <-semaphore }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// semaphore returns the semaphore of the key, a new one when the max open connections of the node changed.
+// The slots held in the previous semaphore are released into it.
+func (p *partitions) semaphore(key partitionKey, size int) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	semaphore, ok := p.semaphores[key]
+	if !ok || cap(semaphore) != size {
+		semaphore = make(chan struct{}, size)
+		p.semaphores[key] = semaphore
+	}
+	return semaphore
+}
@@ -0,0 +1,108 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWorkloadPartitions(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	primary.SetMaxOpenConns(10)
+	resolver := New(WithPrimaryDBs(primary), WithWorkloadPartitions(map[string]float64{"batch": 0.2})).(*sqlDB)
+
+	// the batch class holds 2 of the 10 connections
+	batch := WithWorkloadClass(context.Background(), "batch")
+	var releases []func()
+	for i := 0; i < 2; i++ {
+		release, err := resolver.partitions.acquire(batch, primary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+	ctx, cancel := context.WithTimeout(batch, 10*time.Millisecond)
+	defer cancel()
+	if _, err := resolver.ExecContext(ctx, "DELETE FROM book"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want the batch query waiting for a slot, got %v", err)
+	}
+
+	// the other classes aren't limited by the batch class
+	primaryMock.ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.ExecContext(WithWorkloadClass(context.Background(), "interactive"), "DELETE FROM book"); err != nil {
+		t.Error(err)
+	}
+
+	releases[0]()
+	primaryMock.ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.ExecContext(batch, "DELETE FROM book"); err != nil {
+		t.Errorf("want the released slot used, got %v", err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWorkloadPartitionsRows(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	primary.SetMaxOpenConns(5)
+	resolver := New(WithPrimaryDBs(primary), WithWorkloadPartitions(map[string]float64{"batch": 0.2}))
+
+	// the open rows hold the only slot of the batch class
+	batch := WithWorkloadClass(context.Background(), "batch")
+	primaryMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	rows, err := resolver.QueryContext(batch, "SELECT id FROM book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(batch, 10*time.Millisecond)
+	defer cancel()
+	if _, err := resolver.QueryContext(ctx, "SELECT id FROM book"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want the batch query waiting for the slot held by the rows, got %v", err)
+	}
+
+	// closing the rows releases the slot, and scanning the row releases it again
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	primaryMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	var id int
+	if err := resolver.QueryRowContext(batch, "SELECT id FROM book").Scan(&id); err != nil || id != 2 {
+		t.Fatalf("want the released slot used, got %d, %v", id, err)
+	}
+	primaryMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	ctx, cancel = context.WithTimeout(batch, time.Second)
+	defer cancel()
+	if err := resolver.QueryRowContext(ctx, "SELECT id FROM book").Scan(&id); err != nil || id != 3 {
+		t.Fatalf("want the slot released by the Scan, got %d, %v", id, err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWorkloadPartitionsInvalid(t *testing.T) {
+	for _, shares := range []map[string]float64{
+		{"batch": 0},
+		{"batch": 1.5},
+		{"batch": 0.5, "reporting": 0.6},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want panic for %v", shares)
+				}
+			}()
+			WithWorkloadPartitions(shares)
+		}()
+	}
+}
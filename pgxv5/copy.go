@@ -0,0 +1,23 @@
+package pgxv5
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// CopyFrom is the dbresolver.BulkCopier of the pgx stdlib driver, it loads the rows with the COPY protocol.
+// The table may be qualified by its schema, eg. "public.books", each part is quoted as an identifier:
+//
+//	db := dbresolver.New(dbresolver.WithPrimaryDBs(primary), dbresolver.WithBulkLoad(dbresolver.BulkLoad{Copier: pgxv5.CopyFrom}))
+//	loaded, err := db.BulkInsert(ctx, "books", []string{"id", "title"}, rows)
+func CopyFrom(ctx context.Context, driverConn any, table string, columns []string, rows [][]interface{}) (int64, error) {
+	conn, ok := driverConn.(*stdlib.Conn)
+	if !ok {
+		return 0, fmt.Errorf("pgxv5: the connection %T isn't a pgx stdlib connection", driverConn)
+	}
+	return conn.Conn().CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, pgx.CopyFromRows(rows))
+}
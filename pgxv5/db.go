@@ -0,0 +1,197 @@
+package pgxv5
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Pool is the pgx connection pool contract used by the resolver.
+// It's satisfied by *pgxpool.Pool.
+type Pool interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// DB is the pgx flavored contract of the resolver.
+// It mirrors the pgxpool.Pool API, routing each call to the primary or replica pools.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+	Close()
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Ping(ctx context.Context) error
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	PrimaryPools() []Pool
+	ReplicaPools() []Pool
+	// ReadOnly returns the pool a read query would be routed to, resolved by the load balancer
+	ReadOnly() Pool
+	// ReadWrite returns the pool a write query would be routed to, resolved by the load balancer
+	ReadWrite() Pool
+}
+
+// pgxDB is a logical database with multiple underlying pgx pools
+// forming a single ReadWrite (primary) with multiple ReadOnly(replicas) pools.
+type pgxDB struct {
+	primaries        []Pool
+	replicas         []Pool
+	loadBalancer     dbresolver.LoadBalancer[Pool]
+	queryTypeChecker dbresolver.QueryTypeChecker
+	classifier       dbresolver.Classifier
+}
+
+// PrimaryPools return all the active primary pools
+func (db *pgxDB) PrimaryPools() []Pool {
+	return db.primaries
+}
+
+// ReplicaPools return all the active replica pools
+func (db *pgxDB) ReplicaPools() []Pool {
+	return db.replicas
+}
+
+// Begin starts a transaction on the RW-pool.
+func (db *pgxDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return db.ReadWrite().Begin(ctx)
+}
+
+// BeginTx starts a transaction with the given options on the RW-pool.
+func (db *pgxDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return db.ReadWrite().BeginTx(ctx, txOptions)
+}
+
+// Close closes all the pools concurrently.
+func (db *pgxDB) Close() {
+	_ = db.forEachPool(func(pool Pool) error {
+		pool.Close()
+		return nil
+	})
+}
+
+// CopyFrom uses the PostgreSQL copy protocol to perform bulk data insertion on the RW-pool.
+func (db *pgxDB) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string,
+	rowSrc pgx.CopyFromSource) (int64, error) {
+	return db.ReadWrite().CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+// Exec executes the sql on the RW-pool.
+func (db *pgxDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return db.ReadWrite().Exec(ctx, sql, args...)
+}
+
+// Ping verifies if a connection to each pool is still alive.
+func (db *pgxDB) Ping(ctx context.Context) error {
+	return db.forEachPool(func(pool Pool) error {
+		return pool.Ping(ctx)
+	})
+}
+
+// Query executes a query that returns rows on the RO-pool,
+// unless the query type checker detects a write query.
+func (db *pgxDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	writeFlag := db.queryTypeChecker.Check(sql) == dbresolver.QueryTypeWrite
+
+	var curPool Pool
+	if writeFlag {
+		curPool = db.ReadWrite()
+	} else {
+		curPool = db.ReadOnly()
+	}
+
+	rows, err := curPool.Query(ctx, sql, args...)
+	if !writeFlag && db.isConnectionError(err) {
+		rows, err = db.ReadWrite().Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+// QueryRow executes a query that is expected to return at most one row on the RO-pool,
+// unless the query type checker detects a write query.
+// Errors are deferred until Row's Scan method is called, the read is sent again to the RW-pool
+// when the Scan fails with a connection error.
+func (db *pgxDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if db.queryTypeChecker.Check(sql) == dbresolver.QueryTypeWrite {
+		return db.ReadWrite().QueryRow(ctx, sql, args...)
+	}
+	return &fallbackRow{row: db.ReadOnly().QueryRow(ctx, sql, args...), db: db, ctx: ctx, sql: sql, args: args}
+}
+
+// fallbackRow is the row of a read, scanned again from the RW-pool on a connection error
+type fallbackRow struct {
+	row  pgx.Row
+	db   *pgxDB
+	ctx  context.Context
+	sql  string
+	args []any
+}
+
+// Scan scans the row, from the RW-pool when the RO-pool fails with a connection error
+func (r *fallbackRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if r.db.isConnectionError(err) {
+		err = r.db.ReadWrite().QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	}
+	return err
+}
+
+// SendBatch sends all queued queries to the RW-pool at once,
+// since a batch may mix read and write queries.
+func (db *pgxDB) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return db.ReadWrite().SendBatch(ctx, b)
+}
+
+// ReadOnly returns the readonly pool
+func (db *pgxDB) ReadOnly() Pool {
+	if len(db.replicas) == 0 {
+		return db.resolve(db.primaries)
+	}
+	return db.resolve(db.replicas)
+}
+
+// ReadWrite returns the primary pool
+func (db *pgxDB) ReadWrite() Pool {
+	return db.resolve(db.primaries)
+}
+
+// isConnectionError reports whether the query failed with a connection error, as classified by the classifier
+func (db *pgxDB) isConnectionError(err error) bool {
+	return err != nil && db.classifier.Classify(err) == dbresolver.ErrorClassConnection
+}
+
+// forEachPool calls fn for each pool concurrently, and joins the returned errors
+func (db *pgxDB) forEachPool(fn func(pool Pool) error) error {
+	pools := append(db.primaries[:len(db.primaries):len(db.primaries)], db.replicas...)
+	errs := make([]error, len(pools))
+	var wg sync.WaitGroup
+	for i, pool := range pools {
+		wg.Add(1)
+		go func(i int, pool Pool) {
+			defer wg.Done()
+			errs[i] = fn(pool)
+		}(i, pool)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// resolve returns the only pool without load balancing it, the fast path of the single node topologies
+func (db *pgxDB) resolve(pools []Pool) Pool {
+	if len(pools) == 1 {
+		return pools[0]
+	}
+	return db.loadBalancer.Resolve(pools)
+}
@@ -0,0 +1,178 @@
+package pgxv5_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2/pgxv5"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func createMock(t *testing.T) pgxmock.PgxPoolIface {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	return mock
+}
+
+func assertExpectations(t *testing.T, mocks ...pgxmock.PgxPoolIface) {
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("pgxmock:unmet expectations: %s", err)
+		}
+	}
+}
+
+func TestPgxRouting(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := createMock(t), createMock(t)
+	db := pgxv5.New(pgxv5.WithPrimaryPools(primary), pgxv5.WithReplicaPools(replica))
+
+	t.Run("query on replica", func(t *testing.T) {
+		replica.ExpectQuery("SELECT name FROM users").
+			WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("Hiro"))
+
+		rows, err := db.Query(ctx, "SELECT name FROM users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("query row on replica", func(t *testing.T) {
+		replica.ExpectQuery("SELECT name FROM users").
+			WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("Hiro"))
+
+		var name string
+		if err := db.QueryRow(ctx, "SELECT name FROM users").Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("returning query on primary", func(t *testing.T) {
+		primary.ExpectQuery("INSERT INTO users").
+			WithArgs("Hiro").
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(1))
+
+		var id int
+		if err := db.QueryRow(ctx, "INSERT INTO users(name) VALUES ($1) RETURNING id", "Hiro").Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("exec on primary", func(t *testing.T) {
+		primary.ExpectExec("DELETE FROM users").WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		if _, err := db.Exec(ctx, "DELETE FROM users"); err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("copy from on primary", func(t *testing.T) {
+		primary.ExpectCopyFrom(pgx.Identifier{"users"}, []string{"name"}).WillReturnResult(2)
+
+		n, err := db.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"name"},
+			pgx.CopyFromRows([][]any{{"Hiro"}, {"Iman"}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 2 {
+			t.Errorf("want %v, got %v", 2, n)
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("batch on primary", func(t *testing.T) {
+		expected := primary.ExpectBatch()
+		expected.ExpectExec("UPDATE users").WithArgs("Hiro").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		batch := &pgx.Batch{}
+		batch.Queue("UPDATE users SET name = $1", "Hiro")
+		if err := db.SendBatch(ctx, batch).Close(); err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("transaction on primary", func(t *testing.T) {
+		primary.ExpectBegin()
+		primary.ExpectCommit()
+
+		tx, err := db.Begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("fallback to primary on connection error", func(t *testing.T) {
+		replica.ExpectQuery("SELECT name FROM users").
+			WillReturnError(&net.OpError{Op: "dial", Net: "tcp", Err: net.UnknownNetworkError("down")})
+		primary.ExpectQuery("SELECT name FROM users").
+			WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("Hiro"))
+
+		rows, err := db.Query(ctx, "SELECT name FROM users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Close()
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("query row fallback to primary on connection error", func(t *testing.T) {
+		replica.ExpectQuery("SELECT name FROM users").
+			WillReturnError(&net.OpError{Op: "dial", Net: "tcp", Err: net.UnknownNetworkError("down")})
+		primary.ExpectQuery("SELECT name FROM users").
+			WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("Hiro"))
+
+		var name string
+		if err := db.QueryRow(ctx, "SELECT name FROM users").Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		if name != "Hiro" {
+			t.Errorf("want %v, got %v", "Hiro", name)
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("query row error on replica", func(t *testing.T) {
+		replica.ExpectQuery("SELECT name FROM users").WillReturnError(errors.New("syntax error"))
+
+		var name string
+		if err := db.QueryRow(ctx, "SELECT name FROM users").Scan(&name); err == nil {
+			t.Error("want the error of the replica")
+		}
+		assertExpectations(t, primary, replica)
+	})
+
+	t.Run("ping all pools", func(t *testing.T) {
+		primary.ExpectPing()
+		replica.ExpectPing()
+
+		if err := db.Ping(ctx); err != nil {
+			t.Fatal(err)
+		}
+		assertExpectations(t, primary, replica)
+	})
+}
+
+func TestNewWithoutPrimary(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Should throw panic, but it does not")
+		}
+	}()
+
+	pgxv5.New(pgxv5.WithReplicaPools(createMock(t)))
+}
@@ -0,0 +1,36 @@
+package pgxv5_test
+
+import (
+	"context"
+	"log"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/pgxv5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func ExampleNew() {
+	ctx := context.Background()
+
+	primaryPool, err := pgxpool.New(ctx, "postgres://postgresrw:<password>@localhost:5432/<dbname>")
+	if err != nil {
+		log.Print("go error when connecting to the DB")
+	}
+	replicaPool, err := pgxpool.New(ctx, "postgres://postgresro:<password>@localhost:5433/<dbname>")
+	if err != nil {
+		log.Print("go error when connecting to the DB")
+	}
+
+	db := pgxv5.New(
+		pgxv5.WithPrimaryPools(primaryPool),
+		pgxv5.WithReplicaPools(replicaPool),
+		pgxv5.WithLoadBalancer(dbresolver.RoundRobinLB))
+	defer db.Close()
+
+	// now you can use the pools for all DB operation
+	_, err = db.Exec(ctx, "DELETE FROM book WHERE id=$1", 1) // will use primaryPool
+	if err != nil {
+		log.Print("go error when executing the query to the DB", err)
+	}
+	_ = db.QueryRow(ctx, "SELECT * FROM book WHERE id=$1", 1) // will use replicaPool
+}
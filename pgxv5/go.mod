@@ -0,0 +1,19 @@
+module github.com/bxcodec/dbresolver/v2/pgxv5
+
+go 1.22.0
+
+require (
+	github.com/bxcodec/dbresolver/v2 v2.2.1
+	github.com/jackc/pgx/v5 v5.7.0
+	github.com/pashagolub/pgxmock/v4 v4.3.0
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.1 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/crypto v0.27.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
+)
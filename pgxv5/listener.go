@@ -0,0 +1,26 @@
+package pgxv5
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// WaitForNotification is the dbresolver.NotificationWaiter of the pgx stdlib driver,
+// for the database/sql resolvers of the connections opened with the "pgx" driver:
+//
+//	db := dbresolver.New(dbresolver.WithPrimaryDBs(primary), dbresolver.WithNotificationWaiter(pgxv5.WaitForNotification))
+//	listener, err := db.Listener(ctx, "events")
+func WaitForNotification(ctx context.Context, driverConn any) (dbresolver.Notification, error) {
+	conn, ok := driverConn.(*stdlib.Conn)
+	if !ok {
+		return dbresolver.Notification{}, fmt.Errorf("pgxv5: the connection %T isn't a pgx stdlib connection", driverConn)
+	}
+	notification, err := conn.Conn().WaitForNotification(ctx)
+	if err != nil {
+		return dbresolver.Notification{}, err
+	}
+	return dbresolver.Notification{Channel: notification.Channel, Payload: notification.Payload}, nil
+}
@@ -0,0 +1,14 @@
+package pgxv5_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2/pgxv5"
+)
+
+func TestWaitForNotificationNotPgx(t *testing.T) {
+	if _, err := pgxv5.WaitForNotification(context.Background(), struct{}{}); err == nil {
+		t.Error("want an error for a connection of another driver")
+	}
+}
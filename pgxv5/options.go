@@ -0,0 +1,65 @@
+package pgxv5
+
+import (
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// Option define the option property
+type Option struct {
+	PrimaryPools     []Pool
+	ReplicaPools     []Pool
+	LB               dbresolver.LoadBalancer[Pool]
+	QueryTypeChecker dbresolver.QueryTypeChecker
+	Classifier       dbresolver.Classifier
+}
+
+// OptionFunc used for option chaining
+type OptionFunc func(opt *Option)
+
+// WithPrimaryPools add primary pools to the resolver
+func WithPrimaryPools(primaryPools ...Pool) OptionFunc {
+	return func(opt *Option) {
+		opt.PrimaryPools = primaryPools
+	}
+}
+
+// WithReplicaPools add replica pools to the resolver
+func WithReplicaPools(replicaPools ...Pool) OptionFunc {
+	return func(opt *Option) {
+		opt.ReplicaPools = replicaPools
+	}
+}
+
+// WithQueryTypeChecker sets the query type checker instance.
+// The default one just checks for the presence of the string "RETURNING" in the uppercase query.
+func WithQueryTypeChecker(checker dbresolver.QueryTypeChecker) OptionFunc {
+	return func(opt *Option) {
+		opt.QueryTypeChecker = checker
+	}
+}
+
+// WithLoadBalancer configure the loadbalancer for the resolver
+func WithLoadBalancer(lb dbresolver.LoadBalancerPolicy) OptionFunc {
+	return func(opt *Option) {
+		opt.LB = dbresolver.NewLoadBalancer[Pool](lb)
+	}
+}
+
+// WithErrorClassifier sets how the errors of the queries are classified, dbresolver.PostgresClassifier by default.
+// The reads failing with a connection error are sent again to the RW-pool.
+func WithErrorClassifier(classifier dbresolver.Classifier) OptionFunc {
+	if classifier == nil {
+		panic("pgxv5: invalid nil error classifier")
+	}
+	return func(opt *Option) {
+		opt.Classifier = classifier
+	}
+}
+
+func defaultOption() *Option {
+	return &Option{
+		LB:               dbresolver.NewLoadBalancer[Pool](dbresolver.RoundRobinLB),
+		QueryTypeChecker: &dbresolver.DefaultQueryTypeChecker{},
+		Classifier:       dbresolver.PostgresClassifier,
+	}
+}
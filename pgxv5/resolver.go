@@ -0,0 +1,31 @@
+// Package pgxv5 is the pgx flavored variant of dbresolver.
+//
+// It balances the queries across multiple pgxpool.Pool instances, reusing the
+// load balancers and query type checkers of dbresolver, while exposing the pgx-native API
+// (Query, Exec, CopyFrom, SendBatch) instead of database/sql. The reads failing with a connection error,
+// as classified by the error classifiers of dbresolver, are sent again to a primary pool.
+//
+// The other resilience features of dbresolver are bound to database/sql and aren't supported:
+// the health checks, the retries, the circuit breakers, the query timeouts, the admission control,
+// the replication lag and the discovery of the nodes. pgxpool checks and recycles its own connections.
+package pgxv5
+
+// New will resolve all the passed pools with configurable parameters
+func New(opts ...OptionFunc) DB {
+	opt := defaultOption()
+	for _, optFunc := range opts {
+		optFunc(opt)
+	}
+
+	if len(opt.PrimaryPools) == 0 {
+		panic("required primary pool, set the primary pool " +
+			"with pgxv5.New(pgxv5.WithPrimaryPools(primaryPool))")
+	}
+	return &pgxDB{
+		primaries:        opt.PrimaryPools,
+		replicas:         opt.ReplicaPools,
+		loadBalancer:     opt.LB,
+		queryTypeChecker: opt.QueryTypeChecker,
+		classifier:       opt.Classifier,
+	}
+}
@@ -0,0 +1,121 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// NodeFilter selects nodes, eg. the replicas of a zone
+type NodeFilter func(node NodeInfo) bool
+
+// NodeRole selects the nodes of the role
+func NodeRole(role Role) NodeFilter {
+	return func(node NodeInfo) bool {
+		return node.Role == role
+	}
+}
+
+// NodeLabel selects the nodes with the label value, see WithNodeLabels
+func NodeLabel(key, value string) NodeFilter {
+	return func(node NodeInfo) bool {
+		v, ok := node.Labels[key]
+		return ok && v == value
+	}
+}
+
+type preparedKey struct {
+	node  *sql.DB
+	query string
+}
+
+// Prewarm prepares the queries on the nodes matching all the filters, every node without filter.
+// The prewarmed queries run by Exec, Query and QueryRow then use the statement prepared on the resolved node,
+// so the first requests after a deploy don't pay the prepare latency on every node they touch.
+// A query failing to prepare on a node is run without statement on that node.
+// The statements are closed with the resolver.
+func (db *sqlDB) Prewarm(ctx context.Context, queries []string, filters ...NodeFilter) error {
+	var nodes []*sql.DB
+	var names []string
+	indexes := map[Role]int{}
+	for _, node := range db.Nodes() {
+		if matchNode(node, filters) {
+			name := node.Name()
+			if name == "" {
+				name = fmt.Sprintf("%s %d", node.Role, indexes[node.Role])
+			}
+			nodes = append(nodes, node.DB)
+			names = append(names, name)
+		}
+		indexes[node.Role]++
+	}
+
+	stmts := make([]*sql.Stmt, len(nodes)*len(queries))
+	err := doParallely(ctx, db.prepareConcurrency, len(stmts), func(i int) (err error) {
+		node, query := i/len(queries), queries[i%len(queries)]
+		stmts[i], err = nodes[node].PrepareContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("dbresolver: prewarming %q on %s: %w", query, names[node], err)
+		}
+		return nil
+	})
+
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+	// the prepared statements are copied on write, like the topology
+	prepared := make(map[preparedKey]*sql.Stmt, len(stmts))
+	if current := db.prepared.Load(); current != nil {
+		for key, stmt := range *current {
+			prepared[key] = stmt
+		}
+	}
+	var errClose error
+	for i, stmt := range stmts {
+		if stmt == nil {
+			continue
+		}
+		key := preparedKey{node: nodes[i/len(queries)], query: queries[i%len(queries)]}
+		if _, ok := prepared[key]; ok {
+			// already prewarmed
+			errClose = errors.Join(errClose, stmt.Close())
+			continue
+		}
+		prepared[key] = stmt
+	}
+	db.prepared.Store(&prepared)
+	return errors.Join(err, errClose)
+}
+
+func matchNode(node NodeInfo, filters []NodeFilter) bool {
+	for _, filter := range filters {
+		if !filter(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// preparedStmt returns the statement of the query prewarmed on the node, nil when it's not prewarmed
+func (db *sqlDB) preparedStmt(node *sql.DB, query string) *sql.Stmt {
+	prepared := db.prepared.Load()
+	if prepared == nil {
+		return nil
+	}
+	return (*prepared)[preparedKey{node: node, query: query}]
+}
+
+// closePrepared closes the prewarmed statements
+func (db *sqlDB) closePrepared() error {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+	prepared := db.prepared.Swap(nil)
+	if prepared == nil {
+		return nil
+	}
+	errs := make([]error, 0, len(*prepared))
+	for _, stmt := range *prepared {
+		errs = append(errs, stmt.Close())
+	}
+	return errors.Join(errs...)
+}
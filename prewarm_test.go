@@ -0,0 +1,64 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPrewarm(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, replica1Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, replica2Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica1, replica2),
+		WithNodeLabels(replica1, map[string]string{"zone": "a"}), WithNodeName(replica2, "replica-b"),
+		WithNodeLabels(replica2, map[string]string{"zone": "b"}))
+
+	// only the replicas of the zone a are prewarmed
+	query := "SELECT title FROM book WHERE id = ?"
+	prepared := replica1Mock.ExpectPrepare(query)
+	if err := resolver.Prewarm(context.Background(), []string{query}, NodeRole(RoleReplica), NodeLabel("zone", "a")); err != nil {
+		t.Fatal(err)
+	}
+	prepared.ExpectQuery().WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Dune"))
+	replica2Mock.ExpectQuery(query).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Dune"))
+	for i := 0; i < 2; i++ {
+		var title string
+		if err := resolver.QueryRow(query, 1).Scan(&title); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a failed prepare is reported, the query runs without statement on that node
+	replica2Mock.ExpectPrepare(query).WillReturnError(errors.New("syntax error"))
+	err = resolver.Prewarm(context.Background(), []string{query}, NodeLabel("zone", "b"))
+	if err == nil || !strings.Contains(err.Error(), `prewarming "SELECT title FROM book WHERE id = ?" on replica-b: syntax error`) {
+		t.Errorf("want the prepare error of the named node, got %v", err)
+	}
+
+	prepared.WillBeClosed()
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replica1Mock, replica2Mock} {
+		mock.ExpectClose()
+	}
+	if err := resolver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replica1Mock, replica2Mock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
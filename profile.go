@@ -0,0 +1,66 @@
+package dbresolver
+
+import "time"
+
+// Profile is a set of defaults of the resolver options
+type Profile string
+
+// Supported profiles
+const (
+	// Production checks the health of the nodes every 10 seconds, retries the transient errors 3 times,
+	// opens the circuit of a node after 5 consecutive connection errors, bounds the queries with
+	// DefaultPrimaryQueryTimeout and DefaultReplicaQueryTimeout, admits DefaultMaxInflight queries
+	// with a queue of DefaultMaxQueue, and logs the queries slower than DefaultSlowQueryThreshold
+	Production Profile = "PRODUCTION"
+)
+
+// Defaults of the Production profile
+const (
+	// DefaultSlowQueryThreshold is the slow query threshold of the Production profile
+	DefaultSlowQueryThreshold = time.Second
+	// DefaultPrimaryQueryTimeout is the query timeout of the primaries of the Production profile
+	DefaultPrimaryQueryTimeout = 10 * time.Second
+	// DefaultReplicaQueryTimeout is the query timeout of the replicas of the Production profile
+	DefaultReplicaQueryTimeout = 30 * time.Second
+	// DefaultMaxInflight is the number of queries in flight admitted by the Production profile
+	DefaultMaxInflight = 512
+	// DefaultMaxQueue is the number of queries waiting for a slot with the Production profile
+	DefaultMaxQueue = 1024
+	// DefaultQueueTimeout is how long a query waits for a slot with the Production profile
+	DefaultQueueTimeout = time.Second
+)
+
+// WithProfile applies the defaults of the profile, the options set after it override them.
+// It panics if the profile is not supported.
+func WithProfile(profile Profile) OptionFunc {
+	var opts []OptionFunc
+	switch profile {
+	case Production:
+		opts = []OptionFunc{
+			WithHealthCheckInterval(defaultHealthCheckInterval),
+			WithRetryPolicy(RetryPolicy{MaxAttempts: defaultRetryMaxAttempts}),
+			WithCircuitBreaker(CircuitBreaker{}),
+			WithPrimaryQueryTimeout(DefaultPrimaryQueryTimeout),
+			WithReplicaQueryTimeout(DefaultReplicaQueryTimeout),
+			WithAdmissionControl(AdmissionControl{
+				MaxInflight:  DefaultMaxInflight,
+				MaxQueue:     DefaultMaxQueue,
+				QueueTimeout: DefaultQueueTimeout,
+			}),
+			WithSlowQueryLog(DefaultSlowQueryThreshold, nil),
+		}
+	default:
+		panic("dbresolver: profile " + string(profile) + " is not supported")
+	}
+	return func(opt *Option) {
+		for _, optFunc := range opts {
+			optFunc(opt)
+		}
+	}
+}
+
+// NewProduction resolves the connections with the defaults of the Production profile,
+// the options override them
+func NewProduction(opts ...OptionFunc) DB {
+	return New(append([]OptionFunc{WithProfile(Production)}, opts...)...)
+}
@@ -0,0 +1,145 @@
+package dbresolver
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	var logs bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithSlowQueryLog(time.Nanosecond, logger))
+
+	replicaMock.ExpectQuery("SELECT 1").WillDelayFor(time.Millisecond).WillReturnError(errors.New("canceled"))
+	primaryMock.ExpectExec("DELETE FROM book").WillReturnResult(sqlmock.NewResult(0, 1))
+	_, _ = resolver.Query("SELECT 1", "secret-arg")
+	_, _ = resolver.Exec("DELETE FROM book")
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 slow queries, got %q", logs.String())
+	}
+	for _, want := range []string{"level=WARN", `msg="dbresolver: slow query"`, `query="SELECT 1"`, "latency=",
+		"role=replica", "error=canceled"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("want %s in %s", want, lines[0])
+		}
+	}
+	if strings.Contains(lines[0], "secret-arg") {
+		t.Errorf("want the args omitted, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "role=primary") || strings.Contains(lines[1], "error") {
+		t.Errorf("want the successful primary query, got %s", lines[1])
+	}
+
+	// the slow queries are logged by the logger of the resolver by default
+	resolverLogger := &recordingLogger{}
+	resolver = New(WithPrimaryDBs(primary), WithLogger(resolverLogger), WithSlowQueryLog(time.Nanosecond, nil))
+	primaryMock.ExpectExec("DELETE FROM book").WillDelayFor(time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 1))
+	_, _ = resolver.Exec("DELETE FROM book")
+	var slow int
+	for _, entry := range resolverLogger.entries {
+		if strings.HasPrefix(entry, "WARN dbresolver: slow query [{query DELETE FROM book}") {
+			slow++
+		}
+	}
+	if slow != 1 {
+		t.Errorf("want the slow query logged by the logger of the resolver, got %q", resolverLogger.entries)
+	}
+}
+
+func TestProfile(t *testing.T) {
+	primary, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := NewProduction(WithPrimaryDBs(primary)).(*sqlDB)
+	defer resolver.Close()
+	if len(resolver.hooks) != 1 || resolver.hooks[0].(*slowQueryHooks).Threshold != DefaultSlowQueryThreshold {
+		t.Errorf("want the slow query log of the profile, got %v", resolver.hooks)
+	}
+	if resolver.health == nil || resolver.health.config.Interval != defaultHealthCheckInterval ||
+		resolver.health.config.FailureThreshold != defaultFailureThreshold {
+		t.Errorf("want the health checks of the profile, got %+v", resolver.health)
+	}
+	if resolver.retry == nil || resolver.retry.policy.MaxAttempts != defaultRetryMaxAttempts ||
+		resolver.retry.policy.MaxBackoff != defaultRetryMaxBackoff {
+		t.Errorf("want the bounded retries of the profile, got %+v", resolver.retry)
+	}
+	if resolver.breaker == nil || resolver.breaker.config.FailureThreshold != defaultBreakerFailureThreshold ||
+		resolver.breaker.config.CoolDown != defaultBreakerCoolDown {
+		t.Errorf("want the circuit breaker of the profile, got %+v", resolver.breaker)
+	}
+	if resolver.primaryQueryTimeout != DefaultPrimaryQueryTimeout || resolver.replicaQueryTimeout != DefaultReplicaQueryTimeout {
+		t.Errorf("want the query timeouts of the profile, got %s and %s",
+			resolver.primaryQueryTimeout, resolver.replicaQueryTimeout)
+	}
+	wantAdmission := AdmissionControl{MaxInflight: DefaultMaxInflight, MaxQueue: DefaultMaxQueue, QueueTimeout: DefaultQueueTimeout}
+	if resolver.admission == nil || resolver.admission.config != wantAdmission {
+		t.Errorf("want the admission control of the profile, got %+v", resolver.admission)
+	}
+
+	// the options override the defaults
+	resolver = NewProduction(WithPrimaryDBs(primary), WithSlowQueryLog(0, nil), WithFailureThreshold(1),
+		WithReplicaQueryTimeout(time.Minute)).(*sqlDB)
+	defer resolver.Close()
+	if len(resolver.hooks) != 0 {
+		t.Errorf("want the slow query log disabled, got %v", resolver.hooks)
+	}
+	if resolver.health.config.Interval != defaultHealthCheckInterval || resolver.health.config.FailureThreshold != 1 {
+		t.Errorf("want the failure threshold overridden, got %+v", resolver.health.config)
+	}
+	if resolver.replicaQueryTimeout != time.Minute {
+		t.Errorf("want the replica query timeout overridden, got %s", resolver.replicaQueryTimeout)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("want a panic for an unknown profile")
+		}
+	}()
+	WithProfile("STAGING")
+}
+
+func TestProfileRows(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := NewProduction(WithPrimaryDBs(primary), WithHealthCheckInterval(time.Hour)).(*sqlDB)
+	defer resolver.stop()
+
+	// the reads bounded by the query timeout of the profile return the rows of the driver
+	column := sqlmock.NewColumn("id").OfType("INT8", int64(0)).Nullable(true)
+	primaryMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRowsWithColumnDefinition(column).AddRow(1))
+	rows, err := resolver.Query("SELECT id FROM book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types[0].DatabaseTypeName() != "INT8" {
+		t.Errorf("want the column types of the driver, got %q", types[0].DatabaseTypeName())
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,212 @@
+// Package promresolver exports the metrics of the resolver to Prometheus.
+//
+// The Collector counts the queries routed to each node with a dbresolver.QueryHook, and reports the pool stats,
+// the weight and the health of every node of the resolver, not only of the first primary like DB.Stats.
+package promresolver
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Labels of the metrics
+const (
+	RoleLabel         = "role"
+	NodeLabel         = "node"
+	NameLabel         = "name"
+	ShardLabel        = "shard"
+	LoadBalancerLabel = "load_balancer"
+)
+
+// Config define the metrics of the collector
+type Config struct {
+	// Namespace of the metrics, "dbresolver" by default
+	Namespace string
+	// ConstLabels are attached to every metric, eg. the name of the resolver when the process has several
+	ConstLabels prometheus.Labels
+	// Buckets of the query duration histogram, prometheus.DefBuckets by default
+	Buckets []float64
+}
+
+// Collector is the prometheus.Collector of the metrics of a resolver.
+// It's attached to the resolver with dbresolver.WithQueryHook, then the resolver is set with SetDB:
+//
+//	collector := promresolver.NewCollector(promresolver.Config{})
+//	db := dbresolver.New(dbresolver.WithPrimaryDBs(primary), dbresolver.WithQueryHook(collector))
+//	collector.SetDB(db)
+//	prometheus.MustRegister(collector)
+//
+// The nodes are identified by their role and their index among the nodes of the role, like dbresolver.QueryEvent.
+type Collector struct {
+	db atomic.Pointer[dbresolver.DB]
+
+	queries       *prometheus.CounterVec
+	queryErrors   *prometheus.CounterVec
+	fallbacks     prometheus.Counter
+	prepares      prometheus.Counter
+	queryDuration *prometheus.HistogramVec
+
+	openConnections    *prometheus.Desc
+	inUse              *prometheus.Desc
+	idle               *prometheus.Desc
+	maxOpenConnections *prometheus.Desc
+	waitCount          *prometheus.Desc
+	waitDuration       *prometheus.Desc
+	weight             *prometheus.Desc
+	unhealthy          *prometheus.Desc
+	failedHealthChecks *prometheus.Desc
+	circuitOpen        *prometheus.Desc
+}
+
+var (
+	_ prometheus.Collector = (*Collector)(nil)
+	_ dbresolver.QueryHook = (*Collector)(nil)
+)
+
+// NewCollector creates the collector of the metrics
+func NewCollector(config Config) *Collector {
+	if config.Namespace == "" {
+		config.Namespace = "dbresolver"
+	}
+	if config.Buckets == nil {
+		config.Buckets = prometheus.DefBuckets
+	}
+	nodeDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(config.Namespace, "node", name), help,
+			[]string{RoleLabel, NodeLabel, NameLabel, ShardLabel}, config.ConstLabels)
+	}
+	return &Collector{
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace, Name: "queries_total", ConstLabels: config.ConstLabels,
+			Help: "The queries routed by the resolver to each node.",
+		}, []string{RoleLabel, NodeLabel, NameLabel, LoadBalancerLabel}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace, Name: "query_errors_total", ConstLabels: config.ConstLabels,
+			Help: "The failed queries of each node.",
+		}, []string{RoleLabel, NodeLabel, NameLabel}),
+		fallbacks: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: config.Namespace, Name: "fallbacks_total", ConstLabels: config.ConstLabels,
+			Help: "The read queries sent again to a primary after a replica connection error.",
+		}),
+		prepares: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: config.Namespace, Name: "prepares_total", ConstLabels: config.ConstLabels,
+			Help: "The statements prepared on every node.",
+		}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace, Name: "query_duration_seconds", ConstLabels: config.ConstLabels,
+			Help: "The duration of the queries by role.", Buckets: config.Buckets,
+		}, []string{RoleLabel}),
+
+		openConnections:    nodeDesc("open_connections", "The established connections of the node, in use and idle."),
+		inUse:              nodeDesc("in_use_connections", "The connections of the node currently in use."),
+		idle:               nodeDesc("idle_connections", "The idle connections of the node."),
+		maxOpenConnections: nodeDesc("max_open_connections", "The maximum number of open connections of the node."),
+		waitCount:          nodeDesc("wait_count_total", "The connections of the node waited for."),
+		waitDuration:       nodeDesc("wait_duration_seconds_total", "The time blocked waiting for a connection of the node."),
+		weight:             nodeDesc("weight", "The weight of the node among the nodes of its role."),
+		unhealthy:          nodeDesc("unhealthy", "1 when the node failed its health checks and is out of rotation."),
+		failedHealthChecks: nodeDesc("failed_health_checks_total", "The failed health checks of the node."),
+		circuitOpen:        nodeDesc("circuit_open", "1 when the circuit breaker of the node is open and the node is out of rotation."),
+	}
+}
+
+// SetDB sets the resolver whose nodes are reported
+func (c *Collector) SetDB(db dbresolver.DB) {
+	c.db.Store(&db)
+}
+
+// BeforeQuery implements dbresolver.QueryHook
+func (c *Collector) BeforeQuery(ctx context.Context, _ *dbresolver.QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery counts the query
+func (c *Collector) AfterQuery(_ context.Context, event *dbresolver.QueryEvent) {
+	if event.Prepare {
+		c.prepares.Inc()
+		return
+	}
+	role, node := string(event.Route.Role), nodeIndex(event.Index)
+	c.queries.WithLabelValues(role, node, event.Name, string(event.LoadBalancer)).Inc()
+	if event.Err != nil {
+		c.queryErrors.WithLabelValues(role, node, event.Name).Inc()
+	}
+	if event.Route.Fallback {
+		c.fallbacks.Inc()
+	}
+	c.queryDuration.WithLabelValues(role).Observe(event.Duration.Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.queries.Describe(ch)
+	c.queryErrors.Describe(ch)
+	c.fallbacks.Describe(ch)
+	c.prepares.Describe(ch)
+	c.queryDuration.Describe(ch)
+	for _, desc := range []*prometheus.Desc{c.openConnections, c.inUse, c.idle, c.maxOpenConnections,
+		c.waitCount, c.waitDuration, c.weight, c.unhealthy, c.failedHealthChecks, c.circuitOpen} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector, the nodes are read from the resolver on every collection
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.queries.Collect(ch)
+	c.queryErrors.Collect(ch)
+	c.fallbacks.Collect(ch)
+	c.prepares.Collect(ch)
+	c.queryDuration.Collect(ch)
+
+	db := c.db.Load()
+	if db == nil {
+		return
+	}
+	// the index of the nodes among the nodes of their role, by shard
+	indexes := map[string]int{}
+	for _, node := range (*db).Nodes() {
+		shard := node.Labels[dbresolver.ShardLabel]
+		key := shard + "/" + string(node.Role)
+		labels := []string{string(node.Role), strconv.Itoa(indexes[key]), node.Name(), shard}
+		indexes[key]++
+
+		stats := node.DB.Stats()
+		unhealthy, circuitOpen := 0.0, 0.0
+		if node.Unhealthy {
+			unhealthy = 1
+		}
+		if node.Circuit == dbresolver.CircuitOpen {
+			circuitOpen = 1
+		}
+		for _, metric := range []struct {
+			desc      *prometheus.Desc
+			valueType prometheus.ValueType
+			value     float64
+		}{
+			{c.openConnections, prometheus.GaugeValue, float64(stats.OpenConnections)},
+			{c.inUse, prometheus.GaugeValue, float64(stats.InUse)},
+			{c.idle, prometheus.GaugeValue, float64(stats.Idle)},
+			{c.maxOpenConnections, prometheus.GaugeValue, float64(stats.MaxOpenConnections)},
+			{c.waitCount, prometheus.CounterValue, float64(stats.WaitCount)},
+			{c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds()},
+			{c.weight, prometheus.GaugeValue, float64(node.Weight)},
+			{c.unhealthy, prometheus.GaugeValue, unhealthy},
+			{c.failedHealthChecks, prometheus.CounterValue, float64(node.FailedHealthChecks)},
+			{c.circuitOpen, prometheus.GaugeValue, circuitOpen},
+		} {
+			ch <- prometheus.MustNewConstMetric(metric.desc, metric.valueType, metric.value, labels...)
+		}
+	}
+}
+
+// nodeIndex returns the node label of the index, empty when the node isn't known, eg. for the prepared statements
+func nodeIndex(index int) string {
+	if index < 0 {
+		return ""
+	}
+	return strconv.Itoa(index)
+}
@@ -0,0 +1,74 @@
+package promresolver_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/promresolver"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	replica, replicaMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed", err)
+	}
+	primary.SetMaxOpenConns(10)
+
+	collector := promresolver.NewCollector(promresolver.Config{})
+	db := dbresolver.New(
+		dbresolver.WithPrimaryDBs(primary),
+		dbresolver.WithReplicaDBs(replica),
+		dbresolver.WithNodeName(replica, "replica-1"),
+		dbresolver.WithQueryHook(collector))
+	collector.SetDB(db)
+
+	replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(errors.New("deadlock"))
+	rows, err := db.QueryContext(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := db.ExecContext(context.Background(), "DELETE FROM users"); err == nil {
+		t.Fatal("want the error of the query")
+	}
+
+	expected := `
+# HELP dbresolver_queries_total The queries routed by the resolver to each node.
+# TYPE dbresolver_queries_total counter
+dbresolver_queries_total{load_balancer="ROUND_ROBIN",name="",node="0",role="primary"} 1
+dbresolver_queries_total{load_balancer="ROUND_ROBIN",name="replica-1",node="0",role="replica"} 1
+# HELP dbresolver_query_errors_total The failed queries of each node.
+# TYPE dbresolver_query_errors_total counter
+dbresolver_query_errors_total{name="",node="0",role="primary"} 1
+# HELP dbresolver_node_max_open_connections The maximum number of open connections of the node.
+# TYPE dbresolver_node_max_open_connections gauge
+dbresolver_node_max_open_connections{name="",node="0",role="primary",shard=""} 10
+dbresolver_node_max_open_connections{name="replica-1",node="0",role="replica",shard=""} 0
+# HELP dbresolver_node_weight The weight of the node among the nodes of its role.
+# TYPE dbresolver_node_weight gauge
+dbresolver_node_weight{name="",node="0",role="primary",shard=""} 1
+dbresolver_node_weight{name="replica-1",node="0",role="replica",shard=""} 1
+`
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"dbresolver_queries_total", "dbresolver_query_errors_total",
+		"dbresolver_node_max_open_connections", "dbresolver_node_weight")
+	if err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(collector, "dbresolver_query_duration_seconds"); n != 2 {
+		t.Errorf("want the durations of both roles, got %d", n)
+	}
+	if problems, err := testutil.CollectAndLint(collector); err != nil || len(problems) > 0 {
+		t.Errorf("want the metrics linted, got %v %v", problems, err)
+	}
+}
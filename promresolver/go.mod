@@ -0,0 +1,21 @@
+module github.com/bxcodec/dbresolver/v2/promresolver
+
+go 1.22
+
+require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/bxcodec/dbresolver/v2 v2.2.1
+	github.com/prometheus/client_golang v1.19.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
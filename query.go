@@ -1,6 +1,9 @@
 package dbresolver
 
-import "strings"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
 type QueryType int
 
@@ -21,8 +24,26 @@ type DefaultQueryTypeChecker struct {
 }
 
 func (c DefaultQueryTypeChecker) Check(query string) QueryType {
-	if strings.Contains(strings.ToUpper(query), "RETURNING") {
+	if containsReturning(query) {
 		return QueryTypeWrite
 	}
 	return QueryTypeUnknown
 }
+
+// containsReturning reports whether the uppercase query contains "RETURNING".
+// It's on the hot path of every query, the ASCII queries are searched without allocating an uppercase copy.
+func containsReturning(query string) bool {
+	const returning = "RETURNING"
+	for i := 0; i < len(query); i++ {
+		if query[i] >= utf8.RuneSelf {
+			// the uppercase of some non ASCII letters is ASCII, eg. the dotless i
+			return strings.Contains(strings.ToUpper(query), returning)
+		}
+	}
+	for i := 0; i+len(returning) <= len(query); i++ {
+		if query[i]|0x20 == 'r' && strings.EqualFold(query[i:i+len(returning)], returning) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestDefaultQueryTypeChecker(t *testing.T) {
+	checker := DefaultQueryTypeChecker{}
+	for query, want := range map[string]QueryType{
+		"SELECT id FROM book":                                   QueryTypeUnknown,
+		"INSERT INTO book (title) VALUES ($1) RETURNING id":     QueryTypeWrite,
+		"update book set title = $1 returning id":               QueryTypeWrite,
+		"DELETE FROM book WHERE title = 'Returnin' RETURNIN":    QueryTypeUnknown,
+		"INSERT INTO livre (titre) VALUES ('été') RETURNING id": QueryTypeWrite,
+		"INSERT INTO book (title) VALUES ($1) RETURNıNG id":     QueryTypeWrite,
+		"": QueryTypeUnknown,
+	} {
+		if got := checker.Check(query); got != want {
+			t.Errorf("want %v for %q, got %v", want, query, got)
+		}
+	}
+}
+
+func FuzzContainsReturning(f *testing.F) {
+	f.Add("INSERT INTO book (title) VALUES ($1) RETURNING id")
+	f.Add("select rEtUrNiNg")
+	f.Add("ſelect RETURNıNG")
+	f.Fuzz(func(t *testing.T, query string) {
+		want := strings.Contains(strings.ToUpper(query), "RETURNING")
+		if got := containsReturning(query); got != want {
+			t.Errorf("want %v for %q, got %v", want, query, got)
+		}
+	})
+}
+
+func TestQueryFastPathAllocations(t *testing.T) {
+	ctx := context.Background()
+	res := &result{columns: []string{"id"}}
+	node := newResultDB()
+	defer node.Close()
+	resolver := New(WithPrimaryDBs(node), WithReplicaDBs(node))
+
+	allocs := func(queryContext func(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)) float64 {
+		return testing.AllocsPerRun(100, func() {
+			rows, err := queryContext(ctx, "SELECT id FROM book", res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rows.Close()
+		})
+	}
+	// an un-configured resolver doesn't allocate on top of the bare query
+	if bare, resolved := allocs(node.QueryContext), allocs(resolver.QueryContext); resolved != bare {
+		t.Errorf("want %v allocations like the bare query, got %v", bare, resolved)
+	}
+}
@@ -0,0 +1,128 @@
+package dbresolver
+
+import (
+	"context"
+	"time"
+)
+
+// QueryEvent describes a query sent by the resolver, see QueryHook
+type QueryEvent struct {
+	// Route is where the query is sent, it's zero for Prepare which prepares the query on every node
+	Route Route
+	// Index is the index of the node among the primaries or the replicas of its role,
+	// -1 when the node isn't known, eg. for the prepared statements
+	Index int
+	// Name is the name of the node, empty when the node isn't named or known, see WithNodeName
+	Name string
+	// LoadBalancer is the policy of the load balancer which resolved the node
+	LoadBalancer LoadBalancerPolicy
+	Query        string
+	Args         []interface{}
+	// Prepare is true for the preparation of a statement
+	Prepare bool
+	// Duration and Err are the duration and the error of the query, they are set before AfterQuery
+	Duration time.Duration
+	Err      error
+}
+
+// QueryHook is called before and after every Exec, Query, QueryRow and Prepare of the resolver,
+// its transactions, connections and statements, with the node chosen by the resolver.
+// Unlike Hooks, AfterQuery is called whether the query fails or not.
+type QueryHook interface {
+	// BeforeQuery is called before the query, the returned context is passed to the query and to AfterQuery
+	BeforeQuery(ctx context.Context, event *QueryEvent) context.Context
+	// AfterQuery is called after the query, with its duration and its error
+	AfterQuery(ctx context.Context, event *QueryEvent)
+}
+
+// WithQueryHook attaches the query hook to the queries of the resolver, after the Hooks.
+// It can be used multiple times, the query hooks are called in the given order.
+func WithQueryHook(hook QueryHook) OptionFunc {
+	return func(opt *Option) {
+		opt.QueryHooks = append(opt.QueryHooks, hook)
+	}
+}
+
+// queryHookAdapter calls the query hook from the Hooks of the resolver
+type queryHookAdapter struct {
+	hook QueryHook
+	db   *sqlDB
+}
+
+// queryEventKey holds the event of the query of the adapter in the context
+type queryEventKey struct {
+	adapter *queryHookAdapter
+}
+
+type queryEventStart struct {
+	event *QueryEvent
+	start time.Time
+}
+
+func (a *queryHookAdapter) Before(ctx context.Context, query string, args ...interface{}) (context.Context, error) {
+	route, _ := RouteFromContext(ctx)
+	event := &QueryEvent{Route: route, Index: a.db.nodeIndex(route), Name: a.db.nodeName(route.Node),
+		LoadBalancer: a.db.loadBalancer.Name(), Query: query, Args: args}
+	ctx = a.hook.BeforeQuery(ctx, event)
+	return context.WithValue(ctx, queryEventKey{a}, queryEventStart{event: event, start: a.db.clock.Now()}), nil
+}
+
+func (a *queryHookAdapter) After(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	a.after(ctx, nil)
+	return ctx, nil
+}
+
+func (a *queryHookAdapter) OnError(ctx context.Context, err error, _ string, _ ...interface{}) error {
+	a.after(ctx, err)
+	return err
+}
+
+func (a *queryHookAdapter) after(ctx context.Context, err error) {
+	// the event is missing when a previous hook failed before the query hook
+	if started, ok := ctx.Value(queryEventKey{a}).(queryEventStart); ok {
+		started.event.Duration, started.event.Err = a.db.clock.Now().Sub(started.start), err
+		a.hook.AfterQuery(ctx, started.event)
+	}
+}
+
+// nodeIndex returns the index of the node of the route among the nodes of its role, -1 when it isn't known
+func (db *sqlDB) nodeIndex(route Route) int {
+	if route.Node == nil {
+		return -1
+	}
+	set := db.nodes.Load()
+	if route.Role == RolePrimary {
+		return indexOf(set.primaries, route.Node)
+	}
+	return indexOf(set.replicas, route.Node)
+}
+
+// prepareWithQueryHooks runs the preparation of the statement between the query hooks
+func (db *sqlDB) prepareWithQueryHooks(ctx context.Context, query string,
+	prepare func(ctx context.Context) (Stmt, error)) (Stmt, error) {
+	if len(db.queryHooks) == 0 {
+		return prepare(ctx)
+	}
+
+	events := make([]*QueryEvent, len(db.queryHooks))
+	for i, hook := range db.queryHooks {
+		events[i] = &QueryEvent{Index: -1, LoadBalancer: db.stmtLoadBalancer.Name(), Query: query, Prepare: true}
+		ctx = hook.BeforeQuery(ctx, events[i])
+	}
+	start := db.clock.Now()
+	stmt, err := prepare(ctx)
+	for i, hook := range db.queryHooks {
+		events[i].Duration, events[i].Err = db.clock.Now().Sub(start), err
+		hook.AfterQuery(ctx, events[i])
+	}
+	return stmt, err
+}
+
+// queryHookAdapters returns the Hooks calling the query hooks of the resolver
+func (db *sqlDB) queryHookAdapters() []Hooks {
+	adapters := make([]Hooks, len(db.queryHooks))
+	for i, hook := range db.queryHooks {
+		adapters[i] = &queryHookAdapter{hook: hook, db: db}
+	}
+	return adapters
+}
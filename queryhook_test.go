@@ -0,0 +1,74 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recordingQueryHook records the query events
+type recordingQueryHook struct {
+	calls []string
+}
+
+func (h *recordingQueryHook) BeforeQuery(ctx context.Context, event *QueryEvent) context.Context {
+	h.calls = append(h.calls, fmt.Sprintf("before %s %d %s prepare=%v", event.Route.Role, event.Index, event.Query, event.Prepare))
+	return context.WithValue(ctx, ctxKey{}, "started")
+}
+
+func (h *recordingQueryHook) AfterQuery(ctx context.Context, event *QueryEvent) {
+	h.calls = append(h.calls, fmt.Sprintf("after %s %v failed=%v %v", event.Query, event.Duration, event.Err != nil, ctx.Value(ctxKey{})))
+}
+
+func TestQueryHook(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, replica2Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	now := time.Now()
+	hook := &recordingQueryHook{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica1, replica2), WithQueryHook(hook),
+		WithClock(stubClock{now: &now}))
+
+	replica2Mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(errors.New("deadlock"))
+
+	// the round robin resolves the second replica first
+	rows, err := resolver.QueryContext(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := resolver.Exec("DELETE FROM users"); err == nil {
+		t.Fatal("want the error of the query")
+	}
+
+	primaryMock.ExpectPrepare("SELECT 2").WillReturnError(errors.New("syntax error"))
+	if _, err := resolver.Prepare("SELECT 2"); err == nil {
+		t.Fatal("want the error of the preparation")
+	}
+
+	want := []string{
+		"before replica 1 SELECT 1 prepare=false",
+		"after SELECT 1 0s failed=false started",
+		"before primary 0 DELETE FROM users prepare=false",
+		"after DELETE FROM users 0s failed=true started",
+		"before  -1 SELECT 2 prepare=true",
+		"after SELECT 2 0s failed=true started",
+	}
+	if fmt.Sprint(hook.calls) != fmt.Sprint(want) {
+		t.Errorf("want %q, got %q", want, hook.calls)
+	}
+}
@@ -0,0 +1,230 @@
+package dbresolver
+
+import "strings"
+
+// ParsingQueryTypeChecker parses the SQL statements to route them, see ParseQueryType.
+// The override checker, when set, takes precedence for the queries it doesn't return QueryTypeUnknown for.
+type ParsingQueryTypeChecker struct {
+	Override QueryTypeChecker
+}
+
+func (c ParsingQueryTypeChecker) Check(query string) QueryType {
+	if c.Override != nil {
+		if queryType := c.Override.Check(query); queryType != QueryTypeUnknown {
+			return queryType
+		}
+	}
+	return ParseQueryType(query)
+}
+
+// WithQueryParsing routes the queries by parsing their SQL statements, see ParseQueryType, so Query and QueryRow
+// send the writes to a primary, eg. INSERT ... RETURNING or SELECT ... FOR UPDATE.
+// The query type checker set with WithQueryTypeChecker overrides the parsed type, except the default checker.
+func WithQueryParsing() OptionFunc {
+	return func(opt *Option) {
+		opt.QueryParsing = true
+	}
+}
+
+// queryTypeChecker returns the query type checker of the options, parsing the queries with WithQueryParsing
+func queryTypeChecker(opt *Option) QueryTypeChecker {
+	if !opt.QueryParsing {
+		return opt.QueryTypeChecker
+	}
+	switch opt.QueryTypeChecker.(type) {
+	case nil, DefaultQueryTypeChecker, *DefaultQueryTypeChecker:
+		return ParsingQueryTypeChecker{}
+	default:
+		return ParsingQueryTypeChecker{Override: opt.QueryTypeChecker}
+	}
+}
+
+// readStatements are the first keywords of the statements which read, unless they embed a write
+var readStatements = map[string]bool{
+	"SELECT": true, "WITH": true, "VALUES": true, "TABLE": true,
+	"SHOW": true, "EXPLAIN": true, "DESCRIBE": true, "DESC": true,
+}
+
+// writeKeywords are the keywords making a read statement a write: the data-modifying CTEs,
+// SELECT INTO and the functions changing the state of the session or of a sequence
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "INTO": true,
+	"NEXTVAL": true, "SETVAL": true, "LASTVAL": true, "GET_LOCK": true, "RELEASE_LOCK": true,
+}
+
+// ParseQueryType parses the SQL statements of the query, and returns QueryTypeWrite when any statement writes
+// or must run on a primary: INSERT, UPDATE, DELETE, MERGE, DDL, CTEs with writes, SELECT ... FOR UPDATE/SHARE,
+// SELECT INTO and the other statements. SELECT, VALUES, TABLE, SHOW and EXPLAIN read.
+// The comments, the strings and the quoted identifiers are skipped. It returns QueryTypeUnknown for an empty query.
+//
+// The parser doesn't know the functions writing, besides the sequence and lock functions: the queries calling them,
+// eg. SELECT my_procedure(), must be routed with a QueryTypeChecker, see ParsingQueryTypeChecker.
+func ParseQueryType(query string) QueryType {
+	queryType := QueryTypeUnknown
+	lexer := sqlLexer{query: query}
+	first, previous := "", ""
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			// an unterminated string or comment may hide a write
+			return QueryTypeWrite
+		}
+		switch {
+		case token == "" || token == ";":
+			if first != "" {
+				queryType = QueryTypeRead
+			}
+			if token == "" {
+				return queryType
+			}
+			first, previous = "", ""
+			continue
+		case token == "(" || token == ")" || token == ",":
+		case first == "":
+			first = token
+			if !readStatements[first] {
+				return QueryTypeWrite
+			}
+		case writeKeywords[token],
+			token == "SHARE" && (previous == "FOR" || previous == "KEY" || previous == "IN"):
+			return QueryTypeWrite
+		}
+		previous = token
+	}
+}
+
+// sqlLexer splits a query into its uppercase keywords and identifiers, and the punctuation.
+// The comments, the strings, the quoted identifiers, the numbers and the parameters are skipped.
+type sqlLexer struct {
+	query string
+	pos   int
+}
+
+// next returns the next token, an empty token at the end of the query.
+// It returns false on an unterminated string, quoted identifier or comment.
+func (l *sqlLexer) next() (string, bool) {
+	for l.pos < len(l.query) {
+		c := l.query[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			l.pos++
+		case strings.HasPrefix(l.query[l.pos:], "--"):
+			end := strings.IndexByte(l.query[l.pos:], '\n')
+			if end < 0 {
+				l.pos = len(l.query)
+			} else {
+				l.pos += end + 1
+			}
+		case strings.HasPrefix(l.query[l.pos:], "/*"):
+			if !l.skipBlockComment() {
+				return "", false
+			}
+		case c == '\'' || c == '"' || c == '`':
+			if !l.skipQuoted(c, false) {
+				return "", false
+			}
+		case c == '$':
+			if !l.skipDollar() {
+				return "", false
+			}
+		case c == '(' || c == ')' || c == ',' || c == ';':
+			l.pos++
+			return string(c), true
+		case isWordByte(c):
+			start := l.pos
+			for l.pos < len(l.query) && isWordByte(l.query[l.pos]) {
+				l.pos++
+			}
+			word := l.query[start:l.pos]
+			if isDigit(c) {
+				continue
+			}
+			if l.pos < len(l.query) && l.query[l.pos] == '\'' {
+				// the prefix of a string, eg. N'...', the backslashes escape in the PostgreSQL E'...' strings
+				if !l.skipQuoted('\'', word == "E" || word == "e") {
+					return "", false
+				}
+				continue
+			}
+			return strings.ToUpper(word), true
+		default:
+			l.pos++
+		}
+	}
+	return "", true
+}
+
+// skipBlockComment skips the comment, the PostgreSQL comments nest
+func (l *sqlLexer) skipBlockComment() bool {
+	depth := 0
+	for l.pos < len(l.query) {
+		switch {
+		case strings.HasPrefix(l.query[l.pos:], "/*"):
+			depth++
+			l.pos += 2
+		case strings.HasPrefix(l.query[l.pos:], "*/"):
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return true
+			}
+		default:
+			l.pos++
+		}
+	}
+	return false
+}
+
+// skipQuoted skips the quoted string or identifier, the doubled quotes are escaped quotes.
+// The backslashes escape with the escapes, eg. the PostgreSQL E'...' strings.
+func (l *sqlLexer) skipQuoted(quote byte, escapes bool) bool {
+	l.pos++
+	for l.pos < len(l.query) {
+		switch c := l.query[l.pos]; {
+		case c == '\\' && escapes:
+			l.pos += 2
+		case c == quote && l.pos+1 < len(l.query) && l.query[l.pos+1] == quote:
+			l.pos += 2
+		case c == quote:
+			l.pos++
+			return true
+		default:
+			l.pos++
+		}
+	}
+	return false
+}
+
+// skipDollar skips the parameter, eg. $1, or the PostgreSQL dollar-quoted string, eg. $body$...$body$
+func (l *sqlLexer) skipDollar() bool {
+	end := l.pos + 1
+	if end < len(l.query) && isDigit(l.query[end]) {
+		for end < len(l.query) && isDigit(l.query[end]) {
+			end++
+		}
+		l.pos = end
+		return true
+	}
+	for end < len(l.query) && isWordByte(l.query[end]) {
+		end++
+	}
+	if end >= len(l.query) || l.query[end] != '$' {
+		l.pos++
+		return true
+	}
+	tag := l.query[l.pos : end+1]
+	closing := strings.Index(l.query[end+1:], tag)
+	if closing < 0 {
+		return false
+	}
+	l.pos = end + 1 + closing + len(tag)
+	return true
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || isDigit(c) || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,92 @@
+package dbresolver
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseQueryType(t *testing.T) {
+	for query, want := range map[string]QueryType{
+		"SELECT id FROM book":                                       QueryTypeRead,
+		"  select id from updates -- UPDATE":                        QueryTypeRead,
+		"(SELECT 1) UNION (SELECT 2)":                               QueryTypeRead,
+		"WITH recent AS (SELECT id FROM book) SELECT * FROM recent": QueryTypeRead,
+		"SELECT 'DELETE', \"insert\", $$UPDATE$$, $body$MERGE$body$ /* INTO /* nested */ */ FROM book": QueryTypeRead,
+		"SELECT E'it\\'s DELETE' FROM book":                                QueryTypeRead,
+		"SELECT title FROM book WHERE id = $1":                             QueryTypeRead,
+		"EXPLAIN SELECT 1":                                                 QueryTypeRead,
+		"SHOW server_version; SELECT 1;":                                   QueryTypeRead,
+		"INSERT INTO book (title) VALUES ($1) RETURNING id":                QueryTypeWrite,
+		"update book set title = $1":                                       QueryTypeWrite,
+		"CREATE TABLE book (id int)":                                       QueryTypeWrite,
+		"WITH moved AS (DELETE FROM book RETURNING *) SELECT * FROM moved": QueryTypeWrite,
+		"SELECT id FROM book FOR UPDATE":                                   QueryTypeWrite,
+		"SELECT id FROM book FOR KEY SHARE":                                QueryTypeWrite,
+		"SELECT id FROM book LOCK IN SHARE MODE":                           QueryTypeWrite,
+		"SELECT * INTO archive FROM book":                                  QueryTypeWrite,
+		"SELECT nextval('book_id_seq')":                                    QueryTypeWrite,
+		"SELECT 1; DELETE FROM book":                                       QueryTypeWrite,
+		"SET search_path TO library":                                       QueryTypeWrite,
+		"SELECT 'unterminated; DELETE FROM book":                           QueryTypeWrite,
+		"":                                                                 QueryTypeUnknown,
+		" -- nothing":                                                      QueryTypeUnknown,
+	} {
+		if got := ParseQueryType(query); got != want {
+			t.Errorf("want %v for %q, got %v", want, query, got)
+		}
+	}
+}
+
+func FuzzParseQueryType(f *testing.F) {
+	f.Add("SELECT $tag$ x $tag$ FROM book FOR UPDATE")
+	f.Add("WITH a AS (INSERT INTO b VALUES (1) RETURNING *) SELECT * FROM a")
+	f.Add("SELECT E'\\' /* -- '")
+	f.Fuzz(func(t *testing.T, query string) {
+		// a write stays a write whatever is appended after a statement separator
+		if ParseQueryType(query+";\nDELETE FROM book") != QueryTypeWrite {
+			t.Errorf("want a write for %q followed by a DELETE", query)
+		}
+	})
+}
+
+func TestQueryParsing(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	override := queryTypeCheckerFunc(func(query string) QueryType {
+		if query == "SELECT my_procedure()" {
+			return QueryTypeWrite
+		}
+		return QueryTypeUnknown
+	})
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithQueryParsing(), WithQueryTypeChecker(override))
+
+	primaryMock.ExpectQuery("SELECT id FROM book FOR UPDATE").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	primaryMock.ExpectQuery("SELECT my_procedure()").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	replicaMock.ExpectQuery("SELECT id FROM book").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	for _, query := range []string{"SELECT id FROM book FOR UPDATE", "SELECT my_procedure()", "SELECT id FROM book"} {
+		rows, err := resolver.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = rows.Close()
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+type queryTypeCheckerFunc func(query string) QueryType
+
+func (f queryTypeCheckerFunc) Check(query string) QueryType {
+	return f(query)
+}
@@ -0,0 +1,129 @@
+package dbresolver
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ReadCacheKeyFunc returns the cache key of a read query and its args.
+// The queries are only cached when ok is true, so only the idempotent queries opted in are cached.
+type ReadCacheKeyFunc func(query string, args []interface{}) (key string, ok bool)
+
+// ReadCache define the read result cache of the resolver
+type ReadCache struct {
+	// TTL is the duration a result is served from the cache
+	TTL time.Duration
+	// MaxEntries bounds the cached results, the least recently used one is evicted
+	MaxEntries int
+	// Key selects the cached queries and returns their cache key
+	Key ReadCacheKeyFunc
+}
+
+// WithReadCache serves the read queries selected by the key function from an in-memory cache for the ttl,
+// before routing them to a replica, eg. for the reference data looked up with identical queries
+// thousands of times per second. The results are read entirely on a cache miss, cache only small results.
+// The hooks aren't called for the results served by the cache.
+func WithReadCache(ttl time.Duration, maxEntries int, key ReadCacheKeyFunc) OptionFunc {
+	if ttl <= 0 || maxEntries < 1 || key == nil {
+		panic(fmt.Sprintf("dbresolver: invalid read cache, ttl %v, max entries %d", ttl, maxEntries))
+	}
+	return func(opt *Option) {
+		opt.ReadCache = &ReadCache{TTL: ttl, MaxEntries: maxEntries, Key: key}
+	}
+}
+
+type readCacheEntry struct {
+	key     string
+	result  *result
+	expires time.Time
+}
+
+// readCache is a LRU cache of the results
+type readCache struct {
+	config ReadCache
+	clock  Clock
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+func newReadCache(config ReadCache, clock Clock) *readCache {
+	return &readCache{
+		config:  config,
+		clock:   clock,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns the unexpired result of the key
+func (c *readCache) get(key string) *result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*readCacheEntry)
+	if !c.clock.Now().Before(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return entry.result
+}
+
+// contains reports whether the key has an unexpired result, without refreshing it
+func (c *readCache) contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	return ok && c.clock.Now().Before(elem.Value.(*readCacheEntry).expires)
+}
+
+func (c *readCache) set(key string, res *result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &readCacheEntry{key: key, result: res, expires: c.clock.Now().Add(c.config.TTL)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.config.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*readCacheEntry).key)
+	}
+}
+
+// readCacheKey returns the cache key of the query, ok is false when it's not cached
+func (db *sqlDB) readCacheKey(writeFlag bool, query string, args []interface{}) (key string, ok bool) {
+	if db.readCache == nil || writeFlag {
+		return "", false
+	}
+	return db.readCache.config.Key(query, args)
+}
+
+// cachedResult returns the cached result of the key, it runs the query on a cache miss
+func (db *sqlDB) cachedResult(ctx context.Context, key, query string, args []interface{}) (*result, error) {
+	if res := db.readCache.get(key); res != nil {
+		return res, nil
+	}
+	rows, err := db.query(ctx, query, args, false)
+	if err != nil {
+		return nil, err
+	}
+	res, err := readResult(rows)
+	if err != nil {
+		return nil, err
+	}
+	db.readCache.set(key, res)
+	return res, nil
+}
@@ -0,0 +1,87 @@
+package dbresolver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReadCache(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	query := "SELECT name FROM country WHERE code = ?"
+	key := func(q string, args []interface{}) (string, bool) {
+		if q != query {
+			return "", false
+		}
+		return args[0].(string), true
+	}
+	now := time.Now()
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithReadCache(time.Minute, 1, key),
+		WithClock(stubClock{now: &now})).(*sqlDB)
+
+	country := func(code string) string {
+		t.Helper()
+		var name string
+		if err := resolver.QueryRow(query, code).Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	replicaMock.ExpectQuery(query).WithArgs("FR").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("France"))
+	for i := 0; i < 3; i++ {
+		if name := country("FR"); name != "France" {
+			t.Fatalf("want France, got %s", name)
+		}
+	}
+	rows, err := resolver.Query(query, "FR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() || rows.Next() {
+		t.Error("want the single cached row")
+	}
+	rows.Close()
+
+	// the least recently used result is evicted
+	replicaMock.ExpectQuery(query).WithArgs("DE").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Germany"))
+	replicaMock.ExpectQuery(query).WithArgs("FR").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("France"))
+	country("DE")
+	country("FR")
+
+	// the expired result is queried again
+	now = now.Add(time.Minute)
+	replicaMock.ExpectQuery(query).WithArgs("FR").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("République française"))
+	if name := country("FR"); name != "République française" {
+		t.Errorf("want the refreshed name, got %s", name)
+	}
+
+	// the errors aren't cached
+	replicaMock.ExpectQuery(query).WithArgs("IT").WillReturnError(errors.New("timeout"))
+	if err := resolver.QueryRow(query, "IT").Scan(new(string)); err == nil || err.Error() != "timeout" {
+		t.Errorf("want the query error, got %v", err)
+	}
+
+	// the queries not selected by the key function aren't cached
+	for i := 0; i < 2; i++ {
+		replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+		if err := resolver.QueryRow("SELECT 1").Scan(new(int)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package dbresolver
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// ReadMode define which nodes the reads go to, see DB.SetReadMode
+type ReadMode string
+
+// Supported read modes
+const (
+	// ReplicaPreferred sends the reads to the replicas in rotation, to the primaries when there's none
+	ReplicaPreferred ReadMode = "REPLICA_PREFERRED"
+	// PrimaryOnly sends the reads to the primaries, eg. during the maintenance of the replicas or a lag incident
+	PrimaryOnly ReadMode = "PRIMARY_ONLY"
+	// ReplicaOnly sends the reads to the replicas only, every replica when there's none in rotation,
+	// and the reads don't fall back to a primary, eg. to protect the primaries during a load peak.
+	// The reads go to the primaries when the resolver has no replica.
+	ReplicaOnly ReadMode = "REPLICA_ONLY"
+)
+
+// WithReadMode sets the read mode the resolver starts with, ReplicaPreferred by default, see DB.SetReadMode
+func WithReadMode(mode ReadMode) OptionFunc {
+	if err := validateReadMode(mode); err != nil {
+		panic(err.Error())
+	}
+	return func(opt *Option) {
+		opt.ReadMode = mode
+	}
+}
+
+func validateReadMode(mode ReadMode) error {
+	switch mode {
+	case "", ReplicaPreferred, PrimaryOnly, ReplicaOnly:
+		return nil
+	}
+	return fmt.Errorf("dbresolver: invalid read mode %q", mode)
+}
+
+// SetReadMode switches the read mode of the resolver and its shards at runtime, without redeploying.
+// It applies to the next queries, transactions and connections, and to the statements prepared before.
+func (db *sqlDB) SetReadMode(mode ReadMode) error {
+	if err := validateReadMode(mode); err != nil {
+		return err
+	}
+	db.storeReadMode(mode)
+	for _, name := range db.shardNames {
+		db.shards[name].storeReadMode(mode)
+	}
+	return nil
+}
+
+func (db *sqlDB) storeReadMode(mode ReadMode) {
+	if mode == "" {
+		mode = ReplicaPreferred
+	}
+	db.readMode.Store(&mode)
+}
+
+// currentReadMode returns the read mode of the resolver
+func (db *sqlDB) currentReadMode() ReadMode {
+	return loadReadMode(&db.readMode)
+}
+
+// loadReadMode returns the read mode, ReplicaPreferred when it's unset or the pointer is nil
+func loadReadMode(readMode *atomic.Pointer[ReadMode]) ReadMode {
+	if readMode == nil {
+		return ReplicaPreferred
+	}
+	if mode := readMode.Load(); mode != nil {
+		return *mode
+	}
+	return ReplicaPreferred
+}
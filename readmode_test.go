@@ -0,0 +1,89 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSetReadMode(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica))
+	prepared := primaryMock.ExpectPrepare("SELECT 1")
+	replicaMock.ExpectPrepare("SELECT 1")
+	st, err := resolver.Prepare("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the reads and the prepared statements go to the primary
+	if err := resolver.SetReadMode(PrimaryOnly); err != nil {
+		t.Fatal(err)
+	}
+	primaryMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	var one int
+	if err := resolver.QueryRow("SELECT 1").Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+	prepared.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	if err := st.QueryRow().Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+	decision, err := resolver.ExplainRoute(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision.Role != RolePrimary {
+		t.Errorf("want the reads explained on the primary, got %+v", decision)
+	}
+
+	// back to the replicas
+	if err := resolver.SetReadMode(ReplicaPreferred); err != nil {
+		t.Fatal(err)
+	}
+	replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	if err := resolver.QueryRow("SELECT 1").Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := resolver.SetReadMode("SOMETIMES"); err == nil {
+		t.Error("want an error for an unknown read mode")
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestReplicaOnly(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	unreachable := sql.OpenDB(unreachableConnector{})
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(unreachable), WithReadMode(ReplicaOnly),
+		WithCircuitBreaker(CircuitBreaker{FailureThreshold: 1}))
+
+	// the reads stay on the replica, its circuit open
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.Query("SELECT 1"); err == nil {
+			t.Fatal("want the error of the unreachable replica")
+		}
+	}
+	if _, err := resolver.ReplicaConn(context.Background()); err == nil {
+		t.Error("want the error of the unreachable replica")
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,202 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultRoleDetectionInterval is the default interval between two probes of the roles of the nodes
+const defaultRoleDetectionInterval = 10 * time.Second
+
+// ErrNoWritableNode is returned by DB.Repoll when no node is writable, the roles are kept
+var ErrNoWritableNode = errors.New("dbresolver: no writable node found")
+
+// RoleDetection define how the roles of the nodes are probed in the background, see WithRoleDetection
+type RoleDetection struct {
+	// Interval between two probes of the nodes, 10 seconds by default. It's the timeout of a probe too.
+	Interval time.Duration
+	// OnChange is called with the role changes of a probe, if any
+	OnChange func(changes []TopologyChange)
+}
+
+// WithRoleDetection probes the role of every node in the background on every interval, see DB.Repoll,
+// so the resolver follows a failover without restarting the application, eg. when the cloud provider
+// promotes a replica. The nodes are probed with the detector of WithReadOnlyDetector.
+// The probes stop when the resolver is closed.
+func WithRoleDetection(config RoleDetection) OptionFunc {
+	return func(opt *Option) {
+		opt.RoleDetection = &config
+	}
+}
+
+// Repoll probes concurrently the role of every node with the read-only detector, see WithReadOnlyDetector,
+// and moves the nodes whose role changed: the writable replicas are promoted to primary, then the read-only
+// primaries are demoted to replica. When a replica is promoted, the unreachable primaries are demoted too,
+// the old primary of a failover is often down. The unreachable nodes keep their role otherwise.
+// Nothing changes when no node is writable, the error is ErrNoWritableNode.
+// The applied changes are returned, their desired node describes the new role.
+func (db *sqlDB) Repoll(ctx context.Context) ([]TopologyChange, error) {
+	changes, err := db.repoll(ctx)
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range db.shardNames {
+		shardChanges, err := db.shards[name].Repoll(ctx)
+		for i := range shardChanges {
+			current := *shardChanges[i].Current
+			current.Labels = mergeLabels(current.Labels, map[string]string{ShardLabel: name})
+			shardChanges[i].Current = &current
+		}
+		changes = append(changes, shardChanges...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("shard %s: %w", name, err))
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// repoll probes the nodes of the resolver, without its shards, and applies the role changes
+func (db *sqlDB) repoll(ctx context.Context) ([]TopologyChange, error) {
+	db.repollLock.Lock()
+	defer db.repollLock.Unlock()
+
+	detector := db.readOnlyDetector
+	if detector == nil {
+		detector = PostgresReadOnlyDetector
+	}
+	set := db.nodes.Load()
+	primaries := distinct(set.primaries)
+	nodes := append(primaries[:len(primaries):len(primaries)], distinct(set.replicas)...)
+	readOnly := make([]bool, len(nodes))
+	errs := make([]error, len(nodes))
+	err := doParallely(ctx, db.parallelism, len(nodes), func(i int) error {
+		readOnly[i], errs[i] = detector.IsReadOnly(ctx, nodes[i])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	isPrimary := make(map[*sql.DB]bool, len(primaries))
+	for _, node := range primaries {
+		isPrimary[node] = true
+	}
+	writable := false
+	var promoted, demoted []*sql.DB
+	for i, node := range nodes {
+		if errs[i] != nil {
+			continue
+		}
+		writable = writable || !readOnly[i]
+		switch {
+		case i < len(primaries) && readOnly[i]:
+			demoted = append(demoted, node)
+		case i >= len(primaries) && !readOnly[i] && !isPrimary[node]:
+			promoted = append(promoted, node)
+		}
+	}
+	if !writable {
+		return nil, ErrNoWritableNode
+	}
+	if len(promoted) > 0 {
+		for i, node := range primaries {
+			if errs[i] != nil {
+				demoted = append(demoted, node)
+			}
+		}
+	}
+
+	// promote first, so the last primary can be demoted
+	changes := make([]TopologyChange, 0, len(promoted)+len(demoted))
+	for _, node := range promoted {
+		changes = append(changes, db.roleChange(set, node, RoleReplica, RolePrimary))
+	}
+	for _, node := range demoted {
+		changes = append(changes, db.roleChange(set, node, RolePrimary, RoleReplica))
+	}
+	for _, change := range changes {
+		db.setWeight(change.Current.DB, change.Desired.Role, change.Desired.Weight)
+		db.addNode(change.Current.DB, change.Desired.Role)
+		if err := db.removeNode(change.Current.DB, change.Current.Role); err != nil {
+			return changes, fmt.Errorf("dbresolver: demoting node %s: %w", change.Name, err)
+		}
+	}
+	for _, change := range changes {
+		msg := "dbresolver: node promoted to primary"
+		if change.Desired.Role == RoleReplica {
+			msg = "dbresolver: node demoted to replica"
+		}
+		db.log(ctx, LogLevelWarn, msg, db.nodeFields(change.Current.DB)...)
+	}
+	return changes, nil
+}
+
+// roleChange describes the move of the node from a role to another, it keeps its weight and labels
+func (db *sqlDB) roleChange(set *nodeSet, node *sql.DB, from, to Role) TopologyChange {
+	current := db.nodeInfo(set, node, from)
+	name := db.nodeName(node)
+	return TopologyChange{Type: NodeRoleChanged, Name: name, Current: &current,
+		Desired: &TopologyNode{Name: name, Role: to, Weight: current.Weight, Labels: current.Labels}}
+}
+
+// roleMonitor probes the roles of the nodes of the resolver, see WithRoleDetection
+type roleMonitor struct {
+	db     *sqlDB
+	config RoleDetection
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newRoleMonitor(db *sqlDB, config RoleDetection) *roleMonitor {
+	if config.Interval <= 0 {
+		config.Interval = defaultRoleDetectionInterval
+	}
+	return &roleMonitor{
+		db:     db,
+		config: config,
+		done:   make(chan struct{}),
+	}
+}
+
+// start probes the nodes on every interval until close
+func (m *roleMonitor) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	go func() {
+		defer close(m.done)
+		for {
+			timer := m.db.clock.NewTimer(m.config.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C():
+			}
+			m.probe(ctx)
+		}
+	}()
+}
+
+func (m *roleMonitor) close() {
+	m.cancel()
+	<-m.done
+}
+
+// probe probes the nodes once, and reports the role changes
+func (m *roleMonitor) probe(ctx context.Context) {
+	probeCtx, cancel := context.WithTimeout(ctx, m.config.Interval)
+	defer cancel()
+	changes, err := m.db.repoll(probeCtx)
+	if errors.Is(err, ErrNoWritableNode) {
+		m.db.log(ctx, LogLevelWarn, "dbresolver: no writable node, the roles are kept")
+	}
+	if len(changes) > 0 && m.config.OnChange != nil {
+		m.config.OnChange(changes)
+	}
+}
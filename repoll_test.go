@@ -0,0 +1,100 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// roleDetector reports the nodes of readOnly as read-only, the nodes missing from it as unreachable
+func roleDetector(readOnly map[*sql.DB]bool) ReadOnlyDetector {
+	return ReadOnlyDetectorFunc(func(_ context.Context, db *sql.DB) (bool, error) {
+		ro, ok := readOnly[db]
+		if !ok {
+			return false, errors.New("connection refused")
+		}
+		return ro, nil
+	})
+}
+
+func TestRepoll(t *testing.T) {
+	primary, replica1, replica2 := &sql.DB{}, &sql.DB{}, &sql.DB{}
+	readOnly := map[*sql.DB]bool{primary: false, replica1: true, replica2: true}
+	logger := &recordingLogger{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica1, replica2),
+		WithNodeName(replica1, "replica-1"), WithReadOnlyDetector(roleDetector(readOnly)),
+		WithLogger(logger)).(*sqlDB)
+
+	changes, err := resolver.Repoll(context.Background())
+	if err != nil || len(changes) != 0 {
+		t.Fatalf("want no change, got %+v, %v", changes, err)
+	}
+
+	// the failover promotes the replica, the old primary is down
+	delete(readOnly, primary)
+	readOnly[replica1] = false
+	changes, err = resolver.Repoll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Type != NodeRoleChanged || changes[0].Name != "replica-1" ||
+		changes[0].Current.DB != replica1 || changes[0].Desired.Role != RolePrimary ||
+		changes[1].Current.DB != primary || changes[1].Desired.Role != RoleReplica {
+		t.Fatalf("want the replica promoted and the primary demoted, got %+v", changes)
+	}
+	if primaries, replicas := resolver.PrimaryDBs(), resolver.ReplicaDBs(); len(primaries) != 1 ||
+		primaries[0] != replica1 || len(replicas) != 2 || replicas[0] != replica2 || replicas[1] != primary {
+		t.Errorf("want the roles swapped, got %v and %v", primaries, replicas)
+	}
+	if resolver.ReadWrite() != replica1 {
+		t.Error("want the writes on the promoted replica")
+	}
+	if len(logger.entries) != 2 ||
+		logger.entries[0] != "WARN dbresolver: node promoted to primary [{role primary} {node 0} {name replica-1}]" {
+		t.Errorf("want the role changes logged, got %q", logger.entries)
+	}
+
+	// the old primary comes back as a standby
+	readOnly[primary] = true
+	if changes, err = resolver.Repoll(context.Background()); err != nil || len(changes) != 0 {
+		t.Errorf("want no change, got %+v, %v", changes, err)
+	}
+
+	// nothing changes without writable node
+	readOnly[replica1] = true
+	if _, err = resolver.Repoll(context.Background()); !errors.Is(err, ErrNoWritableNode) {
+		t.Errorf("want ErrNoWritableNode, got %v", err)
+	}
+	if resolver.ReadWrite() != replica1 {
+		t.Error("want the primary kept without writable node")
+	}
+}
+
+func TestRoleDetection(t *testing.T) {
+	primary, replica := &sql.DB{}, &sql.DB{}
+	readOnly := map[*sql.DB]bool{primary: true, replica: false}
+	now := time.Now()
+	clock := stubClock{now: &now}
+	changed := make(chan []TopologyChange, 1)
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithClock(clock),
+		WithReadOnlyDetector(roleDetector(readOnly)),
+		WithRoleDetection(RoleDetection{Interval: time.Hour, OnChange: func(changes []TopologyChange) {
+			changed <- changes
+		}})).(*sqlDB)
+	defer resolver.stop()
+
+	resolver.roles.probe(context.Background())
+	select {
+	case changes := <-changed:
+		if len(changes) != 2 {
+			t.Errorf("want the roles swapped, got %+v", changes)
+		}
+	default:
+		t.Fatal("want the role changes reported")
+	}
+	if resolver.ReadWrite() != replica {
+		t.Error("want the writes on the promoted replica")
+	}
+}
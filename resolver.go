@@ -1,7 +1,20 @@
 package dbresolver
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
 // New will resolve all the passed connection with configurable parameters
 func New(opts ...OptionFunc) DB {
+	db, _ := newResolver(opts)
+	return db
+}
+
+// newResolver applies the options once, and creates the resolver of the options
+func newResolver(opts []OptionFunc) (*sqlDB, *Option) {
 	opt := defaultOption()
 	for _, optFunc := range opts {
 		optFunc(opt)
@@ -11,11 +24,200 @@ func New(opts ...OptionFunc) DB {
 		panic("required primary db connection, set the primary db " +
 			"connection with dbresolver.New(dbresolver.WithPrimaryDBs(primaryDB))")
 	}
-	return &sqlDB{
-		primaries:        opt.PrimaryDBs,
-		replicas:         opt.ReplicaDBs,
-		loadBalancer:     opt.DBLB,
-		stmtLoadBalancer: opt.StmtLB,
-		queryTypeChecker: opt.QueryTypeChecker,
+	db := newSQLDB(opt)
+	if opt.Shards != nil {
+		db.shards, db.shardNames = newShards(opt)
+		db.shardResolver = opt.ShardResolver
+		if db.shardResolver == nil {
+			db.shardResolver = HashShardResolver
+		}
+	}
+	return db, opt
+}
+
+// newSQLDB creates the resolver of the nodes of the options, and starts its background goroutines
+func newSQLDB(opt *Option) *sqlDB {
+	hooks := opt.Hooks
+	if opt.SlowQueryLog.Threshold > 0 {
+		// first, so the duration includes the Before of the other hooks
+		hooks = append([]Hooks{newSlowQueryHooks(opt.SlowQueryLog, opt.Logger, opt.Clock)}, hooks...)
+	}
+	prepareConcurrency := opt.PrepareConcurrency
+	if prepareConcurrency == 0 {
+		prepareConcurrency = opt.MaxParallelism
+	}
+	db := &sqlDB{
+		loadBalancer:        opt.DBLB,
+		stmtLoadBalancer:    opt.StmtLB,
+		queryTypeChecker:    queryTypeChecker(opt),
+		queryHooks:          opt.QueryHooks,
+		logger:              opt.Logger,
+		readOnlyDetector:    opt.ReadOnlyDetector,
+		lifetimeJitter:      opt.LifetimeJitter,
+		redactor:            opt.Redactor,
+		parallelism:         opt.MaxParallelism,
+		prepareConcurrency:  prepareConcurrency,
+		clock:               opt.Clock,
+		notificationWaiter:  opt.NotificationWaiter,
+		bulkLoad:            opt.BulkLoad,
+		defaultTxOptions:    opt.DefaultTxOptions,
+		readOnlyTxOnPrimary: opt.ReadOnlyTxOnPrimary,
+		lazyPrepare:         opt.LazyPrepare,
+		writeStrategy:       opt.WriteStrategy,
+		stickyWindow:        opt.StickyPrimary,
+		classifier:          opt.Classifier,
+		readFallback:        opt.ReadFallback,
+		primaryQueryTimeout: opt.PrimaryQueryTimeout,
+		replicaQueryTimeout: opt.ReplicaQueryTimeout,
+	}
+	if opt.Logger != nil {
+		db.queryHooks = append(opt.QueryHooks[:len(opt.QueryHooks):len(opt.QueryHooks)], logQueryHook{logger: opt.Logger})
+	}
+	// the query hooks are called after the hooks, with the nodes of the resolver
+	db.hooks = append(hooks[:len(hooks):len(hooks)], db.queryHookAdapters()...)
+	db.latency, _ = opt.DBLB.(latencyObserver[*sql.DB])
+	db.affinity, _ = opt.DBLB.(keyResolver[*sql.DB])
+	if opt.CircuitBreaker != nil {
+		config := *opt.CircuitBreaker
+		if db.logger != nil {
+			config.OnStateChange = db.logCircuitChange(config.OnStateChange)
+		}
+		if config.IsFailure == nil {
+			config.IsFailure = db.isConnectionError
+		}
+		db.breaker = newBreaker(config, opt.Clock)
+	}
+	if opt.RetryPolicy != nil {
+		db.retry = newRetrier(*opt.RetryPolicy, opt.Clock, db.classifier)
+	}
+	if opt.WorkloadPartitions != nil {
+		db.partitions = newPartitions(opt.WorkloadPartitions)
+	}
+	if opt.AdaptiveConcurrency != nil {
+		db.concurrency = newConcurrencyLimiter(*opt.AdaptiveConcurrency, opt.Clock)
+	}
+	if opt.AdmissionControl != nil {
+		db.admission = newAdmission(*opt.AdmissionControl, opt.Clock)
+	}
+	if opt.ReadCache != nil {
+		db.readCache = newReadCache(*opt.ReadCache, opt.Clock)
+	}
+	if opt.StmtCache > 0 {
+		db.stmtCache = newStmtCache(opt.StmtCache)
+	}
+	if opt.ReadCoalescing != nil {
+		db.coalescer = newCoalescer(*opt.ReadCoalescing)
+	}
+	// holds the results read in memory, and the errors of QueryRowContext
+	db.results = newResultDB()
+	if opt.ReadMode != "" {
+		db.storeReadMode(opt.ReadMode)
+	}
+	labels := opt.NodeLabels
+	db.labels.Store(&labels)
+	db.storeNodes(nodeSet{
+		primaries:      opt.PrimaryDBs,
+		replicas:       opt.ReplicaDBs,
+		primaryWeights: opt.PrimaryWeights,
+		replicaWeights: opt.ReplicaWeights,
+	})
+
+	discoveries := opt.Discoveries
+	if opt.DNSDiscovery != nil {
+		discoveries = append(discoveries, newDNSDiscoverer(*opt.DNSDiscovery).discovery())
+	}
+	for _, config := range discoveries {
+		d := newDiscovery(db, config)
+		d.start()
+		db.discoveries = append(db.discoveries, d)
+	}
+	if opt.Saturation != nil {
+		db.saturation = newSaturationMonitor(db, *opt.Saturation)
+		db.saturation.start()
+	}
+	if opt.NearestReads != nil {
+		db.nearest = newNearestProber(db, *opt.NearestReads)
+		db.nearest.start()
+	}
+	if opt.HealthCheck != nil {
+		db.health = newHealthChecker(db, *opt.HealthCheck)
+		db.health.start()
+	}
+	if opt.LagMonitor != nil {
+		db.lag = newLagMonitor(db, *opt.LagMonitor)
+		db.lag.start()
+	}
+	if opt.RoleDetection != nil {
+		db.roles = newRoleMonitor(db, *opt.RoleDetection)
+		db.roles.start()
+	}
+	return db
+}
+
+// StartupCheck define how Connect checks the nodes
+type StartupCheck struct {
+	// Timeout of the check of all the nodes
+	Timeout time.Duration
+}
+
+// WithStartupCheck makes Connect verify that every node is reachable and has its configured role
+// (see DB.ValidateTopology) within the timeout, instead of discovering a misconfiguration
+// one failing query at a time. New doesn't run the check, it can't return an error.
+func WithStartupCheck(timeout time.Duration) OptionFunc {
+	return func(opt *Option) {
+		opt.StartupCheck = &StartupCheck{Timeout: timeout}
+	}
+}
+
+// Connect resolves the connections like New, then runs the startup check when WithStartupCheck is set.
+// The error lists every bad node, the DBs aren't closed on error.
+func Connect(ctx context.Context, opts ...OptionFunc) (DB, error) {
+	db, opt := newResolver(opts)
+	if opt.StartupCheck == nil {
+		return db, nil
+	}
+	if opt.StartupCheck.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.StartupCheck.Timeout)
+		defer cancel()
+	}
+	if _, err := db.ValidateTopology(ctx); err != nil {
+		db.stop()
+		return nil, fmt.Errorf("dbresolver: startup check failed: %w", err)
+	}
+	return db, nil
+}
+
+// stop stops the background goroutines, closes the discovered nodes and the resources opened by the resolver,
+// the configured nodes are left open
+func (db *sqlDB) stop() {
+	db.listeners.close()
+	for _, d := range db.discoveries {
+		d.close()
+		for _, node := range d.nodes {
+			_ = node.Close()
+		}
+	}
+	if db.saturation != nil {
+		db.saturation.close()
+	}
+	if db.nearest != nil {
+		db.nearest.close()
+	}
+	if db.health != nil {
+		db.health.close()
+	}
+	if db.lag != nil {
+		db.lag.close()
+	}
+	if db.roles != nil {
+		db.roles.close()
+	}
+	if db.stmtCache != nil {
+		_ = db.stmtCache.close()
+	}
+	_ = db.results.Close()
+	for _, shard := range db.shards {
+		shard.stop()
 	}
 }
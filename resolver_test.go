@@ -1,9 +1,14 @@
 package dbresolver_test
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/bxcodec/dbresolver/v2"
 )
 
@@ -28,3 +33,43 @@ func TestWrapDBWithOneDB(t *testing.T) {
 		t.Errorf("expected %v, got %v", "not nil", db)
 	}
 }
+
+func TestConnect(t *testing.T) {
+	primary, primaryMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	query := "SELECT pg_is_in_recovery()"
+	primaryMock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"pg_is_in_recovery"}).AddRow(false))
+	// the replica is misconfigured as a primary
+	replicaMock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"pg_is_in_recovery"}).AddRow(true))
+	_, err = dbresolver.Connect(context.Background(),
+		dbresolver.WithPrimaryDBs(primary, replica), dbresolver.WithStartupCheck(time.Second))
+	if !errors.Is(err, dbresolver.ErrRoleMismatch) || !strings.Contains(err.Error(), "primary 1") {
+		t.Errorf("want the misconfigured node error, got %v", err)
+	}
+
+	primaryMock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"pg_is_in_recovery"}).AddRow(false))
+	replicaMock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"pg_is_in_recovery"}).AddRow(true))
+	db, err := dbresolver.Connect(context.Background(),
+		dbresolver.WithPrimaryDBs(primary), dbresolver.WithReplicaDBs(replica), dbresolver.WithStartupCheck(time.Second))
+	if err != nil || db == nil {
+		t.Errorf("want the resolver, got %v", err)
+	}
+
+	// without the option, the nodes aren't checked
+	if _, err := dbresolver.Connect(context.Background(), dbresolver.WithPrimaryDBs(replica)); err != nil {
+		t.Error(err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+// result is a query result read entirely in memory, it's never mutated.
+// It's shared by the read cache and the coalesced reads, and served as *sql.Rows by resultConn.
+type result struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// readResult reads and closes the rows
+func readResult(rows *sql.Rows) (*result, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	res := &result{columns: columns}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make([]driver.Value, len(values))
+		for i, value := range values {
+			row[i] = value
+		}
+		res.rows = append(res.rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// newResultDB opens the database serving the results, passed as the only query arg
+func newResultDB() *sql.DB {
+	return sql.OpenDB(resultConnector{})
+}
+
+// resultRows returns the rows of the result
+func resultRows(ctx context.Context, results *sql.DB, res *result) (*sql.Rows, error) {
+	return results.QueryContext(ctx, "", res)
+}
+
+// resultRow returns the first row of the result, or a row holding the error when err isn't nil
+func resultRow(ctx context.Context, results *sql.DB, res *result, err error) *sql.Row {
+	if err != nil {
+		return results.QueryRowContext(ctx, "", err)
+	}
+	return results.QueryRowContext(ctx, "", res)
+}
+
+type resultConnector struct{}
+
+func (resultConnector) Connect(context.Context) (driver.Conn, error) { return resultConn{}, nil }
+func (resultConnector) Driver() driver.Driver                        { return nil }
+
+type resultConn struct{}
+
+func (resultConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("dbresolver: the in-memory results don't prepare statements")
+}
+func (resultConn) Close() error { return nil }
+func (resultConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("dbresolver: the in-memory results don't support transactions")
+}
+
+// CheckNamedValue accepts the result, or the error, as arg
+func (resultConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (resultConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	switch value := args[0].Value.(type) {
+	case error:
+		return nil, value
+	}
+	return &resultRowsIter{result: args[0].Value.(*result)}, nil
+}
+
+type resultRowsIter struct {
+	result *result
+	next   int
+}
+
+func (r *resultRowsIter) Columns() []string { return r.result.columns }
+func (r *resultRowsIter) Close() error      { return nil }
+
+func (r *resultRowsIter) Next(dest []driver.Value) error {
+	if r.next == len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,131 @@
+package dbresolver
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Defaults of the retry policy
+const (
+	defaultRetryMaxAttempts = 3
+	defaultRetryBackoff     = 10 * time.Millisecond
+	defaultRetryMaxBackoff  = time.Second
+	defaultRetryJitter      = 0.5
+)
+
+// RetryPolicy define how the queries failing with a transient error are retried, see WithRetryPolicy
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a query, the first one included, 3 by default
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled on every retry, 10 milliseconds by default
+	Backoff time.Duration
+	// MaxBackoff caps the delay between two attempts, 1 second by default
+	MaxBackoff time.Duration
+	// Jitter is the random fraction removed from each delay, in [0, 1], so the clients failing together
+	// don't retry together. 0.5 by default, negative for no jitter.
+	Jitter float64
+	// Retryable reports whether a failed query can be retried. By default the conflicts, as classified
+	// by the classifier of the resolver, see WithErrorClassifier, are retried, and the connection errors
+	// are retried for the reads only: a write may have been applied before its connection broke.
+	Retryable func(err error) bool
+}
+
+// WithRetryPolicy retries the Exec, Query and QueryRow of the resolver, its connections and its prepared statements
+// failing with a transient error, with an exponential backoff. Each attempt of the resolver is routed again,
+// eg. to another replica. The queries of the transactions aren't retried, the transaction is aborted,
+// neither are the errors while iterating the rows. The retries stop when the context is done.
+func WithRetryPolicy(policy RetryPolicy) OptionFunc {
+	if policy.MaxAttempts < 0 || policy.Jitter > 1 {
+		panic(fmt.Sprintf("dbresolver: invalid retry policy %+v", policy))
+	}
+	return func(opt *Option) {
+		opt.RetryPolicy = &policy
+	}
+}
+
+// IsTransientError reports whether the query failed with a transient error, and can succeed when retried:
+// a conflict, ie. a serialization failure or a deadlock, or a connection error, as classified
+// by DefaultClassifier. The resolver classifies the errors with its classifier, see WithErrorClassifier.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch DefaultClassifier.Classify(err) {
+	case ErrorClassConflict, ErrorClassConnection:
+		return true
+	}
+	return false
+}
+
+// retrier retries the queries with the retry policy, nil without retry policy
+type retrier struct {
+	policy     RetryPolicy
+	clock      Clock
+	classifier Classifier
+}
+
+func newRetrier(policy RetryPolicy, clock Clock, classifier Classifier) *retrier {
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = defaultRetryBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultRetryMaxBackoff
+	}
+	if policy.Jitter == 0 {
+		policy.Jitter = defaultRetryJitter
+	}
+	return &retrier{policy: policy, clock: clock, classifier: classifier}
+}
+
+// do runs fn until it succeeds, its error isn't retryable, the attempts are exhausted or the context is done.
+// It returns the error of the last attempt.
+func (r *retrier) do(ctx context.Context, write bool, fn func() error) error {
+	if r == nil {
+		return fn()
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.policy.MaxAttempts || ctx.Err() != nil || !r.retryable(err, write) {
+			return err
+		}
+
+		timer := r.clock.NewTimer(r.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C():
+		}
+	}
+}
+
+func (r *retrier) retryable(err error, write bool) bool {
+	if r.policy.Retryable != nil {
+		return r.policy.Retryable(err)
+	}
+	switch r.classifier.Classify(err) {
+	case ErrorClassConflict:
+		return true
+	case ErrorClassConnection:
+		return !write
+	}
+	return false
+}
+
+// backoff returns the delay after the attempt
+func (r *retrier) backoff(attempt int) time.Duration {
+	d := r.policy.Backoff
+	for i := 1; i < attempt && d < r.policy.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, r.policy.MaxBackoff)
+	if r.policy.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * r.policy.Jitter * float64(d))
+	}
+	return d
+}
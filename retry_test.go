@@ -0,0 +1,127 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// sqlStateError is a driver error exposing its SQLSTATE, like the errors of pgx and lib/pq
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "SQLSTATE " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestRetryPolicy(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, replica1Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, replica2Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica1, replica2),
+		WithRetryPolicy(RetryPolicy{Backoff: time.Millisecond, Jitter: -1}))
+
+	// the conflicts are retried until the attempts are exhausted
+	primaryMock.ExpectExec("UPDATE users").WillReturnError(sqlStateError("40001"))
+	primaryMock.ExpectExec("UPDATE users").WillReturnError(sqlStateError("40P01"))
+	primaryMock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.Exec("UPDATE users"); err != nil {
+		t.Errorf("want the update retried, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		primaryMock.ExpectExec("UPDATE users").WillReturnError(sqlStateError("40001"))
+	}
+	if _, err := resolver.Exec("UPDATE users"); !errors.Is(err, sqlStateError("40001")) {
+		t.Errorf("want the error of the last attempt, got %v", err)
+	}
+
+	// the connection errors of the writes aren't retried
+	primaryMock.ExpectExec("UPDATE users").WillReturnError(&net.OpError{Op: "read", Err: errors.New("reset")})
+	if _, err := resolver.Exec("UPDATE users"); err == nil {
+		t.Error("want the connection error of the write")
+	}
+
+	// each attempt of a read is routed again, the round robin resolves the second replica first
+	replica2Mock.ExpectQuery("SELECT name FROM users").WillReturnError(sqlStateError("40001"))
+	replica1Mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo"))
+	var name string
+	if err := resolver.QueryRow("SELECT name FROM users").Scan(&name); err != nil || name != "foo" {
+		t.Errorf("want the read retried on the other replica, got %q %v", name, err)
+	}
+
+	// the other errors aren't retried
+	replica2Mock.ExpectQuery("SELECT name FROM users").WillReturnError(errors.New("syntax error"))
+	if _, err := resolver.Query("SELECT name FROM users"); err == nil {
+		t.Error("want the error of the query")
+	}
+
+	// the prepared statements are retried
+	primaryMock.ExpectPrepare("DELETE FROM users")
+	replica1Mock.ExpectPrepare("DELETE FROM users")
+	replica2Mock.ExpectPrepare("DELETE FROM users")
+	stmt, err := resolver.Prepare("DELETE FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnError(sqlStateError("40001"))
+	primaryMock.ExpectExec("DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := stmt.Exec(); err != nil {
+		t.Errorf("want the statement retried, got %v", err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replica1Mock, replica2Mock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	r := newRetrier(RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond, Jitter: -1}, systemClock{}, DefaultClassifier)
+	var backoffs []time.Duration
+	for attempt := 1; attempt <= 4; attempt++ {
+		backoffs = append(backoffs, r.backoff(attempt))
+	}
+	if fmt.Sprint(backoffs) != "[10ms 20ms 40ms 50ms]" {
+		t.Errorf("want the backoff doubled up to the maximum, got %v", backoffs)
+	}
+
+	r = newRetrier(RetryPolicy{Backoff: 10 * time.Millisecond}, systemClock{}, DefaultClassifier)
+	for i := 0; i < 100; i++ {
+		if d := r.backoff(1); d < 5*time.Millisecond || d > 10*time.Millisecond {
+			t.Fatalf("want the backoff jittered by half at most, got %v", d)
+		}
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{sqlStateError("40001"), true},
+		{fmt.Errorf("update: %w", sqlStateError("40P01")), true},
+		{sqlStateError("23505"), false},
+		{driver.ErrBadConn, true},
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, true},
+		{context.DeadlineExceeded, false},
+		{errors.New("syntax error"), false},
+	} {
+		if got := IsTransientError(tt.err); got != tt.want {
+			t.Errorf("IsTransientError(%v): want %v, got %v", tt.err, tt.want, got)
+		}
+	}
+}
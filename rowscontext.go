@@ -0,0 +1,87 @@
+package dbresolver
+
+import (
+	"context"
+	"sync"
+)
+
+// rowsContext is the context of a query holding resources until its rows are closed, eg. the slot of the node
+// or the context of its timeout, without wrapping the rows of the driver.
+// database/sql derives the context of the rows with context.WithCancel, which registers on the AfterFunc method
+// of the context, and cancels it when the rows are closed, which stops the registration. The resources are
+// released once the query returned and every registration is stopped, or when the context is done.
+type rowsContext struct {
+	context.Context
+	// done is closed when the parent context is done. It isn't the channel of the parent:
+	// context.WithCancel only calls AfterFunc on a context that isn't derived from a cancelCtx.
+	done     chan struct{}
+	stopDone func() bool
+
+	mu       sync.Mutex
+	pending  int
+	returned bool
+	release  func()
+}
+
+// withRowsRelease returns the context to pass as is to QueryContext or QueryRowContext, and the function
+// to call once it returned. The release function is called once, when the rows of the query are closed,
+// when the query returned without rows, eg. on error, or when the context is done.
+func withRowsRelease(ctx context.Context, release func()) (context.Context, func()) {
+	c := &rowsContext{Context: ctx, done: make(chan struct{}), release: release}
+	c.stopDone = context.AfterFunc(ctx, func() {
+		close(c.done)
+		if release := c.take(false); release != nil {
+			release()
+		}
+	})
+	return c, func() {
+		c.mu.Lock()
+		c.returned = true
+		c.mu.Unlock()
+		c.releaseIfUnused()
+	}
+}
+
+func (c *rowsContext) Done() <-chan struct{} {
+	return c.done
+}
+
+// AfterFunc registers f on the parent context, see context.AfterFunc.
+// Stopping the last registration once the query returned releases the resources.
+func (c *rowsContext) AfterFunc(f func()) (stop func() bool) {
+	c.mu.Lock()
+	c.pending++
+	c.mu.Unlock()
+	stopParent := context.AfterFunc(c.Context, f)
+	var once sync.Once
+	return func() bool {
+		stopped := stopParent()
+		once.Do(func() {
+			c.mu.Lock()
+			c.pending--
+			c.mu.Unlock()
+			c.releaseIfUnused()
+		})
+		return stopped
+	}
+}
+
+// releaseIfUnused releases the resources once the query returned and every registration is stopped
+func (c *rowsContext) releaseIfUnused() {
+	if release := c.take(true); release != nil {
+		c.stopDone()
+		release()
+	}
+}
+
+// take returns the release function once, nil when it was taken or while the rows are open when unused is set
+func (c *rowsContext) take(unused bool) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if unused && (!c.returned || c.pending > 0) {
+		return nil
+	}
+	release := c.release
+	c.release = nil
+	return release
+}
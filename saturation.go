@@ -0,0 +1,149 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Defaults of the saturation advisory
+const (
+	defaultSaturationInterval   = time.Second
+	defaultSaturationSustain    = 30 * time.Second
+	defaultSaturationInUseRatio = 0.9
+)
+
+// SaturationAdvisory is emitted when a node stays saturated for the sustained period
+type SaturationAdvisory struct {
+	Node NodeInfo
+	// Stats are the last stats of the node
+	Stats sql.DBStats
+	// Waits is the number of connection waits during the last interval
+	Waits int64
+	// Since is when the node became saturated
+	Since time.Time
+}
+
+// Saturation define when a node is saturated, a node is saturated during an interval
+// when the queries waited for a connection more than WaitCount times,
+// or when the ratio of the connections in use reached InUseRatio
+type Saturation struct {
+	// Interval between two samples of the stats of the nodes, 1 second by default
+	Interval time.Duration
+	// Sustain is how long a node stays saturated before the advisory, 30 seconds by default
+	Sustain time.Duration
+	// WaitCount is the number of connection waits per interval above which the node is saturated
+	WaitCount int64
+	// InUseRatio is the ratio of MaxOpenConnections in use from which the node is saturated, 0.9 by default.
+	// It's ignored for the nodes without connection limit.
+	InUseRatio float64
+	// OnSaturation is called once per saturation of a node, from the sampling goroutine
+	OnSaturation func(advisory SaturationAdvisory)
+}
+
+// WithSaturationAdvisory samples the stats of every node in the background, and calls OnSaturation
+// when a node stays saturated for the sustained period, so autoscaling or capacity alerts can react
+// before the queries start failing. The sampling stops when the resolver is closed.
+func WithSaturationAdvisory(config Saturation) OptionFunc {
+	return func(opt *Option) {
+		opt.Saturation = &config
+	}
+}
+
+type saturationState struct {
+	waitCount int64
+	since     time.Time
+	notified  bool
+}
+
+// saturationMonitor samples the stats of the nodes of the resolver
+type saturationMonitor struct {
+	db     *sqlDB
+	config Saturation
+	states map[*sql.DB]*saturationState
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newSaturationMonitor(db *sqlDB, config Saturation) *saturationMonitor {
+	if config.Interval <= 0 {
+		config.Interval = defaultSaturationInterval
+	}
+	if config.Sustain <= 0 {
+		config.Sustain = defaultSaturationSustain
+	}
+	if config.InUseRatio <= 0 {
+		config.InUseRatio = defaultSaturationInUseRatio
+	}
+	return &saturationMonitor{
+		db:     db,
+		config: config,
+		states: map[*sql.DB]*saturationState{},
+		done:   make(chan struct{}),
+	}
+}
+
+// start samples the stats in the background until close
+func (m *saturationMonitor) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	go func() {
+		defer close(m.done)
+		for {
+			timer := m.db.clock.NewTimer(m.config.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case now := <-timer.C():
+				m.sample(now)
+			}
+		}
+	}()
+}
+
+func (m *saturationMonitor) close() {
+	m.cancel()
+	<-m.done
+}
+
+// sample checks the stats of every node, the first sample of a node is the baseline of its waits
+func (m *saturationMonitor) sample(now time.Time) {
+	nodes := m.db.Nodes()
+	current := make(map[*sql.DB]struct{}, len(nodes))
+	for _, node := range nodes {
+		current[node.DB] = struct{}{}
+		stats := node.DB.Stats()
+		state, ok := m.states[node.DB]
+		if !ok {
+			state = &saturationState{waitCount: stats.WaitCount}
+			m.states[node.DB] = state
+		}
+		waits := stats.WaitCount - state.waitCount
+		state.waitCount = stats.WaitCount
+
+		saturated := waits > m.config.WaitCount ||
+			(stats.MaxOpenConnections > 0 &&
+				float64(stats.InUse) >= m.config.InUseRatio*float64(stats.MaxOpenConnections))
+		if !saturated {
+			state.since, state.notified = time.Time{}, false
+			continue
+		}
+		if state.since.IsZero() {
+			state.since = now
+		}
+		if !state.notified && now.Sub(state.since) >= m.config.Sustain {
+			state.notified = true
+			if m.config.OnSaturation != nil {
+				m.config.OnSaturation(SaturationAdvisory{Node: node, Stats: stats, Waits: waits, Since: state.since})
+			}
+		}
+	}
+	for db := range m.states {
+		if _, ok := current[db]; !ok {
+			delete(m.states, db)
+		}
+	}
+}
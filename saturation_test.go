@@ -0,0 +1,77 @@
+package dbresolver
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSaturationAdvisory(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica.SetMaxOpenConns(1)
+
+	var advisories []SaturationAdvisory
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica)).(*sqlDB)
+	monitor := newSaturationMonitor(resolver, Saturation{
+		Sustain: 10 * time.Second,
+		OnSaturation: func(advisory SaturationAdvisory) {
+			advisories = append(advisories, advisory)
+		},
+	})
+
+	// the only connection of the replica is in use
+	conn, err := replica.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for _, elapsed := range []time.Duration{0, 5 * time.Second, 10 * time.Second, 15 * time.Second} {
+		monitor.sample(start.Add(elapsed))
+	}
+	if len(advisories) != 1 {
+		t.Fatalf("want 1 advisory, got %+v", advisories)
+	}
+	if advisories[0].Node.DB != replica || advisories[0].Stats.InUse != 1 || !advisories[0].Since.Equal(start) {
+		t.Errorf("want the advisory of the replica, got %+v", advisories[0])
+	}
+
+	// the saturation is over, a new one is advised again
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	monitor.sample(start.Add(20 * time.Second))
+	if _, err := replica.Conn(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	monitor.sample(start.Add(25 * time.Second))
+	monitor.sample(start.Add(35 * time.Second))
+	if len(advisories) != 2 || !advisories[1].Since.Equal(start.Add(25*time.Second)) {
+		t.Errorf("want a second advisory, got %+v", advisories)
+	}
+}
+
+func TestSaturationAdvisoryStoppedOnClose(t *testing.T) {
+	primary, mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	mock.ExpectClose()
+
+	resolver := New(WithPrimaryDBs(primary), WithSaturationAdvisory(Saturation{Interval: time.Millisecond})).(*sqlDB)
+	time.Sleep(5 * time.Millisecond)
+	if err := resolver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-resolver.saturation.done:
+	default:
+		t.Error("want the sampling stopped")
+	}
+}
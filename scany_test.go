@@ -0,0 +1,85 @@
+package dbresolver_test
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+	"github.com/georgysavva/scany/v2/sqlscan"
+)
+
+// DB, Tx and Conn are accepted by sqlscan
+var (
+	_ sqlscan.Querier = dbresolver.DB(nil)
+	_ sqlscan.Querier = dbresolver.Tx(nil)
+	_ sqlscan.Querier = dbresolver.Conn(nil)
+)
+
+type user struct {
+	ID   int
+	Name string
+}
+
+func TestSqlscan(t *testing.T) {
+	db := dbresolvertest.NewForTest(t, 1, 1)
+
+	db.Replica.ExpectQuery("SELECT id, name FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Hiro").AddRow(2, "Yuki"))
+	var users []user
+	if err := sqlscan.Select(context.Background(), db, &users, `SELECT id, name FROM users`); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[1].Name != "Yuki" {
+		t.Errorf("unexpected users %+v", users)
+	}
+
+	db.Primary.ExpectBegin()
+	db.Primary.ExpectQuery("SELECT id, name FROM users WHERE id = $1 FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Hiro"))
+	db.Primary.ExpectCommit()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var u user
+	if err := sqlscan.Get(context.Background(), tx, &u, `SELECT id, name FROM users WHERE id = $1 FOR UPDATE`, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "Hiro" {
+		t.Errorf("unexpected user %+v", u)
+	}
+}
+
+func ExampleDB_sqlscan() {
+	dbPrimary, err := sql.Open("postgres", "host=localhost port=5432 user=postgresrw dbname=<dbname> sslmode=disable")
+	if err != nil {
+		log.Print("go error when connecting to the DB")
+	}
+	dbReadOnlyReplica, err := sql.Open("postgres", "host=localhost port=5433 user=postgresro dbname=<dbname> sslmode=disable")
+	if err != nil {
+		log.Print("go error when connecting to the DB")
+	}
+	connectionDB := dbresolver.New(
+		dbresolver.WithPrimaryDBs(dbPrimary),
+		dbresolver.WithReplicaDBs(dbReadOnlyReplica))
+
+	type book struct {
+		ID    int
+		Title string
+	}
+
+	// will use replicaReadOnlyDB
+	var books []book
+	err = sqlscan.Select(context.Background(), connectionDB, &books, `SELECT id, title FROM book`)
+	if err != nil {
+		log.Print("go error when executing the query to the DB", err)
+	}
+}
@@ -0,0 +1,104 @@
+package dbresolver
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Session tracks the last write of a user session, so its reads can see its writes despite the replication lag,
+// see WithStickyPrimary. The zero value is a session without write, it's safe for concurrent use.
+type Session struct {
+	// lastWrite is the time of the last write in Unix nanoseconds, zero without write
+	lastWrite atomic.Int64
+}
+
+// LastWrite returns the time of the last write of the session, the zero time without write.
+// It can be stored in a cookie to restore the session on the next request with SetLastWrite.
+func (s *Session) LastWrite() time.Time {
+	nanos := s.lastWrite.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// SetLastWrite sets the time of the last write of the session, eg. restored from a cookie
+func (s *Session) SetLastWrite(t time.Time) {
+	if t.IsZero() {
+		s.lastWrite.Store(0)
+		return
+	}
+	s.lastWrite.Store(t.UnixNano())
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context whose queries belong to the session, see WithStickyPrimary
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session set by WithSession
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(*Session)
+	return session, ok && session != nil
+}
+
+// WithStickyPrimary routes the reads of a session, see WithSession, to a primary during the window
+// following its last write, so the session reads its own writes instead of a replica not replicating them yet.
+// The reads of the other sessions and of the contexts without session still go to the replicas.
+// The writes are the ExecContext and the write queries of the resolver on a primary,
+// and the transactions of BeginTx once committed, except the read-only ones.
+// The queries of Conn and of the prepared statements are neither tracked nor routed by the session.
+// The window should exceed the replication lag of the replicas, see WithLagMonitor.
+func WithStickyPrimary(window time.Duration) OptionFunc {
+	if window <= 0 {
+		panic(fmt.Sprintf("dbresolver: invalid sticky primary window %v", window))
+	}
+	return func(opt *Option) {
+		opt.StickyPrimary = window
+	}
+}
+
+// sticksToPrimary reports whether the reads of the context go to a primary,
+// the session of the context wrote within the sticky window
+func (db *sqlDB) sticksToPrimary(ctx context.Context) bool {
+	if db.stickyWindow == 0 {
+		return false
+	}
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return false
+	}
+	nanos := session.lastWrite.Load()
+	return nanos != 0 && db.clock.Now().Sub(time.Unix(0, nanos)) < db.stickyWindow
+}
+
+// recordWrite records the write of the session of the context, if any
+func (db *sqlDB) recordWrite(ctx context.Context) {
+	if db.stickyWindow == 0 {
+		return
+	}
+	if session, ok := SessionFromContext(ctx); ok {
+		session.SetLastWrite(db.clock.Now())
+	}
+}
+
+// recordWriteQuery records the write of the session when the query type checker classifies
+// the query as a write, the reads sent to a primary by the context or the session aren't writes
+func (db *sqlDB) recordWriteQuery(ctx context.Context, query string) {
+	if db.stickyWindow == 0 {
+		return
+	}
+	if _, ok := SessionFromContext(ctx); !ok {
+		return
+	}
+	if _, ok := RoleOverrideFromContext(ctx); ok {
+		return
+	}
+	if db.queryTypeChecker.Check(query) == QueryTypeWrite {
+		db.recordWrite(ctx)
+	}
+}
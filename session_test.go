@@ -0,0 +1,97 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStickyPrimary(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	now := time.Now()
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithStickyPrimary(time.Second),
+		WithClock(stubClock{now: &now}))
+
+	session := &Session{}
+	ctx := WithSession(context.Background(), session)
+	readOn := func(ctx context.Context, mock sqlmock.Sqlmock) {
+		t.Helper()
+		mock.ExpectQuery("SELECT name FROM users").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("foo"))
+		var name string
+		if err := resolver.QueryRowContext(ctx, "SELECT name FROM users").Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the reads go to the replica before the first write
+	readOn(ctx, replicaMock)
+
+	// the reads of the session go to the primary within the window after a write
+	primaryMock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+	if _, err := resolver.ExecContext(ctx, "INSERT INTO users"); err != nil {
+		t.Fatal(err)
+	}
+	if !session.LastWrite().Equal(now) {
+		t.Errorf("want the write recorded at %v, got %v", now, session.LastWrite())
+	}
+	readOn(ctx, primaryMock)
+	readOn(context.Background(), replicaMock)
+	readOn(WithSession(context.Background(), &Session{}), replicaMock)
+
+	// the sticky reads don't extend the window
+	now = now.Add(time.Second)
+	readOn(ctx, replicaMock)
+
+	// a committed transaction is a write
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectCommit()
+	tx, err := resolver.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	readOn(ctx, primaryMock)
+
+	// a read-only transaction isn't, it runs on the replica once the window is over
+	now = now.Add(time.Second)
+	replicaMock.ExpectBegin()
+	replicaMock.ExpectCommit()
+	tx, err = resolver.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	readOn(ctx, replicaMock)
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestSessionLastWrite(t *testing.T) {
+	var session Session
+	if !session.LastWrite().IsZero() {
+		t.Error("want no write")
+	}
+	lastWrite := time.Unix(1700000000, 42)
+	session.SetLastWrite(lastWrite)
+	if !session.LastWrite().Equal(lastWrite) {
+		t.Errorf("want the restored last write %v, got %v", lastWrite, session.LastWrite())
+	}
+}
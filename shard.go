@@ -0,0 +1,168 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sort"
+)
+
+// ErrUnknownShard is returned when the shard resolver maps a key to a shard which isn't configured
+var ErrUnknownShard = errors.New("dbresolver: unknown shard")
+
+// ShardLabel is the label of the shard of the nodes returned by DB.Nodes
+const ShardLabel = "shard"
+
+// ShardConfig define the nodes of a shard, its own primaries and replicas
+type ShardConfig struct {
+	Primaries []*sql.DB
+	Replicas  []*sql.DB
+	// Options configure the resolver of the shard on top of the options of the resolver, eg. WithReplicaWeights
+	Options []OptionFunc
+}
+
+// ShardResolver maps the shard keys to the shards
+type ShardResolver interface {
+	// Resolve returns the name of the shard of the key, one of the sorted shard names
+	Resolve(key string, shards []string) (string, error)
+}
+
+// ShardResolverFunc is a function implementing ShardResolver
+type ShardResolverFunc func(key string, shards []string) (string, error)
+
+// Resolve calls the function
+func (f ShardResolverFunc) Resolve(key string, shards []string) (string, error) {
+	return f(key, shards)
+}
+
+// HashShardResolver maps the keys to the shards with rendezvous hashing: the shard of a key is the shard
+// with the highest hash of its name and the key. Adding a shard only moves the keys mapped to the new shard.
+// It's the default shard resolver.
+var HashShardResolver ShardResolver = ShardResolverFunc(func(key string, shards []string) (string, error) {
+	var shard string
+	var highest uint64
+	for _, name := range shards {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(name))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(key))
+		if sum := mix64(h.Sum64()); shard == "" || sum > highest {
+			shard, highest = name, sum
+		}
+	}
+	if shard == "" {
+		return "", fmt.Errorf("%w for the key %q, no shard is configured", ErrUnknownShard, key)
+	}
+	return shard, nil
+})
+
+// mix64 is the finalizer of MurmurHash3, the last bytes of the key only change the low bits of FNV
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// WithShards adds the shards to the resolver, each shard being its own set of primaries and replicas.
+// The queries with a shard key, see WithShardKey, are routed to the nodes of the shard of the key,
+// the queries without shard key to the primaries and replicas of the resolver, eg. the unsharded tables.
+// Each shard has its own resolver, configured with the options of the resolver but the nodes
+// and their discovery, then with the options of the shard.
+// It can be used multiple times, the shards are merged.
+func WithShards(shards map[string]ShardConfig) OptionFunc {
+	return func(opt *Option) {
+		if opt.Shards == nil {
+			opt.Shards = make(map[string]ShardConfig, len(shards))
+		}
+		for name, shard := range shards {
+			if len(shard.Primaries) == 0 {
+				panic(fmt.Sprintf("dbresolver: the shard %q has no primary", name))
+			}
+			opt.Shards[name] = shard
+		}
+	}
+}
+
+// WithShardResolver sets how the shard keys are mapped to the shards, HashShardResolver by default
+func WithShardResolver(resolver ShardResolver) OptionFunc {
+	return func(opt *Option) {
+		opt.ShardResolver = resolver
+	}
+}
+
+type shardKeyKey struct{}
+
+// WithShardKey returns a context routing the queries of the sharded resolver to the shard of the key,
+// eg. the tenant or the user ID. The queries of a resolver without shards ignore the key.
+func WithShardKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, shardKeyKey{}, key)
+}
+
+// ShardKeyFromContext returns the shard key of the context, set by WithShardKey
+func ShardKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(shardKeyKey{}).(string)
+	return key, ok
+}
+
+// newShards creates the resolvers of the shards, with the options of the resolver but its nodes, its discoveries
+// and its saturation monitor
+func newShards(opt *Option) (map[string]*sqlDB, []string) {
+	shards := make(map[string]*sqlDB, len(opt.Shards))
+	names := make([]string, 0, len(opt.Shards))
+	for name, config := range opt.Shards {
+		shardOpt := *opt
+		shardOpt.PrimaryDBs, shardOpt.ReplicaDBs = config.Primaries, config.Replicas
+		shardOpt.Discoveries, shardOpt.DNSDiscovery = nil, nil
+		shardOpt.Shards, shardOpt.ShardResolver = nil, nil
+		// the saturation monitor of the resolver samples the nodes of the shards, see Nodes
+		shardOpt.Saturation = nil
+		// the options of the shard must not modify the options of the resolver
+		shardOpt.Hooks = shardOpt.Hooks[:len(shardOpt.Hooks):len(shardOpt.Hooks)]
+		shardOpt.QueryHooks = shardOpt.QueryHooks[:len(shardOpt.QueryHooks):len(shardOpt.QueryHooks)]
+		shardOpt.NodeLabels, shardOpt.ReplicaWeights = maps.Clone(opt.NodeLabels), maps.Clone(opt.ReplicaWeights)
+		shardOpt.PrimaryWeights = maps.Clone(opt.PrimaryWeights)
+		for _, optFunc := range config.Options {
+			optFunc(&shardOpt)
+		}
+		shards[name] = newSQLDB(&shardOpt)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return shards, names
+}
+
+// shard returns the resolver of the shard of the context key, the resolver itself without shards or shard key
+func (db *sqlDB) shard(ctx context.Context) (*sqlDB, error) {
+	shard, _, err := db.namedShard(ctx)
+	return shard, err
+}
+
+// namedShard returns the resolver of the shard of the context key and the name of the shard,
+// the resolver itself and an empty name without shards or shard key
+func (db *sqlDB) namedShard(ctx context.Context) (*sqlDB, string, error) {
+	if db.shutdown.Load() {
+		return nil, "", ErrShutdown
+	}
+	if db.shards == nil {
+		return db, "", nil
+	}
+	key, ok := ShardKeyFromContext(ctx)
+	if !ok {
+		return db, "", nil
+	}
+	name, err := db.shardResolver.Resolve(key, db.shardNames)
+	if err != nil {
+		return nil, "", err
+	}
+	shard, ok := db.shards[name]
+	if !ok {
+		return nil, "", fmt.Errorf("%w %q for the key %q", ErrUnknownShard, name, key)
+	}
+	return shard, name, nil
+}
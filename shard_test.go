@@ -0,0 +1,200 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// shardByKey maps the keys to the shards of the same name
+var shardByKey = ShardResolverFunc(func(key string, _ []string) (string, error) {
+	return key, nil
+})
+
+func TestShards(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shardPrimaries := make(map[string]sqlmock.Sqlmock)
+	shards := make(map[string]ShardConfig)
+	var shardReplica *sql.DB
+	var shardReplicaMock sqlmock.Sqlmock
+	for _, name := range []string{"eu", "us"} {
+		shardPrimary, mock, err := createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+		shardPrimaries[name] = mock
+		shards[name] = ShardConfig{Primaries: []*sql.DB{shardPrimary}}
+	}
+	if shardReplica, shardReplicaMock, err = createMock(); err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shards["eu"] = ShardConfig{Primaries: shards["eu"].Primaries, Replicas: []*sql.DB{shardReplica}}
+	resolver := New(WithPrimaryDBs(primary), WithShards(shards), WithShardResolver(shardByKey))
+
+	eu, us := WithShardKey(context.Background(), "eu"), WithShardKey(context.Background(), "us")
+	shardPrimaries["eu"].ExpectExec("UPDATE book SET title = 'Dune'").WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.ExecContext(eu, "UPDATE book SET title = 'Dune'"); err != nil {
+		t.Error(err)
+	}
+	shardReplicaMock.ExpectQuery("SELECT title FROM book").WillReturnRows(sqlmock.NewRows([]string{"title"}).AddRow("Dune"))
+	var title string
+	if err := resolver.QueryRowContext(eu, "SELECT title FROM book").Scan(&title); err != nil || title != "Dune" {
+		t.Errorf("want the read on the replica of the shard, got %q, %v", title, err)
+	}
+	shardPrimaries["us"].ExpectBegin()
+	shardPrimaries["us"].ExpectCommit()
+	tx, err := resolver.BeginTx(us, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Error(err)
+	}
+
+	// the queries without shard key run on the nodes of the resolver
+	primaryMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	rows, err := resolver.QueryContext(context.Background(), "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = rows.Close()
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, shardPrimaries["eu"], shardPrimaries["us"], shardReplicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestShardsUnknownShard(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shardPrimary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithShardResolver(shardByKey),
+		WithShards(map[string]ShardConfig{"eu": {Primaries: []*sql.DB{shardPrimary}}}))
+
+	ctx := WithShardKey(context.Background(), "apac")
+	if _, err := resolver.ExecContext(ctx, "DELETE FROM book"); !errors.Is(err, ErrUnknownShard) {
+		t.Errorf("want ErrUnknownShard, got %v", err)
+	}
+	if err := resolver.QueryRowContext(ctx, "SELECT 1").Scan(new(int)); !errors.Is(err, ErrUnknownShard) {
+		t.Errorf("want ErrUnknownShard from the row, got %v", err)
+	}
+	if _, err := resolver.PrepareContext(ctx, "SELECT 1"); !errors.Is(err, ErrUnknownShard) {
+		t.Errorf("want ErrUnknownShard, got %v", err)
+	}
+}
+
+func TestShardsNodesAndClose(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shardPrimary, shardMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithShards(map[string]ShardConfig{"eu": {
+		Primaries: []*sql.DB{shardPrimary},
+		Options:   []OptionFunc{WithNodeName(shardPrimary, "eu-primary")},
+	}}))
+
+	nodes := resolver.Nodes()
+	if len(nodes) != 2 || nodes[0].DB != primary || nodes[1].DB != shardPrimary ||
+		nodes[1].Labels[ShardLabel] != "eu" || nodes[1].Name() != "eu-primary" {
+		t.Errorf("want the nodes of the shard labeled with the shard, got %+v", nodes)
+	}
+
+	primaryMock.ExpectClose()
+	shardMock.ExpectClose()
+	if err := resolver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, shardMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestHashShardResolver(t *testing.T) {
+	shards := []string{"s0", "s1", "s2"}
+	counts := make(map[string]int)
+	mapped := make(map[string]string)
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprint(i)
+		shard, err := HashShardResolver.Resolve(key, shards)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[shard]++
+		mapped[key] = shard
+	}
+	for _, shard := range shards {
+		if counts[shard] < 800 || counts[shard] > 1200 {
+			t.Errorf("want the keys spread evenly, got %v", counts)
+		}
+	}
+
+	// adding a shard only moves the keys to the new shard
+	for key, shard := range mapped {
+		got, err := HashShardResolver.Resolve(key, append(shards, "s3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != shard && got != "s3" {
+			t.Fatalf("the key %s moved from %s to %s", key, shard, got)
+		}
+	}
+
+	if _, err := HashShardResolver.Resolve("1", nil); !errors.Is(err, ErrUnknownShard) {
+		t.Errorf("want ErrUnknownShard without shard, got %v", err)
+	}
+}
+
+func TestShardsOptionsIsolated(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shardPrimary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithNodeName(primary, "primary"), WithShards(map[string]ShardConfig{"eu": {
+		Primaries: []*sql.DB{shardPrimary},
+		Options:   []OptionFunc{WithNodeName(primary, "renamed")},
+	}}))
+	if name := resolver.Nodes()[0].Name(); name != "primary" {
+		t.Errorf("want the options of the shard isolated, the primary is named %q", name)
+	}
+}
+
+func TestShardsSaturationSampledOnce(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shardPrimary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithSaturationAdvisory(Saturation{}),
+		WithShards(map[string]ShardConfig{"eu": {Primaries: []*sql.DB{shardPrimary}}})).(*sqlDB)
+	defer resolver.stop()
+	if resolver.saturation == nil || resolver.shards["eu"].saturation != nil {
+		t.Error("want the nodes of the shards sampled by the saturation monitor of the resolver only")
+	}
+}
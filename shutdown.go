@@ -0,0 +1,44 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrShutdown is returned by the queries started after DB.Shutdown
+var ErrShutdown = errors.New("dbresolver: the resolver is shut down")
+
+// shutdownPollInterval is the interval between two checks of the connections in use during a shutdown
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown stops routing the new queries, transactions and connections, they fail with ErrShutdown,
+// then waits for the connections in use to be released, ie. the running queries, the unclosed rows,
+// the transactions and the connections to be done, and closes the resolver like Close.
+// The transactions, connections and statements already open keep running until then.
+// When the context is done first, the resolver is closed anyway and the context error is returned.
+func (db *sqlDB) Shutdown(ctx context.Context) error {
+	db.shutdown.Store(true)
+	return errors.Join(db.drain(ctx), db.Close())
+}
+
+// drain waits until no connection of the nodes is in use, or the context is done
+func (db *sqlDB) drain(ctx context.Context) error {
+	for {
+		inUse := 0
+		for _, node := range db.Nodes() {
+			inUse += node.DB.Stats().InUse
+		}
+		if inUse == 0 {
+			return nil
+		}
+		timer := db.clock.NewTimer(shutdownPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("dbresolver: shutdown with %d connections in use: %w", inUse, ctx.Err())
+		case <-timer.C():
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestShutdown(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica)).(*sqlDB)
+
+	// the rows are in flight until closed
+	replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	rows, err := resolver.Query("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	primaryMock.ExpectClose()
+	replicaMock.ExpectClose()
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- resolver.Shutdown(context.Background())
+	}()
+	for !resolver.shutdown.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the new queries aren't routed anymore
+	if _, err := resolver.Exec("DELETE FROM users"); !errors.Is(err, ErrShutdown) {
+		t.Errorf("want ErrShutdown, got %v", err)
+	}
+	var one int
+	if err := resolver.QueryRow("SELECT 1").Scan(&one); !errors.Is(err, ErrShutdown) {
+		t.Errorf("want ErrShutdown, got %v", err)
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("want the shutdown waiting for the rows, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	rows.Close()
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary))
+
+	primaryMock.ExpectBegin()
+	if _, err := resolver.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := resolver.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want the deadline error with the transaction in flight, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package dbresolver
+
+import (
+	"context"
+	"time"
+)
+
+// SlowQueryLog define how the slow queries are logged
+type SlowQueryLog struct {
+	// Threshold is the duration above which a query is logged, zero disables the log
+	Threshold time.Duration
+	// Logger logs the slow queries at the warn level, the logger of the resolver when nil, see WithLogger,
+	// and slog.Default() without one
+	Logger Logger
+}
+
+// WithSlowQueryLog logs the queries slower than the threshold with their role and duration, not their args.
+// A zero threshold disables the log, eg. to disable the one of a profile.
+func WithSlowQueryLog(threshold time.Duration, logger Logger) OptionFunc {
+	return func(opt *Option) {
+		opt.SlowQueryLog = SlowQueryLog{Threshold: threshold, Logger: logger}
+	}
+}
+
+type slowQueryStartKey struct{}
+
+// slowQueryHooks are the Hooks timing the queries
+type slowQueryHooks struct {
+	SlowQueryLog
+	clock Clock
+}
+
+func newSlowQueryHooks(config SlowQueryLog, logger Logger, clock Clock) *slowQueryHooks {
+	if config.Logger == nil {
+		config.Logger = logger
+	}
+	if config.Logger == nil {
+		config.Logger = NewSlogLogger(nil)
+	}
+	return &slowQueryHooks{SlowQueryLog: config, clock: clock}
+}
+
+func (h *slowQueryHooks) Before(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	return context.WithValue(ctx, slowQueryStartKey{}, h.clock.Now()), nil
+}
+
+func (h *slowQueryHooks) After(ctx context.Context, query string, _ ...interface{}) (context.Context, error) {
+	h.log(ctx, query, nil)
+	return ctx, nil
+}
+
+func (h *slowQueryHooks) OnError(ctx context.Context, err error, query string, _ ...interface{}) error {
+	h.log(ctx, query, err)
+	return err
+}
+
+func (h *slowQueryHooks) log(ctx context.Context, query string, err error) {
+	start, ok := ctx.Value(slowQueryStartKey{}).(time.Time)
+	if !ok {
+		// a previous hook failed before this one
+		return
+	}
+	duration := h.clock.Now().Sub(start)
+	if duration < h.Threshold {
+		return
+	}
+
+	route, _ := RouteFromContext(ctx)
+	fields := []LogField{
+		{LogFieldQuery, query},
+		{LogFieldLatency, duration},
+		{LogFieldRole, string(route.Role)},
+		{LogFieldFallback, route.Fallback},
+	}
+	if err != nil {
+		fields = append(fields, LogField{LogFieldError, err.Error()})
+	}
+	h.Logger.Log(ctx, LogLevelWarn, "dbresolver: slow query", fields...)
+}
@@ -0,0 +1,85 @@
+// Package sqlitedev opens a resolver over in-memory SQLite databases, for the local development and the CI,
+// exercising the full routing code path without running a database server.
+//
+// The primaries and the replicas share the same in-memory database, so the writes are immediately
+// visible from the replicas. The replicas are read-only like real ones: a write routed to a replica fails.
+package sqlitedev
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/bxcodec/dbresolver/v2"
+	_ "modernc.org/sqlite" // the sqlite driver
+)
+
+// DriverName is the name of the SQLite driver
+const DriverName = "sqlite"
+
+var dbCounter atomic.Uint64
+
+type sqliteDB struct {
+	dbresolver.DB
+	// keepAlive keeps the in-memory database, freed when its last connection is closed
+	keepAlive *sql.Conn
+}
+
+// New opens a resolver of primaries and replicas over a new in-memory SQLite database
+func New(primaries, replicas int, opts ...dbresolver.OptionFunc) (_ dbresolver.DB, err error) {
+	if primaries < 1 {
+		return nil, fmt.Errorf("sqlitedev: at least one primary is required")
+	}
+
+	// the memdb VFS shares the database between the connections, with the regular locking
+	name := fmt.Sprintf("file:/dbresolver-%d?vfs=memdb&_pragma=busy_timeout(5000)", dbCounter.Add(1))
+	var opened []*sql.DB
+	defer func() {
+		if err != nil {
+			for _, db := range opened {
+				err = errors.Join(err, db.Close())
+			}
+		}
+	}()
+	open := func(dsn string, n int) ([]*sql.DB, error) {
+		dbs := make([]*sql.DB, 0, n)
+		for i := 0; i < n; i++ {
+			db, err := sql.Open(DriverName, dsn)
+			if err != nil {
+				return nil, err
+			}
+			opened = append(opened, db)
+			dbs = append(dbs, db)
+		}
+		return dbs, nil
+	}
+
+	primaryDBs, err := open(name, primaries)
+	if err != nil {
+		return nil, err
+	}
+	replicaDBs, err := open(name+"&_pragma=query_only(1)", replicas)
+	if err != nil {
+		return nil, err
+	}
+	keepAlive, err := primaryDBs[0].Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]dbresolver.OptionFunc{
+		dbresolver.WithPrimaryDBs(primaryDBs...),
+		dbresolver.WithReplicaDBs(replicaDBs...),
+	}, opts...)
+	return &sqliteDB{
+		DB:        dbresolver.New(opts...),
+		keepAlive: keepAlive,
+	}, nil
+}
+
+// Close closes the resolver, freeing the in-memory database
+func (db *sqliteDB) Close() error {
+	return errors.Join(db.keepAlive.Close(), db.DB.Close())
+}
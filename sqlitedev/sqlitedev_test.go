@@ -0,0 +1,71 @@
+package sqlitedev
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSQLiteDev(t *testing.T) {
+	db, err := New(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "CREATE TABLE book (id INTEGER PRIMARY KEY, title TEXT NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	var id int
+	err = db.QueryRowContext(ctx, "INSERT INTO book (title) VALUES (?) RETURNING id", "Dune").Scan(&id)
+	if err != nil {
+		t.Fatalf("want the RETURNING query routed to the primary: %v", err)
+	}
+
+	// the replicas see the writes
+	for i := 0; i < 2; i++ {
+		var title string
+		if err := db.QueryRowContext(ctx, "SELECT title FROM book WHERE id = ?", id).Scan(&title); err != nil {
+			t.Fatal(err)
+		}
+		if title != "Dune" {
+			t.Errorf("want Dune, got %q", title)
+		}
+	}
+
+	// the replicas are read-only
+	if _, err := db.ReadOnly().ExecContext(ctx, "DELETE FROM book"); err == nil {
+		t.Error("want the write on a replica to fail")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE book SET title = ? WHERE id = ?", "Emma", id); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLiteDevIsolated(t *testing.T) {
+	first, err := New(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := New(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if _, err := first.Exec("CREATE TABLE book (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Exec("CREATE TABLE book (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Errorf("want a new database for every resolver: %v", err)
+	}
+}
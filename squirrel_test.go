@@ -0,0 +1,85 @@
+package dbresolver_test
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	sq "github.com/Masterminds/squirrel"
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/dbresolvertest"
+)
+
+// DB, Tx and Conn are accepted by squirrel's RunWith
+var (
+	_ sq.BaseRunner     = dbresolver.DB(nil)
+	_ sq.ExecerContext  = dbresolver.DB(nil)
+	_ sq.QueryerContext = dbresolver.DB(nil)
+	_ sq.StdSqlCtx      = dbresolver.DB(nil)
+
+	_ sq.BaseRunner     = dbresolver.Tx(nil)
+	_ sq.ExecerContext  = dbresolver.Tx(nil)
+	_ sq.QueryerContext = dbresolver.Tx(nil)
+	_ sq.StdSqlCtx      = dbresolver.Tx(nil)
+
+	_ sq.BaseRunner     = dbresolver.Conn(nil)
+	_ sq.ExecerContext  = dbresolver.Conn(nil)
+	_ sq.QueryerContext = dbresolver.Conn(nil)
+	_ sq.StdSqlCtx      = dbresolver.Conn(nil)
+)
+
+func TestSquirrelRunWith(t *testing.T) {
+	db := dbresolvertest.NewForTest(t, 1, 1)
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).RunWith(db)
+
+	db.Replica.ExpectQuery("SELECT name FROM users WHERE id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Hiro"))
+	var name string
+	err := psql.Select("name").From("users").Where(sq.Eq{"id": 1}).
+		QueryRowContext(context.Background()).
+		Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Primary.ExpectExec("INSERT INTO users (name) VALUES ($1)").
+		WithArgs("Hiro").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	_, err = psql.Insert("users").Columns("name").Values("Hiro").ExecContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func ExampleDB_squirrel() {
+	dbPrimary, err := sql.Open("postgres", "host=localhost port=5432 user=postgresrw dbname=<dbname> sslmode=disable")
+	if err != nil {
+		log.Print("go error when connecting to the DB")
+	}
+	dbReadOnlyReplica, err := sql.Open("postgres", "host=localhost port=5433 user=postgresro dbname=<dbname> sslmode=disable")
+	if err != nil {
+		log.Print("go error when connecting to the DB")
+	}
+	connectionDB := dbresolver.New(
+		dbresolver.WithPrimaryDBs(dbPrimary),
+		dbresolver.WithReplicaDBs(dbReadOnlyReplica))
+
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar).RunWith(connectionDB)
+
+	// will use replicaReadOnlyDB
+	rows, err := psql.Select("*").From("book").Where(sq.Eq{"id": 1}).Query()
+	if err != nil {
+		log.Print("go error when executing the query to the DB", err)
+		return
+	}
+	defer rows.Close()
+
+	// will use primaryDB
+	_, err = psql.Delete("book").Where(sq.Eq{"id": 1}).Exec()
+	if err != nil {
+		log.Print("go error when executing the query to the DB", err)
+	}
+}
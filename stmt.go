@@ -3,8 +3,8 @@ package dbresolver
 import (
 	"context"
 	"database/sql"
-
-	"go.uber.org/multierr"
+	"errors"
+	"sync/atomic"
 )
 
 // Stmt is an aggregate prepared statement.
@@ -25,19 +25,37 @@ type stmt struct {
 	replicaStmts []*sql.Stmt
 	writeFlag    bool
 	dbStmt       map[*sql.DB]*sql.Stmt
+	query        string
+	hooks        []Hooks
+	parallelism  int
+	// retry retries the queries failing with a transient error, nil without retry policy
+	retry *retrier
+	// classifier finds the connection errors of the replicas, falling back to a primary
+	classifier Classifier
+	// readMode is the read mode of the resolver, nil for the statements of a single db
+	readMode *atomic.Pointer[ReadMode]
+	// pending prepares replicaStmts in the background, see PrepareAsync
+	pending *pendingReplicas
+	// health skips the replica statements of the unhealthy nodes, nil without health checks
+	health *healthChecker
 }
 
 // Close closes the statement by concurrently closing all underlying
 // statements concurrently, returning the first non nil error.
 func (s *stmt) Close() error {
-	errPrimaries := doParallely(len(s.primaryStmts), func(i int) error {
+	ctx := context.Background()
+	replicaStmts := s.replicaStmts
+	if s.pending != nil {
+		replicaStmts = s.pending.wait()
+	}
+	errPrimaries := doParallely(ctx, s.parallelism, len(s.primaryStmts), func(i int) error {
 		return s.primaryStmts[i].Close()
 	})
-	errReplicas := doParallely(len(s.replicaStmts), func(i int) error {
-		return s.replicaStmts[i].Close()
+	errReplicas := doParallely(ctx, s.parallelism, len(replicaStmts), func(i int) error {
+		return replicaStmts[i].Close()
 	})
 
-	return multierr.Combine(errPrimaries, errReplicas)
+	return errors.Join(errPrimaries, errReplicas)
 }
 
 // Exec executes a prepared statement with the given arguments
@@ -50,8 +68,15 @@ func (s *stmt) Exec(args ...interface{}) (sql.Result, error) {
 // ExecContext executes a prepared statement with the given arguments
 // and returns a Result summarizing the effect of the statement.
 // Exec uses the master as the underlying physical db.
-func (s *stmt) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
-	return s.RWStmt().ExecContext(ctx, args...)
+func (s *stmt) ExecContext(ctx context.Context, args ...interface{}) (res sql.Result, err error) {
+	err = s.retry.do(ctx, true, func() error {
+		curStmt := s.RWStmt()
+		res, err = execWithHooks(ctx, s.hooks, primaryRoute, s.query, args, func(ctx context.Context) (sql.Result, error) {
+			return curStmt.ExecContext(ctx, args...)
+		})
+		return err
+	})
+	return res, err
 }
 
 // Query executes a prepared query statement with the given
@@ -64,17 +89,34 @@ func (s *stmt) Query(args ...interface{}) (*sql.Rows, error) {
 // QueryContext executes a prepared query statement with the given
 // arguments and returns the query results as a *sql.Rows.
 // Query uses the read only DB as the underlying physical db.
-func (s *stmt) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+func (s *stmt) QueryContext(ctx context.Context, args ...interface{}) (rows *sql.Rows, err error) {
+	err = s.retry.do(ctx, s.writeFlag, func() error {
+		rows, err = s.routeQuery(ctx, args)
+		return err
+	})
+	return rows, err
+}
+
+// routeQuery routes the query, and falls back to the primary when a replica is unreachable
+func (s *stmt) routeQuery(ctx context.Context, args []interface{}) (rows *sql.Rows, err error) {
 	var curStmt *sql.Stmt
+	route := primaryRoute
 	if s.writeFlag {
 		curStmt = s.RWStmt()
 	} else {
-		curStmt = s.ROStmt()
+		curStmt, route = s.roStmt(ctx)
 	}
 
-	rows, err := curStmt.QueryContext(ctx, args...)
-	if isDBConnectionError(err) && !s.writeFlag {
-		rows, err = s.RWStmt().QueryContext(ctx, args...)
+	var queryErr error
+	rows, err = queryWithHooks(ctx, s.hooks, route, s.query, args, func(ctx context.Context) (*sql.Rows, error) {
+		rows, queryErr = curStmt.QueryContext(ctx, args...)
+		return rows, queryErr
+	})
+	if route.Role == RoleReplica && loadReadMode(s.readMode) != ReplicaOnly && isConnectionError(s.classifier, queryErr) {
+		curStmt = s.RWStmt()
+		rows, err = queryWithHooks(ctx, s.hooks, fallbackRoute, s.query, args, func(ctx context.Context) (*sql.Rows, error) {
+			return curStmt.QueryContext(ctx, args...)
+		})
 	}
 	return rows, err
 }
@@ -95,57 +137,82 @@ func (s *stmt) QueryRow(args ...interface{}) *sql.Row {
 // If the query selects no rows, the *Row's Scan will return ErrNoRows.
 // Otherwise, the *sql.Row's Scan scans the first selected row and discards the rest.
 // QueryRowContext uses the read only DB as the underlying physical db.
-func (s *stmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+func (s *stmt) QueryRowContext(ctx context.Context, args ...interface{}) (row *sql.Row) {
+	_ = s.retry.do(ctx, s.writeFlag, func() error {
+		row = s.routeQueryRow(ctx, args)
+		return row.Err()
+	})
+	return row
+}
+
+// routeQueryRow routes the query, and falls back to the primary when a replica is unreachable
+func (s *stmt) routeQueryRow(ctx context.Context, args []interface{}) *sql.Row {
 	var curStmt *sql.Stmt
+	route := primaryRoute
 	if s.writeFlag {
 		curStmt = s.RWStmt()
 	} else {
-		curStmt = s.ROStmt()
+		curStmt, route = s.roStmt(ctx)
 	}
 
-	row := curStmt.QueryRowContext(ctx, args...)
-	if isDBConnectionError(row.Err()) && !s.writeFlag {
-		row = s.RWStmt().QueryRowContext(ctx, args...)
+	row := queryRowWithHooks(ctx, s.hooks, route, s.query, args, func(ctx context.Context) *sql.Row {
+		return curStmt.QueryRowContext(ctx, args...)
+	})
+	if route.Role == RoleReplica && loadReadMode(s.readMode) != ReplicaOnly && isConnectionError(s.classifier, row.Err()) {
+		curStmt = s.RWStmt()
+		row = queryRowWithHooks(ctx, s.hooks, fallbackRoute, s.query, args, func(ctx context.Context) *sql.Row {
+			return curStmt.QueryRowContext(ctx, args...)
+		})
 	}
 	return row
 }
 
 // ROStmt return the replica statement
 func (s *stmt) ROStmt() *sql.Stmt {
-	totalStmtsConn := len(s.replicaStmts) + len(s.primaryStmts)
-	if totalStmtsConn == len(s.primaryStmts) {
-		return s.loadBalancer.Resolve(s.primaryStmts)
+	curStmt, _ := s.roStmt(context.Background())
+	return curStmt
+}
+
+// roStmt return the replica statement and its route, a primary statement when there is no healthy replica.
+// The statement of the affinity key of the context is resolved with AffinityLB.
+func (s *stmt) roStmt(ctx context.Context) (*sql.Stmt, Route) {
+	replicaStmts, nodeStmts := s.replicaStmts, s.dbStmt
+	if s.pending != nil {
+		if replicaStmts = s.pending.ready(); len(replicaStmts) > 0 {
+			nodeStmts = s.pending.nodeStmts
+		}
+	}
+	replicaStmts = s.health.healthyStmts(replicaStmts, nodeStmts)
+	if len(replicaStmts) == 0 || loadReadMode(s.readMode) == PrimaryOnly {
+		return resolve(s.loadBalancer, s.primaryStmts), primaryRoute
 	}
-	return s.loadBalancer.Resolve(s.replicaStmts)
+	if affinity, ok := s.loadBalancer.(keyResolver[*sql.Stmt]); ok && len(replicaStmts) > 1 {
+		if key, ok := AffinityKeyFromContext(ctx); ok {
+			return affinity.resolveKey(replicaStmts, key, nil), replicaRoute
+		}
+	}
+	return resolve(s.loadBalancer, replicaStmts), replicaRoute
 }
 
 // RWStmt return the primary statement
 func (s *stmt) RWStmt() *sql.Stmt {
-	return s.loadBalancer.Resolve(s.primaryStmts)
-}
-
-// stmtForDB returns the corresponding *sql.Stmt instance for the given *sql.DB.
-// Ihis is needed because sql.Tx.Stmt() requires that the passed *sql.Stmt be from the same database
-// as the transaction.
-func (s *stmt) stmtForDB(db *sql.DB) *sql.Stmt {
-	xsm, ok := s.dbStmt[db]
-	if ok {
-		return xsm
-	}
-
-	// return any statement so errors can be detected by Tx.Stmt()
-	return s.RWStmt()
+	return resolve(s.loadBalancer, s.primaryStmts)
 }
 
 // newSingleDBStmt creates a new stmt for a single DB connection.
 // This is used by statements return by transaction and connections.
-func newSingleDBStmt(sourceDB *sql.DB, st *sql.Stmt, writeFlag bool) *stmt {
+// The retrier is nil for the statements of the transactions, they aren't retried.
+func newSingleDBStmt(sourceDB *sql.DB, st *sql.Stmt, writeFlag bool, query string, hooks []Hooks, retry *retrier) *stmt {
 	return &stmt{
 		loadBalancer: &RoundRobinLoadBalancer[*sql.Stmt]{},
 		primaryStmts: []*sql.Stmt{st},
 		dbStmt: map[*sql.DB]*sql.Stmt{
 			sourceDB: st,
 		},
-		writeFlag: writeFlag,
+		writeFlag:  writeFlag,
+		query:      query,
+		hooks:      hooks,
+		retry:      retry,
+		classifier: DefaultClassifier,
 	}
 }
@@ -0,0 +1,128 @@
+package dbresolver
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// WithStmtCache prepares the queries run by Exec, Query and QueryRow on the node they run on, and reuses
+// the statement for the next runs of the same query on the node, saving the parsing and planning
+// of the hot queries without managing the lifetime of the statements.
+// The cache holds at most maxSize statements over all the nodes, the least recently used one is closed
+// once it's no longer running. A query failing to prepare runs without statement.
+// The prewarmed statements are used first, see Prewarm, and the statements are closed with the resolver.
+func WithStmtCache(maxSize int) OptionFunc {
+	if maxSize < 1 {
+		panic(fmt.Sprintf("dbresolver: invalid statement cache size %d", maxSize))
+	}
+	return func(opt *Option) {
+		opt.StmtCache = maxSize
+	}
+}
+
+type stmtCacheEntry struct {
+	key  preparedKey
+	stmt *sql.Stmt
+	// refs counts the queries running the statement, an evicted statement is closed once it's zero
+	refs    int
+	evicted bool
+}
+
+// stmtCache is a LRU cache of the statements of the queries by node
+type stmtCache struct {
+	maxSize int
+
+	mu      sync.Mutex
+	entries map[preparedKey]*list.Element
+	lru     *list.List
+}
+
+func newStmtCache(maxSize int) *stmtCache {
+	return &stmtCache{
+		maxSize: maxSize,
+		entries: make(map[preparedKey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// acquire returns the statement of the query on the node, prepared on a cache miss,
+// nil when the query fails to prepare. The entry is released once the statement ran.
+func (c *stmtCache) acquire(ctx context.Context, node *sql.DB, query string) *stmtCacheEntry {
+	key := preparedKey{node: node, query: query}
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		entry := elem.Value.(*stmtCacheEntry)
+		entry.refs++
+		c.mu.Unlock()
+		return entry
+	}
+	c.mu.Unlock()
+
+	// the query is prepared without lock, so the other queries aren't blocked
+	stmt, err := node.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		// prepared concurrently
+		_ = stmt.Close()
+		c.lru.MoveToFront(elem)
+		entry := elem.Value.(*stmtCacheEntry)
+		entry.refs++
+		return entry
+	}
+	entry := &stmtCacheEntry{key: key, stmt: stmt, refs: 1}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		evicted := oldest.Value.(*stmtCacheEntry)
+		delete(c.entries, evicted.key)
+		evicted.evicted = true
+		if evicted.refs == 0 {
+			_ = evicted.stmt.Close()
+		}
+	}
+	return entry
+}
+
+// release releases the entry, and closes its statement once it's evicted and no longer running
+func (c *stmtCache) release(entry *stmtCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		_ = entry.stmt.Close()
+	}
+}
+
+// close evicts every statement, the running ones are closed once released
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for key, elem := range c.entries {
+		entry := elem.Value.(*stmtCacheEntry)
+		entry.evicted = true
+		if entry.refs == 0 {
+			errs = append(errs, entry.stmt.Close())
+		}
+		delete(c.entries, key)
+	}
+	c.lru.Init()
+	return errors.Join(errs...)
+}
+
+// len returns the number of cached statements
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
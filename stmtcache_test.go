@@ -0,0 +1,52 @@
+package dbresolver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestStmtCache(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithStmtCache(1)).(*sqlDB)
+
+	// the statement is prepared once, then reused
+	insert, update := "INSERT INTO users(name) VALUES (?)", "UPDATE users SET name = ?"
+	prepared := primaryMock.ExpectPrepare(insert).WillBeClosed()
+	prepared.ExpectExec().WithArgs("foo").WillReturnResult(sqlmock.NewResult(1, 1))
+	prepared.ExpectExec().WithArgs("bar").WillReturnResult(sqlmock.NewResult(2, 1))
+	for _, name := range []string{"foo", "bar"} {
+		if _, err := resolver.Exec(insert, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the least recently used statement is evicted and closed
+	primaryMock.ExpectPrepare(update).WillBeClosed().ExpectExec().WithArgs("baz").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	if _, err := resolver.Exec(update, "baz"); err != nil {
+		t.Fatal(err)
+	}
+	if size := resolver.stmtCache.len(); size != 1 {
+		t.Errorf("want 1 cached statement, got %d", size)
+	}
+
+	// the query failing to prepare runs without statement
+	primaryMock.ExpectPrepare("VACUUM").WillReturnError(errors.New("cannot prepare"))
+	primaryMock.ExpectExec("VACUUM").WillReturnResult(sqlmock.NewResult(0, 0))
+	if _, err := resolver.Exec("VACUUM"); err != nil {
+		t.Fatal(err)
+	}
+
+	primaryMock.ExpectClose()
+	if err := resolver.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
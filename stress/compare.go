@@ -0,0 +1,225 @@
+package stress
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// TraceQuery is a query of a replayed trace
+type TraceQuery struct {
+	Query string
+	// Write sends the query with Exec, with Query otherwise
+	Write bool
+}
+
+// Node is a simulated node of a comparison
+type Node struct {
+	// Latency of each query on the node
+	Latency time.Duration
+	// MaxOpenConns bounds the concurrent queries of the node, the other queries queue. It's unbounded when zero.
+	MaxOpenConns int
+	// Weight is the weight of a replica, see WithReplicaWeights. It's 1 when zero.
+	Weight int
+}
+
+// Comparison define the trace replayed with each load balancer policy, and the simulated topology
+type Comparison struct {
+	Trace     []TraceQuery
+	Primaries []Node
+	Replicas  []Node
+	// Workers is the number of workers replaying the trace concurrently, DefaultWorkers by default
+	Workers int
+	// Policies are the compared load balancer policies, RoundRobinLB and RandomLB by default
+	Policies []dbresolver.LoadBalancerPolicy
+	// Options are the other options of the resolvers
+	Options []dbresolver.OptionFunc
+}
+
+// PolicyResult is the outcome of the trace replayed with a load balancer policy
+type PolicyResult struct {
+	Policy dbresolver.LoadBalancerPolicy
+	// Primaries and Replicas are the number of queries answered by each node, in the order of the Comparison
+	Primaries []int
+	Replicas  []int
+	// P50, P90 and P99 are the percentiles of the query latencies, including the queueing
+	P50, P90, P99 time.Duration
+	Max           time.Duration
+	Errors        int
+}
+
+// Compare replays the trace against the simulated nodes with each load balancer policy,
+// and reports the distribution of the queries and the latency percentiles, to pick and tune a policy with data.
+// The results are in the order of the policies.
+func Compare(ctx context.Context, comparison Comparison) ([]PolicyResult, error) {
+	if len(comparison.Primaries) == 0 {
+		return nil, errors.New("stress: at least one primary is required")
+	}
+	if comparison.Workers <= 0 {
+		comparison.Workers = DefaultWorkers
+	}
+	policies := comparison.Policies
+	if len(policies) == 0 {
+		policies = []dbresolver.LoadBalancerPolicy{dbresolver.RoundRobinLB, dbresolver.RandomLB}
+	}
+
+	results := make([]PolicyResult, 0, len(policies))
+	for _, policy := range policies {
+		result, err := replay(ctx, comparison, policy)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// replay replays the trace with the load balancer policy on new simulated nodes
+func replay(ctx context.Context, comparison Comparison, policy dbresolver.LoadBalancerPolicy) (*PolicyResult, error) {
+	primaries, primaryCounts := openSimulatedNodes(comparison.Primaries)
+	replicas, replicaCounts := openSimulatedNodes(comparison.Replicas)
+	weights := make(map[*sql.DB]int)
+	for i, replica := range comparison.Replicas {
+		if replica.Weight > 0 {
+			weights[replicas[i]] = replica.Weight
+		}
+	}
+	db := dbresolver.New(append(append([]dbresolver.OptionFunc{
+		dbresolver.WithPrimaryDBs(primaries...),
+		dbresolver.WithReplicaDBs(replicas...),
+		dbresolver.WithReplicaWeights(weights),
+	}, comparison.Options...), dbresolver.WithLoadBalancer(policy))...)
+
+	latencies := make([]time.Duration, len(comparison.Trace))
+	var (
+		next atomic.Int64
+		errs atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < comparison.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				i := int(next.Add(1) - 1)
+				if i >= len(comparison.Trace) {
+					return
+				}
+				start := time.Now()
+				if err := run(ctx, db, comparison.Trace[i]); err != nil {
+					errs.Add(1)
+				}
+				latencies[i] = time.Since(start)
+			}
+		}()
+	}
+	wg.Wait()
+	closeErr := db.Close()
+
+	result := &PolicyResult{Policy: policy, Errors: int(errs.Load())}
+	for i := range primaryCounts {
+		result.Primaries = append(result.Primaries, int(primaryCounts[i].Load()))
+	}
+	for i := range replicaCounts {
+		result.Replicas = append(result.Replicas, int(replicaCounts[i].Load()))
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50, result.P90, result.P99 = percentile(latencies, 0.5), percentile(latencies, 0.9), percentile(latencies, 0.99)
+	if len(latencies) > 0 {
+		result.Max = latencies[len(latencies)-1]
+	}
+	return result, errors.Join(closeErr, ctx.Err())
+}
+
+func run(ctx context.Context, db dbresolver.DB, query TraceQuery) error {
+	if query.Write {
+		_, err := db.ExecContext(ctx, query.Query)
+		return err
+	}
+	rows, err := db.QueryContext(ctx, query.Query)
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// percentile returns the percentile of the sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// openSimulatedNodes opens the nodes, the counters count the queries answered by each node
+func openSimulatedNodes(nodes []Node) ([]*sql.DB, []atomic.Int64) {
+	dbs := make([]*sql.DB, len(nodes))
+	counts := make([]atomic.Int64, len(nodes))
+	for i, node := range nodes {
+		dbs[i] = sql.OpenDB(simulatedConnector{latency: node.Latency, count: &counts[i]})
+		dbs[i].SetMaxOpenConns(node.MaxOpenConns)
+	}
+	return dbs, counts
+}
+
+type simulatedConnector struct {
+	latency time.Duration
+	count   *atomic.Int64
+}
+
+func (c simulatedConnector) Connect(context.Context) (driver.Conn, error) {
+	return simulatedConn(c), nil
+}
+func (c simulatedConnector) Driver() driver.Driver { return nil }
+
+// simulatedConn answers every query with no row after the latency of the node
+type simulatedConn simulatedConnector
+
+func (c simulatedConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("stress: the simulated nodes don't prepare statements")
+}
+func (c simulatedConn) Close() error { return nil }
+func (c simulatedConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("stress: the simulated nodes don't support transactions")
+}
+
+func (c simulatedConn) answer(ctx context.Context) error {
+	c.count.Add(1)
+	timer := time.NewTimer(c.latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c simulatedConn) ExecContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c simulatedConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.answer(ctx); err != nil {
+		return nil, err
+	}
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return nil }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
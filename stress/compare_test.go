@@ -0,0 +1,72 @@
+package stress_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/stress"
+)
+
+func TestCompare(t *testing.T) {
+	var trace []stress.TraceQuery
+	for i := 0; i < 100; i++ {
+		trace = append(trace, stress.TraceQuery{Query: "SELECT 1"})
+		if i%10 == 0 {
+			trace = append(trace, stress.TraceQuery{Query: "UPDATE book SET views = views + 1", Write: true})
+		}
+	}
+	latency := time.Millisecond
+	results, err := stress.Compare(context.Background(), stress.Comparison{
+		Trace:     trace,
+		Primaries: []stress.Node{{Latency: latency}},
+		Replicas:  []stress.Node{{Latency: latency}, {Latency: latency, MaxOpenConns: 1}},
+		Workers:   4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Policy != dbresolver.RoundRobinLB || results[1].Policy != dbresolver.RandomLB {
+		t.Fatalf("want the round robin and random results, got %+v", results)
+	}
+	for _, result := range results {
+		if result.Errors != 0 {
+			t.Errorf("%s: %d errors", result.Policy, result.Errors)
+		}
+		if result.Primaries[0] != 10 || result.Replicas[0]+result.Replicas[1] != 100 {
+			t.Errorf("%s: want 10 writes and 100 reads, got %v and %v", result.Policy, result.Primaries, result.Replicas)
+		}
+		if result.P50 < latency || result.P50 > result.P90 || result.P90 > result.P99 || result.P99 > result.Max {
+			t.Errorf("%s: unordered percentiles %v %v %v %v", result.Policy, result.P50, result.P90, result.P99, result.Max)
+		}
+	}
+	if roundRobin := results[0]; roundRobin.Replicas[0] != 50 || roundRobin.Replicas[1] != 50 {
+		t.Errorf("round robin: want the reads split evenly, got %v", roundRobin.Replicas)
+	}
+}
+
+func TestCompareWeights(t *testing.T) {
+	trace := make([]stress.TraceQuery, 40)
+	for i := range trace {
+		trace[i] = stress.TraceQuery{Query: "SELECT 1"}
+	}
+	results, err := stress.Compare(context.Background(), stress.Comparison{
+		Trace:     trace,
+		Primaries: []stress.Node{{}},
+		Replicas:  []stress.Node{{Weight: 3}, {}},
+		Policies:  []dbresolver.LoadBalancerPolicy{dbresolver.RoundRobinLB},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Replicas[0] != 30 || results[0].Replicas[1] != 10 {
+		t.Fatalf("want the reads split by weight, got %+v", results)
+	}
+}
+
+func TestCompareWithoutPrimary(t *testing.T) {
+	if _, err := stress.Compare(context.Background(), stress.Comparison{}); err == nil {
+		t.Fatal("want an error without primary")
+	}
+}
@@ -0,0 +1,186 @@
+// Package stress runs a resolver under concurrent load, to validate the distribution of the queries
+// between the nodes, the fallback behavior and the goroutine leaks of a topology and its options.
+package stress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+)
+
+// Defaults of the stress run
+const (
+	DefaultWorkers    = 8
+	DefaultIterations = 1000
+)
+
+// leakTimeout is how long the goroutines of a closed resolver are awaited
+const leakTimeout = time.Second
+
+// Config define the load of the stress run
+type Config struct {
+	// Workers is the number of concurrent workers, DefaultWorkers by default
+	Workers int
+	// Iterations is the number of iterations of each worker, DefaultIterations by default
+	Iterations int
+	// Query is run by each iteration, eg. a read or a write of the application.
+	// Only the routing is stressed when it's nil.
+	Query func(ctx context.Context, db dbresolver.DB) error
+}
+
+// Report is the outcome of a stress run
+type Report struct {
+	// Nodes are the nodes of the resolver, with their weights
+	Nodes []dbresolver.NodeInfo
+	// Resolved is the number of times each node was resolved by ReadOnly and ReadWrite
+	Resolved map[*sql.DB]int
+	// Queries is the number of queries run, Errors the number of failed ones
+	Queries, Errors int
+	// Fallbacks is the number of reads sent again to a primary after a replica connection error
+	Fallbacks int
+	// LeakedGoroutines is the number of goroutines left running once the resolver is closed
+	LeakedGoroutines int
+	// Duration of the load
+	Duration time.Duration
+}
+
+// Run creates a resolver with the options, eg. the topology and the load balancer under test, and stresses it
+// with the concurrent workers. Each iteration resolves a replica and a primary, then runs the query.
+// The resolver and its nodes are closed at the end of the run, to detect the goroutine leaks.
+// The error is the error closing the resolver, or the context error.
+func Run(ctx context.Context, config Config, opts ...dbresolver.OptionFunc) (*Report, error) {
+	if config.Workers <= 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.Iterations <= 0 {
+		config.Iterations = DefaultIterations
+	}
+
+	goroutines := runtime.NumGoroutine()
+	hooks := &fallbackHooks{}
+	db := dbresolver.New(append(opts, dbresolver.WithHooks(hooks))...)
+	report := &Report{Nodes: db.Nodes(), Resolved: make(map[*sql.DB]int)}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		queries atomic.Int64
+		errs    atomic.Int64
+	)
+	start := time.Now()
+	for w := 0; w < config.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resolved := make(map[*sql.DB]int)
+			for i := 0; i < config.Iterations && ctx.Err() == nil; i++ {
+				resolved[db.ReadOnly()]++
+				resolved[db.ReadWrite()]++
+				if config.Query == nil {
+					continue
+				}
+				queries.Add(1)
+				if err := config.Query(ctx, db); err != nil {
+					errs.Add(1)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for node, n := range resolved {
+				report.Resolved[node] += n
+			}
+		}()
+	}
+	wg.Wait()
+	report.Duration = time.Since(start)
+	report.Queries, report.Errors = int(queries.Load()), int(errs.Load())
+	report.Fallbacks = int(hooks.fallbacks.Load())
+
+	err := db.Close()
+	report.LeakedGoroutines = awaitGoroutines(goroutines)
+	return report, errors.Join(err, ctx.Err())
+}
+
+// awaitGoroutines waits for the number of goroutines to fall back to n, and returns the extra goroutines
+func awaitGoroutines(n int) int {
+	deadline := time.Now().Add(leakTimeout)
+	for {
+		extra := runtime.NumGoroutine() - n
+		if extra <= 0 || time.Now().After(deadline) {
+			return max(extra, 0)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Check returns an error when the share of a node in the resolutions of its role deviates from the share
+// of its weight by more than the tolerance, eg. 0.1 for 10%, or when goroutines leaked.
+// A node added multiple times to a role is resolved as one node.
+func (r *Report) Check(tolerance float64) error {
+	var errs []string
+	for _, role := range []dbresolver.Role{dbresolver.RolePrimary, dbresolver.RoleReplica} {
+		totalWeight, totalResolved := 0, 0
+		for _, node := range r.Nodes {
+			if node.Role == role {
+				totalWeight += node.Weight
+				totalResolved += r.Resolved[node.DB]
+			}
+		}
+		if totalResolved == 0 {
+			continue
+		}
+		for i, node := range r.Nodes {
+			if node.Role != role {
+				continue
+			}
+			want := float64(node.Weight) / float64(totalWeight)
+			got := float64(r.Resolved[node.DB]) / float64(totalResolved)
+			if math.Abs(got-want) > tolerance*want {
+				errs = append(errs, fmt.Sprintf("%s %s resolved %.1f%% of the time, want %.1f%%",
+					role, nodeName(node, i), got*100, want*100))
+			}
+		}
+	}
+	if r.LeakedGoroutines > 0 {
+		errs = append(errs, fmt.Sprintf("%d goroutines leaked", r.LeakedGoroutines))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Strings(errs)
+	return fmt.Errorf("stress: %s", strings.Join(errs, ", "))
+}
+
+// nodeName returns the name of the node, its index when it's unnamed
+func nodeName(node dbresolver.NodeInfo, i int) string {
+	if name := node.Name(); name != "" {
+		return name
+	}
+	return fmt.Sprint(i)
+}
+
+// fallbackHooks counts the fallback reads
+type fallbackHooks struct {
+	fallbacks atomic.Int64
+}
+
+func (h *fallbackHooks) Before(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	if route, ok := dbresolver.RouteFromContext(ctx); ok && route.Fallback {
+		h.fallbacks.Add(1)
+	}
+	return ctx, nil
+}
+
+func (h *fallbackHooks) After(ctx context.Context, _ string, _ ...interface{}) (context.Context, error) {
+	return ctx, nil
+}
@@ -0,0 +1,111 @@
+package stress_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/stress"
+)
+
+// connector opens the connections of a node answering every query with no row, or with the error
+type connector struct {
+	err error
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return conn(c), nil }
+func (c connector) Driver() driver.Driver                        { return nil }
+
+type conn connector
+
+func (c conn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c conn) Close() error                        { return nil }
+func (c conn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c conn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return rows{}, nil
+}
+
+type rows struct{}
+
+func (rows) Columns() []string         { return nil }
+func (rows) Close() error              { return nil }
+func (rows) Next([]driver.Value) error { return io.EOF }
+
+func openNodes(n int, err error) []*sql.DB {
+	dbs := make([]*sql.DB, n)
+	for i := range dbs {
+		dbs[i] = sql.OpenDB(connector{err: err})
+	}
+	return dbs
+}
+
+func read(ctx context.Context, db dbresolver.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT 1")
+	if err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+func TestRun(t *testing.T) {
+	for _, lb := range []dbresolver.LoadBalancerPolicy{dbresolver.RoundRobinLB, dbresolver.RandomLB} {
+		t.Run(string(lb), func(t *testing.T) {
+			replicas := openNodes(3, nil)
+			report, err := stress.Run(context.Background(), stress.Config{Query: read},
+				dbresolver.WithPrimaryDBs(openNodes(2, nil)...), dbresolver.WithReplicaDBs(replicas...),
+				dbresolver.WithReplicaWeights(map[*sql.DB]int{replicas[0]: 2}),
+				dbresolver.WithLoadBalancer(lb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Queries != stress.DefaultWorkers*stress.DefaultIterations || report.Errors != 0 {
+				t.Errorf("want every query successful, got %d queries and %d errors", report.Queries, report.Errors)
+			}
+			if err := report.Check(0.1); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRunFallback(t *testing.T) {
+	unreachable := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	report, err := stress.Run(context.Background(), stress.Config{Workers: 2, Iterations: 10, Query: read},
+		dbresolver.WithPrimaryDBs(openNodes(1, nil)...), dbresolver.WithReplicaDBs(openNodes(1, unreachable)...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Fallbacks != 20 || report.Errors != 0 {
+		t.Errorf("want every read falling back to the primary, got %d fallbacks and %d errors",
+			report.Fallbacks, report.Errors)
+	}
+}
+
+func TestReportCheck(t *testing.T) {
+	primary, replica1, replica2 := &sql.DB{}, &sql.DB{}, &sql.DB{}
+	report := &stress.Report{
+		Nodes: []dbresolver.NodeInfo{
+			{DB: primary, Role: dbresolver.RolePrimary, Weight: 1},
+			{DB: replica1, Role: dbresolver.RoleReplica, Weight: 1},
+			{DB: replica2, Role: dbresolver.RoleReplica, Weight: 1,
+				Labels: map[string]string{dbresolver.NodeNameLabel: "replica-b"}},
+		},
+		Resolved:         map[*sql.DB]int{primary: 100, replica1: 70, replica2: 30},
+		LeakedGoroutines: 2,
+	}
+	err := report.Check(0.1)
+	if err == nil || !strings.Contains(err.Error(), "replica replica-b resolved 30.0% of the time, want 50.0%") ||
+		!strings.Contains(err.Error(), "replica 1 resolved 70.0%") || !strings.Contains(err.Error(), "2 goroutines leaked") {
+		t.Errorf("want the imbalance and the leak reported, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package stubdriver registers a trivial in-process database/sql driver accepting any query,
+// so the runnable examples and the CI exercise the resolver without a live database.
+//
+// The databases are identified by their DSN: the databases opened with the same DSN share their canned rows.
+// A query without canned rows returns no row, an Exec affects no row.
+package stubdriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+)
+
+// DriverName is the name of the registered driver, eg. sql.Open(stubdriver.DriverName, "primary")
+const DriverName = "dbresolver_stub"
+
+func init() {
+	sql.Register(DriverName, Driver{})
+}
+
+type cannedKey struct {
+	dsn   string
+	query string
+}
+
+type canned struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+var (
+	mu   sync.RWMutex
+	rows = map[cannedKey]canned{}
+)
+
+// SetRows sets the rows returned by the query on the databases of the DSN
+func SetRows(dsn, query string, columns []string, values ...[]driver.Value) {
+	mu.Lock()
+	defer mu.Unlock()
+	rows[cannedKey{dsn: dsn, query: query}] = canned{columns: columns, rows: values}
+}
+
+// Reset forgets the canned rows of the DSN
+func Reset(dsn string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for key := range rows {
+		if key.dsn == dsn {
+			delete(rows, key)
+		}
+	}
+}
+
+// Driver is the stub driver
+type Driver struct{}
+
+// Open opens a connection to the database of the DSN
+func (Driver) Open(dsn string) (driver.Conn, error) {
+	return conn{dsn: dsn}, nil
+}
+
+type conn struct {
+	dsn string
+}
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{conn: c, query: query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return tx{}, nil }
+
+// CheckNamedValue accepts any arg
+func (c conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c conn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	canned := rows[cannedKey{dsn: c.dsn, query: query}]
+	return &cannedRows{canned: canned}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	conn  conn
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+func (s stmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s stmt) Query([]driver.Value) (driver.Rows, error)  { return nil, driver.ErrSkip }
+
+func (s stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+type cannedRows struct {
+	canned
+	next int
+}
+
+func (r *cannedRows) Columns() []string { return r.columns }
+func (r *cannedRows) Close() error      { return nil }
+
+func (r *cannedRows) Next(dest []driver.Value) error {
+	if r.next == len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
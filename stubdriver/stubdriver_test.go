@@ -0,0 +1,61 @@
+package stubdriver_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/bxcodec/dbresolver/v2/stubdriver"
+)
+
+func TestStubDriver(t *testing.T) {
+	dsn := t.Name()
+	stubdriver.SetRows(dsn, "SELECT id, title FROM book", []string{"id", "title"},
+		[]driver.Value{int64(1), "Dune"}, []driver.Value{int64(2), "Emma"})
+	t.Cleanup(func() { stubdriver.Reset(dsn) })
+
+	db, err := sql.Open(stubdriver.DriverName, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, title FROM book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for rows.Next() {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, title)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(titles) != 2 || titles[0] != "Dune" || titles[1] != "Emma" {
+		t.Errorf("want the canned rows, got %v", titles)
+	}
+
+	// any other query is accepted
+	if _, err := db.Exec("DELETE FROM book WHERE id = $1", 1); err != nil {
+		t.Error(err)
+	}
+	var title string
+	if err := db.QueryRow("SELECT title FROM book WHERE id = $1", 3).Scan(&title); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("want no row without canned rows, got %v", err)
+	}
+	stmt, err := db.Prepare("SELECT id, title FROM book")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	var id int
+	if err := stmt.QueryRow().Scan(&id, &title); err != nil || title != "Dune" {
+		t.Errorf("want the canned rows of the statement, got %q, %v", title, err)
+	}
+}
@@ -0,0 +1,191 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNodeNotFound is returned when no node has the given name
+var ErrNodeNotFound = errors.New("dbresolver: node not found")
+
+// SwapNodeDSN replaces the pool of the named node by a new pool opened with the DSN,
+// eg. to rotate its password or to migrate it to a new host without downtime.
+//
+// The new pool is opened with the driver of the node and the same maximum of open connections, and pinged.
+// The maximum of idle connections and the maximum lifetime and idle time of the connections set through
+// the resolver are applied to it too, the ones set directly on the pool of the node can't be read back.
+// When healthy, it replaces the node atomically with its role, labels and weight, then the old pool is closed,
+// waiting for its in-flight queries. The statements prepared before the swap must be prepared again.
+// The nodes of the shards are swapped in their shard. The nodes of the discoverers can't be swapped,
+// the discoverers own them.
+func (db *sqlDB) SwapNodeDSN(ctx context.Context, name, dsn string) error {
+	old, err := db.nodeByName(name)
+	if err != nil {
+		return err
+	}
+	owner := db.owner(old)
+	if owner == nil {
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+	}
+
+	node, err := openWithDriver(old.Driver(), dsn)
+	if err != nil {
+		return fmt.Errorf("dbresolver: opening node %s: %w", name, RedactError(err, dsn, owner.redactor))
+	}
+	node.SetMaxOpenConns(old.Stats().MaxOpenConnections)
+	owner.poolSettings.apply(node, owner.jitterLifetime)
+	if err := node.PingContext(ctx); err != nil {
+		_ = node.Close()
+		return fmt.Errorf("dbresolver: node %s is unhealthy: %w", name, RedactError(err, dsn, owner.redactor))
+	}
+
+	if !owner.replaceNode(old, node) {
+		// removed while the new pool was opened
+		_ = node.Close()
+		return fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+	}
+	return old.Close()
+}
+
+// nodeByName returns the node with the name
+func (db *sqlDB) nodeByName(name string) (*sql.DB, error) {
+	var found *sql.DB
+	for _, node := range db.Nodes() {
+		if node.Name() != name {
+			continue
+		}
+		if found != nil && found != node.DB {
+			return nil, fmt.Errorf("dbresolver: multiple nodes are named %s", name)
+		}
+		found = node.DB
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNodeNotFound, name)
+	}
+	return found, nil
+}
+
+// owner returns the resolver of the node, the resolver itself or one of its shards, nil when it isn't in the topology
+func (db *sqlDB) owner(node *sql.DB) *sqlDB {
+	set := db.nodes.Load()
+	if containsDB(set.primaries, node) || containsDB(set.replicas, node) {
+		return db
+	}
+	for _, name := range db.shardNames {
+		if owner := db.shards[name].owner(node); owner != nil {
+			return owner
+		}
+	}
+	return nil
+}
+
+// replaceNode replaces the old node by the new one, with its labels and weight.
+// It returns false when the old node isn't in the topology anymore.
+func (db *sqlDB) replaceNode(old, node *sql.DB) bool {
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+
+	replace := func(dbs []*sql.DB) ([]*sql.DB, bool) {
+		res := make([]*sql.DB, len(dbs))
+		found := false
+		for i, d := range dbs {
+			if d == old {
+				d, found = node, true
+			}
+			res[i] = d
+		}
+		return res, found
+	}
+	set := *db.nodes.Load()
+	primaries, inPrimaries := replace(set.primaries)
+	replicas, inReplicas := replace(set.replicas)
+	if !inPrimaries && !inReplicas {
+		return false
+	}
+	set.primaries, set.replicas = primaries, replicas
+
+	if labels := db.nodeLabels(old); labels != nil {
+		db.storeLabels(old, nil)
+		db.storeLabels(node, labels)
+	}
+	if weight, ok := set.primaryWeights[old]; ok {
+		set.primaryWeights = withWeight(withWeight(set.primaryWeights, old, 1), node, weight)
+	}
+	if weight, ok := set.replicaWeights[old]; ok {
+		set.replicaWeights = withWeight(withWeight(set.replicaWeights, old, 1), node, weight)
+	}
+	db.storeNodes(set)
+	return true
+}
+
+// poolSettings are the settings of the pools of the nodes set through the resolver, nil when unset
+type poolSettings struct {
+	mu              sync.Mutex
+	maxIdleConns    *int
+	connMaxLifetime *time.Duration
+	connMaxIdleTime *time.Duration
+}
+
+func (s *poolSettings) setMaxIdleConns(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxIdleConns = &n
+}
+
+func (s *poolSettings) setConnMaxLifetime(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connMaxLifetime = &d
+}
+
+func (s *poolSettings) setConnMaxIdleTime(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connMaxIdleTime = &d
+}
+
+// apply applies the settings to the pool, the lifetime with the jitter of the resolver
+func (s *poolSettings) apply(node *sql.DB, jitterLifetime func(d time.Duration) time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.maxIdleConns != nil {
+		node.SetMaxIdleConns(*s.maxIdleConns)
+	}
+	if s.connMaxLifetime != nil {
+		node.SetConnMaxLifetime(jitterLifetime(*s.connMaxLifetime))
+	}
+	if s.connMaxIdleTime != nil {
+		node.SetConnMaxIdleTime(*s.connMaxIdleTime)
+	}
+}
+
+// openWithDriver opens the pool of the DSN with the driver
+func openWithDriver(drv driver.Driver, dsn string) (*sql.DB, error) {
+	if driverCtx, ok := drv.(driver.DriverContext); ok {
+		connector, err := driverCtx.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(connector), nil
+	}
+	return sql.OpenDB(dsnConnector{driver: drv, dsn: dsn}), nil
+}
+
+// dsnConnector is the connector of the drivers not implementing driver.DriverContext
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
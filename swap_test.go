@@ -0,0 +1,120 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSwapNodeDSN(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := sqlmock.NewWithDSN("swap_replica_old")
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	_, newMock, err := sqlmock.NewWithDSN("swap_replica_new", sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	_, unhealthyMock, err := sqlmock.NewWithDSN("swap_replica_unhealthy", sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := New(
+		WithPrimaryDBs(primary),
+		WithReplicaDBs(replica),
+		WithNodeName(replica, "replica-1"),
+		WithReplicaWeights(map[*sql.DB]int{replica: 2}),
+	)
+
+	if err := resolver.SwapNodeDSN(context.Background(), "replica-2", "swap_replica_new"); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("want %v, got %v", ErrNodeNotFound, err)
+	}
+
+	unhealthyMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	unhealthyMock.ExpectClose()
+	if err := resolver.SwapNodeDSN(context.Background(), "replica-1", "swap_replica_unhealthy"); err == nil {
+		t.Error("want the unhealthy node error")
+	}
+	if got := resolver.ReplicaDBs(); got[0] != replica {
+		t.Errorf("want the node kept, got %v", got)
+	}
+
+	newMock.ExpectPing()
+	replicaMock.ExpectClose()
+	if err := resolver.SwapNodeDSN(context.Background(), "replica-1", "swap_replica_new"); err != nil {
+		t.Fatal(err)
+	}
+	nodes := resolver.Nodes()
+	if nodes[1].DB == replica || nodes[1].Role != RoleReplica || nodes[1].Name() != "replica-1" || nodes[1].Weight != 2 {
+		t.Errorf("want the replica swapped with its name and weight, got %+v", nodes[1])
+	}
+	if got := resolver.ReadOnly(); got != nodes[1].DB {
+		t.Errorf("want the reads routed to the new pool, got %v", got)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{replicaMock, newMock, unhealthyMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestSwapShardNodeDSN(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shardReplica, shardReplicaMock, err := sqlmock.NewWithDSN("swap_shard_replica_old")
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	shardPrimary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	_, newMock, err := sqlmock.NewWithDSN("swap_shard_replica_new", sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := New(WithPrimaryDBs(primary), WithShards(map[string]ShardConfig{"eu": {
+		Primaries: []*sql.DB{shardPrimary},
+		Replicas:  []*sql.DB{shardReplica},
+		Options:   []OptionFunc{WithNodeName(shardReplica, "eu-replica")},
+	}}))
+	// closes the idle connection of the old pool
+	shardReplicaMock.ExpectClose()
+	resolver.SetMaxIdleConns(0)
+
+	newMock.ExpectPing()
+	if err := resolver.SwapNodeDSN(context.Background(), "eu-replica", "swap_shard_replica_new"); err != nil {
+		t.Fatal(err)
+	}
+	var swapped *sql.DB
+	for _, node := range resolver.Nodes() {
+		if node.Name() == "eu-replica" {
+			swapped = node.DB
+		}
+	}
+	if swapped == nil || swapped == shardReplica {
+		t.Fatalf("want the replica of the shard swapped, got %v", swapped)
+	}
+	// the connection of the ping isn't kept idle with the maximum of idle connections of the resolver
+	if idle := swapped.Stats().Idle; idle != 0 {
+		t.Errorf("want no idle connection on the new pool, got %d", idle)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{shardReplicaMock, newMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Errors of the tenants
+var (
+	// ErrNoTenant is returned when the context has no tenant, see WithTenant
+	ErrNoTenant = errors.New("dbresolver: no tenant in the context")
+	// ErrTenantsClosed is returned once the tenants are closed
+	ErrTenantsClosed = errors.New("dbresolver: the tenants are closed")
+)
+
+// Tenancy define how the clusters of the tenants are opened, see NewTenants
+type Tenancy struct {
+	// Open opens the resolver of the cluster of the tenant, with its own primaries and replicas, eg. with OpenDSN
+	Open func(ctx context.Context, tenant string) (DB, error)
+	// MaxOpenConns and MaxIdleConns limit the pool of each node of a tenant, see DB.SetMaxOpenConns
+	// and DB.SetMaxIdleConns. The pools are left as opened when they're zero.
+	MaxOpenConns int
+	MaxIdleConns int
+}
+
+// Tenants are the clusters of the tenants, eg. with a database per tenant.
+// The cluster of a tenant is opened on its first use, and kept open until it's evicted or the tenants are closed.
+type Tenants struct {
+	config Tenancy
+
+	mu       sync.Mutex
+	clusters map[string]*tenantCluster
+	closed   bool
+}
+
+// tenantCluster is the cluster of a tenant, db and err are set before opened is closed
+type tenantCluster struct {
+	opened chan struct{}
+	db     DB
+	err    error
+}
+
+// NewTenants creates the tenants opening their cluster with the tenancy
+func NewTenants(config Tenancy) *Tenants {
+	if config.Open == nil {
+		panic("dbresolver: the tenancy requires an Open function")
+	}
+	return &Tenants{config: config, clusters: make(map[string]*tenantCluster)}
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context selecting the cluster of the tenant in Tenants.DB
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the context, set by WithTenant
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// DB returns the resolver of the cluster of the tenant of the context, ErrNoTenant without tenant
+func (t *Tenants) DB(ctx context.Context) (DB, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return nil, ErrNoTenant
+	}
+	return t.Tenant(ctx, tenant)
+}
+
+// Tenant returns the resolver of the cluster of the tenant, opening it on its first use.
+// The concurrent first uses share the opening, a failed opening is retried by the next use.
+func (t *Tenants) Tenant(ctx context.Context, tenant string) (DB, error) {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, ErrTenantsClosed
+	}
+	cluster, ok := t.clusters[tenant]
+	if !ok {
+		cluster = &tenantCluster{opened: make(chan struct{})}
+		t.clusters[tenant] = cluster
+		t.mu.Unlock()
+		t.open(ctx, tenant, cluster)
+	} else {
+		t.mu.Unlock()
+	}
+
+	select {
+	case <-cluster.opened:
+		return cluster.db, cluster.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// open opens the cluster of the tenant, and forgets it on failure
+func (t *Tenants) open(ctx context.Context, tenant string, cluster *tenantCluster) {
+	defer close(cluster.opened)
+	db, err := t.config.Open(ctx, tenant)
+	if err != nil {
+		cluster.err = fmt.Errorf("dbresolver: opening the cluster of the tenant %q: %w", tenant, err)
+		t.mu.Lock()
+		delete(t.clusters, tenant)
+		t.mu.Unlock()
+		return
+	}
+	if t.config.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(t.config.MaxOpenConns)
+	}
+	if t.config.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(t.config.MaxIdleConns)
+	}
+	cluster.db = db
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		// the tenants were closed while opening
+		cluster.db, cluster.err = nil, ErrTenantsClosed
+		_ = db.Close()
+	}
+}
+
+// Opened returns the sorted tenants with an open cluster
+func (t *Tenants) Opened() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tenants := make([]string, 0, len(t.clusters))
+	for tenant, cluster := range t.clusters {
+		select {
+		case <-cluster.opened:
+			if cluster.err == nil {
+				tenants = append(tenants, tenant)
+			}
+		default:
+		}
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// Evict closes the cluster of the tenant, eg. an offboarded or inactive tenant.
+// The next use of the tenant opens its cluster again.
+func (t *Tenants) Evict(tenant string) error {
+	t.mu.Lock()
+	cluster, ok := t.clusters[tenant]
+	delete(t.clusters, tenant)
+	t.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	<-cluster.opened
+	if cluster.db == nil {
+		return nil
+	}
+	return cluster.db.Close()
+}
+
+// Close closes the clusters of every tenant, the tenants can't be used afterwards
+func (t *Tenants) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	clusters := t.clusters
+	t.clusters = make(map[string]*tenantCluster)
+	t.mu.Unlock()
+
+	var errs []error
+	for _, cluster := range clusters {
+		<-cluster.opened
+		if cluster.db != nil {
+			errs = append(errs, cluster.db.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
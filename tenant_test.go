@@ -0,0 +1,120 @@
+package dbresolver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTenants(t *testing.T) {
+	var opens atomic.Int32
+	mocks := make(map[string]sqlmock.Sqlmock)
+	var mu sync.Mutex
+	tenants := NewTenants(Tenancy{
+		Open: func(_ context.Context, tenant string) (DB, error) {
+			opens.Add(1)
+			primary, mock, err := createMock()
+			if err != nil {
+				return nil, err
+			}
+			mu.Lock()
+			mocks[tenant] = mock
+			mu.Unlock()
+			return New(WithPrimaryDBs(primary)), nil
+		},
+		MaxOpenConns: 3,
+	})
+
+	// the concurrent first uses open the cluster once
+	var wg sync.WaitGroup
+	dbs := make([]DB, 10)
+	for i := range dbs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			db, err := tenants.DB(WithTenant(context.Background(), "acme"))
+			if err != nil {
+				t.Error(err)
+			}
+			dbs[i] = db
+		}(i)
+	}
+	wg.Wait()
+	if opens.Load() != 1 {
+		t.Fatalf("want the cluster opened once, opened %d times", opens.Load())
+	}
+	for _, db := range dbs {
+		if db != dbs[0] {
+			t.Fatal("want the same resolver for the tenant")
+		}
+	}
+	if max := dbs[0].Stats().MaxOpenConnections; max != 3 {
+		t.Errorf("want the pool limit of the tenant, got %d", max)
+	}
+
+	if _, err := tenants.Tenant(context.Background(), "globex"); err != nil {
+		t.Fatal(err)
+	}
+	if opened := tenants.Opened(); len(opened) != 2 || opened[0] != "acme" || opened[1] != "globex" {
+		t.Errorf("want the opened tenants, got %v", opened)
+	}
+
+	mocks["globex"].ExpectClose()
+	if err := tenants.Evict("globex"); err != nil {
+		t.Fatal(err)
+	}
+	if opened := tenants.Opened(); len(opened) != 1 {
+		t.Errorf("want the evicted tenant closed, got %v", opened)
+	}
+
+	mocks["acme"].ExpectClose()
+	if err := tenants.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for tenant, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: %v", tenant, err)
+		}
+	}
+	if _, err := tenants.Tenant(context.Background(), "acme"); !errors.Is(err, ErrTenantsClosed) {
+		t.Errorf("want ErrTenantsClosed, got %v", err)
+	}
+}
+
+func TestTenantsOpenError(t *testing.T) {
+	errOpen := errors.New("unknown tenant")
+	fail := true
+	tenants := NewTenants(Tenancy{
+		Open: func(context.Context, string) (DB, error) {
+			if fail {
+				return nil, errOpen
+			}
+			primary, mock, err := createMock()
+			if err != nil {
+				return nil, err
+			}
+			mock.ExpectClose()
+			return New(WithPrimaryDBs(primary)), nil
+		},
+	})
+
+	if _, err := tenants.DB(context.Background()); !errors.Is(err, ErrNoTenant) {
+		t.Errorf("want ErrNoTenant, got %v", err)
+	}
+	if _, err := tenants.Tenant(context.Background(), "acme"); !errors.Is(err, errOpen) {
+		t.Errorf("want the open error, got %v", err)
+	}
+
+	// the failed opening is retried
+	fail = false
+	if _, err := tenants.Tenant(context.Background(), "acme"); err != nil {
+		t.Fatalf("want the cluster opened again, got %v", err)
+	}
+	if err := tenants.Close(); err != nil {
+		t.Error(err)
+	}
+}
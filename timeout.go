@@ -0,0 +1,76 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WithPrimaryQueryTimeout bounds the Exec, Query and QueryRow of the resolver sent to a primary
+// when their context has no deadline, eg. to give the writes a tight SLA. The deadline covers reading
+// the rows, and each attempt of the retries and the fallbacks has its own deadline.
+// The queries of the transactions, the connections and the prepared statements aren't bounded.
+func WithPrimaryQueryTimeout(timeout time.Duration) OptionFunc {
+	if timeout <= 0 {
+		panic(fmt.Sprintf("dbresolver: invalid primary query timeout %v", timeout))
+	}
+	return func(opt *Option) {
+		opt.PrimaryQueryTimeout = timeout
+	}
+}
+
+// WithReplicaQueryTimeout bounds the Exec, Query and QueryRow of the resolver sent to a replica
+// when their context has no deadline, so the long analytic reads can't hold the replica connections forever,
+// see WithPrimaryQueryTimeout.
+func WithReplicaQueryTimeout(timeout time.Duration) OptionFunc {
+	if timeout <= 0 {
+		panic(fmt.Sprintf("dbresolver: invalid replica query timeout %v", timeout))
+	}
+	return func(opt *Option) {
+		opt.ReplicaQueryTimeout = timeout
+	}
+}
+
+// withQueryTimeout returns the context of a query sent to the route, with the query timeout of its role
+// when the context has no deadline. The cancel function is nil without timeout.
+func (db *sqlDB) withQueryTimeout(ctx context.Context, route Route) (context.Context, context.CancelFunc) {
+	timeout := db.primaryQueryTimeout
+	if route.Role == RoleReplica {
+		timeout = db.replicaQueryTimeout
+	}
+	if timeout == 0 {
+		return ctx, nil
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, nil
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timedExecContext runs execContext with the query timeout of the route
+func (db *sqlDB) timedExecContext(ctx context.Context, route Route, node *sql.DB, query string,
+	args []interface{}) (sql.Result, error) {
+	timedCtx, cancel := db.withQueryTimeout(ctx, route)
+	if cancel == nil {
+		return db.execContext(ctx, node, query, args)
+	}
+	defer cancel()
+	return db.execContext(timedCtx, node, query, args)
+}
+
+// timedQueryContext runs queryContext with the query timeout of the route.
+// The rows are read within the deadline, and closing them releases the context.
+func (db *sqlDB) timedQueryContext(ctx context.Context, route Route, node *sql.DB, query string,
+	args []interface{}, coalesce bool) (*sql.Rows, error) {
+	ctx, cancel := db.withQueryTimeout(ctx, route)
+	return db.queryContext(ctx, node, query, args, coalesce, cancel)
+}
+
+// timedQueryRowContext runs queryRowContext with the query timeout of the route, like timedQueryContext.
+// The row is scanned within the deadline, and the Scan releases the context.
+func (db *sqlDB) timedQueryRowContext(ctx context.Context, route Route, node *sql.DB, query string,
+	args []interface{}, coalesce bool) *sql.Row {
+	ctx, cancel := db.withQueryTimeout(ctx, route)
+	return db.queryRowContext(ctx, node, query, args, coalesce, cancel)
+}
@@ -0,0 +1,194 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica),
+		WithReplicaQueryTimeout(20*time.Millisecond), WithPrimaryQueryTimeout(time.Second))
+
+	// the slow read is canceled by the replica timeout
+	replicaMock.ExpectQuery("SELECT pg_sleep(1)").WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	start := time.Now()
+	if _, err := resolver.Query("SELECT pg_sleep(1)"); err == nil {
+		t.Error("want the read canceled by the replica timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("want the read canceled after 20ms, got %v", elapsed)
+	}
+
+	// the write gets the primary timeout
+	primaryMock.ExpectExec("UPDATE users SET name = 'foo'").WillDelayFor(50 * time.Millisecond).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.Exec("UPDATE users SET name = 'foo'"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the deadline of the caller is kept
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	replicaMock.ExpectQuery("SELECT 1").WillDelayFor(50 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	var one int
+	if err := resolver.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil || one != 1 {
+		t.Fatalf("want the read within the deadline of the caller, got %d, %v", one, err)
+	}
+
+	// the rows are read within the deadline
+	replicaMock.ExpectQuery("SELECT 2").WillReturnRows(sqlmock.NewRows([]string{"2"}).AddRow(2))
+	rows, err := resolver.Query("SELECT 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Error(err)
+	}
+	rows.Close()
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// ctxConnector opens connections recording the context of their last query, returning one row
+type ctxConnector struct {
+	ctx chan context.Context
+}
+
+func (c ctxConnector) Connect(context.Context) (driver.Conn, error) { return ctxConn(c), nil }
+func (c ctxConnector) Driver() driver.Driver                        { return nil }
+
+type ctxConn ctxConnector
+
+func (c ctxConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c ctxConn) Close() error                        { return nil }
+func (c ctxConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c ctxConn) QueryContext(ctx context.Context, _ string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.ctx <- ctx
+	return &ctxRows{}, nil
+}
+
+type ctxRows struct {
+	done bool
+}
+
+func (r *ctxRows) Columns() []string { return []string{"1"} }
+func (r *ctxRows) Close() error      { return nil }
+
+func (r *ctxRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func TestQueryTimeoutReleased(t *testing.T) {
+	connector := ctxConnector{ctx: make(chan context.Context, 1)}
+	primary := sql.OpenDB(connector)
+	replica := sql.OpenDB(connector)
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithReplicaQueryTimeout(time.Hour))
+	defer resolver.Close()
+
+	// closing the rows releases the context of the timeout
+	rows, err := resolver.Query("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := <-connector.ctx
+	var one int
+	if !rows.Next() || rows.Scan(&one) != nil || one != 1 {
+		t.Fatalf("want the row streamed, got %d, %v", one, rows.Err())
+	}
+	if ctx.Err() != nil {
+		t.Fatal("want the context alive while the rows are open")
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("want the context released after rows.Close, got %v", ctx.Err())
+	}
+
+	// scanning the row releases the context of the timeout
+	row := resolver.QueryRow("SELECT 1")
+	ctx = <-connector.ctx
+	if err := row.Scan(&one); err != nil || one != 1 {
+		t.Fatalf("want the row scanned, got %d, %v", one, err)
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Errorf("want the context released after the Scan, got %v", ctx.Err())
+	}
+}
+
+func TestQueryTimeoutNativeRows(t *testing.T) {
+	primary, _, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithReplicaQueryTimeout(time.Hour))
+
+	// the rows of the driver are returned, with their column types and their result sets
+	column := sqlmock.NewColumn("id").OfType("INT8", int64(0)).Nullable(true)
+	replicaMock.ExpectQuery("SELECT id FROM book; SELECT id FROM author").WillReturnRows(
+		sqlmock.NewRowsWithColumnDefinition(column).AddRow(int64(1)),
+		sqlmock.NewRowsWithColumnDefinition(column).AddRow(int64(2)))
+	rows, err := resolver.Query("SELECT id FROM book; SELECT id FROM author")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nullable, _ := types[0].Nullable()
+	if types[0].DatabaseTypeName() != "INT8" || types[0].ScanType() != reflect.TypeOf(int64(0)) || !nullable {
+		t.Errorf("want the column types of the driver, got %s %v %v",
+			types[0].DatabaseTypeName(), types[0].ScanType(), nullable)
+	}
+	var ids []int64
+	for {
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("want the rows of both result sets, got %v", ids)
+	}
+}
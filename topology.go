@@ -0,0 +1,122 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrRoleMismatch is reported for a primary which is read-only, or a replica which is writable
+var ErrRoleMismatch = errors.New("dbresolver: the node role doesn't match its configured role")
+
+// ReadOnlyDetector detects whether a node is read-only, eg. a PostgreSQL standby in recovery
+type ReadOnlyDetector interface {
+	IsReadOnly(ctx context.Context, db *sql.DB) (bool, error)
+}
+
+// ReadOnlyDetectorFunc is a function implementing ReadOnlyDetector
+type ReadOnlyDetectorFunc func(ctx context.Context, db *sql.DB) (bool, error)
+
+// IsReadOnly calls the function
+func (f ReadOnlyDetectorFunc) IsReadOnly(ctx context.Context, db *sql.DB) (bool, error) {
+	return f(ctx, db)
+}
+
+// QueryReadOnlyDetector detects the read-only nodes with a query returning a single boolean
+func QueryReadOnlyDetector(query string) ReadOnlyDetector {
+	return ReadOnlyDetectorFunc(func(ctx context.Context, db *sql.DB) (bool, error) {
+		var readOnly bool
+		err := db.QueryRowContext(ctx, query).Scan(&readOnly)
+		return readOnly, err
+	})
+}
+
+// Read-only detectors of the common databases
+var (
+	// PostgresReadOnlyDetector detects the standbys in recovery, it's the default detector
+	PostgresReadOnlyDetector = QueryReadOnlyDetector("SELECT pg_is_in_recovery()")
+	// MySQLReadOnlyDetector detects the servers with the read_only system variable enabled
+	MySQLReadOnlyDetector = QueryReadOnlyDetector("SELECT @@global.read_only")
+)
+
+// WithReadOnlyDetector sets how DB.ValidateTopology and DB.Repoll detect the read-only nodes,
+// PostgresReadOnlyDetector by default
+func WithReadOnlyDetector(detector ReadOnlyDetector) OptionFunc {
+	return func(opt *Option) {
+		opt.ReadOnlyDetector = detector
+	}
+}
+
+// TopologyReport is the result of DB.ValidateTopology
+type TopologyReport struct {
+	Nodes []NodeReport
+}
+
+// NodeReport is the validation result of a node
+type NodeReport struct {
+	NodeInfo
+	// ReadOnly is the detected state of the node, it's only relevant when Err is nil
+	ReadOnly bool
+	// Err is ErrRoleMismatch when the node doesn't have its configured role,
+	// or the error of the detection, eg. when the node is unreachable
+	Err error
+}
+
+// Err returns the errors of the nodes, nil when the topology is valid
+func (r *TopologyReport) Err() error {
+	failed := 0
+	for _, node := range r.Nodes {
+		if node.Err != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, failed)
+	indexes := map[Role]int{}
+	for _, node := range r.Nodes {
+		if node.Err != nil {
+			errs = append(errs, fmt.Errorf("%s %d: %w", node.Role, indexes[node.Role], node.Err))
+		}
+		indexes[node.Role]++
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateTopology checks concurrently that every primary is writable and every replica is read-only.
+// The report lists every node, the error is the one of the report.
+// The nodes not checked yet when the context is done report the context error.
+func (db *sqlDB) ValidateTopology(ctx context.Context) (*TopologyReport, error) {
+	detector := db.readOnlyDetector
+	if detector == nil {
+		detector = PostgresReadOnlyDetector
+	}
+
+	nodes := db.Nodes()
+	report := &TopologyReport{Nodes: make([]NodeReport, len(nodes))}
+	checked := make([]bool, len(nodes))
+	for i := range nodes {
+		report.Nodes[i].NodeInfo = nodes[i]
+	}
+	err := doParallely(ctx, db.parallelism, len(nodes), func(i int) error {
+		node := &report.Nodes[i]
+		checked[i] = true
+		node.ReadOnly, node.Err = detector.IsReadOnly(ctx, node.DB)
+		if node.Err == nil && node.ReadOnly != (node.Role == RoleReplica) {
+			node.Err = ErrRoleMismatch
+		}
+		return nil
+	})
+	if err != nil {
+		// the nodes not checked before the context was done
+		for i := range report.Nodes {
+			if !checked[i] {
+				report.Nodes[i].Err = err
+			}
+		}
+	}
+	return report, report.Err()
+}
@@ -0,0 +1,132 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestValidateTopology(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	misconfigured, misconfiguredMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	unreachable, unreachableMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	query := "SELECT pg_is_in_recovery()"
+	recovery := func(inRecovery bool) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"pg_is_in_recovery"}).AddRow(inRecovery)
+	}
+	primaryMock.ExpectQuery(query).WillReturnRows(recovery(false))
+	replicaMock.ExpectQuery(query).WillReturnRows(recovery(true))
+	misconfiguredMock.ExpectQuery(query).WillReturnRows(recovery(false))
+	unreachableMock.ExpectQuery(query).WillReturnError(errors.New("connection refused"))
+
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica, misconfigured, unreachable))
+	report, err := resolver.ValidateTopology(context.Background())
+	if err == nil {
+		t.Fatal("want the topology error")
+	}
+	if len(report.Nodes) != 4 {
+		t.Fatalf("want 4 nodes, got %+v", report.Nodes)
+	}
+	if report.Nodes[0].Err != nil || report.Nodes[1].Err != nil {
+		t.Errorf("want the primary and the replica valid, got %+v", report.Nodes[:2])
+	}
+	if !errors.Is(report.Nodes[2].Err, ErrRoleMismatch) || report.Nodes[2].ReadOnly {
+		t.Errorf("want the writable replica reported, got %+v", report.Nodes[2])
+	}
+	if report.Nodes[3].Err == nil || errors.Is(report.Nodes[3].Err, ErrRoleMismatch) {
+		t.Errorf("want the unreachable replica reported, got %+v", report.Nodes[3])
+	}
+	if want := "replica 1: " + ErrRoleMismatch.Error(); !errors.Is(err, ErrRoleMismatch) || err.Error()[:len(want)] != want {
+		t.Errorf("want the error of the writable replica first, got %v", err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock, misconfiguredMock, unreachableMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestValidateTopologyDetector(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	primaryMock.ExpectQuery("SELECT @@global.read_only").
+		WillReturnRows(sqlmock.NewRows([]string{"@@global.read_only"}).AddRow(0))
+
+	resolver := New(WithPrimaryDBs(primary), WithReadOnlyDetector(MySQLReadOnlyDetector))
+	if _, err := resolver.ValidateTopology(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateTopologyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	detector := ReadOnlyDetectorFunc(func(ctx context.Context, db *sql.DB) (bool, error) {
+		// the first check cancels the remaining ones
+		cancel()
+		return false, nil
+	})
+
+	resolver := New(WithPrimaryDBs(&sql.DB{}), WithReplicaDBs(&sql.DB{}, &sql.DB{}),
+		WithReadOnlyDetector(detector), WithMaxParallelism(1))
+	report, err := resolver.ValidateTopology(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want the context error, got %v", err)
+	}
+	if report.Nodes[0].Err != nil || !errors.Is(report.Nodes[1].Err, context.Canceled) ||
+		!errors.Is(report.Nodes[2].Err, context.Canceled) {
+		t.Errorf("want the nodes after the cancellation reported, got %+v", report.Nodes)
+	}
+}
+
+func TestConnectOptions(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	// the options are applied once
+	applied := 0
+	counted := func(*Option) { applied++ }
+	primaryMock.ExpectQuery("SELECT pg_is_in_recovery()").WillReturnError(errors.New("unreachable"))
+	if _, err := Connect(context.Background(), WithPrimaryDBs(primary), WithStartupCheck(time.Second), counted); err == nil {
+		t.Fatal("want the startup check failed")
+	}
+	if applied != 1 {
+		t.Errorf("want the options applied once, got %d", applied)
+	}
+
+	// the failed resolver closes what it opened, and leaves the configured nodes open
+	resolver, _ := newResolver([]OptionFunc{WithPrimaryDBs(primary), WithStmtCache(8)})
+	resolver.stop()
+	if err := resolver.results.Ping(); err == nil {
+		t.Error("want the results closed")
+	}
+	primaryMock.ExpectPing()
+	if err := primary.Ping(); err != nil {
+		t.Errorf("want the configured node open, got %v", err)
+	}
+}
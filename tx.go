@@ -3,6 +3,7 @@ package dbresolver
 import (
 	"context"
 	"database/sql"
+	"errors"
 )
 
 // Tx is a *sql.Tx wrapper.
@@ -25,10 +26,23 @@ type Tx interface {
 type tx struct {
 	sourceDB *sql.DB
 	tx       *sql.Tx
+	// route is the route of the queries of the transaction, a replica for the read-only transactions
+	route Route
+	hooks []Hooks
+	// onCommit is called after a successful commit, nil when nothing tracks the commit
+	onCommit func()
+	// results serves the rows of the statements which couldn't be prepared in the transaction
+	results *sql.DB
 }
 
 func (t *tx) Commit() error {
-	return t.tx.Commit()
+	if err := t.tx.Commit(); err != nil {
+		return err
+	}
+	if t.onCommit != nil {
+		t.onCommit()
+	}
+	return nil
 }
 
 func (t *tx) Rollback() error {
@@ -40,7 +54,9 @@ func (t *tx) Exec(query string, args ...interface{}) (sql.Result, error) {
 }
 
 func (t *tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return t.tx.ExecContext(ctx, query, args...)
+	return execWithHooks(ctx, t.hooks, t.route.to(t.sourceDB), query, args, func(ctx context.Context) (sql.Result, error) {
+		return t.tx.ExecContext(ctx, query, args...)
+	})
 }
 
 func (t *tx) Prepare(query string) (Stmt, error) {
@@ -53,7 +69,7 @@ func (t *tx) PrepareContext(ctx context.Context, query string) (Stmt, error) {
 		return nil, err
 	}
 
-	return newSingleDBStmt(t.sourceDB, txstmt, true), nil
+	return newSingleDBStmt(t.sourceDB, txstmt, true, query, t.hooks, nil), nil
 }
 
 func (t *tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
@@ -61,7 +77,9 @@ func (t *tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
 }
 
 func (t *tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return t.tx.QueryContext(ctx, query, args...)
+	return queryWithHooks(ctx, t.hooks, t.route.to(t.sourceDB), query, args, func(ctx context.Context) (*sql.Rows, error) {
+		return t.tx.QueryContext(ctx, query, args...)
+	})
 }
 
 func (t *tx) QueryRow(query string, args ...interface{}) *sql.Row {
@@ -69,16 +87,54 @@ func (t *tx) QueryRow(query string, args ...interface{}) *sql.Row {
 }
 
 func (t *tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return t.tx.QueryRowContext(ctx, query, args...)
+	return queryRowWithHooks(ctx, t.hooks, t.route.to(t.sourceDB), query, args, func(ctx context.Context) *sql.Row {
+		return t.tx.QueryRowContext(ctx, query, args...)
+	})
 }
 
 func (t *tx) Stmt(s Stmt) Stmt {
 	return t.StmtContext(context.Background(), s)
 }
 
+// StmtContext returns the statement bound to the transaction, it runs on the DB of the transaction.
+// The statements prepared on the DB of the transaction are bound to it, the other ones are prepared again
+// in the transaction, eg. a statement prepared before the primary of the transaction was added,
+// or prepared on a connection of another DB. The executions of a statement failing to prepare return the error.
 func (t *tx) StmtContext(ctx context.Context, s Stmt) Stmt {
-	if rstmt, ok := s.(*stmt); ok {
-		return newSingleDBStmt(t.sourceDB, t.tx.StmtContext(ctx, rstmt.stmtForDB(t.sourceDB)), true)
+	var rstmt *stmt
+	switch st := s.(type) {
+	case *stmt:
+		rstmt = st
+	case *asyncStmt:
+		rstmt = st.stmt
+	case *lazyStmt:
+		return t.lazyStmtContext(ctx, st)
+	default:
+		return s
+	}
+	if dbStmt, ok := rstmt.dbStmt[t.sourceDB]; ok {
+		return newSingleDBStmt(t.sourceDB, t.tx.StmtContext(ctx, dbStmt), true, rstmt.query, t.hooks, nil)
+	}
+	txStmt, err := t.tx.PrepareContext(ctx, rstmt.query)
+	if err != nil {
+		return &failedStmt{err: err, results: t.results}
+	}
+	return newSingleDBStmt(t.sourceDB, txStmt, true, rstmt.query, t.hooks, nil)
+}
+
+// lazyStmtContext binds the lazy statement to the transaction, it's prepared on the DB of the transaction
+// when it isn't yet, or in the transaction when it can't be
+func (t *tx) lazyStmtContext(ctx context.Context, s *lazyStmt) Stmt {
+	dbStmt, err := s.stmtFor(ctx, t.sourceDB)
+	if err == nil {
+		return newSingleDBStmt(t.sourceDB, t.tx.StmtContext(ctx, dbStmt), true, s.query, t.hooks, nil)
+	}
+	if errors.Is(err, errLazyStmtClosed) {
+		return &failedStmt{err: err, results: s.db.results}
+	}
+	txStmt, err := t.tx.PrepareContext(ctx, s.query)
+	if err != nil {
+		return &failedStmt{err: err, results: s.db.results}
 	}
-	return s
+	return newSingleDBStmt(t.sourceDB, txStmt, true, s.query, t.hooks, nil)
 }
@@ -0,0 +1,168 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestTxStmt(t *testing.T) {
+	primary1, primary1Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	primary2, primary2Mock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	resolver := New(WithPrimaryDBs(primary1))
+	ctx := context.Background()
+
+	query := "UPDATE users SET name = $1"
+	primary1Mock.ExpectPrepare(query)
+	stmt, err := resolver.PrepareContext(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the transaction runs on a primary added after the preparation, the statement is prepared in the transaction
+	resolver.AddPrimary(primary2)
+	if err := resolver.RemovePrimary(primary1); err != nil {
+		t.Fatal(err)
+	}
+	primary2Mock.ExpectBegin()
+	primary2Mock.ExpectPrepare(query).ExpectExec().WithArgs("foo").WillReturnResult(sqlmock.NewResult(0, 1))
+	primary2Mock.ExpectCommit()
+	tx, err := resolver.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.StmtContext(ctx, stmt).ExecContext(ctx, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the statements prepared by a transaction are bound to the other transactions of its DB
+	primary2Mock.ExpectBegin()
+	primary2Mock.ExpectPrepare(query)
+	primary2Mock.ExpectCommit()
+	primary2Mock.ExpectBegin()
+	primary2Mock.ExpectPrepare(query).ExpectExec().WithArgs("bar").WillReturnResult(sqlmock.NewResult(0, 1))
+	tx1, err := resolver.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txStmt, err := tx1.PrepareContext(ctx, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx1.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	tx2, err := resolver.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx2.Stmt(txStmt).Exec("bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the executions of a statement failing to prepare in the transaction return the error
+	prepareErr := errors.New("syntax error")
+	primary2Mock.ExpectPrepare(query).WillReturnError(prepareErr)
+	failed := tx2.StmtContext(ctx, stmt)
+	if _, err := failed.ExecContext(ctx, "baz"); !errors.Is(err, prepareErr) {
+		t.Errorf("want the prepare error, got %v", err)
+	}
+	if err := failed.QueryRowContext(ctx, "baz").Scan(new(int)); !errors.Is(err, prepareErr) {
+		t.Errorf("want the row holding the prepare error, got %v", err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primary1Mock, primary2Mock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestReadOnlyTx(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica, replicaMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	logger := &recordingLogger{}
+	resolver := New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithLogger(logger))
+	ctx := context.Background()
+	readOnly := &sql.TxOptions{ReadOnly: true}
+
+	// the read-only transactions run on a replica
+	replicaMock.ExpectBegin()
+	replicaMock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"1"}).AddRow(1))
+	replicaMock.ExpectCommit()
+	tx, err := resolver.BeginTx(ctx, readOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var one int
+	if err := tx.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.entries) != 1 || !strings.Contains(logger.entries[0], "{role replica}") {
+		t.Errorf("want the query of the transaction routed to the replica, got %q", logger.entries)
+	}
+
+	// the context forces the primary
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectRollback()
+	tx, err = resolver.BeginTx(WithPrimary(ctx), readOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unreachable replica falls back to the primary
+	replicaMock.ExpectBegin().WillReturnError(&net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectRollback()
+	tx, err = resolver.BeginTx(ctx, readOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// opted out, they run on the primary
+	resolver = New(WithPrimaryDBs(primary), WithReplicaDBs(replica), WithReadOnlyTxOnPrimary())
+	primaryMock.ExpectBegin()
+	primaryMock.ExpectRollback()
+	tx, err = resolver.BeginTx(ctx, readOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package dbresolver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// MaxNodeWeight is the highest weight of a node, it bounds the rotation of the load balancers
+const MaxNodeWeight = 100
+
+// WithReplicaWeights sets the share of the read queries sent to each replica relatively to the other replicas,
+// the replicas without a weight have a weight of 1. The load balancers resolve the replicas from a rotation
+// interleaving them by weight, eg. with {replica1: 3, replica2: 1} replica1 gets 3 out of every 4 queries.
+// The weights are between 0 and MaxNodeWeight, a replica of weight 0 is out of rotation, see SetNodeWeight.
+// It can be used multiple times, the weights are merged.
+func WithReplicaWeights(weights map[*sql.DB]int) OptionFunc {
+	return func(opt *Option) {
+		if opt.ReplicaWeights == nil {
+			opt.ReplicaWeights = make(map[*sql.DB]int, len(weights))
+		}
+		for replica, weight := range weights {
+			if err := validateWeight(weight); err != nil {
+				panic(fmt.Sprintf("dbresolver: invalid replica weight %d, %v", weight, err))
+			}
+			opt.ReplicaWeights[replica] = weight
+		}
+	}
+}
+
+// WithPrimaryWeights sets the share of the queries sent to each primary relatively to the other primaries,
+// like WithReplicaWeights for the replicas
+func WithPrimaryWeights(weights map[*sql.DB]int) OptionFunc {
+	return func(opt *Option) {
+		if opt.PrimaryWeights == nil {
+			opt.PrimaryWeights = make(map[*sql.DB]int, len(weights))
+		}
+		for primary, weight := range weights {
+			if err := validateWeight(weight); err != nil {
+				panic(fmt.Sprintf("dbresolver: invalid primary weight %d, %v", weight, err))
+			}
+			opt.PrimaryWeights[primary] = weight
+		}
+	}
+}
+
+// WeightedDB is a node with its weight, see Weighted
+type WeightedDB struct {
+	DB     *sql.DB
+	Weight int
+}
+
+// Weighted returns the node with its weight, eg. WithWeightedReplicaDBs(Weighted(db1, 3), Weighted(db2, 1))
+func Weighted(db *sql.DB, weight int) WeightedDB {
+	return WeightedDB{DB: db, Weight: weight}
+}
+
+// WithWeightedPrimaryDBs adds the primaries to the resolver with their weights, see WithPrimaryWeights
+func WithWeightedPrimaryDBs(primaries ...WeightedDB) OptionFunc {
+	dbs, weights := splitWeighted(primaries)
+	return func(opt *Option) {
+		WithPrimaryDBs(dbs...)(opt)
+		WithPrimaryWeights(weights)(opt)
+	}
+}
+
+// WithWeightedReplicaDBs adds the replicas to the resolver with their weights, see WithReplicaWeights
+func WithWeightedReplicaDBs(replicas ...WeightedDB) OptionFunc {
+	dbs, weights := splitWeighted(replicas)
+	return func(opt *Option) {
+		WithReplicaDBs(dbs...)(opt)
+		WithReplicaWeights(weights)(opt)
+	}
+}
+
+func splitWeighted(nodes []WeightedDB) ([]*sql.DB, map[*sql.DB]int) {
+	dbs := make([]*sql.DB, len(nodes))
+	weights := make(map[*sql.DB]int, len(nodes))
+	for i, node := range nodes {
+		dbs[i], weights[node.DB] = node.DB, node.Weight
+	}
+	return dbs, weights
+}
+
+// SetNodeWeight changes the weight of the node in each of its roles at runtime, eg. to drain a node gradually
+// by lowering its weight, the nodes of the shards included. The weights are between 0 and MaxNodeWeight.
+// A node of weight 0 is out of rotation but stays in the topology: it's checked, reported by Nodes and Stats,
+// and its role keeps it in rotation when every node of the role has a weight of 0, eg. a single primary.
+// The prepared statements keep the weights they were prepared with.
+func (db *sqlDB) SetNodeWeight(node *sql.DB, weight int) error {
+	if err := validateWeight(weight); err != nil {
+		return fmt.Errorf("dbresolver: invalid weight %d, %w", weight, err)
+	}
+	db.topologyLock.Lock()
+	defer db.topologyLock.Unlock()
+
+	set := *db.nodes.Load()
+	isPrimary, isReplica := containsDB(set.primaries, node), containsDB(set.replicas, node)
+	if !isPrimary && !isReplica {
+		for _, name := range db.shardNames {
+			if err := db.shards[name].SetNodeWeight(node, weight); err == nil {
+				return nil
+			}
+		}
+		return errors.New("dbresolver: the node isn't in the topology")
+	}
+	if isPrimary {
+		set.primaryWeights = withWeight(set.primaryWeights, node, weight)
+	}
+	if isReplica {
+		set.replicaWeights = withWeight(set.replicaWeights, node, weight)
+	}
+	db.storeNodes(set)
+	return nil
+}
+
+// validateWeight checks that the weight is between 0 and MaxNodeWeight
+func validateWeight(weight int) error {
+	if weight < 0 || weight > MaxNodeWeight {
+		return fmt.Errorf("the weights must be between 0 and %d", MaxNodeWeight)
+	}
+	return nil
+}
+
+// withWeight returns a copy of the weights with the weight of the node, the weights are copied on write
+func withWeight(weights map[*sql.DB]int, node *sql.DB, weight int) map[*sql.DB]int {
+	res := make(map[*sql.DB]int, len(weights)+1)
+	for d, w := range weights {
+		res[d] = w
+	}
+	if weight == 1 {
+		delete(res, node)
+	} else {
+		res[node] = weight
+	}
+	return res
+}
+
+func containsDB(dbs []*sql.DB, node *sql.DB) bool {
+	for _, d := range dbs {
+		if d == node {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeWeight returns the weight of the node, 1 by default
+func nodeWeight(weights map[*sql.DB]int, node *sql.DB) int {
+	if weight, ok := weights[node]; ok {
+		return weight
+	}
+	return 1
+}
+
+// weightedRotation returns the rotation of the nodes, each node appearing as many times as its weight
+// divided by the GCD of the weights, so the rotation holds at most MaxNodeWeight times the nodes.
+// The nodes are interleaved with the smooth weighted round-robin of nginx, eg. a a b a for the weights 3 and 1.
+// The nodes of weight 0 are skipped, the nodes are returned as is when they have the same weight,
+// or when they all have a weight of 0.
+func weightedRotation[T any](nodes []T, weight func(i int) int) []T {
+	weights := make([]int, len(nodes))
+	divisor, total, drained := 0, 0, 0
+	for i := range nodes {
+		weights[i] = weight(i)
+		divisor = gcd(divisor, weights[i])
+		if weights[i] == 0 {
+			drained++
+		}
+	}
+	if divisor == 0 {
+		return nodes
+	}
+	for i := range weights {
+		weights[i] /= divisor
+		total += weights[i]
+	}
+	if total == len(nodes) && drained == 0 {
+		return nodes
+	}
+
+	rotation := make([]T, 0, total)
+	current := make([]int, len(nodes))
+	for len(rotation) < total {
+		best := -1
+		for i := range nodes {
+			if weights[i] == 0 {
+				continue
+			}
+			current[i] += weights[i]
+			if best == -1 || current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		rotation = append(rotation, nodes[best])
+	}
+	return rotation
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
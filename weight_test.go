@@ -0,0 +1,181 @@
+package dbresolver
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestWeightedRotation(t *testing.T) {
+	tests := []struct {
+		weights []int
+		want    string
+	}{
+		{weights: []int{1, 1, 1}, want: "[0 1 2]"},
+		{weights: []int{2, 2}, want: "[0 1]"},
+		{weights: []int{3, 1}, want: "[0 0 1 0]"},
+		{weights: []int{5, 1, 1}, want: "[0 0 1 0 2 0 0]"},
+		{weights: []int{60, 40}, want: "[0 1 0 1 0]"},
+		{weights: []int{0, 2, 1}, want: "[1 2 1]"},
+		{weights: []int{0, 1, 1}, want: "[1 2]"},
+		{weights: []int{0, 0}, want: "[0 1]"},
+		{weights: []int{}, want: "[]"},
+	}
+	for _, tt := range tests {
+		nodes := make([]int, len(tt.weights))
+		for i := range nodes {
+			nodes[i] = i
+		}
+		got := weightedRotation(nodes, func(i int) int { return tt.weights[i] })
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%v: want %s, got %v", tt.weights, tt.want, got)
+		}
+	}
+}
+
+func TestReplicaWeights(t *testing.T) {
+	primary, primaryMock, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica1, replicaMock1, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+	replica2, replicaMock2, err := createMock()
+	if err != nil {
+		t.Fatal("creating of mock failed")
+	}
+
+	resolver := New(
+		WithPrimaryDBs(primary),
+		WithReplicaDBs(replica1, replica2),
+		WithReplicaWeights(map[*sql.DB]int{replica1: 3}),
+	)
+
+	counts := map[*sql.DB]int{}
+	for i := 0; i < 40; i++ {
+		counts[resolver.ReadOnly()]++
+	}
+	if counts[replica1] != 30 || counts[replica2] != 10 {
+		t.Errorf("want 30 and 10 queries, got %d and %d", counts[replica1], counts[replica2])
+	}
+
+	if replicas := resolver.ReplicaDBs(); len(replicas) != 2 {
+		t.Errorf("want the 2 replicas, got %v", replicas)
+	}
+	nodes := resolver.Nodes()
+	if nodes[0].Weight != 1 || nodes[1].Weight != 3 || nodes[2].Weight != 1 {
+		t.Errorf("want the weights of the nodes, got %+v", nodes)
+	}
+
+	primaryMock.ExpectPrepare("SELECT 1")
+	replicaMock1.ExpectPrepare("SELECT 1")
+	replicaMock2.ExpectPrepare("SELECT 1")
+	st, err := resolver.Prepare("SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicaStmts := st.(*stmt).replicaStmts; len(replicaStmts) != 4 {
+		t.Errorf("want the statements repeated by weight, got %d", len(replicaStmts))
+	}
+
+	// the weights are kept when the topology changes
+	resolver.RemoveReplica(replica1)
+	resolver.AddReplica(replica1)
+	if nodes := resolver.Nodes(); nodes[2].DB != replica1 || nodes[2].Weight != 3 {
+		t.Errorf("want the weight of the added replica, got %+v", nodes)
+	}
+}
+
+func TestReplicaWeightsInvalid(t *testing.T) {
+	for _, weight := range []int{-1, MaxNodeWeight + 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want a panic for the weight %d", weight)
+				}
+			}()
+			WithReplicaWeights(map[*sql.DB]int{{}: weight})(&Option{})
+		}()
+	}
+}
+
+func TestWeightedDBs(t *testing.T) {
+	nodes := make([]*sql.DB, 4)
+	for i := range nodes {
+		node, _, err := createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+		nodes[i] = node
+	}
+	primary1, primary2, replica1, replica2 := nodes[0], nodes[1], nodes[2], nodes[3]
+	resolver := New(
+		WithWeightedPrimaryDBs(Weighted(primary1, 1), Weighted(primary2, 3)),
+		WithWeightedReplicaDBs(Weighted(replica1, 3), Weighted(replica2, 1)),
+	)
+
+	counts := map[*sql.DB]int{}
+	for i := 0; i < 40; i++ {
+		counts[resolver.ReadWrite()]++
+	}
+	for i := 0; i < 40; i++ {
+		counts[resolver.ReadOnly()]++
+	}
+	if counts[primary1] != 10 || counts[primary2] != 30 || counts[replica1] != 30 || counts[replica2] != 10 {
+		t.Errorf("want the queries shared by weight, got %v", counts)
+	}
+
+	// the replica is drained gradually at runtime
+	if err := resolver.SetNodeWeight(replica2, 9); err != nil {
+		t.Fatal(err)
+	}
+	counts = map[*sql.DB]int{}
+	for i := 0; i < 40; i++ {
+		counts[resolver.ReadOnly()]++
+	}
+	if counts[replica1] != 10 || counts[replica2] != 30 {
+		t.Errorf("want the queries shared by the new weights, got %v", counts)
+	}
+	if nodes := resolver.Nodes(); nodes[1].Weight != 3 || nodes[3].Weight != 9 {
+		t.Errorf("want the weights of the nodes, got %+v", nodes)
+	}
+
+	// a node of weight 0 is out of rotation, but stays in the topology
+	if err := resolver.SetNodeWeight(replica2, 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if resolver.ReadOnly() != replica1 {
+			t.Fatal("want the drained replica out of rotation")
+		}
+	}
+	if nodes := resolver.Nodes(); len(nodes) != 4 || nodes[3].Weight != 0 {
+		t.Errorf("want the drained replica in the nodes, got %+v", nodes)
+	}
+	// the nodes of a role are all in rotation when they're all drained
+	if err := resolver.SetNodeWeight(primary1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := resolver.SetNodeWeight(primary2, 0); err != nil {
+		t.Fatal(err)
+	}
+	counts = map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		counts[resolver.ReadWrite()]++
+	}
+	if counts[primary1] != 2 || counts[primary2] != 2 {
+		t.Errorf("want the writes on the drained primaries, got %v", counts)
+	}
+
+	if err := resolver.SetNodeWeight(replica2, -1); err == nil {
+		t.Error("want an error for a negative weight")
+	}
+	if err := resolver.SetNodeWeight(replica2, MaxNodeWeight+1); err == nil {
+		t.Error("want an error for a weight above MaxNodeWeight")
+	}
+	if err := resolver.SetNodeWeight(&sql.DB{}, 2); err == nil {
+		t.Error("want an error for a node out of the topology")
+	}
+}
@@ -0,0 +1,93 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WritePolicy define how the writes are sent to the primaries
+type WritePolicy string
+
+// Supported write policies
+const (
+	// WriteLoadBalanced sends each write to a primary resolved by the load balancer, for multi-master replication
+	WriteLoadBalanced WritePolicy = "LOAD_BALANCED"
+	// WriteFanOut sends each write to every primary, it fails when a primary fails
+	WriteFanOut WritePolicy = "FAN_OUT"
+	// WriteQuorum sends each write to every primary, it fails when less than the quorum succeed
+	WriteQuorum WritePolicy = "QUORUM"
+	// WriteFailover sends the writes to the first primary in order, then to the next ones
+	// when it's unreachable, for the active-passive setups
+	WriteFailover WritePolicy = "FAILOVER"
+)
+
+// WriteStrategy define how the writes are sent to the primaries, see WithWriteStrategy
+type WriteStrategy struct {
+	Policy WritePolicy
+	// Quorum is the number of primaries a write must succeed on with WriteQuorum, a majority of the primaries by default
+	Quorum int
+}
+
+// WithWriteStrategy sets how ExecContext sends the writes to the primaries, WriteLoadBalanced by default.
+//
+// WriteFanOut and WriteQuorum execute the write on every primary concurrently, like ExecAllPrimaries,
+// and return an *ExecAllResult; they aren't retried by the retry policy, the write succeeded on some primaries.
+// WriteFailover resolves the primary of every query, transaction and connection to the first healthy primary
+// in the order of WithPrimaryDBs, and ExecContext tries the next ones when it's unreachable.
+// The write queries sent with Query and QueryRow, the transactions and the connections use a single primary.
+func WithWriteStrategy(strategy WriteStrategy) OptionFunc {
+	switch strategy.Policy {
+	case "", WriteLoadBalanced, WriteFanOut, WriteQuorum, WriteFailover:
+	default:
+		panic(fmt.Sprintf("dbresolver: invalid write policy %q", strategy.Policy))
+	}
+	if strategy.Quorum < 0 {
+		panic(fmt.Sprintf("dbresolver: invalid write quorum %d, the quorum must be positive", strategy.Quorum))
+	}
+	return func(opt *Option) {
+		opt.WriteStrategy = strategy
+	}
+}
+
+// fansOut reports whether the writes are executed on every primary
+func (s WriteStrategy) fansOut() bool {
+	return s.Policy == WriteFanOut || s.Policy == WriteQuorum
+}
+
+// execFanOut executes the write on every primary, with the quorum of WriteQuorum
+func (db *sqlDB) execFanOut(ctx context.Context, query string, args []interface{}) (sql.Result, error) {
+	res, err := db.ExecAllPrimaries(ctx, nil, query, args...)
+	if err == nil || db.writeStrategy.Policy != WriteQuorum {
+		return res, err
+	}
+	quorum := db.writeStrategy.Quorum
+	if quorum == 0 {
+		quorum = len(res.Primaries)/2 + 1
+	}
+	succeeded := 0
+	for _, primary := range res.Primaries {
+		if primary.Err == nil {
+			succeeded++
+		}
+	}
+	if succeeded >= quorum {
+		return res, nil
+	}
+	return res, fmt.Errorf("dbresolver: the write succeeded on %d primaries out of %d, the quorum is %d: %w",
+		succeeded, len(res.Primaries), quorum, err)
+}
+
+// execFailover executes the write on the first healthy primary, then on the next ones when it's unreachable
+func (db *sqlDB) execFailover(ctx context.Context, query string, args []interface{}) (res sql.Result, err error) {
+	for _, primary := range db.healthyPrimaries(db.nodes.Load().primaries) {
+		res, err = execWithHooks(ctx, db.hooks, primaryRoute.to(primary), query, args,
+			func(ctx context.Context) (sql.Result, error) {
+				return db.timedExecContext(ctx, primaryRoute, primary, query, args)
+			})
+		if err = db.nodeError(primary, err); !db.isConnectionError(err) {
+			return res, err
+		}
+	}
+	return res, err
+}
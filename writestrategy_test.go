@@ -0,0 +1,91 @@
+package dbresolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWriteStrategy(t *testing.T) {
+	primaries := make([]*sql.DB, 3)
+	mocks := make([]sqlmock.Sqlmock, 3)
+	for i := range primaries {
+		var err error
+		primaries[i], mocks[i], err = createMock()
+		if err != nil {
+			t.Fatal("creating of mock failed")
+		}
+	}
+	ctx := context.Background()
+	query := "UPDATE book SET title = $1"
+
+	// the fan-out fails when a primary fails
+	resolver := New(WithPrimaryDBs(primaries...), WithWriteStrategy(WriteStrategy{Policy: WriteFanOut}))
+	mocks[0].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	mocks[1].ExpectExec(query).WillReturnError(errors.New("lock timeout"))
+	mocks[2].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	res, err := resolver.ExecContext(ctx, query, "Dune")
+	if err == nil || !strings.Contains(err.Error(), "primary 1: lock timeout") {
+		t.Errorf("want the error of the failed primary, got %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("want the rows affected on every primary, got %d", n)
+	}
+
+	// the quorum is a majority by default
+	resolver = New(WithPrimaryDBs(primaries...), WithWriteStrategy(WriteStrategy{Policy: WriteQuorum}))
+	mocks[0].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	mocks[1].ExpectExec(query).WillReturnError(errors.New("lock timeout"))
+	mocks[2].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.ExecContext(ctx, query, "Dune"); err != nil {
+		t.Errorf("want the write succeeding on 2 primaries out of 3, got %v", err)
+	}
+	mocks[0].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	mocks[1].ExpectExec(query).WillReturnError(errors.New("lock timeout"))
+	mocks[2].ExpectExec(query).WillReturnError(errors.New("lock timeout"))
+	if _, err := resolver.ExecContext(ctx, query, "Dune"); err == nil ||
+		!strings.Contains(err.Error(), "the write succeeded on 1 primaries out of 3, the quorum is 2") {
+		t.Errorf("want the quorum missed, got %v", err)
+	}
+
+	// the failover writes to the first primary, then to the next one when it's unreachable
+	resolver = New(WithPrimaryDBs(primaries...), WithWriteStrategy(WriteStrategy{Policy: WriteFailover}))
+	for i := 0; i < 2; i++ {
+		if resolver.ReadWrite() != primaries[0] {
+			t.Error("want the first primary")
+		}
+	}
+	mocks[0].ExpectExec(query).WillReturnError(&net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	mocks[1].ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := resolver.ExecContext(ctx, query, "Dune"); err != nil {
+		t.Errorf("want the write on the next primary, got %v", err)
+	}
+	mocks[0].ExpectExec(query).WillReturnError(errors.New("lock timeout"))
+	if _, err := resolver.ExecContext(ctx, query, "Dune"); err == nil {
+		t.Error("want the error of the reachable primary")
+	}
+
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWithWriteStrategyPanics(t *testing.T) {
+	for _, strategy := range []WriteStrategy{{Policy: "ACTIVE_ACTIVE"}, {Policy: WriteQuorum, Quorum: -1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want a panic for %+v", strategy)
+				}
+			}()
+			WithWriteStrategy(strategy)
+		}()
+	}
+}
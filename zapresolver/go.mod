@@ -0,0 +1,10 @@
+module github.com/bxcodec/dbresolver/v2/zapresolver
+
+go 1.22
+
+require (
+	github.com/bxcodec/dbresolver/v2 v2.2.1
+	go.uber.org/zap v1.27.0
+)
+
+require go.uber.org/multierr v1.11.0 // indirect
@@ -0,0 +1,50 @@
+// Package zapresolver logs the queries and the rotation changes of the resolver with zap.
+package zapresolver
+
+import (
+	"context"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logger adapts a *zap.Logger to dbresolver.Logger
+type Logger struct {
+	logger *zap.Logger
+}
+
+var _ dbresolver.Logger = (*Logger)(nil)
+
+// New adapts the zap logger, attached to the resolver with dbresolver.WithLogger:
+//
+//	db := dbresolver.New(dbresolver.WithPrimaryDBs(primary), dbresolver.WithLogger(zapresolver.New(logger)))
+func New(logger *zap.Logger) *Logger {
+	return &Logger{logger: logger}
+}
+
+// Log logs the entry at the zap level of the resolver level
+func (l *Logger) Log(_ context.Context, level dbresolver.LogLevel, msg string, fields ...dbresolver.LogField) {
+	zapLevel := zapLevel(level)
+	if !l.logger.Core().Enabled(zapLevel) {
+		return
+	}
+	zapFields := make([]zap.Field, len(fields))
+	for i, field := range fields {
+		zapFields[i] = zap.Any(field.Key, field.Value)
+	}
+	l.logger.Log(zapLevel, msg, zapFields...)
+}
+
+func zapLevel(level dbresolver.LogLevel) zapcore.Level {
+	switch {
+	case level >= dbresolver.LogLevelError:
+		return zapcore.ErrorLevel
+	case level >= dbresolver.LogLevelWarn:
+		return zapcore.WarnLevel
+	case level >= dbresolver.LogLevelInfo:
+		return zapcore.InfoLevel
+	default:
+		return zapcore.DebugLevel
+	}
+}
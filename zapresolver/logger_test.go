@@ -0,0 +1,36 @@
+package zapresolver_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bxcodec/dbresolver/v2"
+	"github.com/bxcodec/dbresolver/v2/zapresolver"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zapresolver.New(zap.New(core))
+
+	logger.Log(context.Background(), dbresolver.LogLevelDebug, "dbresolver: query")
+	logger.Log(context.Background(), dbresolver.LogLevelError, "dbresolver: query failed",
+		dbresolver.LogField{Key: dbresolver.LogFieldRole, Value: "primary"},
+		dbresolver.LogField{Key: dbresolver.LogFieldLatency, Value: time.Second})
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("want the entries above the level only, got %v", entries)
+	}
+	entry := entries[0]
+	if entry.Level != zapcore.ErrorLevel || entry.Message != "dbresolver: query failed" {
+		t.Errorf("want the failed query at the error level, got %v", entry)
+	}
+	fields := entry.ContextMap()
+	if fields[dbresolver.LogFieldRole] != "primary" || fields[dbresolver.LogFieldLatency] != time.Second {
+		t.Errorf("want the fields of the entry, got %v", fields)
+	}
+}